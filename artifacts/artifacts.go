@@ -0,0 +1,196 @@
+// Package artifacts stores large run outputs (time series, per-key stats,
+// operation timelines) as zstd-compressed, chunked JSON Lines files described
+// by an index, so exhaustive campaigns do not produce multi-gigabyte blobs.
+package artifacts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// IndexFile is the name of the index written to the artifact directory.
+const IndexFile = "index.json"
+
+// DefaultMaxChunkBytes is the uncompressed size at which a chunk is rotated.
+const DefaultMaxChunkBytes = 64 << 20
+
+// Index describes every artifact in a directory.
+type Index struct {
+	CreatedAt time.Time  `json:"created_at"`
+	Artifacts []Artifact `json:"artifacts"`
+}
+
+// Artifact is a named stream of records split over one or more chunks.
+type Artifact struct {
+	Name    string  `json:"name"`
+	Kind    string  `json:"kind"`
+	Records int64   `json:"records"`
+	Chunks  []Chunk `json:"chunks"`
+}
+
+// Chunk is one compressed JSON Lines file.
+type Chunk struct {
+	File            string `json:"file"`
+	Records         int64  `json:"records"`
+	RawBytes        int64  `json:"raw_bytes"`
+	CompressedBytes int64  `json:"compressed_bytes"`
+}
+
+// Manager creates artifacts in a directory and maintains its index.
+type Manager struct {
+	dir           string
+	maxChunkBytes int64
+	mu            sync.Mutex
+	index         Index
+}
+
+// NewManager creates dir if needed. maxChunkBytes <= 0 selects DefaultMaxChunkBytes.
+func NewManager(dir string, maxChunkBytes int64) (*Manager, error) {
+	if maxChunkBytes <= 0 {
+		maxChunkBytes = DefaultMaxChunkBytes
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Manager{dir: dir, maxChunkBytes: maxChunkBytes, index: Index{CreatedAt: time.Now()}}, nil
+}
+
+// Create starts a new artifact. name may contain '/' to group artifacts.
+func (m *Manager) Create(name, kind string) (*Writer, error) {
+	w := &Writer{m: m, artifact: Artifact{Name: name, Kind: kind}, base: sanitize(name)}
+	if err := w.rotate(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Close writes the index. Writers must be closed first.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := json.MarshalIndent(m.index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(m.dir, IndexFile), data, 0o644)
+}
+
+// ReadIndex loads the index of an artifact directory.
+func ReadIndex(dir string) (Index, error) {
+	var idx Index
+	data, err := os.ReadFile(filepath.Join(dir, IndexFile))
+	if err != nil {
+		return idx, err
+	}
+	return idx, json.Unmarshal(data, &idx)
+}
+
+// Writer appends JSON records to an artifact, rotating chunks by size.
+type Writer struct {
+	m        *Manager
+	artifact Artifact
+	base     string
+	file     *os.File
+	counter  *countingWriter
+	enc      *zstd.Encoder
+	chunk    Chunk
+}
+
+// Write appends one record as a JSON line.
+func (w *Writer) Write(record any) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if w.chunk.RawBytes > 0 && w.chunk.RawBytes+int64(len(line)) > w.m.maxChunkBytes {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	if _, err := w.enc.Write(line); err != nil {
+		return err
+	}
+	w.chunk.Records++
+	w.chunk.RawBytes += int64(len(line))
+	return nil
+}
+
+// Close finishes the last chunk and records the artifact in the index.
+func (w *Writer) Close() error {
+	if err := w.finishChunk(); err != nil {
+		return err
+	}
+	w.m.mu.Lock()
+	w.m.index.Artifacts = append(w.m.index.Artifacts, w.artifact)
+	w.m.mu.Unlock()
+	return nil
+}
+
+func (w *Writer) rotate() error {
+	if err := w.finishChunk(); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s.%04d.jsonl.zst", w.base, len(w.artifact.Chunks))
+	f, err := os.Create(filepath.Join(w.m.dir, name))
+	if err != nil {
+		return err
+	}
+	w.file = f
+	w.counter = &countingWriter{w: f}
+	w.enc, err = zstd.NewWriter(w.counter)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.chunk = Chunk{File: name}
+	return nil
+}
+
+func (w *Writer) finishChunk() error {
+	if w.file == nil {
+		return nil
+	}
+	if err := w.enc.Close(); err != nil {
+		return err
+	}
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.chunk.CompressedBytes = w.counter.n
+	w.artifact.Chunks = append(w.artifact.Chunks, w.chunk)
+	w.artifact.Records += w.chunk.Records
+	w.file = nil
+	return nil
+}
+
+type countingWriter struct {
+	w interface{ Write([]byte) (int, error) }
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// sanitize turns an artifact name into a flat, filesystem-safe file prefix.
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		case r == '/':
+			return '_'
+		}
+		return '-'
+	}, name)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"caching-benchmark/artifacts"
+	"log"
+)
+
+// writeArtifacts stores each run's time series and raw latencies in dir.
+func writeArtifacts(dir string, maxChunkBytes int64, allResults []scenarioResults) error {
+	m, err := artifacts.NewManager(dir, maxChunkBytes)
+	if err != nil {
+		return err
+	}
+	for _, sr := range allResults {
+		for _, r := range sr.results {
+			prefix := sr.name + "/" + r.StrategyName + "/"
+
+			w, err := m.Create(prefix+"samples", "samples")
+			if err != nil {
+				return err
+			}
+			for _, s := range r.Samples {
+				if err := w.Write(s); err != nil {
+					return err
+				}
+			}
+			if err := w.Close(); err != nil {
+				return err
+			}
+
+			w, err = m.Create(prefix+"latencies", "latencies_ns")
+			if err != nil {
+				return err
+			}
+			for _, lat := range r.Latencies {
+				if err := w.Write(lat.Nanoseconds()); err != nil {
+					return err
+				}
+			}
+			if err := w.Close(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := m.Close(); err != nil {
+		return err
+	}
+	log.Printf("Wrote artifacts to %s", dir)
+	return nil
+}
@@ -1,113 +1,838 @@
 package benchmark
 
 import (
+	"caching-benchmark/codec"
+	"caching-benchmark/serialize"
+	"caching-benchmark/tracing"
 	"caching-benchmark/workload"
 	"context"
-	"crypto/rand"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
+	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
+// Options configures a Runner.
+type Options struct {
+	// Concurrency is the number of workers issuing operations.
+	Concurrency int
+	// ValueSizeBytes is the size of the random payload used for writes.
+	ValueSizeBytes int
+	// Seed seeds all per-worker randomness so that runs are reproducible.
+	Seed int64
+	// DrainTimeout bounds the strategy's Drain phase. Zero selects defaultDrainTimeout.
+	DrainTimeout time.Duration
+	// Hooks are optional per-operation callbacks.
+	Hooks Hooks
+	// InvalidateKey, when set with InvalidateInterval, is rewritten through the
+	// strategy at that interval by an unmeasured background writer.
+	InvalidateKey      string
+	InvalidateInterval time.Duration
+	// TrackFetches records the backend fetches of FetchInstrumented
+	// strategies to find the peak number of concurrent fetches of one key,
+	// for stampede analysis. It adds work to every L1 miss, so it is off
+	// unless a scenario asks for it.
+	TrackFetches bool
+	// External, when its Rate is positive, writes to the backend from
+	// outside the strategy during the run, counted in Result.ExternalWrites.
+	External ExternalWriters
+	// MaxInFlight caps the number of concurrent strategy calls regardless of
+	// Concurrency. Time spent waiting for a slot is reported as queue wait,
+	// separately from the service time in Latencies. Zero means uncapped.
+	MaxInFlight int
+	// SampleInterval is how often in-flight and queued operation counts are
+	// sampled into Result.Samples. Zero selects defaultSampleInterval.
+	SampleInterval time.Duration
+	// TargetRate switches the runner to open-loop mode: operations are
+	// released at this many per second regardless of completions, and time an
+	// operation spends behind its intended start is reported as queue wait.
+	// Zero runs closed-loop, with each worker issuing its next operation as
+	// soon as the previous one completes.
+	TargetRate float64
+	// SlowestN is the number of slowest operations kept, with their context,
+	// in Result.SlowestOps. Zero disables tracking.
+	SlowestN int
+	// Clock, when set, is recorded in Result and used to warn when latencies
+	// are below the host's reliable timer resolution.
+	Clock *ClockCheck
+	// Codec, when set, encodes every written value with a header identifying
+	// WriterID and validates every value read; values failing validation are
+	// errors counted in Result.CorruptReads. Encoding is part of the measured path.
+	Codec    codec.Codec
+	WriterID uint32
+	// OpTimeout bounds each Read and Write; operations exceeding it fail
+	// with context.DeadlineExceeded and are counted in Result.TotalTimeouts.
+	// With Retry, it bounds each attempt. Zero leaves operations unbounded.
+	OpTimeout time.Duration
+	// Retry retries operations failing with transient errors, counting the
+	// retries in Result.Retries. Latencies then hold the service time of
+	// an operation's last attempt, and ResponseTimes, recorded in
+	// closed-loop runs too, include the failed attempts and backoff.
+	Retry RetryPolicy
+	// Faults are injected into the backend at their offsets during the run.
+	Faults []Fault
+	// KeyDeriver, when set, derives each operation's cache key inside the
+	// measured path. Data must be populated under the derived keys.
+	KeyDeriver workload.KeyDeriver
+	// KeyClasses, when set, maps workload keys (before KeyDeriver) to class
+	// names; reads and hits are then also counted per class in
+	// Result.KeyClasses.
+	KeyClasses map[string]string
+	// RankedKeys, when positive, is the number of keys the workload's
+	// Operation.Rank ranges over; reads and hits are then also counted per
+	// popularity band in Result.Hotness.
+	RankedKeys int
+	// Staleness, when set, stamps every write and checks every read against
+	// it. Share one tracker between runners to detect cross-strategy staleness.
+	Staleness *StalenessTracker
+	// ReadYourWrites makes every worker read each key back right after its
+	// own successful write and count, in Result.ReadYourWritesViolations,
+	// reads that do not observe it. It needs Staleness to identify the
+	// write. The read-backs are not counted or timed as operations, but they
+	// do go through, and may fill, the strategy's caches.
+	ReadYourWrites bool
+	// RestartAt, when positive, simulates an application restart this long
+	// into the run: workers pause while the strategy is closed and
+	// re-initialized, Redis keeping its data, and Result.Restart reports
+	// how hit rate and backend traffic recovered. RestartPrewarmKeys are
+	// read through the restarted strategy before workers resume, modeling a
+	// service that warms its cache from a hot-key list.
+	RestartAt          time.Duration
+	RestartPrewarmKeys []string
+	// L1Snapshot are keys a Warmable strategy loads into its L1 after Init,
+	// before measurement starts; DumpL1 saves its L1 keys in
+	// Result.L1Snapshot at the end of the run. Other strategies ignore both.
+	L1Snapshot []string
+	DumpL1     bool
+	// Timeline records a latency histogram for every second of the run and
+	// reports in Result.Timeline each second's p99 next to the invalidations
+	// received and GC pauses in it, attributing p99 spikes to them.
+	Timeline bool
+	// Tracer, when set, receives spans of TraceSampleRate of the
+	// operations: a root span per operation, tagged with its key, layer and
+	// outcome, with the strategy's L1 lookup, L2 fetch, L1 populate and
+	// invalidation publish steps beneath it. SlowOp.TraceID links traced
+	// slow operations to their traces.
+	Tracer          *tracing.Exporter
+	TraceSampleRate float64
+	// L1Schedule changes the L1 budget of strategies implementing
+	// L1Resizer at the given offsets into the run, resizing the L1 in place
+	// or, with L1ResizeCold, emptying it as if recreated with the new
+	// budget. Result.L1Phases reports the hit rate and p99 between changes;
+	// it implies Timeline. Other strategies keep their initial budget.
+	L1Schedule   []L1Resize
+	L1ResizeCold bool
+	// DisableBufferPool allocates the runner's value buffers on every use
+	// instead of reusing pooled ones, for comparing allocation and GC cost.
+	DisableBufferPool bool
+	// Payload is the kind of value written (see GeneratePayload); empty
+	// selects PayloadHex. VaryValues generates a new value for every write
+	// instead of one per worker; generation is then part of the measured path.
+	Payload    string
+	VaryValues bool
+	// Serializer, when set, caches values as serialize.Records: every write
+	// marshals a record holding the value, before Codec encoding, and every
+	// read unmarshals it; failures count as CorruptReads. Both are part of
+	// the measured path and also timed separately in Result.Serialization.
+	Serializer serialize.Serializer
+	// ProfileDir, when set, receives CPU, heap and mutex profiles of the
+	// run, in files named after ProfileName (or the strategy name when
+	// empty); their paths are listed in Result.Profiles. Only one run in a
+	// process can profile the CPU at a time.
+	ProfileDir  string
+	ProfileName string
+	// ProgressInterval, when positive, logs completed operations, current
+	// throughput, hit rate and an ETA at this interval while workers run.
+	ProgressInterval time.Duration
+}
+
+const defaultDrainTimeout = 30 * time.Second
+
 type Runner struct {
-	strategy       CachingStrategy
-	workload       []workload.Operation
-	concurrency    int
-	valueSizeBytes int
-	result         Result
+	strategy        CachingStrategy
+	workload        []workload.Operation
+	concurrency     int
+	valueSizeBytes  int
+	seed            int64
+	drainTimeout    time.Duration
+	hooks           Hooks
+	invalidateKey   string
+	invalidateEvery time.Duration
+	trackFetches    bool
+	external        ExternalWriters
+	fetchTracker    *FetchTracker
+	inFlight        chan struct{}
+	queueWaitMu     sync.Mutex
+	sampleInterval  time.Duration
+	gauges          gauges
+	staleness       *StalenessTracker
+	readYourWrites  bool
+	restartAt       time.Duration
+	prewarmKeys     []string
+	l1Snapshot      []string
+	dumpL1Keys      bool
+	timeline        *timeline
+	tracer          *tracing.Exporter
+	traceSampleRate float64
+	l1Schedule      []L1Resize
+	l1ResizeCold    bool
+	l1Phases        []L1Phase
+	backendBase     BackendStats
+	restartMu       sync.RWMutex
+	keyDeriver      workload.KeyDeriver
+	targetRate      float64
+	faults          []Fault
+	startTime       time.Time
+	events          eventLog
+	slowestN        int
+	opTimeout       time.Duration
+	retry           RetryPolicy
+	workers         []workerTally
+	codec           codec.Codec
+	writerID        uint32
+	errorsMu        sync.Mutex
+	keyClasses      *keyClasses
+	hotness         *hotness
+	buffers         *bufferPool
+	payload         string
+	varyValues      bool
+	serialization   *serialization
+	profileDir      string
+	profileName     string
+	progressEvery   time.Duration
+	slowestMu       sync.Mutex
+	slowest         slowOpHeap
+	result          Result
 }
 
-func NewRunner(strategy CachingStrategy, workload []workload.Operation, concurrency, valueSizeBytes int) *Runner {
+// scheduledOp is an operation with its intended start time; due is zero in
+// closed-loop mode.
+type scheduledOp struct {
+	op  workload.Operation
+	due time.Time
+}
+
+func NewRunner(strategy CachingStrategy, workload []workload.Operation, opts Options) *Runner {
+	if opts.DrainTimeout <= 0 {
+		opts.DrainTimeout = defaultDrainTimeout
+	}
+	if opts.SampleInterval <= 0 {
+		opts.SampleInterval = defaultSampleInterval
+	}
+	var inFlight chan struct{}
+	if opts.MaxInFlight > 0 {
+		inFlight = make(chan struct{}, opts.MaxInFlight)
+	}
+	pool := buffers
+	if opts.DisableBufferPool {
+		pool = nil
+	}
+	var ser *serialization
+	if opts.Serializer != nil {
+		ser = &serialization{serializer: opts.Serializer}
+	}
+	if opts.ProfileName == "" {
+		opts.ProfileName = strategy.Name()
+	}
 	return &Runner{
-		strategy:       strategy,
-		workload:       workload,
-		concurrency:    concurrency,
-		valueSizeBytes: valueSizeBytes,
+		strategy:        strategy,
+		workload:        workload,
+		concurrency:     opts.Concurrency,
+		valueSizeBytes:  opts.ValueSizeBytes,
+		seed:            opts.Seed,
+		drainTimeout:    opts.DrainTimeout,
+		hooks:           opts.Hooks,
+		invalidateKey:   opts.InvalidateKey,
+		invalidateEvery: opts.InvalidateInterval,
+		trackFetches:    opts.TrackFetches,
+		external:        opts.External,
+		inFlight:        inFlight,
+		sampleInterval:  opts.SampleInterval,
+		staleness:       opts.Staleness,
+		readYourWrites:  opts.ReadYourWrites && opts.Staleness != nil,
+		restartAt:       opts.RestartAt,
+		prewarmKeys:     opts.RestartPrewarmKeys,
+		l1Snapshot:      opts.L1Snapshot,
+		dumpL1Keys:      opts.DumpL1,
+		timeline:        newTimeline(opts.Timeline || len(opts.L1Schedule) > 0),
+		tracer:          opts.Tracer,
+		traceSampleRate: opts.TraceSampleRate,
+		l1Schedule:      opts.L1Schedule,
+		l1ResizeCold:    opts.L1ResizeCold,
+		keyDeriver:      opts.KeyDeriver,
+		targetRate:      opts.TargetRate,
+		faults:          opts.Faults,
+		slowestN:        opts.SlowestN,
+		opTimeout:       opts.OpTimeout,
+		retry:           opts.Retry,
+		codec:           opts.Codec,
+		writerID:        opts.WriterID,
+		keyClasses:      newKeyClasses(opts.KeyClasses),
+		hotness:         newHotness(opts.RankedKeys),
+		buffers:         pool,
+		payload:         opts.Payload,
+		varyValues:      opts.VaryValues,
+		serialization:   ser,
+		profileDir:      opts.ProfileDir,
+		profileName:     opts.ProfileName,
+		progressEvery:   opts.ProgressInterval,
 		result: Result{
-			StrategyName: strategy.Name(),
-			Latencies:    make([]time.Duration, 0, len(workload)),
+			StrategyName:     strategy.Name(),
+			Latencies:        make([]time.Duration, 0, len(workload)),
+			StalenessTracked: opts.Staleness != nil,
+			OfferedRate:      opts.TargetRate,
+			Clock:            opts.Clock,
+			MaxInFlight:      opts.MaxInFlight,
 		},
 	}
 }
 
 func (r *Runner) Run(ctx context.Context) (Result, error) {
+	if fi, ok := r.strategy.(FetchInstrumented); ok && r.trackFetches {
+		r.fetchTracker = NewFetchTracker()
+		fi.SetFetchRecorder(r.fetchTracker)
+	}
+	if ii, ok := r.strategy.(InvalidationInstrumented); ok {
+		ii.SetInvalidationRecorder(&r.gauges)
+	}
+
 	log.Printf("Initializing strategy: %s", r.strategy.Name())
-	if err := r.strategy.Init(ctx); err != nil {
+	if err := r.initStrategy(ctx); err != nil {
 		return r.result, fmt.Errorf("failed to initialize strategy: %w", err)
 	}
-	defer r.strategy.Close(ctx)
+	r.backendBase = r.prewarm(ctx)
+	// External writers connect before measurement starts.
+	stopExternal := r.startExternalWriters(ctx)
 
 	var wg sync.WaitGroup
 	wg.Add(r.concurrency)
+	r.workers = make([]workerTally, r.concurrency)
 
-	opsChan := make(chan workload.Operation, len(r.workload))
-	for _, op := range r.workload {
-		opsChan <- op
-	}
-	close(opsChan)
-
+	opsChan := make(chan scheduledOp, len(r.workload))
 	latencyChan := make(chan time.Duration, len(r.workload))
+	var responseChan chan time.Duration
+	if r.targetRate > 0 || r.retry.MaxRetries > 0 {
+		responseChan = make(chan time.Duration, len(r.workload))
+	}
+	// Profiling starts before the clock so its setup is not timed.
+	var prof *profiler
+	if r.profileDir != "" {
+		var err error
+		if prof, err = startProfiles(r.profileDir, r.profileName); err != nil {
+			log.Printf("Warning: profiling disabled for this run: %v", err)
+		}
+	}
+	// Collect first so HeapBefore is the live heap, before the clock starts
+	// so the collection is not timed.
+	runtime.GC()
+	memBefore := memorySnapshot()
 	startTime := time.Now()
+	r.startTime = startTime
+	r.result.StartTime = startTime
+	if r.targetRate > 0 {
+		log.Printf("Open-loop mode: offering %.0f ops/sec", r.targetRate)
+		go r.dispatch(ctx, opsChan, startTime)
+	} else {
+		for _, op := range r.workload {
+			opsChan <- scheduledOp{op: op}
+		}
+		close(opsChan)
+	}
 
 	log.Printf("Starting benchmark with %d concurrent workers...", r.concurrency)
 	for i := 0; i < r.concurrency; i++ {
-		go r.worker(ctx, &wg, opsChan, latencyChan)
+		go r.worker(ctx, i, &wg, opsChan, latencyChan, responseChan)
 	}
 
-	wg.Wait()
-	close(latencyChan)
+	stopInvalidator := r.startInvalidator(ctx)
+	stopFaults := r.startFaults(ctx, startTime)
+	stopRestart := r.startRestart(ctx, startTime)
+	stopL1Schedule := r.startL1Schedule(ctx, startTime)
+	stopProgress := r.startProgress(ctx, startTime)
+	samplerCtx, stopSampler := context.WithCancel(ctx)
+	samplerDone := make(chan struct{})
+	go r.gauges.sampleLoop(samplerCtx, r.sampleInterval, startTime, &r.result.Samples, samplerDone)
 
+	wg.Wait()
+	// The clock stops before anything else, so the snapshots, collections
+	// and cleanup below are not timed.
 	r.result.TotalDuration = time.Since(startTime)
-	r.result.TotalOperations = int64(len(r.workload))
+	stopProgress()
+	memAfter := memorySnapshot()
+	r.result.Memory = memoryDelta(memBefore, memAfter)
+	// Stopped after the snapshot, so the profiler's collection and file
+	// writes count neither toward the run's duration nor its GC cycles.
+	if prof != nil {
+		var err error
+		if r.result.Profiles, err = prof.stop(); err != nil {
+			log.Printf("Warning: writing profiles: %v", err)
+		}
+	}
+	stopInvalidator()
+	stopExternal()
+	stopFaults()
+	stopRestart()
+	stopL1Schedule()
+	stopSampler()
+	<-samplerDone
+	close(latencyChan)
+	if responseChan != nil {
+		close(responseChan)
+		r.result.ResponseTimes = make([]time.Duration, 0, len(responseChan))
+		for rt := range responseChan {
+			r.result.ResponseTimes = append(r.result.ResponseTimes, rt)
+		}
+	}
+	r.measureHeap(memBefore, memAfter)
 
 	for lat := range latencyChan {
 		r.result.Latencies = append(r.result.Latencies, lat)
 	}
+	// Only operations that completed are counted, so a cancelled run reports
+	// the partial workload it actually executed.
+	r.result.TotalOperations = int64(len(r.result.Latencies))
+	if n := r.result.TotalOperations; n > 0 {
+		r.result.Memory.AllocsPerOp = float64(r.result.Memory.Mallocs) / float64(n)
+		r.result.Memory.BytesPerOp = float64(r.result.Memory.AllocBytes) / float64(n)
+	}
+	if ctx.Err() != nil {
+		r.result.Incomplete = true
+		log.Printf("Run for strategy %s cancelled after %d of %d operations", r.strategy.Name(), r.result.TotalOperations, len(r.workload))
+	}
+
+	r.drain(ctx)
+	if br, ok := r.strategy.(BackendReporter); ok {
+		r.result.Backend = br.BackendStats().Sub(r.backendBase)
+	}
+	if cr, ok := r.strategy.(ConnectionReporter); ok {
+		stats := cr.ConnectionStats(ctx)
+		r.result.Connections = &stats
+	}
+	if tr, ok := r.strategy.(TrackingReporter); ok {
+		stats := tr.TrackingStats()
+		r.result.Tracking = &stats
+	}
+	if lr, ok := r.strategy.(ListenerReporter); ok {
+		stats := lr.ListenerStats()
+		r.result.Listener = &stats
+	}
+	if hr, ok := r.strategy.(HotKeyReporter); ok {
+		stats := hr.HotKeyStats()
+		r.result.HotKeys = &stats
+	}
+	if cr, ok := r.strategy.(CompressionReporter); ok {
+		if stats := cr.CompressionStats(); stats.Algorithm != "" {
+			r.result.Compression = &stats
+		}
+	}
+	if fr, ok := r.strategy.(FailoverReporter); ok {
+		r.reportFailover(fr.FailoverStats())
+	}
+	if mr, ok := r.strategy.(MetricsReporter); ok {
+		r.result.StrategyMetrics = mr.Metrics()
+	}
+	if r.fetchTracker != nil {
+		r.result.BackendFetches, r.result.MaxConcurrentFetches, r.result.HottestFetchKey = r.fetchTracker.Stats()
+	}
+	r.dumpL1(ctx)
+	r.closeStrategy(ctx)
+	r.result.Lifecycle.Warmup = warmup(r.result.Samples)
+	r.result.Timeline = r.timeline.build(r.result.Samples)
+	if len(r.l1Phases) > 0 {
+		r.summarizeL1Phases()
+	}
 
 	r.calculateFinalMetrics()
+	r.result.KeyClasses = r.keyClasses.stats()
+	r.result.Hotness = r.hotness.stats()
+	if r.serialization != nil {
+		r.result.Serialization = r.serialization.stats()
+	}
+	r.result.Fairness = fairness(r.workers)
+	r.finishSlowest()
+	r.summarizeFaults()
+	r.checkLittlesLaw()
 	r.printResults()
 
 	return r.result, nil
 }
 
-func (r *Runner) worker(ctx context.Context, wg *sync.WaitGroup, ops <-chan workload.Operation, latencies chan<- time.Duration) {
+// dispatch releases operations at their intended start times in open-loop
+// mode. Operations no worker is free to take wait in ops and count as queued.
+func (r *Runner) dispatch(ctx context.Context, ops chan<- scheduledOp, start time.Time) {
+	defer close(ops)
+	interval := time.Duration(float64(time.Second) / r.targetRate)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for i, op := range r.workload {
+		due := start.Add(time.Duration(i) * interval)
+		// Operations already due are sent without sleeping, so timer
+		// overshoot delays the schedule but does not lower the offered rate.
+		if d := time.Until(due); d > 0 {
+			timer.Reset(d)
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+		}
+		r.gauges.queued.Add(1)
+		ops <- scheduledOp{op: op, due: due}
+	}
+}
+
+// worker executes operations from ops, sending each one's service time on
+// latencies and, for scheduled operations, its response time from the
+// intended start on responses.
+func (r *Runner) worker(ctx context.Context, id int, wg *sync.WaitGroup, ops <-chan scheduledOp, latencies, responses chan<- time.Duration) {
 	defer wg.Done()
-	// Each worker generates its value once to avoid repeated allocation.
-	valueToWrite := generateValue(r.valueSizeBytes)
+	// Each worker generates its value once to avoid repeated allocation,
+	// unless values vary per write.
+	// Seeding by worker id keeps the payloads identical across strategies.
+	rng := rand.New(rand.NewSource(r.seed + int64(id)))
+	valueToWrite := r.generateValue(rng)
+	var record serialize.Record
+	if r.serialization != nil {
+		record = SampleRecord(rng, "")
+	}
+	var slowest slowOpHeap
+	if r.slowestN > 0 {
+		defer func() { r.mergeSlowest(slowest) }()
+	}
+	classLatencies := r.keyClasses.newLatencies()
+	if classLatencies != nil {
+		defer r.keyClasses.merge(classLatencies)
+	}
+	seconds := r.timeline.newSeconds()
+	if seconds != nil {
+		defer r.timeline.merge(seconds)
+	}
+	var tally workerTally
+	defer func() { r.workers[id] = tally }()
+	// Sampling draws from its own source so tracing leaves the values
+	// generated from rng unchanged.
+	traceRng := rand.New(rand.NewSource(r.seed - int64(id) - 1))
+
+	for sop := range ops {
+		op := sop.op
+		if !sop.due.IsZero() {
+			r.gauges.queued.Add(-1)
+			r.recordQueueWait(time.Since(sop.due))
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		if !r.acquire(ctx) {
+			return
+		}
 
-	for op := range ops {
 		var err error
 		var hit bool
 		var start time.Time
+		layer := "backend"
+		// written is the sequence number of the worker's own completed
+		// write, for the read-your-writes check.
+		var written int64
 
+		class := r.keyClasses.lookup(op.Key)
+		band := r.hotness.band(op.Rank)
+		r.holdStrategy()
+		r.gauges.inFlight.Add(1)
 		start = time.Now()
-		switch op.Type {
-		case workload.ReadOp:
-			_, hit, err = r.strategy.Read(ctx, op.Key)
-			if err == nil {
-				if hit {
-					atomic.AddInt64(&r.result.TotalHits, 1)
-				} else {
-					atomic.AddInt64(&r.result.TotalMisses, 1)
+		if r.keyDeriver != nil {
+			op.Key = r.keyDeriver.Derive(op.Key)
+		}
+		if r.hooks.BeforeOp != nil {
+			err = r.hooks.BeforeOp(ctx, op)
+		}
+		opCtx := ctx
+		var tries attempts
+		var span *tracing.Span
+		if r.tracer != nil && traceRng.Float64() < r.traceSampleRate {
+			opCtx, span = tracing.Root(opCtx, r.tracer, "cache."+op.Type.String())
+		}
+		if err == nil {
+			switch op.Type {
+			case workload.ReadOp, workload.RangeReadOp:
+				var value string
+				var latest int64
+				var notFound bool
+				if r.staleness != nil {
+					latest = r.staleness.Latest(op.Key)
+				}
+				err = r.call(opCtx, &tries, func(ctx context.Context) (err error) {
+					if op.Type == workload.RangeReadOp {
+						value, hit, err = readRange(ctx, r.strategy, op)
+					} else {
+						value, hit, err = r.strategy.Read(ctx, op.Key)
+					}
+					return err
+				})
+				if errors.Is(err, ErrNotFound) {
+					// An absent key is a miss, not a failure.
+					r.recordError(err)
+					err = nil
+					notFound = true
+					atomic.AddInt64(&r.result.NotFoundReads, 1)
+					if hit {
+						atomic.AddInt64(&r.result.NegativeHits, 1)
+						layer = "negative-cache"
+					}
+				} else if err == nil && r.codec != nil && op.Type == workload.ReadOp {
+					var payload []byte
+					buf := r.buffers.copyString(value)
+					if _, payload, err = r.codec.Decode(buf); err != nil {
+						atomic.AddInt64(&r.result.CorruptReads, 1)
+					} else if r.serialization != nil {
+						if value, err = r.serialization.unmarshal(payload); err != nil {
+							atomic.AddInt64(&r.result.CorruptReads, 1)
+						}
+					}
+					if r.serialization != nil {
+						r.buffers.put(buf)
+					} else if r.buffers == nil {
+						value = string(payload)
+					} else {
+						// Only the staleness stamp is needed past this point,
+						// so the payload's buffer can go back to the pool.
+						value = string(payload[:min(len(payload), maxStampLen)])
+						r.buffers.put(buf)
+					}
+				} else if err == nil && r.serialization != nil && op.Type == workload.ReadOp {
+					buf := r.buffers.copyString(value)
+					if value, err = r.serialization.unmarshal(buf); err != nil {
+						atomic.AddInt64(&r.result.CorruptReads, 1)
+					}
+					r.buffers.put(buf)
+				}
+				if err == nil {
+					r.keyClasses.record(class, hit, notFound)
+					r.hotness.record(band, hit)
+					if hit {
+						atomic.AddInt64(&r.result.TotalHits, 1)
+						r.gauges.hits.Add(1)
+						if layer == "backend" {
+							layer = "L1"
+						}
+					} else {
+						atomic.AddInt64(&r.result.TotalMisses, 1)
+						r.gauges.misses.Add(1)
+					}
+					if op.Type == workload.RangeReadOp {
+						atomic.AddInt64(&r.result.RangeReads, 1)
+						atomic.AddInt64(&r.result.RangeBytes, int64(len(value)))
+					} else if r.staleness != nil && r.staleness.IsStale(value, latest) {
+						atomic.AddInt64(&r.result.StaleReads, 1)
+						if len(r.faults) > 0 {
+							r.events.add(&r.events.staleReads, time.Since(r.startTime))
+						}
+					}
+				}
+			case workload.WriteOp:
+				if r.varyValues {
+					valueToWrite = r.generateValue(rng)
+				}
+				value, seq := valueToWrite, int64(0)
+				if r.staleness != nil {
+					seq, value = r.staleness.Stamp(valueToWrite)
+				}
+				if r.serialization != nil {
+					value, err = r.serialization.marshal(&record, value)
+				}
+				if err == nil && r.codec != nil {
+					h := codec.Header{WriterID: r.writerID, Timestamp: time.Now(), Seq: uint64(seq)}
+					buf := r.buffers.copyString(value)
+					value = string(r.codec.Encode(h, buf))
+					r.buffers.put(buf)
+				}
+				if err == nil {
+					err = r.call(opCtx, &tries, func(ctx context.Context) error {
+						return r.strategy.Write(ctx, op.Key, value)
+					})
+				}
+				if err == nil {
+					atomic.AddInt64(&r.result.TotalWrites, 1)
+					if r.staleness != nil {
+						r.staleness.Commit(op.Key, seq)
+						written = seq
+					}
+				}
+			case workload.ScanOp:
+				err = r.call(opCtx, &tries, func(ctx context.Context) error {
+					return r.scan(ctx, op.Key, op.Length)
+				})
+			case workload.RMWOp:
+				var seq int64
+				err = r.call(opCtx, &tries, func(ctx context.Context) (err error) {
+					seq, err = r.readModifyWrite(ctx, op.Key)
+					return err
+				})
+				if err == nil && r.staleness != nil {
+					r.staleness.Commit(op.Key, seq)
+					written = seq
 				}
 			}
-		case workload.WriteOp:
-			err = r.strategy.Write(ctx, op.Key, valueToWrite)
-			if err == nil {
-				atomic.AddInt64(&r.result.TotalWrites, 1)
+		}
+		// Failed attempts and backoff count toward the response time only.
+		latency := time.Since(start) - tries.wasted
+		if span != nil {
+			span.SetAttribute("benchmark.strategy", r.result.StrategyName)
+			span.SetAttribute("cache.key", op.Key)
+			span.SetAttribute("cache.layer", layer)
+			span.SetAttribute("cache.hit", hit)
+			if tries.retries > 0 {
+				span.SetAttribute("benchmark.retries", tries.retries)
 			}
+			span.End(err)
+		}
+		r.releaseStrategy()
+		r.gauges.inFlight.Add(-1)
+		r.gauges.completed.Add(1)
+		r.release()
+		if err != nil && ctx.Err() != nil {
+			// Aborted by cancellation; not a strategy failure.
+			return
+		}
+		if r.readYourWrites && written > 0 {
+			r.checkReadYourWrite(ctx, op.Key, written)
 		}
-		latency := time.Since(start)
 		latencies <- latency
+		tally.add(latency)
+		classLatencies.add(class, latency)
+		seconds.add(time.Since(r.startTime), latency)
+		if !sop.due.IsZero() {
+			responses <- time.Since(sop.due)
+		} else if responses != nil {
+			responses <- time.Since(start)
+		}
+		if tries.retries > 0 {
+			atomic.AddInt64(&r.result.Retries, int64(tries.retries))
+			atomic.AddInt64(&r.result.RetriedOps, 1)
+			if err == nil {
+				atomic.AddInt64(&r.result.RetryRecoveries, 1)
+			}
+		}
+		if r.slowestN > 0 {
+			slowest.offer(SlowOp{Key: op.Key, Type: op.Type, Layer: layer, At: start.Sub(r.startTime), Latency: latency, Err: err, TraceID: span.TraceID()}, r.slowestN)
+		}
 
 		if err != nil {
 			atomic.AddInt64(&r.result.TotalErrors, 1)
+			if errors.Is(err, context.DeadlineExceeded) {
+				atomic.AddInt64(&r.result.TotalTimeouts, 1)
+			}
+			r.recordError(err)
+			r.gauges.errors.Add(1)
+			if len(r.faults) > 0 {
+				r.events.add(&r.events.errors, time.Since(r.startTime))
+			}
+			if r.hooks.OnError != nil {
+				r.hooks.OnError(ctx, op, err)
+			}
+		}
+		if r.hooks.AfterOp != nil {
+			r.hooks.AfterOp(ctx, op, OpOutcome{Hit: hit, Latency: latency, Err: err})
 		}
 	}
 }
 
+// acquire waits for an in-flight slot when MaxInFlight is set and records the
+// wait. It returns false if ctx was cancelled while waiting.
+func (r *Runner) acquire(ctx context.Context) bool {
+	if r.inFlight == nil {
+		return true
+	}
+	start := time.Now()
+	r.gauges.queued.Add(1)
+	defer r.gauges.queued.Add(-1)
+	select {
+	case r.inFlight <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	}
+	r.recordQueueWait(time.Since(start))
+	return true
+}
+
+func (r *Runner) recordQueueWait(wait time.Duration) {
+	r.queueWaitMu.Lock()
+	r.result.TotalQueueWait += wait
+	if wait > r.result.MaxQueueWait {
+		r.result.MaxQueueWait = wait
+	}
+	r.queueWaitMu.Unlock()
+}
+
+func (r *Runner) release() {
+	if r.inFlight != nil {
+		<-r.inFlight
+	}
+}
+
+// startInvalidator periodically rewrites invalidateKey through the strategy so
+// that every cached copy is invalidated. It returns a function that stops it.
+func (r *Runner) startInvalidator(ctx context.Context) (stop func()) {
+	if r.invalidateKey == "" || r.invalidateEvery <= 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rng := rand.New(rand.NewSource(r.seed))
+		value := r.generateValue(rng)
+		if r.serialization != nil {
+			rec := SampleRecord(rng, value)
+			b, _ := r.serialization.serializer.Marshal(&rec)
+			value = string(b)
+		}
+		if r.codec != nil {
+			value = string(r.codec.Encode(codec.Header{WriterID: r.writerID, Timestamp: time.Now()}, []byte(value)))
+		}
+		ticker := time.NewTicker(r.invalidateEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.holdStrategy()
+				if err := r.strategy.Write(ctx, r.invalidateKey, value); err == nil {
+					atomic.AddInt64(&r.result.Invalidations, 1)
+				}
+				r.releaseStrategy()
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// drain gives the strategy a bounded window to finish asynchronous work.
+// Drain time is excluded from TotalDuration so it does not affect throughput.
+func (r *Runner) drain(ctx context.Context) {
+	// Drain still runs after an interrupt so asynchronous work is not abandoned.
+	drainCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.drainTimeout)
+	defer cancel()
+
+	start := time.Now()
+	lost, err := r.strategy.Drain(drainCtx)
+	r.result.DrainDuration = time.Since(start)
+	r.result.LostWrites = lost
+	if err != nil {
+		log.Printf("Drain for strategy %s did not complete: %v", r.strategy.Name(), err)
+	}
+}
+
 func (r *Runner) calculateFinalMetrics() {
 	if r.result.TotalHits+r.result.TotalMisses > 0 {
 		r.result.HitRate = float64(r.result.TotalHits) / float64(r.result.TotalHits+r.result.TotalMisses)
@@ -115,25 +840,240 @@ func (r *Runner) calculateFinalMetrics() {
 	if r.result.TotalDuration.Seconds() > 0 {
 		r.result.OpsPerSecond = float64(r.result.TotalOperations) / r.result.TotalDuration.Seconds()
 	}
+	if r.result.TotalOperations > 0 {
+		r.result.BackendRequestsPer1kOps = float64(r.result.Backend.Requests) * 1000 / float64(r.result.TotalOperations)
+	}
 }
 
 func (r *Runner) printResults() {
 	log.Println("--- Benchmark Results ---")
 	log.Printf("Strategy: %s", r.result.StrategyName)
+	if r.result.Incomplete {
+		log.Println("Status: INCOMPLETE (interrupted)")
+	}
 	log.Printf("Total Duration: %v", r.result.TotalDuration)
 	log.Printf("Total Operations: %d", r.result.TotalOperations)
 	log.Printf("Concurrency: %d", r.concurrency)
+	if r.targetRate > 0 {
+		log.Printf("Offered Ops/sec: %.2f", r.targetRate)
+	}
 	log.Printf("Ops/sec: %.2f", r.result.OpsPerSecond)
 	log.Printf("L1 Cache Hit Rate: %.2f%%", r.result.HitRate*100)
 	log.Printf("Total Hits: %d", r.result.TotalHits)
 	log.Printf("Total Misses: %d", r.result.TotalMisses)
 	log.Printf("Total Writes: %d", r.result.TotalWrites)
 	log.Printf("Total Errors: %d", r.result.TotalErrors)
+	for _, category := range errorCategories(r.result.ErrorCategories) {
+		c := r.result.ErrorCategories[category]
+		log.Printf("  %s: %d (e.g. %q)", category, c.Count, c.Example)
+	}
+	if r.opTimeout > 0 {
+		log.Printf("Timeouts (> %v): %d", r.opTimeout, r.result.TotalTimeouts)
+	}
+	if m := r.result.StrategyMetrics; len(m) > 0 {
+		log.Println("Strategy Metrics:")
+		for _, name := range metricNames(m) {
+			log.Printf("  %s: %d", name, m[name])
+		}
+		if hits, misses := m["l1.hits"], m["l1.misses"]; hits+misses > 0 {
+			log.Printf("  L1 hit rate by the strategy's own count: %.2f%% (measured %.2f%%)", hitRate(hits, misses)*100, r.result.HitRate*100)
+		}
+	}
+	if f := r.result.Fairness; f != nil {
+		log.Printf("Worker Fairness: %s", f)
+		if f.Starved() {
+			log.Printf("WARNING: some workers were starved; aggregate throughput hides their latency")
+		}
+	}
+	if r.retry.MaxRetries > 0 {
+		log.Printf("Retries: %d over %d operations, %d of which then succeeded", r.result.Retries, r.result.RetriedOps, r.result.RetryRecoveries)
+	}
+	if r.codec != nil {
+		log.Printf("Corrupt Reads (%s codec): %d", r.codec.Name(), r.result.CorruptReads)
+	}
+	if n := r.result.Scans; n > 0 {
+		log.Printf("Scans: %d returning %.1f keys on average, %.1f%% of values cached",
+			n, float64(r.result.ScanKeys)/float64(n), float64(r.result.ScanHits)/float64(max(r.result.ScanKeys, 1))*100)
+	}
+	if r.result.RMWs+r.result.RMWConflicts > 0 {
+		log.Printf("Read-Modify-Writes: %d (%d retries after conflicts, %d abandoned)", r.result.RMWs, r.result.RMWRetries, r.result.RMWConflicts)
+	}
+	if m := r.result.Memory; r.result.TotalOperations > 0 {
+		pooling := "on"
+		if r.buffers == nil {
+			pooling = "off"
+		}
+		log.Printf("Allocations: %.1f MB in %d objects (%.1f KB/op, %.1f allocs/op, buffer pool %s)",
+			float64(m.AllocBytes)/(1<<20), m.Mallocs, m.BytesPerOp/1024, m.AllocsPerOp, pooling)
+		log.Printf("GC: %d cycles, %v total pause, %v longest", m.NumGC, m.GCPause, m.MaxGCPause)
+		log.Printf("Heap: %+.1f MB retained (%.1f MB live before, %.1f MB after), %.1f MB peak",
+			float64(m.HeapGrowth())/(1<<20), float64(m.HeapBefore)/(1<<20), float64(m.HeapAfter)/(1<<20), float64(m.PeakHeap)/(1<<20))
+	}
+	for _, path := range r.result.Profiles {
+		log.Printf("Profile: %s", path)
+	}
+	log.Printf("Drain Duration: %v", r.result.DrainDuration)
+	log.Printf("Init Duration: %v, Warm-up: %v, Close Duration: %v", r.result.Lifecycle.Init, r.result.Lifecycle.Warmup, r.result.Lifecycle.Close)
+	if r.result.PrewarmedL1Keys > 0 {
+		log.Printf("L1 Pre-Warmed: %d of %d snapshot keys in %v", r.result.PrewarmedL1Keys, len(r.l1Snapshot), r.result.Lifecycle.Prewarm)
+	}
+	log.Printf("Lost Writes: %d", r.result.LostWrites)
+	if _, ok := r.strategy.(BackendReporter); ok {
+		log.Printf("Backend Requests: %d (%.1f per 1000 ops)", r.result.Backend.Requests, r.result.BackendRequestsPer1kOps)
+		log.Printf("Backend Commands: %d, Max Batch Size: %d", r.result.Backend.Commands, r.result.Backend.MaxBatchSize)
+		log.Printf("Backend Bytes Sent/Received: %d/%d", r.result.Backend.BytesSent, r.result.Backend.BytesReceived)
+	}
+	if r.fetchTracker != nil {
+		log.Printf("Backend Fetches: %d", r.result.BackendFetches)
+		log.Printf("Max Concurrent Fetches (same key): %d (key %q)", r.result.MaxConcurrentFetches, r.result.HottestFetchKey)
+	}
+	if c := r.result.Connections; c != nil {
+		log.Printf("Connections: %s open, %d pipelined per node, blocking pool %d, max in flight %s",
+			c.OpenString(), c.Pipelined, c.BlockingPoolSize, inFlightString(r.result.MaxInFlight))
+	}
+	if t := r.result.Tracking; t != nil {
+		log.Printf("Tracking: peak %d tracked keys (max %d), peak %d tracking clients, %d invalidations received",
+			t.PeakTrackedKeys, t.TrackingTableMaxKeys, t.PeakTrackingClients, t.InvalidationsReceived)
+		if msg, ok := t.Bottleneck(r.result.TotalWrites); ok {
+			log.Printf("WARNING: server-side tracking is the bottleneck: %s", msg)
+		}
+	}
+	if h := r.result.HotKeys; h != nil {
+		log.Printf("Hot Keys: %d distinct, %d hot reads, hottest %q (%d accesses in one window)", h.DistinctHotKeys, h.HotReads, h.HottestKey, h.HottestCount)
+	}
+	if c := r.result.Compression; c != nil {
+		var avgCompress, avgDecompress time.Duration
+		if c.Compressions > 0 {
+			avgCompress = c.CompressTime / time.Duration(c.Compressions)
+		}
+		if c.Decompressions > 0 {
+			avgDecompress = c.DecompressTime / time.Duration(c.Decompressions)
+		}
+		log.Printf("Compression (%s): ratio %.2f (L1 budget holds %.2f× the values), %d compressions averaging %v, %d decompressions averaging %v, %v CPU in total",
+			c.Algorithm, c.Ratio(), c.Ratio(), c.Compressions, avgCompress, c.Decompressions, avgDecompress, c.CompressTime+c.DecompressTime)
+	}
+	if s := r.result.Serialization; s != nil {
+		avgSize := 0.0
+		if s.Encodes > 0 {
+			avgSize = float64(s.EncodedBytes) / float64(s.Encodes)
+		}
+		log.Printf("Serialization (%s): %d encodes averaging %v (%.0f B), %d decodes averaging %v",
+			s.Format, s.Encodes, s.AvgEncode(), avgSize, s.Decodes, s.AvgDecode())
+	}
+	if n := r.result.RangeReads; n > 0 {
+		mode := "whole values fetched and sliced on the client"
+		if _, ok := r.strategy.(RangeReader); ok {
+			mode = "fragments read on the server"
+		}
+		log.Printf("Range Reads: %d, %.0f B returned on average (%s)", n, float64(r.result.RangeBytes)/float64(n), mode)
+	}
+	if r.result.NotFoundReads > 0 {
+		log.Printf("Not-Found Reads: %d (%d served from negative cache)", r.result.NotFoundReads, r.result.NegativeHits)
+	}
+	for _, c := range r.result.KeyClasses {
+		log.Printf("Key Class %s: %d reads, hit rate %.2f%%, %d not found, p99 latency %v over %d ops", c.Name, c.Reads, c.HitRate*100, c.NotFound, c.P99Latency, c.Ops)
+	}
+	if len(r.result.Hotness) > 0 {
+		bands := make([]string, len(r.result.Hotness))
+		for i, h := range r.result.Hotness {
+			bands[i] = fmt.Sprintf("%s %.2f%%", h.Name, h.HitRate*100)
+		}
+		log.Printf("Hit Rate by Key Popularity: %s", strings.Join(bands, ", "))
+	}
+	if r.staleness != nil {
+		log.Printf("Stale Reads: %d", r.result.StaleReads)
+	}
+	if rs := r.result.Restart; rs != nil {
+		log.Printf("Restart at %v: %s", rs.At.Round(time.Millisecond), rs)
+	}
+	for _, p := range r.result.L1Phases {
+		log.Printf("L1 Phase %s", p)
+	}
+	if r.timeline != nil {
+		spikes := Spikes(r.result.Timeline)
+		log.Printf("Latency Spikes: %d of %d seconds", len(spikes), len(r.result.Timeline))
+		for _, s := range spikes {
+			log.Printf("  %ds: p99 %v, %d invalidations, %d GC cycles (%v pause) -> %s",
+				s.Second, s.P99, s.Invalidations, s.GCCycles, s.GCPause, s.Cause)
+		}
+	}
+	if r.readYourWrites {
+		log.Printf("Read-Your-Writes Violations: %d of %d checks", r.result.ReadYourWritesViolations, r.result.ReadYourWritesChecks)
+	}
+	if len(r.result.Samples) > 0 {
+		peakInFlight, peakQueued, meanInFlight, meanQueued := summarizeSamples(r.result.Samples)
+		log.Printf("In-Flight Ops (mean/peak): %.1f/%d", meanInFlight, peakInFlight)
+		log.Printf("Queued Ops (mean/peak): %.1f/%d", meanQueued, peakQueued)
+	}
+	if c := r.result.Clock; c != nil && len(r.result.Latencies) > 0 {
+		if msg, ok := c.Unreliable(medianLatency(r.result.Latencies)); ok {
+			log.Printf("WARNING: %s", msg)
+		}
+	}
+	if ll := r.result.LittlesLaw; ll != nil {
+		log.Printf("Little's Law: measured %.2f in flight, predicted %.2f", ll.MeasuredInFlight, ll.PredictedInFlight)
+		if msg, ok := ll.Violation(); ok {
+			log.Printf("WARNING: inconsistent measurements: %s", msg)
+		}
+	}
+	if (r.inFlight != nil || r.targetRate > 0) && r.result.TotalOperations > 0 {
+		if r.inFlight != nil {
+			log.Printf("Max In-Flight: %d", cap(r.inFlight))
+		}
+		log.Printf("Avg Queue Wait: %v", r.result.TotalQueueWait/time.Duration(r.result.TotalOperations))
+		log.Printf("Max Queue Wait: %v", r.result.MaxQueueWait)
+		r.logResponseTimes()
+	} else if r.retry.MaxRetries > 0 {
+		r.logResponseTimes()
+	}
+	if r.invalidateKey != "" {
+		log.Printf("Background Invalidations: %d", r.result.Invalidations)
+	}
+	if r.result.ExternalWrites+r.result.ExternalWriteErrors > 0 {
+		log.Printf("External Writes: %d (%d failed)", r.result.ExternalWrites, r.result.ExternalWriteErrors)
+	}
+	if len(r.result.SlowestOps) > 0 {
+		log.Printf("Slowest %d Operations:", len(r.result.SlowestOps))
+		for _, op := range r.result.SlowestOps {
+			detail := ""
+			if op.Err != nil {
+				detail = ", error: " + op.Err.Error()
+			}
+			if op.TraceID != "" {
+				detail += ", trace " + op.TraceID
+			}
+			log.Printf("  %v %s %q via %s at +%v%s", op.Latency, op.Type, op.Key, op.Layer, op.At.Round(time.Millisecond), detail)
+		}
+	}
+	if l := r.result.Listener; l != nil && l.Disconnects > 0 {
+		log.Printf("Invalidation Listener: %d disconnects, down %v in total (longest %v), %d L1 flushes after resubscribe",
+			l.Disconnects, l.DownTime.Round(time.Millisecond), l.MaxDownTime.Round(time.Millisecond), l.L1Flushes)
+	}
+	if f := r.result.Failover; f != nil {
+		log.Printf("Failover: switched to standby at %v after %v detection; L1 hit rate %.2f%% before, %.2f%% after",
+			f.SwitchedAfter.Round(time.Millisecond), f.DetectionTime.Round(time.Millisecond), f.HitRateBefore*100, f.HitRateAfter*100)
+	}
+	for _, f := range r.result.Faults {
+		if f.Err != nil {
+			log.Printf("Fault %s at %v: not injected: %v", f.Name, f.At.Round(time.Millisecond), f.Err)
+			continue
+		}
+		log.Printf("Fault %s at %v: %d errors, recovered after %v, %d stale reads",
+			f.Name, f.At.Round(time.Millisecond), f.Errors, f.RecoveryTime.Round(time.Millisecond), f.StaleReads)
+	}
 	log.Println("-------------------------")
 }
 
-func generateValue(size int) string {
-	b := make([]byte, size)
-	rand.Read(b)
-	return fmt.Sprintf("%x", b)
+// medianLatency returns the median without reordering latencies.
+func medianLatency(latencies []time.Duration) time.Duration {
+	sorted := sortedCopy(latencies)
+	return sorted[len(sorted)/2]
+}
+
+// generateValue draws a value of the runner's payload kind from rng.
+func (r *Runner) generateValue(rng *rand.Rand) string {
+	if r.payload == "" || r.payload == PayloadHex {
+		return generateValue(rng, r.valueSizeBytes, r.buffers)
+	}
+	return GeneratePayload(r.payload, rng, r.valueSizeBytes)
 }
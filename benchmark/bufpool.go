@@ -0,0 +1,125 @@
+package benchmark
+
+import (
+	"math/bits"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// bufferPool reuses the runner's scratch buffers for large values. Buffers
+// are kept in power-of-two size classes so that a pooled buffer always fits
+// any request of its class. A nil *bufferPool allocates every buffer.
+//
+// Only buffers the runner owns are pooled: written values are handed to the
+// strategy, which may retain them in L1 or a write-behind queue, and read
+// values are allocated by the client library.
+type bufferPool struct {
+	classes [64]sync.Pool
+}
+
+// buffers is shared by all runners, so a scenario's later strategy runs
+// start with warm pools.
+var buffers = &bufferPool{}
+
+// get returns a buffer of length n.
+func (p *bufferPool) get(n int) []byte {
+	if p == nil || n == 0 {
+		return make([]byte, n)
+	}
+	class := bits.Len(uint(n - 1))
+	if b, ok := p.classes[class].Get().(*[]byte); ok {
+		return (*b)[:n]
+	}
+	return make([]byte, n, 1<<class)
+}
+
+// put returns b to the pool; b must not be used afterwards.
+func (p *bufferPool) put(b []byte) {
+	if p == nil || cap(b) == 0 || cap(b)&(cap(b)-1) != 0 {
+		return
+	}
+	b = b[:0]
+	p.classes[bits.Len(uint(cap(b)-1))].Put(&b)
+}
+
+// copyString returns a buffer holding s.
+func (p *bufferPool) copyString(s string) []byte {
+	b := p.get(len(s))
+	copy(b, s)
+	return b
+}
+
+// MemoryStats is the Go heap activity of the process while a run's workers
+// were active. It includes the strategy's background goroutines and client
+// library as well as the runner itself.
+type MemoryStats struct {
+	// AllocBytes and Mallocs are the bytes and objects allocated.
+	AllocBytes uint64
+	Mallocs    uint64
+	// NumGC is the number of completed GC cycles and GCPause their total
+	// stop-the-world pause time.
+	NumGC   uint32
+	GCPause time.Duration
+	// MaxGCPause is the longest single pause among them.
+	MaxGCPause time.Duration
+	// AllocsPerOp and BytesPerOp divide the allocations by the operations
+	// completed.
+	AllocsPerOp float64
+	BytesPerOp  float64
+	// HeapBefore and HeapAfter are the live heap after a forced collection
+	// just before the workers started and once they finished, with the
+	// strategy still open. PeakHeap is the largest heap sampled in between,
+	// garbage included.
+	HeapBefore uint64
+	HeapAfter  uint64
+	PeakHeap   uint64
+}
+
+// HeapGrowth is the live heap the run retained: chiefly the strategy's L1
+// and client buffers, plus whatever the runner kept for its results.
+func (m MemoryStats) HeapGrowth() int64 {
+	return int64(m.HeapAfter) - int64(m.HeapBefore)
+}
+
+// memorySnapshot reads the runtime's memory statistics.
+func memorySnapshot() runtime.MemStats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m
+}
+
+// memoryDelta returns the heap activity between two snapshots.
+func memoryDelta(before, after runtime.MemStats) MemoryStats {
+	return MemoryStats{
+		AllocBytes: after.TotalAlloc - before.TotalAlloc,
+		Mallocs:    after.Mallocs - before.Mallocs,
+		NumGC:      after.NumGC - before.NumGC,
+		GCPause:    time.Duration(after.PauseTotalNs - before.PauseTotalNs),
+		MaxGCPause: maxGCPause(before, after),
+	}
+}
+
+// maxGCPause returns the longest pause of the cycles completed between two
+// snapshots, of which the runtime remembers the last 256.
+func maxGCPause(before, after runtime.MemStats) time.Duration {
+	var longest uint64
+	first := max(before.NumGC+1, after.NumGC-min(after.NumGC, 255))
+	for n := first; n <= after.NumGC && n > 0; n++ {
+		longest = max(longest, after.PauseNs[(n+255)%256])
+	}
+	return time.Duration(longest)
+}
+
+// measureHeap records the run's live heap growth, and its peak from the
+// snapshots around the workers and the samples taken while they ran.
+func (r *Runner) measureHeap(before, after runtime.MemStats) {
+	m := &r.result.Memory
+	m.PeakHeap = max(before.HeapAlloc, after.HeapAlloc)
+	for _, s := range r.result.Samples {
+		m.PeakHeap = max(m.PeakHeap, s.HeapBytes)
+	}
+	runtime.GC()
+	m.HeapBefore = before.HeapAlloc
+	m.HeapAfter = memorySnapshot().HeapAlloc
+}
@@ -0,0 +1,64 @@
+package benchmark
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// ClockCheck describes how precisely the host can time operations. Results
+// from hosts with coarse timers are not comparable at sub-microsecond scale.
+type ClockCheck struct {
+	Platform  string
+	GoVersion string
+	// Resolution is the smallest observed step of the monotonic clock.
+	Resolution time.Duration
+	// Overhead is the cost of timing an empty operation, which is included
+	// in every measured latency.
+	Overhead time.Duration
+}
+
+const clockSamples = 100000
+
+// MeasureClock measures the clock resolution and timing overhead of the host.
+func MeasureClock() ClockCheck {
+	c := ClockCheck{
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		GoVersion: runtime.Version(),
+	}
+
+	prev := time.Now()
+	for i := 0; i < clockSamples; i++ {
+		now := time.Now()
+		if d := now.Sub(prev); d > 0 && (c.Resolution == 0 || d < c.Resolution) {
+			c.Resolution = d
+		}
+		prev = now
+	}
+
+	// The median of empty spans timed like an operation, as the mean is
+	// skewed by preemption.
+	spans := make([]time.Duration, clockSamples)
+	for i := range spans {
+		start := time.Now()
+		spans[i] = time.Since(start)
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i] < spans[j] })
+	c.Overhead = spans[len(spans)/2]
+	return c
+}
+
+// minReliableSteps is how many clock steps a latency must span for its
+// quantization error to stay within 10%.
+const minReliableSteps = 10
+
+// Unreliable reports whether latencies around median are too close to the
+// clock resolution to be measured reliably.
+func (c ClockCheck) Unreliable(median time.Duration) (string, bool) {
+	if c.Resolution == 0 || median >= minReliableSteps*c.Resolution {
+		return "", false
+	}
+	return fmt.Sprintf("median latency %v is within %dx the %s clock resolution of %v; sub-resolution latencies are not comparable across platforms",
+		median, minReliableSteps, c.Platform, c.Resolution), true
+}
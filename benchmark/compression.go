@@ -0,0 +1,31 @@
+package benchmark
+
+import "time"
+
+// CompressionStats describes a strategy's value compression.
+type CompressionStats struct {
+	Algorithm string
+	// RawBytes and CompressedBytes total the values compressed.
+	RawBytes        int64
+	CompressedBytes int64
+	Compressions    int64
+	Decompressions  int64
+	CompressTime    time.Duration
+	DecompressTime  time.Duration
+}
+
+// CompressionReporter is implemented by strategies that can compress the
+// values they cache. An empty Algorithm means compression is off.
+type CompressionReporter interface {
+	CompressionStats() CompressionStats
+}
+
+// Ratio is the raw size of the compressed values over their compressed
+// size; with entries costed by size, it is also the factor by which
+// compression multiplies the number of values an L1 budget holds.
+func (c CompressionStats) Ratio() float64 {
+	if c.CompressedBytes == 0 {
+		return 0
+	}
+	return float64(c.RawBytes) / float64(c.CompressedBytes)
+}
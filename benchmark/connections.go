@@ -0,0 +1,42 @@
+package benchmark
+
+import (
+	"context"
+	"strconv"
+)
+
+// ConnectionStats describes the Redis connections behind a strategy's
+// clients, so client tuning can be told apart from strategy design.
+type ConnectionStats struct {
+	// Pipelined is the number of connections per node that commands are
+	// pipelined over.
+	Pipelined int
+	// BlockingPoolSize caps the dedicated connections taken by blocking
+	// commands and transactions.
+	BlockingPoolSize int
+	// Open is the number of the strategy's connections the server listed at
+	// the end of the run, including dedicated and subscriber connections;
+	// -1 when they could not be counted.
+	Open int
+}
+
+// ConnectionReporter is implemented by strategies that connect to Redis.
+type ConnectionReporter interface {
+	ConnectionStats(ctx context.Context) ConnectionStats
+}
+
+// OpenString formats Open, "unknown" when the connections were not counted.
+func (c ConnectionStats) OpenString() string {
+	if c.Open < 0 {
+		return "unknown"
+	}
+	return strconv.Itoa(c.Open)
+}
+
+// inFlightString formats a MaxInFlight cap.
+func inFlightString(n int) string {
+	if n <= 0 {
+		return "uncapped"
+	}
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,83 @@
+package benchmark
+
+import (
+	"caching-benchmark/codec"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"sort"
+	"syscall"
+)
+
+// Error categories reported in Result.ErrorCategories.
+const (
+	// ErrCategoryNotFound counts reads of absent keys. They are misses, not
+	// failures, and are excluded from TotalErrors.
+	ErrCategoryNotFound      = "not-found"
+	ErrCategoryTimeout       = "timeout"
+	ErrCategoryConnection    = "connection"
+	ErrCategorySerialization = "serialization"
+	ErrCategoryOther         = "other"
+	// ErrCategoryConflict counts read-modify-writes abandoned after
+	// MaxRMWRetries conflicts.
+	ErrCategoryConflict = "conflict"
+)
+
+// ErrorCount is the number of errors in one category and the first message seen.
+type ErrorCount struct {
+	Count   int64
+	Example string
+}
+
+// Classify returns the category of err.
+func Classify(err error) string {
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return ErrCategoryNotFound
+	case errors.Is(err, ErrRMWConflict):
+		return ErrCategoryConflict
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ErrCategoryTimeout
+	case errors.Is(err, codec.ErrMalformed), errors.Is(err, codec.ErrChecksum),
+		errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return ErrCategorySerialization
+	case errors.As(err, new(*net.OpError)),
+		errors.Is(err, net.ErrClosed),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return ErrCategoryConnection
+	}
+	return ErrCategoryOther
+}
+
+// recordError counts err under its category.
+func (r *Runner) recordError(err error) {
+	category := Classify(err)
+	r.errorsMu.Lock()
+	defer r.errorsMu.Unlock()
+	if r.result.ErrorCategories == nil {
+		r.result.ErrorCategories = make(map[string]ErrorCount)
+	}
+	c := r.result.ErrorCategories[category]
+	if c.Count == 0 {
+		c.Example = err.Error()
+	}
+	c.Count++
+	r.result.ErrorCategories[category] = c
+}
+
+// errorCategories returns the categories present in counts in a stable order.
+func errorCategories(counts map[string]ErrorCount) []string {
+	categories := make([]string, 0, len(counts))
+	for c := range counts {
+		categories = append(categories, c)
+	}
+	sort.Strings(categories)
+	return categories
+}
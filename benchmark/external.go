@@ -0,0 +1,114 @@
+package benchmark
+
+import (
+	"caching-benchmark/codec"
+	"caching-benchmark/serialize"
+	"context"
+	"log"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Writer is what ExternalWriters write through: another instance of the
+// measured strategy, or a bare Redis client. Every CachingStrategy is one.
+type Writer interface {
+	Init(ctx context.Context) error
+	Write(ctx context.Context, key, value string) error
+	Close(ctx context.Context) error
+}
+
+// ExternalWriters simulates other application instances mutating data
+// while a run is measured, so that invalidation handling is exercised even
+// by read-only workloads. Workers goroutines, apart from the measured
+// workers, write to keys drawn uniformly from Keys at Rate writes per
+// second in total, through Writer, which the runner initializes before the
+// workers start and closes after they finish. Keys are used as-is, without
+// Options.KeyDeriver. With Options.Staleness, reads that miss an external
+// write count as stale.
+type ExternalWriters struct {
+	Writer  Writer
+	Rate    float64
+	Workers int
+	Keys    []string
+}
+
+// startExternalWriters starts the external writer pool. It returns a
+// function that stops the pool and closes its Writer.
+func (r *Runner) startExternalWriters(ctx context.Context) (stop func()) {
+	ext := r.external
+	if ext.Writer == nil || ext.Rate <= 0 || len(ext.Keys) == 0 {
+		return func() {}
+	}
+	if err := ext.Writer.Init(ctx); err != nil {
+		log.Printf("Warning: external writers disabled for this run: %v", err)
+		return func() {}
+	}
+	workers := max(ext.Workers, 1)
+	log.Printf("External writers: %d writing %.0f/sec to %d keys", workers, ext.Rate, len(ext.Keys))
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	interval := time.Duration(float64(workers) * float64(time.Second) / ext.Rate)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Seeded apart from the measured workers' sources.
+			rng := rand.New(rand.NewSource(r.seed ^ int64(0x5eed+i)))
+			base := r.generateValue(rng)
+			var record serialize.Record
+			if r.serialization != nil {
+				record = SampleRecord(rng, "")
+			}
+			next := time.Now()
+			for {
+				// Writes already due are issued without sleeping, so a slow
+				// Writer delays the schedule but does not lower the rate.
+				next = next.Add(interval)
+				if !sleepUntil(ctx, next) {
+					return
+				}
+				key := ext.Keys[rng.Intn(len(ext.Keys))]
+				value, seq := base, int64(0)
+				if r.staleness != nil {
+					seq, value = r.staleness.Stamp(base)
+				}
+				var err error
+				if r.serialization != nil {
+					// Unlike the measured workers', these encodings are
+					// not timed.
+					var b []byte
+					record.Body = value
+					if b, err = r.serialization.serializer.Marshal(&record); err == nil {
+						value = string(b)
+					}
+				}
+				if err == nil && r.codec != nil {
+					value = string(r.codec.Encode(codec.Header{WriterID: r.writerID, Timestamp: time.Now(), Seq: uint64(seq)}, []byte(value)))
+				}
+				if err == nil {
+					err = ext.Writer.Write(ctx, key, value)
+				}
+				if err != nil {
+					if ctx.Err() == nil {
+						atomic.AddInt64(&r.result.ExternalWriteErrors, 1)
+					}
+					continue
+				}
+				atomic.AddInt64(&r.result.ExternalWrites, 1)
+				if r.staleness != nil {
+					r.staleness.Commit(key, seq)
+				}
+			}
+		}()
+	}
+	return func() {
+		cancel()
+		wg.Wait()
+		if err := ext.Writer.Close(context.Background()); err != nil {
+			log.Printf("Warning: closing external writer: %v", err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package benchmark
+
+import "time"
+
+// FailoverStats describes a strategy's switch from a primary to a standby backend.
+type FailoverStats struct {
+	FailedOver bool
+	SwitchedAt time.Time
+	// DetectionTime runs from the first failed health check to the switch.
+	DetectionTime time.Duration
+}
+
+// FailoverReporter is implemented by strategies with client-side failover.
+type FailoverReporter interface {
+	FailoverStats() FailoverStats
+}
+
+// FailoverReport summarizes a failover within a run.
+type FailoverReport struct {
+	// SwitchedAfter is the offset from the start of the run of the switch.
+	SwitchedAfter time.Duration
+	DetectionTime time.Duration
+	// L1 hit rate before and after the switch.
+	HitRateBefore float64
+	HitRateAfter  float64
+}
+
+// reportFailover fills in Result.Failover when the strategy failed over,
+// splitting the hit rate at the switch using the sampled hit counters.
+func (r *Runner) reportFailover(stats FailoverStats) {
+	if !stats.FailedOver {
+		return
+	}
+	report := &FailoverReport{
+		SwitchedAfter: stats.SwitchedAt.Sub(r.startTime),
+		DetectionTime: stats.DetectionTime,
+	}
+	var hitsBefore, missesBefore int64
+	for _, s := range r.result.Samples {
+		if s.Elapsed > report.SwitchedAfter {
+			break
+		}
+		hitsBefore, missesBefore = s.Hits, s.Misses
+	}
+	report.HitRateBefore = ratio(hitsBefore, hitsBefore+missesBefore)
+	hitsAfter, missesAfter := r.result.TotalHits-hitsBefore, r.result.TotalMisses-missesBefore
+	report.HitRateAfter = ratio(hitsAfter, hitsAfter+missesAfter)
+	r.result.Failover = report
+}
+
+func ratio(n, d int64) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
@@ -0,0 +1,105 @@
+package benchmark
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// unfairIndex is the fairness index below which workers are reported as
+// starved: it is 0.9 when half the workers completed half as many
+// operations as the other half.
+const unfairIndex = 0.9
+
+// WorkerStats summarizes the operations one worker completed.
+type WorkerStats struct {
+	Worker int
+	Ops    int64
+	Mean   time.Duration
+	// P99 is accurate to a quarter octave, like the timeline's.
+	P99 time.Duration
+	Max time.Duration
+}
+
+// WorkerFairness describes how evenly the workers shared the run. A
+// pipelining client can serve some goroutines ahead of others under
+// contention, starving them while the aggregate throughput looks healthy.
+type WorkerFairness struct {
+	Workers []WorkerStats
+	// Index is Jain's fairness index of the workers' operation counts:
+	// 1 when all completed as many, down to 1/len(Workers) when one
+	// completed them all.
+	Index float64
+	// Imbalance is the spread between the busiest and least busy workers'
+	// operation counts over the mean count; 0 when all completed as many.
+	Imbalance float64
+	// P99Spread is the highest per-worker p99 over the median one.
+	P99Spread float64
+}
+
+// Starved reports whether some workers were served markedly less than others.
+func (f *WorkerFairness) Starved() bool {
+	return f != nil && f.Index < unfairIndex
+}
+
+// workerTally accumulates one worker's latencies without locking; the
+// worker stores it in Runner.workers when it exits.
+type workerTally struct {
+	ops   int64
+	total time.Duration
+	max   time.Duration
+	hist  latencyHistogram
+}
+
+func (t *workerTally) add(latency time.Duration) {
+	t.ops++
+	t.total += latency
+	t.max = max(t.max, latency)
+	t.hist[latencyBucket(latency)]++
+}
+
+// fairness summarizes the workers' tallies; nil for fewer than two workers.
+func fairness(tallies []workerTally) *WorkerFairness {
+	if len(tallies) < 2 {
+		return nil
+	}
+	f := &WorkerFairness{Workers: make([]WorkerStats, len(tallies))}
+	var sum, sumSquares float64
+	lo, hi := tallies[0].ops, tallies[0].ops
+	p99s := make([]time.Duration, 0, len(tallies))
+	for i := range tallies {
+		t := &tallies[i]
+		w := WorkerStats{Worker: i, Ops: t.ops, Max: t.max}
+		if t.ops > 0 {
+			w.Mean = t.total / time.Duration(t.ops)
+			w.P99, _ = t.hist.quantile(0.99)
+			p99s = append(p99s, w.P99)
+		}
+		f.Workers[i] = w
+		n := float64(t.ops)
+		sum += n
+		sumSquares += n * n
+		lo, hi = min(lo, t.ops), max(hi, t.ops)
+	}
+	if sum == 0 {
+		return nil
+	}
+	f.Index = sum * sum / (float64(len(tallies)) * sumSquares)
+	f.Imbalance = float64(hi-lo) / (sum / float64(len(tallies)))
+	sort.Slice(p99s, func(i, j int) bool { return p99s[i] < p99s[j] })
+	if median := p99s[len(p99s)/2]; median > 0 {
+		f.P99Spread = float64(p99s[len(p99s)-1]) / float64(median)
+	}
+	return f
+}
+
+// String summarizes the fairness, e.g. "index 0.998, 1520-1610 ops per
+// worker (imbalance 5.8%), worst worker p99 1.3x the median".
+func (f *WorkerFairness) String() string {
+	lo, hi := f.Workers[0].Ops, f.Workers[0].Ops
+	for _, w := range f.Workers[1:] {
+		lo, hi = min(lo, w.Ops), max(hi, w.Ops)
+	}
+	return fmt.Sprintf("index %.3f, %d-%d ops per worker (imbalance %.1f%%), worst worker p99 %.1fx the median",
+		f.Index, lo, hi, f.Imbalance*100, f.P99Spread)
+}
@@ -0,0 +1,100 @@
+package benchmark
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Fault is a disruption injected into the backend during a run.
+type Fault struct {
+	// At is the offset from the start of the run at which Inject is called.
+	At     time.Duration
+	Name   string
+	Inject func(ctx context.Context) error
+}
+
+// FaultReport describes how a run behaved after one injected fault, up to
+// the next fault or the end of the run.
+type FaultReport struct {
+	Name string
+	// At is the offset at which the fault was actually injected.
+	At time.Duration
+	// Err is set if the fault could not be injected.
+	Err        error
+	Errors     int64
+	StaleReads int64
+	// RecoveryTime runs from injection to the last error it was followed by;
+	// zero when the fault caused no errors.
+	RecoveryTime time.Duration
+}
+
+// eventLog records when errors and stale reads happened, as offsets from the
+// start of the run, so they can be attributed to faults.
+type eventLog struct {
+	mu         sync.Mutex
+	errors     []time.Duration
+	staleReads []time.Duration
+}
+
+func (l *eventLog) add(events *[]time.Duration, at time.Duration) {
+	l.mu.Lock()
+	*events = append(*events, at)
+	l.mu.Unlock()
+}
+
+// startFaults injects each fault at its offset from start. It returns a
+// function that stops injection and waits for any fault in progress.
+func (r *Runner) startFaults(ctx context.Context, start time.Time) (stop func()) {
+	if len(r.faults) == 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, f := range r.faults {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Until(start.Add(f.At))):
+			}
+			report := FaultReport{Name: f.Name, At: time.Since(start)}
+			log.Printf("Injecting fault %s at %v", f.Name, report.At.Round(time.Millisecond))
+			if err := f.Inject(ctx); err != nil {
+				report.Err = err
+				log.Printf("Failed to inject fault %s: %v", f.Name, err)
+			}
+			r.result.Faults = append(r.result.Faults, report)
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// summarizeFaults attributes the errors and stale reads following each fault to it.
+func (r *Runner) summarizeFaults() {
+	reports := r.result.Faults
+	for i := range reports {
+		from := reports[i].At
+		to := r.result.TotalDuration
+		if i+1 < len(reports) {
+			to = reports[i+1].At
+		}
+		for _, at := range r.events.errors {
+			if at >= from && at < to {
+				reports[i].Errors++
+				reports[i].RecoveryTime = max(reports[i].RecoveryTime, at-from)
+			}
+		}
+		for _, at := range r.events.staleReads {
+			if at >= from && at < to {
+				reports[i].StaleReads++
+			}
+		}
+	}
+}
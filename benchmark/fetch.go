@@ -0,0 +1,152 @@
+package benchmark
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FetchRecorder receives the time span of every backend (L2) fetch a strategy issues.
+type FetchRecorder interface {
+	Record(key string, start, end time.Time)
+}
+
+// FetchInstrumented is implemented by strategies that can report their
+// backend fetches. The runner calls SetFetchRecorder before Init.
+type FetchInstrumented interface {
+	SetFetchRecorder(rec FetchRecorder)
+}
+
+// BackendStats counts the traffic a strategy sends to its backend store.
+type BackendStats struct {
+	// Requests counts round trips; a pipelined batch counts once.
+	Requests      int64
+	Commands      int64
+	BytesSent     int64
+	BytesReceived int64
+	MaxBatchSize  int64
+}
+
+// BackendReporter is implemented by strategies that count their backend traffic.
+type BackendReporter interface {
+	BackendStats() BackendStats
+}
+
+// fetchShards is the number of independently locked shards of a
+// FetchTracker's per-key state.
+const fetchShards = 64
+
+// fetchSpan is one recorded fetch and the number of fetches of its key,
+// itself included, in flight when it started.
+type fetchSpan struct {
+	start, end time.Time
+	open       int
+}
+
+// FetchTracker is a FetchRecorder that computes, per key, the peak number of
+// overlapping backend fetches. This exposes cache stampedes where many
+// workers miss on the same key at once.
+//
+// Keys are spread over locked shards, so fetches of different keys rarely
+// contend, and only the spans that can still overlap a later fetch are
+// kept: those that ended within twice the longest fetch seen, which bounds
+// the state by the fetches in flight rather than by the run's length.
+type FetchTracker struct {
+	shards  [fetchShards]fetchShard
+	total   atomic.Int64
+	longest atomic.Int64
+	peak    atomic.Int64
+	// hottestKey is the key the peak was reached on, guarded by hottestMu.
+	hottestMu  sync.Mutex
+	hottestKey string
+}
+
+type fetchShard struct {
+	mu      sync.Mutex
+	spans   map[string][]fetchSpan
+	records int
+}
+
+// fetchSweepEvery is how many records a shard takes between sweeps of the
+// keys not fetched since their spans expired.
+const fetchSweepEvery = 1024
+
+func NewFetchTracker() *FetchTracker {
+	t := &FetchTracker{}
+	for i := range t.shards {
+		t.shards[i].spans = make(map[string][]fetchSpan)
+	}
+	return t
+}
+
+func (t *FetchTracker) Record(key string, start, end time.Time) {
+	t.total.Add(1)
+	d := int64(end.Sub(start))
+	for longest := t.longest.Load(); d > longest && !t.longest.CompareAndSwap(longest, d); longest = t.longest.Load() {
+	}
+	// Later fetches start no earlier than the longest fetch before their
+	// end; the margin covers records arriving slightly out of order.
+	cutoff := end.Add(-2 * time.Duration(t.longest.Load()))
+
+	sh := &t.shards[fnv32(key)%fetchShards]
+	sh.mu.Lock()
+	spans := expireSpans(sh.spans[key], cutoff)
+	// The peak is reached at some fetch's start, so it suffices to count
+	// the fetches open at the new one's start, and those starting during it.
+	s := fetchSpan{start: start, end: end, open: 1}
+	peak := 0
+	for i := range spans {
+		o := &spans[i]
+		if !o.start.After(start) && o.end.After(start) {
+			s.open++
+		}
+		if !o.start.Before(start) && o.start.Before(end) {
+			o.open++
+			peak = max(peak, o.open)
+		}
+	}
+	peak = max(peak, s.open)
+	sh.spans[key] = append(spans, s)
+	if sh.records++; sh.records%fetchSweepEvery == 0 {
+		for k, spans := range sh.spans {
+			if spans = expireSpans(spans, cutoff); len(spans) == 0 {
+				delete(sh.spans, k)
+			} else {
+				sh.spans[k] = spans
+			}
+		}
+	}
+	sh.mu.Unlock()
+
+	if int64(peak) > t.peak.Load() {
+		t.hottestMu.Lock()
+		if int64(peak) > t.peak.Load() {
+			t.peak.Store(int64(peak))
+			t.hottestKey = key
+		}
+		t.hottestMu.Unlock()
+	}
+}
+
+// expireSpans drops the spans that ended before cutoff, in place.
+func expireSpans(spans []fetchSpan, cutoff time.Time) []fetchSpan {
+	return slices.DeleteFunc(spans, func(s fetchSpan) bool { return s.end.Before(cutoff) })
+}
+
+// fnv32 is FNV-1a, inlined so picking a key's shard does not allocate.
+func fnv32(key string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h = (h ^ uint32(key[i])) * 16777619
+	}
+	return h
+}
+
+// Stats returns the total number of fetches and the highest per-key
+// concurrency observed, along with the key it occurred on.
+func (t *FetchTracker) Stats() (total int64, maxConcurrent int, hottestKey string) {
+	t.hottestMu.Lock()
+	defer t.hottestMu.Unlock()
+	return t.total.Load(), int(t.peak.Load()), t.hottestKey
+}
@@ -0,0 +1,28 @@
+package benchmark
+
+import (
+	"caching-benchmark/workload"
+	"context"
+	"time"
+)
+
+// Hooks lets code embedding the harness attach per-operation instrumentation,
+// fault injection or custom accounting without modifying the worker loop.
+// Every hook is optional and is called concurrently from all workers.
+type Hooks struct {
+	// BeforeOp runs inside the measured span just before the operation.
+	// Returning a non-nil error skips the operation and records the error as
+	// its outcome, which allows faults and delays to be injected.
+	BeforeOp func(ctx context.Context, op workload.Operation) error
+	// AfterOp runs after every operation, including failed ones.
+	AfterOp func(ctx context.Context, op workload.Operation, outcome OpOutcome)
+	// OnError runs for every operation that returned an error.
+	OnError func(ctx context.Context, op workload.Operation, err error)
+}
+
+// OpOutcome describes the result of a single operation as seen by the runner.
+type OpOutcome struct {
+	Hit     bool
+	Latency time.Duration
+	Err     error
+}
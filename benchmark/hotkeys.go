@@ -0,0 +1,18 @@
+package benchmark
+
+// HotKeyStats describes hot keys detected by server-side bookkeeping.
+type HotKeyStats struct {
+	// HotReads counts reads of keys whose access count in the current
+	// window had reached the hot threshold.
+	HotReads int64
+	// DistinctHotKeys counts the keys detected as hot at least once.
+	DistinctHotKeys int64
+	// HottestKey is the key with the highest windowed access count seen.
+	HottestKey   string
+	HottestCount int64
+}
+
+// HotKeyReporter is implemented by strategies that detect hot keys.
+type HotKeyReporter interface {
+	HotKeyStats() HotKeyStats
+}
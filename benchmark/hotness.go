@@ -0,0 +1,86 @@
+package benchmark
+
+import "fmt"
+
+// HotnessStats counts the reads of the keys in one band of popularity
+// ranks, such as the hottest 1% of keys.
+type HotnessStats struct {
+	// Name describes the band as percentiles of popularity, e.g. "top 1%".
+	Name    string
+	Reads   int64
+	Hits    int64
+	HitRate float64
+}
+
+// hotnessBands are the upper bounds of the popularity bands as fractions
+// of the ranked keys: the top 1%, the rest of the top decile, then each
+// remaining decile.
+var hotnessBands = []float64{0.01, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1}
+
+// hotness counts reads per popularity band of the operations' Rank.
+type hotness struct {
+	numKeys  int
+	counters []classCounters
+}
+
+func newHotness(numKeys int) *hotness {
+	if numKeys <= 0 {
+		return nil
+	}
+	return &hotness{numKeys: numKeys, counters: make([]classCounters, len(hotnessBands))}
+}
+
+// band returns the band of a 1-based popularity rank, or -1 if the rank is
+// unknown.
+func (h *hotness) band(rank int) int {
+	if h == nil || rank <= 0 {
+		return -1
+	}
+	for i, bound := range hotnessBands {
+		if float64(rank) <= bound*float64(h.numKeys) {
+			return i
+		}
+	}
+	return len(hotnessBands) - 1
+}
+
+func (h *hotness) record(band int, hit bool) {
+	if band < 0 {
+		return
+	}
+	c := &h.counters[band]
+	c.reads.Add(1)
+	if hit {
+		c.hits.Add(1)
+	}
+}
+
+// stats returns the per-band counts from hottest to coldest, or nil when no
+// ranked key was read.
+func (h *hotness) stats() []HotnessStats {
+	if h == nil {
+		return nil
+	}
+	out := make([]HotnessStats, len(hotnessBands))
+	var reads int64
+	lower := 0.0
+	for i, bound := range hotnessBands {
+		c := &h.counters[i]
+		s := HotnessStats{Reads: c.reads.Load(), Hits: c.hits.Load()}
+		if lower == 0 {
+			s.Name = fmt.Sprintf("top %g%%", bound*100)
+		} else {
+			s.Name = fmt.Sprintf("%g-%g%%", lower*100, bound*100)
+		}
+		if s.Reads > 0 {
+			s.HitRate = float64(s.Hits) / float64(s.Reads)
+		}
+		reads += s.Reads
+		out[i] = s
+		lower = bound
+	}
+	if reads == 0 {
+		return nil
+	}
+	return out
+}
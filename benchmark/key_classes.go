@@ -0,0 +1,135 @@
+package benchmark
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// KeyClassStats counts the reads of one class of keys, such as the keys
+// populated with the same TTL.
+type KeyClassStats struct {
+	Name  string
+	Reads int64
+	Hits  int64
+	// NotFound counts reads of keys absent from the backend, e.g. expired.
+	NotFound int64
+	HitRate  float64
+	// Ops counts every operation on the class's keys and P99Latency is
+	// their 99th percentile latency.
+	Ops        int64
+	P99Latency time.Duration
+}
+
+// keyClasses maps workload keys to classes and counts reads per class.
+type keyClasses struct {
+	index    map[string]int
+	names    []string
+	counters []classCounters
+	mu       sync.Mutex
+	// latencies holds every operation's latency per class, merged from
+	// the workers' classLatencies.
+	latencies [][]time.Duration
+}
+
+// classLatencies are one worker's latencies per class, merged into the
+// runner's when the worker exits so recording them takes no lock.
+type classLatencies [][]time.Duration
+
+func (l classLatencies) add(class int, latency time.Duration) {
+	if class >= 0 {
+		l[class] = append(l[class], latency)
+	}
+}
+
+type classCounters struct {
+	reads, hits, notFound atomic.Int64
+}
+
+func newKeyClasses(classOf map[string]string) *keyClasses {
+	if len(classOf) == 0 {
+		return nil
+	}
+	kc := &keyClasses{index: make(map[string]int, len(classOf))}
+	ids := make(map[string]int)
+	for key, name := range classOf {
+		id, ok := ids[name]
+		if !ok {
+			id = len(kc.names)
+			ids[name] = id
+			kc.names = append(kc.names, name)
+		}
+		kc.index[key] = id
+	}
+	kc.counters = make([]classCounters, len(kc.names))
+	kc.latencies = make([][]time.Duration, len(kc.names))
+	return kc
+}
+
+// newLatencies returns an empty classLatencies for a worker.
+func (kc *keyClasses) newLatencies() classLatencies {
+	if kc == nil {
+		return nil
+	}
+	return make(classLatencies, len(kc.names))
+}
+
+func (kc *keyClasses) merge(l classLatencies) {
+	if kc == nil {
+		return
+	}
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+	for i := range l {
+		kc.latencies[i] = append(kc.latencies[i], l[i]...)
+	}
+}
+
+// lookup returns the class of a workload key, or -1 if it has none.
+func (kc *keyClasses) lookup(key string) int {
+	if kc == nil {
+		return -1
+	}
+	if id, ok := kc.index[key]; ok {
+		return id
+	}
+	return -1
+}
+
+func (kc *keyClasses) record(class int, hit, notFound bool) {
+	if class < 0 {
+		return
+	}
+	c := &kc.counters[class]
+	c.reads.Add(1)
+	if hit {
+		c.hits.Add(1)
+	}
+	if notFound {
+		c.notFound.Add(1)
+	}
+}
+
+// stats returns the per-class counts in order of first appearance.
+func (kc *keyClasses) stats() []KeyClassStats {
+	if kc == nil {
+		return nil
+	}
+	out := make([]KeyClassStats, len(kc.names))
+	for i, name := range kc.names {
+		c := &kc.counters[i]
+		s := KeyClassStats{Name: name, Reads: c.reads.Load(), Hits: c.hits.Load(), NotFound: c.notFound.Load()}
+		if s.Reads > 0 {
+			s.HitRate = float64(s.Hits) / float64(s.Reads)
+		}
+		if lat := kc.latencies[i]; len(lat) > 0 {
+			sort.Slice(lat, func(a, b int) bool { return lat[a] < lat[b] })
+			s.Ops = int64(len(lat))
+			s.P99Latency = lat[int(math.Ceil(0.99*float64(len(lat))))-1]
+		}
+		out[i] = s
+	}
+	return out
+}
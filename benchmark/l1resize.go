@@ -0,0 +1,130 @@
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// L1Resize changes the L1 budget of an L1Resizer strategy At into the run.
+type L1Resize struct {
+	At     time.Duration
+	Budget int64
+}
+
+// L1Resizer is implemented by strategies whose L1 budget can change while
+// they serve traffic, as when a container's memory limit is adjusted or
+// shared with a growing application heap. With cold set the L1 is emptied
+// first, as if recreated with the new budget.
+type L1Resizer interface {
+	ResizeL1(budget int64, cold bool) error
+}
+
+// L1Phase describes the part of a run between one L1 budget change and the
+// next, or the end of the run.
+type L1Phase struct {
+	// At is the offset from the start of the run the phase began at, and
+	// Budget the L1 budget set then; the first phase runs on the strategy's
+	// initial budget and has Budget zero.
+	At     time.Duration
+	Budget int64
+	// Err is set if the budget could not be changed.
+	Err error
+	// HitRate is over the whole phase and FirstSecondHitRate over its first
+	// second, the L1's immediate response to the change.
+	HitRate            float64
+	FirstSecondHitRate float64
+	// P99 is over the whole seconds of the run the phase spans.
+	P99 time.Duration
+}
+
+// String summarizes the phase, e.g. "+10s at 4.0 MB: hit rate 61.2%
+// (48.0% in the first second), p99 1.2ms".
+func (p L1Phase) String() string {
+	budget := "initial budget"
+	if p.Budget > 0 {
+		budget = fmt.Sprintf("%.1f MB", float64(p.Budget)/(1<<20))
+	}
+	if p.Err != nil {
+		return fmt.Sprintf("+%v at %s: resize failed: %v", p.At.Round(time.Millisecond), budget, p.Err)
+	}
+	return fmt.Sprintf("+%v at %s: hit rate %.1f%% (%.1f%% in the first second), p99 %v",
+		p.At.Round(time.Millisecond), budget, p.HitRate*100, p.FirstSecondHitRate*100, p.P99)
+}
+
+// startL1Schedule applies the L1 budget changes at their offsets from
+// start. It returns a function that stops the schedule and waits for it.
+func (r *Runner) startL1Schedule(ctx context.Context, start time.Time) (stop func()) {
+	if len(r.l1Schedule) == 0 {
+		return func() {}
+	}
+	resizer, ok := r.strategy.(L1Resizer)
+	if !ok {
+		log.Printf("Strategy %s cannot resize its L1; running on its initial budget", r.strategy.Name())
+		return func() {}
+	}
+
+	r.l1Phases = []L1Phase{{}}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, step := range r.l1Schedule {
+			if !sleepUntil(ctx, start.Add(step.At)) {
+				return
+			}
+			phase := L1Phase{At: time.Since(start), Budget: step.Budget}
+			phase.Err = resizer.ResizeL1(step.Budget, r.l1ResizeCold)
+			if phase.Err != nil {
+				log.Printf("Failed to resize L1 of %s: %v", r.strategy.Name(), phase.Err)
+			} else {
+				log.Printf("Resized L1 of %s to %.1f MB at %v", r.strategy.Name(), float64(step.Budget)/(1<<20), phase.At.Round(time.Millisecond))
+			}
+			r.l1Phases = append(r.l1Phases, phase)
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// summarizeL1Phases fills in each phase's hit rates from the samples and
+// its p99 from the per-second latency histograms.
+func (r *Runner) summarizeL1Phases() {
+	phases := r.l1Phases
+	samples := r.result.Samples
+	for i := range phases {
+		p := &phases[i]
+		end := r.result.TotalDuration
+		if i+1 < len(phases) {
+			end = phases[i+1].At
+		}
+		from, to := sampleAt(samples, p.At), sampleAt(samples, end)
+		p.HitRate = hitRate(to.Hits-from.Hits, to.Misses-from.Misses)
+		first := sampleAt(samples, min(p.At+time.Second, end))
+		p.FirstSecondHitRate = hitRate(first.Hits-from.Hits, first.Misses-from.Misses)
+		p.P99 = r.timeline.p99(p.At, end)
+	}
+	r.result.L1Phases = phases
+}
+
+// p99 returns the p99 latency of the operations completed in the whole
+// seconds from from to to, or of the second from falls in when the span is
+// shorter than a second.
+func (t *timeline) p99(from, to time.Duration) time.Duration {
+	if t == nil {
+		return 0
+	}
+	first := int(from / time.Second)
+	last := max(int(to/time.Second), first+1)
+	var merged latencyHistogram
+	for s := first; s < min(last, len(t.seconds)); s++ {
+		for b, n := range t.seconds[s] {
+			merged[b] += n
+		}
+	}
+	p99, _ := merged.quantile(0.99)
+	return p99
+}
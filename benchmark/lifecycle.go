@@ -0,0 +1,80 @@
+package benchmark
+
+import (
+	"context"
+	"time"
+)
+
+// LifecycleStats times the phases of a run around its steady state.
+type LifecycleStats struct {
+	// DataPrep is the time the caller spent populating the datastore for
+	// the run; the runner leaves it zero.
+	DataPrep time.Duration
+	// Init is the strategy's Init, e.g. connecting and enabling tracking.
+	Init time.Duration
+	// Prewarm is the time spent loading an L1 snapshot after Init.
+	Prewarm time.Duration
+	// Warmup is how long after the start the hit rate over a
+	// warmupWindow first reached warmupFraction of its steady-state value,
+	// the hit rate over the second half of the run. It is zero for runs
+	// without hits.
+	Warmup time.Duration
+	// Close is the strategy's Close, after Drain.
+	Close time.Duration
+}
+
+const (
+	warmupFraction = 0.95
+	warmupWindow   = time.Second
+)
+
+// initStrategy initializes the strategy, timing it.
+func (r *Runner) initStrategy(ctx context.Context) error {
+	start := time.Now()
+	err := r.strategy.Init(ctx)
+	r.result.Lifecycle.Init = time.Since(start)
+	return err
+}
+
+// closeStrategy closes the strategy, timing it.
+func (r *Runner) closeStrategy(ctx context.Context) {
+	start := time.Now()
+	r.strategy.Close(ctx)
+	r.result.Lifecycle.Close = time.Since(start)
+}
+
+// warmup returns how long the run took to reach its steady-state hit rate,
+// from the cumulative hits and misses in samples.
+func warmup(samples []Sample) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	last := samples[len(samples)-1]
+	mid := samples[len(samples)/2]
+	steady := windowHitRate(mid, last)
+	if steady <= 0 {
+		return 0
+	}
+	// from is the first sample of the current window.
+	from := Sample{}
+	for _, s := range samples {
+		if s.Elapsed-from.Elapsed < warmupWindow {
+			continue
+		}
+		if windowHitRate(from, s) >= warmupFraction*steady {
+			return from.Elapsed
+		}
+		from = s
+	}
+	return last.Elapsed
+}
+
+// windowHitRate returns the hit rate of the reads between two cumulative
+// samples.
+func windowHitRate(from, to Sample) float64 {
+	reads := to.Hits - from.Hits + to.Misses - from.Misses
+	if reads <= 0 {
+		return 0
+	}
+	return float64(to.Hits-from.Hits) / float64(reads)
+}
@@ -0,0 +1,20 @@
+package benchmark
+
+import "time"
+
+// ListenerStats describes lost invalidation subscriptions during a run.
+type ListenerStats struct {
+	Disconnects int64
+	// DownTime is the total time without a live subscription; invalidations
+	// published during it were missed. MaxDownTime is the longest window.
+	DownTime    time.Duration
+	MaxDownTime time.Duration
+	// L1Flushes counts L1 clears made after resubscribing.
+	L1Flushes int64
+}
+
+// ListenerReporter is implemented by strategies that receive invalidations
+// over a subscription.
+type ListenerReporter interface {
+	ListenerStats() ListenerStats
+}
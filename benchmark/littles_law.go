@@ -0,0 +1,60 @@
+package benchmark
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// LittlesLaw compares the mean number of operations in flight, as sampled,
+// with the number Little's Law predicts from throughput and mean latency
+// (L = λW). The two disagreeing points at a measurement bug in the harness,
+// such as dropped latency samples or a timer around the wrong span.
+type LittlesLaw struct {
+	MeasuredInFlight  float64
+	PredictedInFlight float64
+}
+
+const (
+	// littlesLawTolerance is the relative deviation above which the
+	// measurements are reported as inconsistent.
+	littlesLawTolerance = 0.25
+	// minLittlesLawSamples is the fewest in-flight samples needed for a
+	// meaningful mean; shorter runs are not checked.
+	minLittlesLawSamples = 5
+)
+
+// Deviation returns the relative difference between measured and predicted
+// concurrency.
+func (l LittlesLaw) Deviation() float64 {
+	if l.PredictedInFlight == 0 {
+		return 0
+	}
+	return math.Abs(l.MeasuredInFlight-l.PredictedInFlight) / l.PredictedInFlight
+}
+
+// Violation reports whether the measurements are mutually inconsistent.
+func (l LittlesLaw) Violation() (string, bool) {
+	if l.Deviation() <= littlesLawTolerance {
+		return "", false
+	}
+	return fmt.Sprintf("mean in-flight ops %.2f but throughput x mean latency predicts %.2f (%.0f%% off); latency or throughput measurements may be wrong",
+		l.MeasuredInFlight, l.PredictedInFlight, l.Deviation()*100), true
+}
+
+// checkLittlesLaw fills in Result.LittlesLaw when the run was long enough to check.
+func (r *Runner) checkLittlesLaw() {
+	if len(r.result.Samples) < minLittlesLawSamples || len(r.result.Latencies) == 0 {
+		return
+	}
+	var total time.Duration
+	for _, lat := range r.result.Latencies {
+		total += lat
+	}
+	meanLatency := total.Seconds() / float64(len(r.result.Latencies))
+	_, _, meanInFlight, _ := summarizeSamples(r.result.Samples)
+	r.result.LittlesLaw = &LittlesLaw{
+		MeasuredInFlight:  meanInFlight,
+		PredictedInFlight: r.result.OpsPerSecond * meanLatency,
+	}
+}
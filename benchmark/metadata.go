@@ -0,0 +1,80 @@
+package benchmark
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// Metadata describes the machine, software and configuration a run
+// measured, so that results remain interpretable and comparable later.
+// Host fields are empty where the platform does not expose them.
+type Metadata struct {
+	GoVersion  string `json:"go_version"`
+	GOMAXPROCS int    `json:"gomaxprocs"`
+	NumCPU     int    `json:"num_cpu"`
+	CPUModel   string `json:"cpu_model,omitempty"`
+	// MemoryBytes is the host's total physical memory.
+	MemoryBytes int64 `json:"memory_bytes,omitempty"`
+	// OS is the platform and, where known, kernel release.
+	OS string `json:"os"`
+	// RedisVersion is the server's version as reported by INFO.
+	RedisVersion string `json:"redis_version,omitempty"`
+	// Libraries maps the modules the binary was built with, such as the
+	// strategies' client and cache libraries, to their versions.
+	Libraries map[string]string `json:"libraries,omitempty"`
+	// ConfigHash identifies the scenario configuration, so runs of the
+	// same scenario can be matched even after it is renamed.
+	ConfigHash string `json:"config_hash,omitempty"`
+}
+
+// HostMetadata collects the metadata of the running process and its host;
+// the caller fills in RedisVersion and ConfigHash.
+func HostMetadata() Metadata {
+	m := Metadata{
+		GoVersion:  runtime.Version(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		NumCPU:     runtime.NumCPU(),
+		OS:         runtime.GOOS + "/" + runtime.GOARCH,
+		CPUModel:   procField("/proc/cpuinfo", "model name"),
+	}
+	if release, err := os.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		m.OS += " " + strings.TrimSpace(string(release))
+	}
+	if total, ok := strings.CutSuffix(procField("/proc/meminfo", "MemTotal"), " kB"); ok {
+		if kb, err := strconv.ParseInt(strings.TrimSpace(total), 10, 64); err == nil {
+			m.MemoryBytes = kb << 10
+		}
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && len(info.Deps) > 0 {
+		m.Libraries = make(map[string]string, len(info.Deps))
+		for _, dep := range info.Deps {
+			version := dep.Version
+			if dep.Replace != nil {
+				version = dep.Replace.Path + " " + dep.Replace.Version
+			}
+			m.Libraries[dep.Path] = strings.TrimSpace(version)
+		}
+	}
+	return m
+}
+
+// procField returns the value of the first "name: value" line of a /proc
+// file, or "" when there is none.
+func procField(path, name string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if k, v, ok := strings.Cut(scanner.Text(), ":"); ok && strings.TrimSpace(k) == name {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,22 @@
+package benchmark
+
+import "sort"
+
+// MetricsReporter is implemented by strategies that expose internal
+// counters, such as their L1 library's hits and evictions or the
+// invalidation messages they processed, so that a strategy's view of a run
+// can be cross-checked against the harness's. Counters are cumulative since
+// Init, so they include any pre-warming.
+type MetricsReporter interface {
+	Metrics() map[string]int64
+}
+
+// metricNames returns the names in metrics in a stable order.
+func metricNames(metrics map[string]int64) []string {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,118 @@
+package benchmark
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// Payload kinds accepted by GeneratePayload.
+const (
+	// PayloadHex is random bytes, hex-encoded: incompressible beyond the
+	// encoding and twice the requested size. It is the default.
+	PayloadHex = "hex"
+	// PayloadText is words drawn from a small vocabulary, compressing like
+	// natural-language text.
+	PayloadText = "text"
+	// PayloadJSON is a JSON document with typed fields and a text body.
+	PayloadJSON = "json"
+	// PayloadBinary is random bytes: incompressible.
+	PayloadBinary = "binary"
+)
+
+// PayloadKinds lists the valid payload kinds.
+var PayloadKinds = []string{PayloadHex, PayloadText, PayloadJSON, PayloadBinary}
+
+// ValidPayload reports an error for an unknown payload kind; empty selects PayloadHex.
+func ValidPayload(kind string) error {
+	for _, k := range PayloadKinds {
+		if kind == k || kind == "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown payload %q (want one of %s)", kind, strings.Join(PayloadKinds, ", "))
+}
+
+// GeneratePayload returns a value of the given kind drawn from rng. Every
+// kind but PayloadHex is exactly size bytes long. Unknown kinds generate
+// PayloadHex values; check them with ValidPayload.
+func GeneratePayload(kind string, rng *rand.Rand, size int) string {
+	switch kind {
+	case PayloadText:
+		return generateText(rng, size)
+	case PayloadJSON:
+		return generateJSON(rng, size)
+	case PayloadBinary:
+		b := make([]byte, size)
+		rng.Read(b)
+		return string(b)
+	}
+	return generateValue(rng, size, buffers)
+}
+
+// textVocabulary gives text payloads the word-level redundancy of prose.
+var textVocabulary = strings.Fields(`the cache server client key value read write
+	invalidation latency throughput request response update user session order
+	product price stock region account event stream message tracking memory
+	eviction expire connection pipeline cluster shard replica primary standby
+	hit miss stale fresh hot cold policy budget window batch queue`)
+
+func generateText(rng *rand.Rand, size int) string {
+	var b strings.Builder
+	b.Grow(size + 16)
+	for b.Len() < size {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(textVocabulary[rng.Intn(len(textVocabulary))])
+	}
+	return b.String()[:size]
+}
+
+// generateJSON builds a document of typed fields whose text body pads it to
+// size bytes. Values too small for the fields are plain text.
+func generateJSON(rng *rand.Rand, size int) string {
+	var b strings.Builder
+	b.WriteString(`{"id":`)
+	b.WriteString(strconv.Itoa(rng.Intn(1 << 30)))
+	b.WriteString(`,"name":"`)
+	b.WriteString(textVocabulary[rng.Intn(len(textVocabulary))])
+	b.WriteString(`","active":`)
+	b.WriteString(strconv.FormatBool(rng.Intn(2) == 0))
+	b.WriteString(`,"score":`)
+	b.WriteString(strconv.FormatFloat(rng.Float64()*100, 'f', 2, 64))
+	b.WriteString(`,"tags":["`)
+	for i := 0; i < 3; i++ {
+		if i > 0 {
+			b.WriteString(`","`)
+		}
+		b.WriteString(textVocabulary[rng.Intn(len(textVocabulary))])
+	}
+	b.WriteString(`"],"body":"`)
+	const closing = `"}`
+	pad := size - b.Len() - len(closing)
+	if pad < 0 {
+		return generateText(rng, size)
+	}
+	b.WriteString(generateText(rng, pad))
+	b.WriteString(closing)
+	return b.String()
+}
+
+// generateValue returns size random bytes, hex-encoded. With a pool the
+// random bytes are drawn into a pooled buffer and encoded directly; without
+// one they are formatted through fmt, allocating twice more.
+func generateValue(rng *rand.Rand, size int, pool *bufferPool) string {
+	if pool == nil {
+		b := make([]byte, size)
+		rng.Read(b)
+		return fmt.Sprintf("%x", b)
+	}
+	b := pool.get(size)
+	rng.Read(b)
+	value := hex.EncodeToString(b)
+	pool.put(b)
+	return value
+}
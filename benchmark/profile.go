@@ -0,0 +1,82 @@
+package benchmark
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+)
+
+// mutexProfileFraction samples one in this many mutex contention events
+// while profiling.
+const mutexProfileFraction = 5
+
+// profiler captures pprof profiles of one run: CPU while the workers are
+// active, then the heap and mutex contention once they finish. Mutex
+// profiling is enabled only between startProfiles and stop, so runs without
+// profiling pay nothing for it; block profiling is never enabled.
+type profiler struct {
+	base          string
+	cpu           *os.File
+	prevMutexRate int
+}
+
+// startProfiles starts CPU profiling into dir, naming files after name. A
+// name already used in dir, as by the points of a sweep, is numbered.
+func startProfiles(dir, name string) (*profiler, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	base := filepath.Join(dir, name)
+	for i := 2; ; i++ {
+		if _, err := os.Stat(base + ".cpu.pprof"); os.IsNotExist(err) {
+			break
+		}
+		base = filepath.Join(dir, fmt.Sprintf("%s-%d", name, i))
+	}
+	cpu, err := os.Create(base + ".cpu.pprof")
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.StartCPUProfile(cpu); err != nil {
+		cpu.Close()
+		os.Remove(cpu.Name())
+		return nil, err
+	}
+	return &profiler{
+		base:          base,
+		cpu:           cpu,
+		prevMutexRate: runtime.SetMutexProfileFraction(mutexProfileFraction),
+	}, nil
+}
+
+// stop ends CPU profiling and writes the heap and mutex profiles, returning
+// the paths of the files written. Mutex contention is cumulative over the
+// process, so later runs' profiles include earlier ones' contention.
+func (p *profiler) stop() ([]string, error) {
+	pprof.StopCPUProfile()
+	runtime.SetMutexProfileFraction(p.prevMutexRate)
+	paths := []string{p.cpu.Name()}
+	if err := p.cpu.Close(); err != nil {
+		return paths, err
+	}
+	// Collect first so the heap profile reflects the run's garbage.
+	runtime.GC()
+	for _, kind := range []string{"heap", "mutex"} {
+		path := p.base + "." + kind + ".pprof"
+		f, err := os.Create(path)
+		if err != nil {
+			return paths, err
+		}
+		err = pprof.Lookup(kind).WriteTo(f, 0)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
@@ -0,0 +1,52 @@
+package benchmark
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// startProgress logs, every progressEvery, the operations completed, the
+// throughput and hit rate since the previous report, and the estimated time
+// to completion at that throughput.
+func (r *Runner) startProgress(ctx context.Context, start time.Time) (stop func()) {
+	if r.progressEvery <= 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		total := int64(len(r.workload))
+		ticker := time.NewTicker(r.progressEvery)
+		defer ticker.Stop()
+		var lastCompleted, lastHits, lastMisses int64
+		last := start
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				completed := r.gauges.completed.Load()
+				hits, misses := r.gauges.hits.Load(), r.gauges.misses.Load()
+				rate := float64(completed-lastCompleted) / now.Sub(last).Seconds()
+				hitRate := 0.0
+				if reads := hits - lastHits + misses - lastMisses; reads > 0 {
+					hitRate = float64(hits-lastHits) / float64(reads) * 100
+				}
+				eta := "unknown"
+				if rate > 0 {
+					eta = time.Duration(float64(total-completed) / rate * float64(time.Second)).Round(time.Second).String()
+				}
+				log.Printf("Progress: %d/%d ops (%.1f%%) after %v, %.0f ops/sec, %.1f%% hit rate, ETA %s",
+					completed, total, float64(completed)/float64(max(total, 1))*100, now.Sub(start).Round(time.Second), rate, hitRate, eta)
+				lastCompleted, lastHits, lastMisses, last = completed, hits, misses, now
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
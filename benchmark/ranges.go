@@ -0,0 +1,29 @@
+package benchmark
+
+import (
+	"caching-benchmark/workload"
+	"context"
+)
+
+// RangeReader is implemented by strategies that can read a fragment of a
+// value without fetching all of it, such as with GETRANGE. Strategies that
+// do not implement it serve range reads by reading, and caching, the whole
+// value and slicing it on the client.
+type RangeReader interface {
+	// ReadRange returns up to length bytes of key's value starting at
+	// offset, and whether it was a cache hit.
+	ReadRange(ctx context.Context, key string, offset, length int) (value string, hit bool, err error)
+}
+
+// readRange performs the range read op against s.
+func readRange(ctx context.Context, s CachingStrategy, op workload.Operation) (string, bool, error) {
+	if rr, ok := s.(RangeReader); ok {
+		return rr.ReadRange(ctx, op.Key, op.Offset, op.Length)
+	}
+	value, hit, err := s.Read(ctx, op.Key)
+	if err != nil {
+		return value, hit, err
+	}
+	start := min(op.Offset, len(value))
+	return value[start:min(start+op.Length, len(value))], hit, nil
+}
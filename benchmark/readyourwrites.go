@@ -0,0 +1,43 @@
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// checkReadYourWrite reads key back right after the worker's own write with
+// seq to it, through the strategy's normal read path, and counts a violation
+// when the read returns an older value or none. A newer value, from a
+// concurrent writer, satisfies the check. Reads that fail are not checked.
+func (r *Runner) checkReadYourWrite(ctx context.Context, key string, seq int64) {
+	r.holdStrategy()
+	value, _, err := r.strategy.Read(ctx, key)
+	r.releaseStrategy()
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return
+	}
+	atomic.AddInt64(&r.result.ReadYourWritesChecks, 1)
+	if err == nil {
+		value, err = r.decodeStamped(value)
+	}
+	if err != nil || ParseStamp(value) < seq {
+		atomic.AddInt64(&r.result.ReadYourWritesViolations, 1)
+	}
+}
+
+// decodeStamped undoes the codec and serialization applied to a written
+// value, returning the staleness-stamped value.
+func (r *Runner) decodeStamped(value string) (string, error) {
+	if r.codec != nil {
+		_, payload, err := r.codec.Decode([]byte(value))
+		if err != nil {
+			return "", err
+		}
+		value = string(payload)
+	}
+	if r.serialization != nil {
+		return r.serialization.unmarshal([]byte(value))
+	}
+	return value, nil
+}
@@ -0,0 +1,35 @@
+package benchmark
+
+import (
+	"log"
+	"sort"
+	"time"
+)
+
+// logResponseTimes contrasts, in open-loop mode, the service time of
+// operations with their response time from the intended start. A strategy
+// that stalls delays every operation scheduled behind the stall, which the
+// service times alone omit. In closed-loop mode with retries, response
+// times start at the first attempt and add the retries to the service time.
+func (r *Runner) logResponseTimes() {
+	if len(r.result.ResponseTimes) == 0 {
+		return
+	}
+	service := sortedCopy(r.result.Latencies)
+	response := sortedCopy(r.result.ResponseTimes)
+	at := func(sorted []time.Duration, p float64) time.Duration {
+		return sorted[min(len(sorted)-1, int(p*float64(len(sorted))))]
+	}
+	log.Printf("Service Time (p50/p99/max): %v/%v/%v", at(service, 0.5), at(service, 0.99), service[len(service)-1])
+	from := "intended start"
+	if r.targetRate == 0 {
+		from = "first attempt"
+	}
+	log.Printf("Response Time from %s (p50/p99/max): %v/%v/%v", from, at(response, 0.5), at(response, 0.99), response[len(response)-1])
+}
+
+func sortedCopy(latencies []time.Duration) []time.Duration {
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
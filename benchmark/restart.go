@@ -0,0 +1,197 @@
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// restartWindow is the span before a restart and after it over which hit
+// rate and backend traffic are compared, and the width of the sliding window
+// recovery is judged over.
+const restartWindow = time.Second
+
+// RestartStats describes a simulated application restart, in which the
+// strategy is closed and re-initialized mid-run while Redis keeps its data,
+// and how the strategy recovered from the cold start.
+type RestartStats struct {
+	// At is the offset from the start of the run at which the restart began.
+	At time.Duration
+	// Downtime is how long workers were paused: Close, Init and any
+	// pre-warming.
+	Downtime time.Duration
+	// Err is set if the strategy could not be closed or re-initialized.
+	Err error
+	// PrewarmedKeys is the number of keys read to warm the strategy before
+	// workers resumed.
+	PrewarmedKeys int
+	// HitRateBefore is the hit rate over the restartWindow before the
+	// restart.
+	HitRateBefore float64
+	// Recovered is set once the hit rate over a restartWindow reached
+	// warmupFraction of HitRateBefore; RecoveryTime is when, from the moment
+	// workers resumed.
+	Recovered    bool
+	RecoveryTime time.Duration
+	// BackendBefore and BackendAfter are the strategy's backend requests per
+	// second over the restartWindow before the restart and the first one
+	// after workers resumed: the herd of L2 fetches a cold L1 sends. Both are
+	// zero for strategies that do not report backend traffic.
+	BackendBefore float64
+	BackendAfter  float64
+}
+
+// String summarizes the restart and recovery, e.g. "down 12ms, hit rate
+// 91.0% before, recovered in 2.3s, backend 850 -> 9200 req/s".
+func (s RestartStats) String() string {
+	if s.Err != nil {
+		return fmt.Sprintf("failed: %v", s.Err)
+	}
+	recovery := "not recovered"
+	if s.Recovered {
+		recovery = fmt.Sprintf("recovered in %v", s.RecoveryTime.Round(time.Millisecond))
+	}
+	msg := fmt.Sprintf("down %v, hit rate %.1f%% before, %s, backend %.0f -> %.0f req/s",
+		s.Downtime.Round(time.Millisecond), s.HitRateBefore*100, recovery, s.BackendBefore, s.BackendAfter)
+	if s.PrewarmedKeys > 0 {
+		msg += fmt.Sprintf(", %d keys pre-warmed", s.PrewarmedKeys)
+	}
+	return msg
+}
+
+// holdStrategy keeps the strategy from being restarted until the matching
+// releaseStrategy; it waits while a restart is in progress.
+func (r *Runner) holdStrategy() {
+	if r.restartAt > 0 {
+		r.restartMu.RLock()
+	}
+}
+
+func (r *Runner) releaseStrategy() {
+	if r.restartAt > 0 {
+		r.restartMu.RUnlock()
+	}
+}
+
+// startRestart restarts the strategy at restartAt from start. It returns a
+// function that stops the restart, or its recovery tracking, and waits for
+// it.
+func (r *Runner) startRestart(ctx context.Context, start time.Time) (stop func()) {
+	if r.restartAt <= 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if !sleepUntil(ctx, start.Add(r.restartAt-restartWindow)) {
+			return
+		}
+		hits, misses, backend, from := r.gauges.hits.Load(), r.gauges.misses.Load(), r.backendRequests(), time.Now()
+		if !sleepUntil(ctx, start.Add(r.restartAt)) {
+			return
+		}
+		stats := &RestartStats{
+			HitRateBefore: hitRate(r.gauges.hits.Load()-hits, r.gauges.misses.Load()-misses),
+			BackendBefore: float64(r.backendRequests()-backend) / time.Since(from).Seconds(),
+		}
+
+		r.restartMu.Lock()
+		stats.At = time.Since(start)
+		log.Printf("Restarting strategy %s at %v", r.strategy.Name(), stats.At.Round(time.Millisecond))
+		stats.Err = r.strategy.Close(ctx)
+		if stats.Err == nil {
+			stats.Err = r.strategy.Init(ctx)
+		}
+		if stats.Err == nil {
+			for _, key := range r.prewarmKeys {
+				if _, _, err := r.strategy.Read(ctx, key); err == nil {
+					stats.PrewarmedKeys++
+				}
+			}
+		}
+		stats.Downtime = time.Since(start) - stats.At
+		r.restartMu.Unlock()
+		if stats.Err != nil {
+			log.Printf("Failed to restart strategy %s: %v", r.strategy.Name(), stats.Err)
+		}
+		r.result.Restart = stats
+
+		resumed := time.Now()
+		r.trackRecovery(ctx, stats, resumed)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// trackRecovery measures the backend traffic right after workers resumed
+// and how long the hit rate took to recover, until ctx is cancelled.
+func (r *Runner) trackRecovery(ctx context.Context, stats *RestartStats, resumed time.Time) {
+	type point struct {
+		at           time.Time
+		hits, misses int64
+	}
+	backend, measured := r.backendRequests(), false
+	window := []point{{resumed, r.gauges.hits.Load(), r.gauges.misses.Load()}}
+	ticker := time.NewTicker(defaultSampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if !measured && now.Sub(resumed) >= restartWindow {
+				stats.BackendAfter = float64(r.backendRequests()-backend) / now.Sub(resumed).Seconds()
+				measured = true
+			}
+			if stats.Recovered {
+				if measured {
+					return
+				}
+				continue
+			}
+			p := point{now, r.gauges.hits.Load(), r.gauges.misses.Load()}
+			window = append(window, p)
+			for len(window) > 1 && p.at.Sub(window[1].at) >= restartWindow {
+				window = window[1:]
+			}
+			if p.at.Sub(window[0].at) < restartWindow {
+				continue
+			}
+			if hitRate(p.hits-window[0].hits, p.misses-window[0].misses) >= warmupFraction*stats.HitRateBefore {
+				stats.Recovered = true
+				stats.RecoveryTime = now.Sub(resumed)
+			}
+		}
+	}
+}
+
+// backendRequests returns the strategy's cumulative backend requests, or
+// zero for strategies that do not report them.
+func (r *Runner) backendRequests() int64 {
+	if br, ok := r.strategy.(BackendReporter); ok {
+		return br.BackendStats().Requests
+	}
+	return 0
+}
+
+// sleepUntil waits until t and reports whether ctx is still live.
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(time.Until(t)):
+		return true
+	}
+}
+
+func hitRate(hits, misses int64) float64 {
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
@@ -0,0 +1,74 @@
+package benchmark
+
+import (
+	"context"
+	"time"
+)
+
+// RetryPolicy retries operations failing with transient errors, timeouts
+// and connection errors, in the runner rather than in a strategy's client,
+// so that every strategy gets the same second chances. Backoff is the
+// wait before the first retry; it doubles on each further retry, up to
+// MaxBackoff when positive.
+type RetryPolicy struct {
+	MaxRetries int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// Transient reports whether err is worth retrying: a timeout or a
+// connection error, rather than a miss, a conflict or bad data that would
+// fail again.
+func Transient(err error) bool {
+	switch Classify(err) {
+	case ErrCategoryTimeout, ErrCategoryConnection:
+		return true
+	}
+	return false
+}
+
+// wait returns the backoff before retry n, counting from one.
+func (p RetryPolicy) wait(n int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < n; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	if p.MaxBackoff > 0 {
+		d = min(d, p.MaxBackoff)
+	}
+	return d
+}
+
+// attempts tracks the retries of one operation.
+type attempts struct {
+	retries int
+	// wasted is the time spent in failed attempts and backing off, which
+	// counts toward the operation's response time but not its service time.
+	wasted time.Duration
+}
+
+// call runs fn, bounded by Options.OpTimeout, and retries it under the
+// runner's RetryPolicy while it fails with a transient error and ctx, the
+// run's context, is not done.
+func (r *Runner) call(ctx context.Context, a *attempts, fn func(ctx context.Context) error) error {
+	for {
+		start := time.Now()
+		opCtx, cancel := ctx, context.CancelFunc(func() {})
+		if r.opTimeout > 0 {
+			opCtx, cancel = context.WithTimeout(ctx, r.opTimeout)
+		}
+		err := fn(opCtx)
+		cancel()
+		if err == nil || a.retries >= r.retry.MaxRetries || !Transient(err) || ctx.Err() != nil {
+			return err
+		}
+		a.retries++
+		if !sleepUntil(ctx, time.Now().Add(r.retry.wait(a.retries))) {
+			return err
+		}
+		a.wasted += time.Since(start)
+	}
+}
@@ -0,0 +1,54 @@
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// incrementCounter is the modification made by RMW operations: it
+// increments the counter a value starts with ("#<n>:"), from zero for values
+// without one, and keeps the rest of the value.
+func incrementCounter(old string) string {
+	var n int64
+	if strings.HasPrefix(old, "#") {
+		if end := strings.IndexByte(old, ':'); end > 0 {
+			if v, err := strconv.ParseInt(old[1:end], 10, 64); err == nil {
+				n, old = v, old[end+1:]
+			}
+		}
+	}
+	return "#" + strconv.FormatInt(n+1, 10) + ":" + old
+}
+
+// unstamp removes the prefix added by StalenessTracker.Stamp, if any.
+func unstamp(value string) string {
+	if ParseStamp(value) == 0 {
+		return value
+	}
+	return value[strings.IndexByte(value, ':')+1:]
+}
+
+// readModifyWrite performs an RMW operation, stamping the new value when
+// staleness is tracked. It returns the stamp of the value written.
+func (r *Runner) readModifyWrite(ctx context.Context, key string) (seq int64, err error) {
+	modify := incrementCounter
+	if r.staleness != nil {
+		modify = func(old string) string {
+			var value string
+			seq, value = r.staleness.Stamp(incrementCounter(unstamp(old)))
+			return value
+		}
+	}
+	retries, err := r.strategy.ReadModifyWrite(ctx, key, modify)
+	atomic.AddInt64(&r.result.RMWRetries, int64(retries))
+	switch {
+	case err == nil:
+		atomic.AddInt64(&r.result.RMWs, 1)
+	case errors.Is(err, ErrRMWConflict):
+		atomic.AddInt64(&r.result.RMWConflicts, 1)
+	}
+	return seq, err
+}
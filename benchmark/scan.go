@@ -0,0 +1,36 @@
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// Scanner is implemented by strategies that can read every key under a
+// prefix, as session and feed workloads do over Redis hashes and sorted
+// sets. Scan operations against other strategies fail with
+// ErrScanUnsupported.
+type Scanner interface {
+	// Scan returns up to limit keys starting with prefix, with their
+	// values, and how many of those values were served from a cache.
+	Scan(ctx context.Context, prefix string, limit int) (values map[string]string, hits int, err error)
+}
+
+// ErrScanUnsupported is returned for scans of a strategy that is not a Scanner.
+var ErrScanUnsupported = errors.New("strategy does not support scans")
+
+// scan performs a scan op and counts it.
+func (r *Runner) scan(ctx context.Context, prefix string, limit int) error {
+	sc, ok := r.strategy.(Scanner)
+	if !ok {
+		return ErrScanUnsupported
+	}
+	values, hits, err := sc.Scan(ctx, prefix, limit)
+	if err != nil {
+		return err
+	}
+	atomic.AddInt64(&r.result.Scans, 1)
+	atomic.AddInt64(&r.result.ScanKeys, int64(len(values)))
+	atomic.AddInt64(&r.result.ScanHits, int64(hits))
+	return nil
+}
@@ -0,0 +1,99 @@
+package benchmark
+
+import (
+	"caching-benchmark/serialize"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// SerializationStats breaks the time spent serializing values out of the
+// operation latencies that include it.
+type SerializationStats struct {
+	Format  string
+	Encodes int64
+	Decodes int64
+	// EncodeTime and DecodeTime are the totals over all operations.
+	EncodeTime time.Duration
+	DecodeTime time.Duration
+	// EncodedBytes is the total size of the encoded records.
+	EncodedBytes int64
+}
+
+// AvgEncode returns the mean time to marshal one record.
+func (s SerializationStats) AvgEncode() time.Duration {
+	if s.Encodes == 0 {
+		return 0
+	}
+	return s.EncodeTime / time.Duration(s.Encodes)
+}
+
+// AvgDecode returns the mean time to unmarshal one record.
+func (s SerializationStats) AvgDecode() time.Duration {
+	if s.Decodes == 0 {
+		return 0
+	}
+	return s.DecodeTime / time.Duration(s.Decodes)
+}
+
+// SampleRecord returns the object cached by serialized runs, with body as
+// its Body and its other fields drawn from rng.
+func SampleRecord(rng *rand.Rand, body string) serialize.Record {
+	word := func() string { return textVocabulary[rng.Intn(len(textVocabulary))] }
+	return serialize.Record{
+		ID:     rng.Int63(),
+		Name:   word(),
+		Active: rng.Intn(2) == 0,
+		Score:  rng.Float64() * 100,
+		Tags:   []string{word(), word(), word()},
+		Body:   body,
+	}
+}
+
+// serialization times a Runner's Options.Serializer.
+type serialization struct {
+	serializer   serialize.Serializer
+	encodes      atomic.Int64
+	decodes      atomic.Int64
+	encodeNanos  atomic.Int64
+	decodeNanos  atomic.Int64
+	encodedBytes atomic.Int64
+}
+
+// marshal encodes rec with value as its body.
+func (s *serialization) marshal(rec *serialize.Record, value string) (string, error) {
+	rec.Body = value
+	start := time.Now()
+	b, err := s.serializer.Marshal(rec)
+	s.encodeNanos.Add(int64(time.Since(start)))
+	if err != nil {
+		return "", err
+	}
+	s.encodes.Add(1)
+	s.encodedBytes.Add(int64(len(b)))
+	return string(b), nil
+}
+
+// unmarshal decodes b and returns the record's body.
+func (s *serialization) unmarshal(b []byte) (string, error) {
+	var rec serialize.Record
+	start := time.Now()
+	err := s.serializer.Unmarshal(b, &rec)
+	s.decodeNanos.Add(int64(time.Since(start)))
+	if err != nil {
+		return "", err
+	}
+	s.decodes.Add(1)
+	return rec.Body, nil
+}
+
+func (s *serialization) stats() *SerializationStats {
+	return &SerializationStats{
+		Format:       s.serializer.Name(),
+		Encodes:      s.encodes.Load(),
+		Decodes:      s.decodes.Load(),
+		EncodeTime:   time.Duration(s.encodeNanos.Load()),
+		DecodeTime:   time.Duration(s.decodeNanos.Load()),
+		EncodedBytes: s.encodedBytes.Load(),
+	}
+}
@@ -0,0 +1,66 @@
+package benchmark
+
+import (
+	"caching-benchmark/workload"
+	"container/heap"
+	"sort"
+	"time"
+)
+
+// SlowOp records one of a run's slowest operations with enough context to
+// investigate it.
+type SlowOp struct {
+	Key  string
+	Type workload.OperationType
+	// Layer is the tier that served the operation: "L1", "negative-cache" or "backend".
+	Layer string
+	// At is the offset from the start of the run at which the operation began.
+	At      time.Duration
+	Latency time.Duration
+	Err     error
+	// TraceID identifies the operation's trace when it was sampled by
+	// Options.Tracer.
+	TraceID string
+}
+
+// slowOpHeap is a min-heap by latency holding the slowest operations seen.
+type slowOpHeap []SlowOp
+
+func (h slowOpHeap) Len() int           { return len(h) }
+func (h slowOpHeap) Less(i, j int) bool { return h[i].Latency < h[j].Latency }
+func (h slowOpHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *slowOpHeap) Push(x any)        { *h = append(*h, x.(SlowOp)) }
+func (h *slowOpHeap) Pop() any {
+	old := *h
+	op := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return op
+}
+
+// offer keeps op if it is among the n slowest seen so far.
+func (h *slowOpHeap) offer(op SlowOp, n int) {
+	if h.Len() < n {
+		heap.Push(h, op)
+		return
+	}
+	if op.Latency > (*h)[0].Latency {
+		(*h)[0] = op
+		heap.Fix(h, 0)
+	}
+}
+
+// mergeSlowest folds a worker's slowest operations into the run's.
+func (r *Runner) mergeSlowest(local slowOpHeap) {
+	r.slowestMu.Lock()
+	defer r.slowestMu.Unlock()
+	for _, op := range local {
+		r.slowest.offer(op, r.slowestN)
+	}
+}
+
+// finishSlowest stores the slowest operations in Result, slowest first.
+func (r *Runner) finishSlowest() {
+	ops := append([]SlowOp(nil), r.slowest...)
+	sort.Slice(ops, func(i, j int) bool { return ops[i].Latency > ops[j].Latency })
+	r.result.SlowestOps = ops
+}
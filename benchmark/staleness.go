@@ -0,0 +1,70 @@
+package benchmark
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// StalenessTracker detects stale reads by stamping every write with a global
+// sequence number. A read is stale when it returns a value older than the
+// newest write to that key that had completed before the read started.
+// One tracker can be shared by several runners to observe cross-strategy staleness.
+type StalenessTracker struct {
+	seq       atomic.Int64
+	mu        sync.RWMutex
+	committed map[string]int64
+}
+
+func NewStalenessTracker() *StalenessTracker {
+	return &StalenessTracker{committed: make(map[string]int64)}
+}
+
+// Stamp reserves a sequence number and returns it with value prefixed by it.
+func (t *StalenessTracker) Stamp(value string) (int64, string) {
+	seq := t.seq.Add(1)
+	return seq, "v" + strconv.FormatInt(seq, 10) + ":" + value
+}
+
+// Commit records that the write with seq to key has completed.
+func (t *StalenessTracker) Commit(key string, seq int64) {
+	t.mu.Lock()
+	if seq > t.committed[key] {
+		t.committed[key] = seq
+	}
+	t.mu.Unlock()
+}
+
+// Latest returns the newest committed sequence number for key.
+func (t *StalenessTracker) Latest(key string) int64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.committed[key]
+}
+
+// IsStale reports whether value is older than the sequence number observed
+// by Latest before the read began. Unstamped values (e.g. pre-populated data)
+// count as sequence zero.
+func (t *StalenessTracker) IsStale(value string, latestBeforeRead int64) bool {
+	return ParseStamp(value) < latestBeforeRead
+}
+
+// maxStampLen is the longest prefix Stamp adds: "v", an int64 and ":".
+const maxStampLen = 1 + 19 + 1
+
+// ParseStamp extracts the sequence number written by Stamp, or zero.
+func ParseStamp(value string) int64 {
+	if !strings.HasPrefix(value, "v") {
+		return 0
+	}
+	end := strings.IndexByte(value, ':')
+	if end < 0 {
+		return 0
+	}
+	seq, err := strconv.ParseInt(value[1:end], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return seq
+}
@@ -2,9 +2,21 @@ package benchmark
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrNotFound is returned by CachingStrategy.Read when the key does not exist.
+// The runner counts it as a successful read of an absent key, not as an error.
+var ErrNotFound = errors.New("key not found")
+
+// ErrRMWConflict is returned by CachingStrategy.ReadModifyWrite when the key
+// kept changing underneath it for MaxRMWRetries attempts.
+var ErrRMWConflict = errors.New("read-modify-write conflict")
+
+// MaxRMWRetries bounds the retries of a conflicting ReadModifyWrite.
+const MaxRMWRetries = 16
+
 // CachingStrategy defines the interface for a caching implementation.
 // This allows us to benchmark different strategies with the same test harness.
 type CachingStrategy interface {
@@ -18,6 +30,15 @@ type CachingStrategy interface {
 	Read(ctx context.Context, key string) (value string, hit bool, err error)
 	// Write performs a write operation for a given key and value.
 	Write(ctx context.Context, key, value string) error
+	// ReadModifyWrite replaces key's value with modify applied to it, ""
+	// for an absent key, and returns how many attempts were retried after
+	// a conflicting write. Strategies backed by Redis are atomic, e.g. with
+	// WATCH/MULTI; modify may then be called once per attempt.
+	ReadModifyWrite(ctx context.Context, key string, modify func(old string) string) (retries int, err error)
+	// Drain completes any asynchronous work (write-behind buffers, in-flight
+	// refreshes, pending invalidations) once the workload has finished.
+	// It returns the number of writes that could not be persisted before ctx expired.
+	Drain(ctx context.Context) (lostWrites int64, err error)
 	// Close cleans up any resources used by the strategy.
 	Close(ctx context.Context) error
 }
@@ -31,7 +52,150 @@ type Result struct {
 	TotalWrites     int64
 	TotalErrors     int64
 	TotalDuration   time.Duration
+	DrainDuration   time.Duration
+	LostWrites      int64
 	HitRate         float64
 	OpsPerSecond    float64
 	Latencies       []time.Duration
+	// Incomplete is set when the run was cancelled before the workload finished.
+	Incomplete bool
+	// Backend fetch accounting, populated for strategies implementing
+	// FetchInstrumented when Options.TrackFetches is set.
+	BackendFetches       int64
+	MaxConcurrentFetches int
+	HottestFetchKey      string
+	Invalidations        int64
+	// Queue wait for the MaxInFlight semaphore and, in open-loop mode, behind
+	// each operation's intended start time; Latencies exclude it.
+	TotalQueueWait time.Duration
+	MaxQueueWait   time.Duration
+	// Backend traffic, populated for strategies implementing BackendReporter.
+	Backend                 BackendStats
+	BackendRequestsPer1kOps float64
+	// Samples is the in-flight/queued time series taken during the run.
+	Samples []Sample
+	// StaleReads counts reads older than the latest completed write, when
+	// StalenessTracked is set.
+	StaleReads       int64
+	StalenessTracked bool
+	// ReadYourWritesChecks counts the read-backs of Options.ReadYourWrites
+	// and ReadYourWritesViolations those that missed the worker's own write.
+	ReadYourWritesChecks     int64
+	ReadYourWritesViolations int64
+	// Environment and ServerVersion identify the Redis deployment the run used.
+	Environment   string
+	ServerVersion string
+	// Tracking is populated for strategies implementing TrackingReporter.
+	Tracking *TrackingStats
+	// NotFoundReads counts reads of absent keys; NegativeHits are those
+	// answered from a cached absence without reaching the backend.
+	NotFoundReads int64
+	NegativeHits  int64
+	// OfferedRate is the open-loop target rate in ops/sec; zero for closed-loop runs.
+	OfferedRate float64
+	// LittlesLaw cross-checks concurrency, throughput and latency; nil when
+	// the run was too short to sample.
+	LittlesLaw *LittlesLaw
+	// Faults reports the run's behavior after each injected fault.
+	Faults []FaultReport
+	// Failover is set when a FailoverReporter strategy switched to its standby.
+	Failover *FailoverReport
+	// SlowestOps are the run's slowest operations, slowest first.
+	SlowestOps []SlowOp
+	// Clock describes the host's timer precision for the run.
+	Clock *ClockCheck
+	// Listener, for ListenerReporter strategies, describes lost invalidation
+	// subscriptions.
+	Listener *ListenerStats
+	// TotalTimeouts counts operations that exceeded Options.OpTimeout; they
+	// are also included in TotalErrors.
+	TotalTimeouts int64
+	// CorruptReads counts values that failed Options.Codec validation; they
+	// are also included in TotalErrors.
+	CorruptReads int64
+	// ErrorCategories breaks errors down by Classify category. The
+	// ErrCategoryNotFound entry counts misses, which are not in TotalErrors.
+	ErrorCategories map[string]ErrorCount
+	// KeyClasses reports reads per Options.KeyClasses class.
+	KeyClasses []KeyClassStats
+	// HotKeys is populated for strategies implementing HotKeyReporter.
+	HotKeys *HotKeyStats
+	// Memory is the process's heap activity while the workers ran.
+	Memory MemoryStats
+	// RangeReads counts successful range reads and RangeBytes the bytes
+	// they returned; they are also counted as hits or misses.
+	RangeReads int64
+	RangeBytes int64
+	// Serialization is set when Options.Serializer was.
+	Serialization *SerializationStats
+	// Compression is set for CompressionReporter strategies compressing values.
+	Compression *CompressionStats
+	// Profiles lists the pprof files written when Options.ProfileDir was set.
+	Profiles []string
+	// RMWs counts successful read-modify-writes, RMWRetries the attempts
+	// they retried after conflicts and RMWConflicts those abandoned with
+	// ErrRMWConflict.
+	RMWs         int64
+	RMWRetries   int64
+	RMWConflicts int64
+	// Scans counts completed prefix scans, ScanKeys the keys they returned
+	// and ScanHits the values among those served from a cache.
+	Scans    int64
+	ScanKeys int64
+	ScanHits int64
+	// Hotness reports reads per popularity band when Options.RankedKeys was
+	// set, hottest first.
+	Hotness []HotnessStats
+	// ResponseTimes, in open-loop runs, are the operations' latencies from
+	// their intended start, including any time queued behind it; Latencies
+	// hold only their service time. With Options.Retry they are also
+	// recorded in closed-loop runs, from the first attempt, and include
+	// failed attempts and backoff.
+	ResponseTimes []time.Duration
+	// StartTime is when the workers started; Samples are relative to it.
+	StartTime time.Time
+	// Lifecycle times Init, warm-up and Close, and the caller's data
+	// preparation, apart from the steady state.
+	Lifecycle LifecycleStats
+	// Connections describes the strategy's Redis connections; nil for
+	// strategies that do not report them.
+	Connections *ConnectionStats
+	// MaxInFlight is the run's cap on concurrent strategy calls; zero means
+	// uncapped.
+	MaxInFlight int
+	// Restart describes the simulated application restart; nil when the
+	// run had none.
+	Restart *RestartStats
+	// PrewarmedL1Keys is the number of Options.L1Snapshot keys loaded
+	// before measurement, and L1Snapshot the keys in L1 at the end of the
+	// run when Options.DumpL1 is set.
+	PrewarmedL1Keys int
+	L1Snapshot      []string
+	// Timeline is the per-second latency timeline with spikes annotated,
+	// when Options.Timeline or Options.L1Schedule is set.
+	Timeline []TimelineSecond
+	// L1Phases describes the run between the L1 budget changes of
+	// Options.L1Schedule; nil without one or for strategies that are not
+	// L1Resizers.
+	L1Phases []L1Phase
+	// Retries counts the attempts Options.Retry re-issued after transient
+	// errors, RetriedOps the operations retried at least once and
+	// RetryRecoveries those that then succeeded. Only an operation's final
+	// error is counted in TotalErrors.
+	Retries         int64
+	RetriedOps      int64
+	RetryRecoveries int64
+	// Fairness describes how evenly the workers completed operations; nil
+	// with a single worker.
+	Fairness *WorkerFairness
+	// Metadata describes the host, software and scenario configuration of
+	// the run; it is filled in by the caller.
+	Metadata *Metadata
+	// StrategyMetrics are the internal counters of MetricsReporter
+	// strategies, taken after Drain.
+	StrategyMetrics map[string]int64
+	// ExternalWrites counts the writes of Options.External and
+	// ExternalWriteErrors those that failed; neither is an operation.
+	ExternalWrites      int64
+	ExternalWriteErrors int64
 }
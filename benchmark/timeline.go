@@ -0,0 +1,221 @@
+package benchmark
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// InvalidationRecorder receives every invalidation a strategy's cache
+// instance receives, whether a Redis tracking push or a peer's pub/sub
+// message. keys is the number of keys it invalidates; a full flush counts as one.
+type InvalidationRecorder interface {
+	RecordInvalidations(keys int)
+}
+
+// InvalidationInstrumented is implemented by strategies that can report the
+// invalidations they receive. The runner calls SetInvalidationRecorder before Init.
+type InvalidationInstrumented interface {
+	SetInvalidationRecorder(rec InvalidationRecorder)
+}
+
+// RecordInvalidations counts received invalidations for the sampler.
+func (g *gauges) RecordInvalidations(keys int) {
+	g.invalidations.Add(int64(keys))
+}
+
+const (
+	// Latencies are bucketed in quarter octaves from 1µs, so a reported
+	// percentile is at most 19% above the true value.
+	timelineBucketsPerOctave = 4
+	timelineBuckets          = 26 * timelineBucketsPerOctave // up to ~67s
+
+	// spikeFactor is how far a second's p99 must exceed the run's median
+	// per-second p99 to count as a spike.
+	spikeFactor = 2
+	// stormFactor is how far a second's received invalidations must exceed
+	// the run's median per-second count to count as an invalidation storm.
+	stormFactor = 3
+)
+
+// TimelineSecond summarizes one second of a run, with the activity that can
+// explain a latency spike in it.
+type TimelineSecond struct {
+	Second   int
+	Ops      int64
+	P50, P99 time.Duration
+	// Invalidations is the number of keys invalidated by messages received
+	// in this second, for strategies implementing InvalidationInstrumented.
+	Invalidations int64
+	GCCycles      uint32
+	GCPause       time.Duration
+	// Spike marks a p99 of at least spikeFactor times the run's median
+	// per-second p99, and Cause attributes it: "invalidation storm", "GC",
+	// both, or "Redis or network" when neither was unusually active.
+	Spike bool
+	Cause string
+}
+
+type latencyHistogram [timelineBuckets]uint32
+
+// secondHistograms holds one latency histogram per second of a run, indexed
+// by when operations completed.
+type secondHistograms []latencyHistogram
+
+// timeline collects per-second latency histograms from the workers.
+type timeline struct {
+	mu      sync.Mutex
+	seconds secondHistograms
+}
+
+// newTimeline returns nil, disabling per-second histograms, unless enabled.
+func newTimeline(enabled bool) *timeline {
+	if !enabled {
+		return nil
+	}
+	return &timeline{}
+}
+
+// newSeconds returns worker-local histograms, or nil when t is disabled.
+func (t *timeline) newSeconds() *secondHistograms {
+	if t == nil {
+		return nil
+	}
+	return &secondHistograms{}
+}
+
+func (t *timeline) merge(h *secondHistograms) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for len(t.seconds) < len(*h) {
+		t.seconds = append(t.seconds, latencyHistogram{})
+	}
+	for s := range *h {
+		for b, n := range (*h)[s] {
+			t.seconds[s][b] += n
+		}
+	}
+}
+
+func (h *secondHistograms) add(elapsed, latency time.Duration) {
+	if h == nil {
+		return
+	}
+	s := int(elapsed / time.Second)
+	for len(*h) <= s {
+		*h = append(*h, latencyHistogram{})
+	}
+	(*h)[s][latencyBucket(latency)]++
+}
+
+func latencyBucket(d time.Duration) int {
+	us := float64(d) / float64(time.Microsecond)
+	if us <= 1 {
+		return 0
+	}
+	return min(int(math.Ceil(math.Log2(us)*timelineBucketsPerOctave)), timelineBuckets-1)
+}
+
+// quantile returns the upper bound of the bucket holding quantile q, and
+// the number of latencies recorded.
+func (h *latencyHistogram) quantile(q float64) (time.Duration, int64) {
+	var total int64
+	for _, n := range h {
+		total += int64(n)
+	}
+	if total == 0 {
+		return 0, 0
+	}
+	rank := int64(math.Ceil(q * float64(total)))
+	var seen int64
+	for b, n := range h {
+		if seen += int64(n); seen >= rank {
+			return time.Duration(math.Exp2(float64(b)/timelineBucketsPerOctave) * float64(time.Microsecond)), total
+		}
+	}
+	return 0, total
+}
+
+// build combines the per-second histograms with the sampler's
+// invalidation and GC counters and annotates latency spikes.
+func (t *timeline) build(samples []Sample) []TimelineSecond {
+	if t == nil || len(t.seconds) == 0 {
+		return nil
+	}
+	out := make([]TimelineSecond, len(t.seconds))
+	for s := range t.seconds {
+		sec := TimelineSecond{Second: s}
+		sec.P50, sec.Ops = t.seconds[s].quantile(0.50)
+		sec.P99, _ = t.seconds[s].quantile(0.99)
+		from, to := sampleAt(samples, time.Duration(s)*time.Second), sampleAt(samples, time.Duration(s+1)*time.Second)
+		sec.Invalidations = to.Invalidations - from.Invalidations
+		sec.GCCycles = to.NumGC - from.NumGC
+		sec.GCPause = to.GCPause - from.GCPause
+		out[s] = sec
+	}
+	annotateSpikes(out)
+	return out
+}
+
+// sampleAt returns the last sample taken at or before elapsed, or the zero
+// Sample before the first.
+func sampleAt(samples []Sample, elapsed time.Duration) Sample {
+	i := sort.Search(len(samples), func(i int) bool { return samples[i].Elapsed > elapsed })
+	if i == 0 {
+		return Sample{}
+	}
+	return samples[i-1]
+}
+
+// annotateSpikes marks seconds whose p99 spikes and attributes each spike to
+// an invalidation storm when invalidations were well above their median, and
+// to GC when the second's GC pauses cover at least half of the excess latency.
+func annotateSpikes(seconds []TimelineSecond) {
+	var p99s []time.Duration
+	var invalidations []int64
+	for _, s := range seconds {
+		if s.Ops > 0 {
+			p99s = append(p99s, s.P99)
+		}
+		invalidations = append(invalidations, s.Invalidations)
+	}
+	if len(p99s) == 0 {
+		return
+	}
+	sort.Slice(p99s, func(i, j int) bool { return p99s[i] < p99s[j] })
+	sort.Slice(invalidations, func(i, j int) bool { return invalidations[i] < invalidations[j] })
+	medianP99 := p99s[len(p99s)/2]
+	medianInvalidations := invalidations[len(invalidations)/2]
+
+	for i := range seconds {
+		s := &seconds[i]
+		if s.Ops == 0 || s.P99 < spikeFactor*medianP99 {
+			continue
+		}
+		s.Spike = true
+		var causes []string
+		if s.Invalidations > 0 && s.Invalidations > stormFactor*medianInvalidations {
+			causes = append(causes, "invalidation storm")
+		}
+		if s.GCPause > 0 && 2*s.GCPause >= s.P99-medianP99 {
+			causes = append(causes, "GC")
+		}
+		if len(causes) == 0 {
+			causes = append(causes, "Redis or network")
+		}
+		s.Cause = strings.Join(causes, " + ")
+	}
+}
+
+// Spikes returns the seconds marked as latency spikes.
+func Spikes(seconds []TimelineSecond) []TimelineSecond {
+	var spikes []TimelineSecond
+	for _, s := range seconds {
+		if s.Spike {
+			spikes = append(spikes, s)
+		}
+	}
+	return spikes
+}
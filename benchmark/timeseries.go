@@ -0,0 +1,148 @@
+package benchmark
+
+import (
+	"context"
+	"math"
+	"runtime/metrics"
+	"sync/atomic"
+	"time"
+)
+
+const defaultSampleInterval = 100 * time.Millisecond
+
+// Sample is a point-in-time snapshot of the runner's load.
+type Sample struct {
+	Elapsed time.Duration
+	// InFlight is the number of operations currently executing in the strategy.
+	InFlight int64
+	// Queued is the number of operations ready to run but not yet started,
+	// e.g. waiting for a MaxInFlight slot or behind schedule in open-loop mode.
+	Queued    int64
+	Completed int64
+	// Errors is the cumulative number of failed operations, showing error
+	// spikes such as those caused by injected faults.
+	Errors int64
+	// Hits and Misses are cumulative read outcomes, for hit rate over time.
+	Hits   int64
+	Misses int64
+	// HeapBytes is the process's allocated heap and NumGC its completed GC
+	// cycles, from runtime/metrics, which unlike runtime.ReadMemStats does
+	// not stop the world. AllocBytes is the cumulative heap allocation.
+	HeapBytes  uint64
+	AllocBytes uint64
+	NumGC      uint32
+	// GCPause is the cumulative stop-the-world GC pause time, estimated
+	// from the runtime's pause histogram to within its bucket widths.
+	GCPause time.Duration
+	// Invalidations is the cumulative number of keys invalidated by
+	// messages the strategy received, for strategies implementing
+	// InvalidationInstrumented.
+	Invalidations int64
+}
+
+// gauges are the live counters read by the sampler.
+type gauges struct {
+	inFlight  atomic.Int64
+	queued    atomic.Int64
+	completed atomic.Int64
+	errors    atomic.Int64
+	hits      atomic.Int64
+	misses    atomic.Int64
+	// invalidations is fed by the strategy through RecordInvalidations.
+	invalidations atomic.Int64
+}
+
+// sampleLoop records a Sample every interval until ctx is cancelled.
+func (g *gauges) sampleLoop(ctx context.Context, interval time.Duration, start time.Time, out *[]Sample, done chan<- struct{}) {
+	defer close(done)
+	runtimeSamples := newRuntimeSamples()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			metrics.Read(runtimeSamples)
+			*out = append(*out, Sample{
+				Elapsed:       now.Sub(start),
+				InFlight:      g.inFlight.Load(),
+				Queued:        g.queued.Load(),
+				Completed:     g.completed.Load(),
+				Errors:        g.errors.Load(),
+				Hits:          g.hits.Load(),
+				Misses:        g.misses.Load(),
+				HeapBytes:     runtimeSamples[0].Value.Uint64(),
+				AllocBytes:    runtimeSamples[1].Value.Uint64(),
+				NumGC:         uint32(runtimeSamples[2].Value.Uint64()),
+				GCPause:       histogramTotal(runtimeSamples[3].Value.Float64Histogram()),
+				Invalidations: g.invalidations.Load(),
+			})
+		}
+	}
+}
+
+// sampledMetrics are the runtime metrics read into every Sample, in the
+// order sampleLoop reads them.
+var sampledMetrics = []string{
+	"/memory/classes/heap/objects:bytes",
+	"/gc/heap/allocs:bytes",
+	"/gc/cycles/total:gc-cycles",
+	"/gc/pauses:seconds",
+}
+
+// newRuntimeSamples allocates the metrics.Sample slice for sampledMetrics.
+func newRuntimeSamples() []metrics.Sample {
+	samples := make([]metrics.Sample, len(sampledMetrics))
+	for i, name := range sampledMetrics {
+		samples[i].Name = name
+	}
+	return samples
+}
+
+// histogramTotal estimates the sum of a histogram of seconds by counting
+// every observation at its bucket's midpoint, or its finite bound for the
+// unbounded end buckets.
+func histogramTotal(h *metrics.Float64Histogram) time.Duration {
+	if h == nil {
+		return 0
+	}
+	var total float64
+	for i, n := range h.Counts {
+		if n == 0 {
+			continue
+		}
+		lo, hi := h.Buckets[i], h.Buckets[i+1]
+		var v float64
+		switch {
+		case math.IsInf(lo, -1):
+			v = hi
+		case math.IsInf(hi, 1):
+			v = lo
+		default:
+			v = (lo + hi) / 2
+		}
+		total += float64(n) * v
+	}
+	return time.Duration(total * float64(time.Second))
+}
+
+// summarizeSamples returns the peak and mean of InFlight and Queued.
+func summarizeSamples(samples []Sample) (peakInFlight, peakQueued int64, meanInFlight, meanQueued float64) {
+	if len(samples) == 0 {
+		return 0, 0, 0, 0
+	}
+	var sumInFlight, sumQueued int64
+	for _, s := range samples {
+		sumInFlight += s.InFlight
+		sumQueued += s.Queued
+		if s.InFlight > peakInFlight {
+			peakInFlight = s.InFlight
+		}
+		if s.Queued > peakQueued {
+			peakQueued = s.Queued
+		}
+	}
+	n := float64(len(samples))
+	return peakInFlight, peakQueued, float64(sumInFlight) / n, float64(sumQueued) / n
+}
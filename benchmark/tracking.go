@@ -0,0 +1,37 @@
+package benchmark
+
+import "fmt"
+
+// TrackingStats describes the server-side CLIENT TRACKING state observed
+// during a client-side caching run.
+type TrackingStats struct {
+	PeakTrackedKeys     int64
+	PeakTrackingClients int64
+	// TrackingTableMaxKeys is the server's tracking-table-max-keys; zero means unlimited.
+	TrackingTableMaxKeys  int64
+	InvalidationsReceived int64
+}
+
+// TrackingReporter is implemented by strategies that rely on Redis CLIENT TRACKING.
+type TrackingReporter interface {
+	TrackingStats() TrackingStats
+}
+
+// trackingSaturation is the fraction of tracking-table-max-keys above which
+// the server is considered to be evicting tracked keys.
+const trackingSaturation = 0.9
+
+// Bottleneck reports whether the server-side tracking table, rather than the
+// client strategy, limited the run. Invalidations well beyond the number of
+// writes mean Redis is evicting tracked keys to stay under its limit.
+func (t TrackingStats) Bottleneck(writes int64) (string, bool) {
+	if t.TrackingTableMaxKeys > 0 && float64(t.PeakTrackedKeys) >= trackingSaturation*float64(t.TrackingTableMaxKeys) {
+		return fmt.Sprintf("tracking table peaked at %d keys against tracking-table-max-keys=%d; Redis is evicting tracked keys and invalidating client caches",
+			t.PeakTrackedKeys, t.TrackingTableMaxKeys), true
+	}
+	if writes > 0 && t.InvalidationsReceived > 2*writes {
+		return fmt.Sprintf("received %d invalidations for %d writes; invalidations are not explained by the workload's writes",
+			t.InvalidationsReceived, writes), true
+	}
+	return "", false
+}
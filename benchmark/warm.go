@@ -0,0 +1,65 @@
+package benchmark
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Warmable is implemented by strategies whose L1 key set can be saved and
+// restored, modeling deployments that persist hot-key lists across
+// restarts.
+type Warmable interface {
+	// DumpL1 returns the keys currently held in L1.
+	DumpL1(ctx context.Context) ([]string, error)
+	// LoadL1 fills L1 with keys, reading their values from the shared tier,
+	// and returns how many it loaded.
+	LoadL1(ctx context.Context, keys []string) (int, error)
+}
+
+// Sub returns the traffic counted since base. MaxBatchSize is kept, as the
+// largest batch cannot be attributed.
+func (b BackendStats) Sub(base BackendStats) BackendStats {
+	b.Requests -= base.Requests
+	b.Commands -= base.Commands
+	b.BytesSent -= base.BytesSent
+	b.BytesReceived -= base.BytesReceived
+	return b
+}
+
+// prewarm loads the L1 snapshot into a Warmable strategy before
+// measurement, timing it, and returns the backend traffic it caused so it
+// can be left out of the run's.
+func (r *Runner) prewarm(ctx context.Context) BackendStats {
+	w, ok := r.strategy.(Warmable)
+	if !ok || len(r.l1Snapshot) == 0 {
+		return BackendStats{}
+	}
+	var base BackendStats
+	br, counted := r.strategy.(BackendReporter)
+	start := time.Now()
+	n, err := w.LoadL1(ctx, r.l1Snapshot)
+	r.result.Lifecycle.Prewarm = time.Since(start)
+	r.result.PrewarmedL1Keys = n
+	if err != nil {
+		log.Printf("Warning: pre-warming L1 stopped after %d of %d keys: %v", n, len(r.l1Snapshot), err)
+	}
+	if counted {
+		base = br.BackendStats()
+	}
+	return base
+}
+
+// dumpL1 records the L1 key set of a Warmable strategy at the end of the run.
+func (r *Runner) dumpL1(ctx context.Context) {
+	w, ok := r.strategy.(Warmable)
+	if !ok || !r.dumpL1Keys {
+		return
+	}
+	keys, err := w.DumpL1(ctx)
+	if err != nil {
+		log.Printf("Warning: dumping L1 keys: %v", err)
+		return
+	}
+	r.result.L1Snapshot = keys
+}
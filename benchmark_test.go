@@ -0,0 +1,97 @@
+package main
+
+import (
+	"caching-benchmark/benchmark"
+	"caching-benchmark/implementations"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// BenchmarkScenarios exposes every plain default scenario and strategy as a
+// standard Go benchmark, so results can be compared with benchstat:
+//
+//	go test -run '^$' -bench 'Scenarios/read-heavy' -count 10 . | tee new.txt
+//
+// Each iteration is one operation of the scenario's workload, so ns/op is
+// wall-clock time per operation across all workers (the inverse of
+// throughput, including strategy setup and drain). Hit rate, p99 latency and
+// backend requests are reported as extra metrics. Scenarios with a special run mode (sweeps,
+// interop, faults, failover, added network latency) are skipped. The Redis
+// address defaults to implementations.DefaultAddr and can be overridden with
+// BENCH_REDIS_ADDR; the benchmark is skipped when no server is reachable.
+//
+// Like the benchmark binary, this flushes the target datastore.
+func BenchmarkScenarios(b *testing.B) {
+	addr := os.Getenv("BENCH_REDIS_ADDR")
+	if addr == "" {
+		addr = implementations.DefaultAddr
+	}
+	if err := pingRedis(addr); err != nil {
+		b.Skipf("Redis at %s is unreachable: %v", addr, err)
+	}
+
+	for _, cfg := range defaultScenarios() {
+		if !plainScenario(cfg) {
+			continue
+		}
+		cfg.Addr = addr
+		b.Run(slug(cfg.Name), func(b *testing.B) {
+			for _, spec := range strategySpecs(cfg) {
+				b.Run(slug(spec), func(b *testing.B) {
+					benchmarkStrategy(b, cfg, spec)
+				})
+			}
+		})
+	}
+}
+
+// plainScenario reports whether cfg runs each strategy once, directly
+// against the server.
+func plainScenario(cfg Config) bool {
+	return len(cfg.Sweeps) == 0 && len(cfg.LoadFractions) == 0 && len(cfg.ConcurrencyLevels) == 0 &&
+		len(cfg.KeyCounts) == 0 && !cfg.Interop && !cfg.Failover && len(cfg.Faults) == 0 && cfg.RTT == 0
+}
+
+func benchmarkStrategy(b *testing.B, cfg Config, spec string) {
+	ctx := context.Background()
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = defaultSeed
+	}
+	cfg.NumOperations = b.N
+	w := generateWorkload(cfg, seed)
+	ns, err := buildStrategy(cfg, spec)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if _, err := prepareData(ctx, cfg, seed); err != nil {
+		b.Fatal(err)
+	}
+	runner := benchmark.NewRunner(ns.strategy, w, runnerOptions(cfg, ns, seed))
+
+	b.ResetTimer()
+	result, err := runner.Run(ctx)
+	b.StopTimer()
+	if err != nil {
+		b.Fatal(err)
+	}
+	sortLatencies(result.Latencies)
+	b.ReportMetric(result.HitRate*100, "hit%")
+	b.ReportMetric(ms(nearestRank(result.Latencies, 99)), "p99-ms")
+	b.ReportMetric(result.BackendRequestsPer1kOps, "backend-req/1kop")
+}
+
+func pingRedis(addr string) error {
+	client, err := rueidis.NewClient(rueidis.ClientOption{InitAddress: []string{addr}, DisableCache: true})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return client.Do(ctx, client.B().Ping().Build()).Error()
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// campaign re-submits a preset list of scenarios on a cron schedule, records
+// each completed run in the results database, and posts an alert to a
+// webhook when a metric drifts beyond its threshold week over week.
+type campaign struct {
+	schedule  *cronSchedule
+	scenarios []Config
+	resultsDB string
+	commit    string
+	webhook   string
+	threshold float64
+
+	mu sync.Mutex
+}
+
+// historyRecord is one row of the results database written by -results-db.
+type historyRecord struct {
+	Time     time.Time `json:"time"`
+	RunID    string    `json:"run_id"`
+	Scenario string    `json:"scenario"`
+	// GitCommit, Environment, ServerVersion and Config identify what the
+	// run measured.
+	GitCommit     string          `json:"git_commit,omitempty"`
+	Environment   string          `json:"environment,omitempty"`
+	ServerVersion string          `json:"server_version,omitempty"`
+	Config        json.RawMessage `json:"config,omitempty"`
+	resultSummary
+}
+
+// driftAlert describes one metric that moved beyond the threshold relative
+// to the run a week earlier.
+type driftAlert struct {
+	Scenario string  `json:"scenario"`
+	Strategy string  `json:"strategy"`
+	Metric   string  `json:"metric"`
+	Previous float64 `json:"previous"`
+	Current  float64 `json:"current"`
+	Change   float64 `json:"change"`
+}
+
+// trendWindow is how far from exactly one week a baseline run may be.
+const trendWindow = 12 * time.Hour
+
+// newCampaign resolves the comma-separated scenario names against the
+// default scenarios.
+func newCampaign(expr, scenarioNames, resultsDB, webhook string, threshold float64) (*campaign, error) {
+	sched, err := parseCron(expr)
+	if err != nil {
+		return nil, err
+	}
+	if resultsDB == "" {
+		return nil, errors.New("a campaign needs -results-db to compare runs against")
+	}
+	c := &campaign{schedule: sched, resultsDB: resultsDB, commit: gitCommit(), webhook: webhook, threshold: threshold}
+	defaults := defaultScenarios()
+	for _, name := range strings.Split(scenarioNames, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, cfg := range defaults {
+			if cfg.Name == name {
+				c.scenarios = append(c.scenarios, cfg)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown campaign scenario %q", name)
+		}
+	}
+	return c, nil
+}
+
+// run submits the campaign's scenarios each time the schedule fires.
+func (c *campaign) run(ctx context.Context, s *apiServer) {
+	for {
+		next := c.schedule.next(time.Now())
+		if next.IsZero() {
+			log.Printf("Campaign schedule never fires again; stopping")
+			return
+		}
+		log.Printf("Next campaign run at %s", next.Format(time.RFC3339))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+		for _, cfg := range c.scenarios {
+			if _, err := s.enqueue(cfg, true); err != nil {
+				log.Printf("Campaign: failed to queue %q: %v", cfg.Name, err)
+			}
+		}
+	}
+}
+
+// record inserts a finished run's results into the results database as
+// runID and alerts on any week-over-week drift.
+func (c *campaign) record(runID string, sr scenarioResults) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	past, err := queryRuns(c.resultsDB, historyFilter{scenario: sr.name, since: now.Add(-7*24*time.Hour - trendWindow)})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Campaign: reading results: %v", err)
+	}
+	if err := recordRuns(c.resultsDB, runID, c.commit, []scenarioResults{sr}); err != nil {
+		log.Printf("Campaign: recording results: %v", err)
+	}
+
+	var alerts []driftAlert
+	for _, r := range sr.results {
+		cur := historyRecord{Time: now, RunID: runID, Scenario: sr.name, resultSummary: summarize(r)}
+		if base, ok := weekAgo(past, cur); ok {
+			alerts = append(alerts, c.drift(base, cur)...)
+		}
+	}
+	if len(alerts) == 0 {
+		return
+	}
+	for _, a := range alerts {
+		log.Printf("Campaign drift: %s / %s %s %.4g -> %.4g (%+.1f%%)", a.Scenario, a.Strategy, a.Metric, a.Previous, a.Current, a.Change*100)
+	}
+	if c.webhook != "" {
+		if err := postAlerts(c.webhook, alerts); err != nil {
+			log.Printf("Campaign: webhook: %v", err)
+		}
+	}
+}
+
+// weekAgo finds the record for the same scenario and strategy closest to one
+// week before cur, within trendWindow.
+func weekAgo(past []historyRecord, cur historyRecord) (historyRecord, bool) {
+	target := cur.Time.Add(-7 * 24 * time.Hour)
+	var best historyRecord
+	bestDist := time.Duration(math.MaxInt64)
+	for _, r := range past {
+		if r.Scenario != cur.Scenario || r.Strategy != cur.Strategy || r.Incomplete {
+			continue
+		}
+		dist := r.Time.Sub(target)
+		if dist < 0 {
+			dist = -dist
+		}
+		if dist <= trendWindow && dist < bestDist {
+			best, bestDist = r, dist
+		}
+	}
+	return best, bestDist <= trendWindow
+}
+
+// drift compares the metrics where a move in the bad direction matters.
+func (c *campaign) drift(base, cur historyRecord) []driftAlert {
+	metrics := []struct {
+		name        string
+		prev, now   float64
+		higherIsBad bool
+	}{
+		{"ops_per_second", base.OpsPerSecond, cur.OpsPerSecond, false},
+		{"hit_rate", base.HitRate, cur.HitRate, false},
+		{"p95_latency_ms", base.P95LatencyMs, cur.P95LatencyMs, true},
+		{"backend_requests_per_1k_ops", base.BackendReqPer1k, cur.BackendReqPer1k, true},
+	}
+	var alerts []driftAlert
+	for _, m := range metrics {
+		if m.prev == 0 {
+			continue
+		}
+		change := (m.now - m.prev) / m.prev
+		if (m.higherIsBad && change > c.threshold) || (!m.higherIsBad && change < -c.threshold) {
+			alerts = append(alerts, driftAlert{
+				Scenario: cur.Scenario,
+				Strategy: cur.Strategy,
+				Metric:   m.name,
+				Previous: m.prev,
+				Current:  m.now,
+				Change:   change,
+			})
+		}
+	}
+	return alerts
+}
+
+// postAlerts sends the alerts as JSON. The "text" field makes the payload
+// readable by chat webhooks that only render a message body.
+func postAlerts(url string, alerts []driftAlert) error {
+	var text strings.Builder
+	fmt.Fprintf(&text, "Cache benchmark drift (%d metrics):", len(alerts))
+	for _, a := range alerts {
+		fmt.Fprintf(&text, "\n%s / %s: %s %.4g -> %.4g (%+.1f%%)", a.Scenario, a.Strategy, a.Metric, a.Previous, a.Current, a.Change*100)
+	}
+	body, err := json.Marshal(map[string]any{"text": text.String(), "alerts": alerts})
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"caching-benchmark/benchmark"
+	"caching-benchmark/workload"
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sync"
+	"text/tabwriter"
+)
+
+// runCanary splits one workload between the scenario's two strategies as a
+// migration canary would: each operation goes to the candidate, the second
+// strategy, with probability CanaryFraction and to the control otherwise,
+// and workers are split in the same proportion. Both cohorts run at the same
+// time against the same keys and are reported side by side.
+func runCanary(ctx context.Context, cfg Config, w []workload.Operation, seed int64, strategies []namedStrategy) []benchmark.Result {
+	control, candidate := strategies[0], strategies[1]
+	log.Printf("\n--- Canary: %.0f%% %s, %.0f%% %s ---", (1-cfg.CanaryFraction)*100, control.name, cfg.CanaryFraction*100, candidate.name)
+
+	var shares [2][]workload.Operation
+	rng := rand.New(rand.NewSource(seed))
+	for _, op := range w {
+		cohort := 0
+		if rng.Float64() < cfg.CanaryFraction {
+			cohort = 1
+		}
+		shares[cohort] = append(shares[cohort], op)
+	}
+	candidateWorkers := min(cfg.Concurrency-1, max(1, int(float64(cfg.Concurrency)*cfg.CanaryFraction+0.5)))
+	workers := [2]int{cfg.Concurrency - candidateWorkers, candidateWorkers}
+	labels := [2]string{
+		fmt.Sprintf(" (control, %.0f%%)", (1-cfg.CanaryFraction)*100),
+		fmt.Sprintf(" (canary, %.0f%%)", cfg.CanaryFraction*100),
+	}
+
+	var tracker *benchmark.StalenessTracker
+	if cfg.TrackStaleness {
+		// Shared, so a cohort reading past the other's completed write counts.
+		tracker = benchmark.NewStalenessTracker()
+	}
+	results := make([]benchmark.Result, 2)
+	var wg sync.WaitGroup
+	for i, ns := range []namedStrategy{control, candidate} {
+		opts := runnerOptions(cfg, ns, seed+int64(i)*int64(cfg.Concurrency))
+		opts.Concurrency = workers[i]
+		opts.Staleness = tracker
+		if i > 0 {
+			// Profiles cover the whole process, so the first runner's
+			// include every concurrent runner.
+			opts.ProfileDir = ""
+		}
+
+		wg.Add(1)
+		go func(i int, ns namedStrategy, opts benchmark.Options) {
+			defer wg.Done()
+			result, err := benchmark.NewRunner(ns.strategy, shares[i], opts).Run(ctx)
+			if err != nil {
+				log.Printf("Error running canary cohort %s: %v", ns.strategy.Name(), err)
+			}
+			result.StrategyName += labels[i]
+			results[i] = result
+		}(i, ns, opts)
+	}
+	wg.Wait()
+	printCanaryComparison(results[0], results[1])
+	return results
+}
+
+// printCanaryComparison reports the canary cohort against the control
+// cohort, with the difference for each metric.
+func printCanaryComparison(control, canary benchmark.Result) {
+	log.Println("\n--- Canary vs Control ---")
+	sortLatencies(control.Latencies)
+	sortLatencies(canary.Latencies)
+	cp50, cp99 := nearestRank(control.Latencies, 50), nearestRank(control.Latencies, 99)
+	kp50, kp99 := nearestRank(canary.Latencies, 50), nearestRank(canary.Latencies, 99)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.AlignRight|tabwriter.Debug)
+	fmt.Fprintln(w, "Metric\tControl\tCanary\tDelta\t")
+	fmt.Fprintf(w, "Operations\t%d\t%d\t\t\n", control.TotalOperations, canary.TotalOperations)
+	fmt.Fprintf(w, "Hit Rate (%%)\t%.2f\t%.2f\t%+.2f\t\n", control.HitRate*100, canary.HitRate*100, (canary.HitRate-control.HitRate)*100)
+	fmt.Fprintf(w, "P50 Latency (ms)\t%.4f\t%.4f\t%+.4f\t\n", ms(cp50), ms(kp50), ms(kp50-cp50))
+	fmt.Fprintf(w, "P99 Latency (ms)\t%.4f\t%.4f\t%+.4f\t\n", ms(cp99), ms(kp99), ms(kp99-cp99))
+	fmt.Fprintf(w, "Error Rate (%%)\t%.3f\t%.3f\t%+.3f\t\n", errorRate(control)*100, errorRate(canary)*100, (errorRate(canary)-errorRate(control))*100)
+	fmt.Fprintf(w, "Backend Req/1k Ops\t%.1f\t%.1f\t%+.1f\t\n", control.BackendRequestsPer1kOps, canary.BackendRequestsPer1kOps, canary.BackendRequestsPer1kOps-control.BackendRequestsPer1kOps)
+	if control.StalenessTracked {
+		fmt.Fprintf(w, "Stale Reads\t%d\t%d\t%+d\t\n", control.StaleReads, canary.StaleReads, canary.StaleReads-control.StaleReads)
+	}
+	w.Flush()
+}
+
+// errorRate is the fraction of r's operations that failed.
+func errorRate(r benchmark.Result) float64 {
+	if r.TotalOperations == 0 {
+		return 0
+	}
+	return float64(r.TotalErrors) / float64(r.TotalOperations)
+}
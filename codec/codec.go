@@ -0,0 +1,126 @@
+// Package codec defines the on-wire layout of benchmark values, so values
+// written by this harness can be produced and validated by clients in other
+// languages during multi-client experiments.
+//
+// Layout v1 (all integers big-endian):
+//
+//	offset  size  field
+//	0       2     magic "CB"
+//	2       1     version, 1
+//	3       1     flags, reserved, 0
+//	4       4     writer ID (uint32)
+//	8       8     write timestamp (int64, Unix nanoseconds)
+//	16      8     sequence number (uint64), 0 when not tracked
+//	24      4     payload length n (uint32)
+//	28      4     CRC-32C (Castagnoli) of bytes 0-27 followed by the payload
+//	32      n     payload
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"time"
+)
+
+// Header is the metadata carried with every encoded value.
+type Header struct {
+	Version   uint8
+	WriterID  uint32
+	Timestamp time.Time
+	Seq       uint64
+}
+
+// Codec encodes payloads with a Header and validates them on decode.
+type Codec interface {
+	Name() string
+	Encode(h Header, payload []byte) []byte
+	Decode(b []byte) (Header, []byte, error)
+}
+
+var (
+	// ErrMalformed is returned for values that are not in the codec's layout.
+	ErrMalformed = errors.New("codec: malformed value")
+	// ErrChecksum is returned for values whose checksum does not match.
+	ErrChecksum = errors.New("codec: checksum mismatch")
+)
+
+// ByName returns the codec registered as "raw" or "v1".
+func ByName(name string) (Codec, error) {
+	switch name {
+	case "raw":
+		return Raw{}, nil
+	case "v1":
+		return V1{}, nil
+	}
+	return nil, fmt.Errorf("unknown codec %q (want raw or v1)", name)
+}
+
+// WriterID derives a stable writer ID from a client name.
+func WriterID(name string) uint32 {
+	return crc32.ChecksumIEEE([]byte(name))
+}
+
+// Raw stores payloads unchanged and decodes them with an empty Header.
+type Raw struct{}
+
+func (Raw) Name() string { return "raw" }
+
+func (Raw) Encode(_ Header, payload []byte) []byte { return payload }
+
+func (Raw) Decode(b []byte) (Header, []byte, error) { return Header{}, b, nil }
+
+const (
+	v1HeaderSize = 32
+	v1Version    = 1
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// V1 implements layout version 1 described in the package documentation.
+type V1 struct{}
+
+func (V1) Name() string { return "v1" }
+
+func (V1) Encode(h Header, payload []byte) []byte {
+	b := make([]byte, v1HeaderSize+len(payload))
+	b[0], b[1] = 'C', 'B'
+	b[2] = v1Version
+	binary.BigEndian.PutUint32(b[4:], h.WriterID)
+	binary.BigEndian.PutUint64(b[8:], uint64(h.Timestamp.UnixNano()))
+	binary.BigEndian.PutUint64(b[16:], h.Seq)
+	binary.BigEndian.PutUint32(b[24:], uint32(len(payload)))
+	copy(b[v1HeaderSize:], payload)
+	binary.BigEndian.PutUint32(b[28:], v1Checksum(b))
+	return b
+}
+
+func (V1) Decode(b []byte) (Header, []byte, error) {
+	if len(b) < v1HeaderSize || b[0] != 'C' || b[1] != 'B' {
+		return Header{}, nil, ErrMalformed
+	}
+	if b[2] != v1Version {
+		return Header{}, nil, fmt.Errorf("%w: unsupported version %d", ErrMalformed, b[2])
+	}
+	n := binary.BigEndian.Uint32(b[24:])
+	if uint64(len(b)-v1HeaderSize) != uint64(n) {
+		return Header{}, nil, fmt.Errorf("%w: payload length %d, header says %d", ErrMalformed, len(b)-v1HeaderSize, n)
+	}
+	if binary.BigEndian.Uint32(b[28:]) != v1Checksum(b) {
+		return Header{}, nil, ErrChecksum
+	}
+	h := Header{
+		Version:   b[2],
+		WriterID:  binary.BigEndian.Uint32(b[4:]),
+		Timestamp: time.Unix(0, int64(binary.BigEndian.Uint64(b[8:]))),
+		Seq:       binary.BigEndian.Uint64(b[16:]),
+	}
+	return h, b[v1HeaderSize:], nil
+}
+
+// v1Checksum covers the header up to the checksum field and the payload.
+func v1Checksum(b []byte) uint32 {
+	sum := crc32.Update(0, castagnoli, b[:28])
+	return crc32.Update(sum, castagnoli, b[v1HeaderSize:])
+}
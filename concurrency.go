@@ -0,0 +1,160 @@
+package main
+
+import (
+	"caching-benchmark/benchmark"
+	"caching-benchmark/workload"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// kneeFraction defines the saturation knee: the lowest concurrency whose
+// throughput reaches this fraction of the strategy's peak. Past it, adding
+// workers mostly adds queueing latency.
+const kneeFraction = 0.9
+
+// concurrencyPoint is one closed-loop run at a fixed number of workers.
+type concurrencyPoint struct {
+	workers    int
+	opsPerSec  float64
+	avgLatency time.Duration
+	p95Latency time.Duration
+}
+
+// concurrencyCurve is the throughput-vs-latency curve of one strategy
+// across the concurrency ladder.
+type concurrencyCurve struct {
+	strategy string
+	points   []concurrencyPoint
+}
+
+// knee returns the index of the saturation knee, or -1 for an empty curve.
+func (c concurrencyCurve) knee() int {
+	var peak float64
+	for _, p := range c.points {
+		peak = max(peak, p.opsPerSec)
+	}
+	for i, p := range c.points {
+		if p.opsPerSec >= kneeFraction*peak {
+			return i
+		}
+	}
+	return -1
+}
+
+// runConcurrencySweep runs every strategy closed-loop at each of
+// cfg.ConcurrencyLevels workers.
+func runConcurrencySweep(ctx context.Context, cfg Config, w []workload.Operation, seed int64) ([]benchmark.Result, error) {
+	var results []benchmark.Result
+	var curves []concurrencyCurve
+	defer func() {
+		printConcurrencyCurves(curves)
+		if cfg.CurveDir != "" && len(curves) > 0 {
+			if err := writeConcurrencyCurves(cfg.CurveDir, cfg.Name, curves); err != nil {
+				log.Printf("Failed to write concurrency curves: %v", err)
+			}
+		}
+	}()
+
+	for _, spec := range strategySpecs(cfg) {
+		var curve concurrencyCurve
+		for _, workers := range cfg.ConcurrencyLevels {
+			// Every run gets a fresh strategy: strategies are not reusable after Close.
+			ns, err := buildStrategy(cfg, spec)
+			if err != nil {
+				return results, err
+			}
+			log.Printf("\n--- Running Strategy: %s with %d workers ---", ns.strategy.Name(), workers)
+			prep, err := prepareData(ctx, cfg, seed)
+			if err != nil {
+				return results, fmt.Errorf("failed to prepare data for strategy %s: %w", ns.strategy.Name(), err)
+			}
+			levelCfg := cfg
+			levelCfg.Concurrency = workers
+			result, err := benchmark.NewRunner(ns.strategy, w, runnerOptions(levelCfg, ns, seed)).Run(ctx)
+			if err != nil {
+				log.Printf("Error running strategy %s with %d workers: %v", ns.strategy.Name(), workers, err)
+				continue
+			}
+			result.Lifecycle.DataPrep = prep
+
+			curve.strategy = result.StrategyName
+			point := concurrencyPoint{workers: workers, opsPerSec: result.OpsPerSecond}
+			point.avgLatency, point.p95Latency = latencyStats(result.Latencies)
+			curve.points = append(curve.points, point)
+
+			result.StrategyName = fmt.Sprintf("%s [%d workers]", result.StrategyName, workers)
+			results = append(results, result)
+			if result.Incomplete {
+				curves = append(curves, curve)
+				return results, nil
+			}
+		}
+		if len(curve.points) > 0 {
+			curves = append(curves, curve)
+		}
+	}
+	return results, nil
+}
+
+// printConcurrencyCurves prints one table per strategy with a row per
+// concurrency level, marking the saturation knee.
+func printConcurrencyCurves(curves []concurrencyCurve) {
+	for _, c := range curves {
+		knee := c.knee()
+		log.Printf("\n--- Throughput vs Concurrency: %s (knee at %d workers) ---", c.strategy, c.points[knee].workers)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.AlignRight|tabwriter.Debug)
+		fmt.Fprintln(w, "Workers\tOps/sec\tAvg Latency (ms)\tP95 Latency (ms)\tKnee\t")
+		for i, p := range c.points {
+			mark := ""
+			if i == knee {
+				mark = "*"
+			}
+			fmt.Fprintf(w, "%d\t%.2f\t%.4f\t%.4f\t%s\t\n", p.workers, p.opsPerSec, ms(p.avgLatency), ms(p.p95Latency), mark)
+		}
+		w.Flush()
+	}
+}
+
+// writeConcurrencyCurves writes the scenario's curves to dir as CSV.
+func writeConcurrencyCurves(dir, scenario string, curves []concurrencyCurve) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, slug(scenario)+".csv")
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(f)
+	cw.Write([]string{"strategy", "workers", "ops_per_second", "avg_latency_ms", "p95_latency_ms", "knee"})
+	for _, c := range curves {
+		knee := c.knee()
+		for i, p := range c.points {
+			cw.Write([]string{
+				c.strategy,
+				strconv.Itoa(p.workers),
+				formatFloat(p.opsPerSec),
+				formatFloat(ms(p.avgLatency)),
+				formatFloat(ms(p.p95Latency)),
+				strconv.FormatBool(i == knee),
+			})
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	log.Printf("Concurrency curves written to %s", path)
+	return nil
+}
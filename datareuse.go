@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	"caching-benchmark/implementations"
+	"caching-benchmark/workload"
+
+	"github.com/redis/rueidis"
+)
+
+// populatedDataset fingerprints the data last populated at an address, so
+// the next strategy run of the same scenario can verify it and rewrite only
+// the keys the previous run wrote instead of flushing and repopulating.
+type populatedDataset struct {
+	// id is the datasetID of the populated data.
+	id uint64
+	// count is the number of keys in the benchmark's keyspace afterwards.
+	count int64
+	// dirty are the indexes of the keys the scenario's workload writes.
+	dirty []int
+}
+
+// fingerprintSamples is the number of clean keys whose value is checked
+// before populated data is reused.
+const fingerprintSamples = 32
+
+// fingerprintBytes is how much of the end of each sampled value is
+// compared; unlike the head, it holds no codec header.
+const fingerprintBytes = 64
+
+var (
+	populatedMu sync.Mutex
+	populated   = make(map[string]populatedDataset)
+)
+
+// datasetID hashes a dataset: its keys, their TTLs and an identity of the
+// value they are populated with.
+func datasetID(ks keyspace, keys []string, value string, ttls []time.Duration) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s\x00%d\x00", ks.prefix, len(keys))
+	for i, key := range keys {
+		h.Write([]byte(key))
+		if ttls != nil {
+			fmt.Fprintf(h, "\x00%d", ttls[i])
+		}
+		h.Write([]byte{0})
+	}
+	h.Write([]byte(value))
+	return h.Sum64()
+}
+
+// dirtyKeys returns the indexes of the populated keys the scenario's
+// workload, or its stampede invalidator, writes. keys[i] holds logical key
+// "key-<i>".
+func dirtyKeys(cfg Config, seed int64) []int {
+	written := make(map[string]bool)
+	for _, op := range generateWorkload(cfg, seed) {
+		if op.Type == workload.WriteOp || op.Type == workload.RMWOp {
+			written[op.Key] = true
+		}
+	}
+	if cfg.StampedeInterval > 0 {
+		written[hotKey] = true
+	}
+	var dirty []int
+	for i := 0; i < cfg.NumKeys; i++ {
+		if written[fmt.Sprintf("key-%d", i)] {
+			dirty = append(dirty, i)
+		}
+	}
+	return dirty
+}
+
+// reuseData tries to reuse the data a previous run of the scenario
+// populated at cfg.Addr. It verifies the keyspace's key count and samples
+// clean keys against value, then rewrites only the dirty keys. It reports
+// false, having changed nothing, when the data cannot be reused.
+func reuseData(ctx context.Context, cfg Config, seed int64, id uint64, keys []string, value string, ttls []time.Duration) (bool, error) {
+	populatedMu.Lock()
+	prev, ok := populated[cfg.Addr]
+	populatedMu.Unlock()
+	if !ok || prev.id != id {
+		return false, nil
+	}
+
+	client, err := rueidis.NewClient(rueidis.ClientOption{InitAddress: []string{dataAddr(cfg.Addr)}, DisableCache: true})
+	if err != nil {
+		return false, err
+	}
+	defer client.Close()
+
+	count, err := countKeys(ctx, client, cfg.keyspace())
+	if err != nil {
+		return false, err
+	}
+	if count != prev.count {
+		log.Printf("Populated data changed (%d keys, expected %d); repopulating", count, prev.count)
+		return false, nil
+	}
+	dirty := make(map[int]bool, len(prev.dirty))
+	for _, i := range prev.dirty {
+		dirty[i] = true
+	}
+	var samples rueidis.Commands
+	var sampled []int
+	step := max(1, len(keys)/fingerprintSamples)
+	for i := 0; i < len(keys) && len(sampled) < fingerprintSamples; i += step {
+		if dirty[i] {
+			continue
+		}
+		samples = append(samples, client.B().Getrange().Key(keys[i]).Start(-fingerprintBytes).End(-1).Build())
+		sampled = append(sampled, i)
+	}
+	want := value[max(0, len(value)-fingerprintBytes):]
+	for j, resp := range client.DoMulti(ctx, samples...) {
+		got, err := resp.ToString()
+		if err != nil {
+			return false, err
+		}
+		if got != want {
+			log.Printf("Populated key %q changed; repopulating", keys[sampled[j]])
+			return false, nil
+		}
+	}
+
+	log.Printf("Reusing populated data (%d keys verified by count and %d samples); rewriting %d keys written by the previous run", count, len(sampled), len(prev.dirty))
+	rewrite := make([]string, len(prev.dirty))
+	var rewriteTTLs []time.Duration
+	if ttls != nil {
+		rewriteTTLs = make([]time.Duration, len(prev.dirty))
+	}
+	for j, i := range prev.dirty {
+		rewrite[j] = keys[i]
+		if ttls != nil {
+			rewriteTTLs[j] = ttls[i]
+		}
+	}
+	if err := populate(ctx, client, cfg.populateOptions(), rewrite, value, rewriteTTLs); err != nil {
+		return false, err
+	}
+	// The next run follows this scenario's workload, which may write other keys.
+	populatedMu.Lock()
+	populated[cfg.Addr] = populatedDataset{id: id, count: count, dirty: dirtyKeys(cfg, seed)}
+	populatedMu.Unlock()
+	return true, nil
+}
+
+// rememberData records the dataset just populated at cfg.Addr for reuseData.
+// On failure it forgets the address, so the next run repopulates.
+func rememberData(ctx context.Context, cfg Config, seed int64, id uint64) {
+	populatedMu.Lock()
+	delete(populated, cfg.Addr)
+	populatedMu.Unlock()
+
+	client, err := rueidis.NewClient(rueidis.ClientOption{InitAddress: []string{dataAddr(cfg.Addr)}, DisableCache: true})
+	if err != nil {
+		return
+	}
+	defer client.Close()
+	count, err := countKeys(ctx, client, cfg.keyspace())
+	if err != nil {
+		return
+	}
+	populatedMu.Lock()
+	populated[cfg.Addr] = populatedDataset{
+		id:    id,
+		count: count,
+		dirty: dirtyKeys(cfg, seed),
+	}
+	populatedMu.Unlock()
+}
+
+// countKeys returns the number of keys the benchmark owns: those under the
+// prefix when the keyspace is shared, otherwise the whole datastore.
+func countKeys(ctx context.Context, client rueidis.Client, ks keyspace) (int64, error) {
+	if !ks.noFlush {
+		return client.Do(ctx, client.B().Dbsize().Build()).AsInt64()
+	}
+	pattern := escapeGlob(ks.prefix) + "*"
+	var n int64
+	var cursor uint64
+	for {
+		entry, err := client.Do(ctx, client.B().Scan().Cursor(cursor).Match(pattern).Count(1000).Build()).AsScanEntry()
+		if err != nil {
+			return 0, err
+		}
+		n += int64(len(entry.Elements))
+		cursor = entry.Cursor
+		if cursor == 0 {
+			return n, nil
+		}
+	}
+}
+
+func dataAddr(addr string) string {
+	if addr == "" {
+		return implementations.DefaultAddr
+	}
+	return addr
+}
@@ -0,0 +1,248 @@
+// Package env starts and stops the Redis deployments that benchmark
+// scenarios run against, so runs are reproducible without manual setup.
+// Managed deployments are Docker containers driven through the docker CLI.
+package env
+
+import (
+	"caching-benchmark/implementations"
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// Environment is a Redis deployment that scenarios run against.
+type Environment struct {
+	// Name labels the environment in results.
+	Name string
+	// Addr is the endpoint clients connect to; for a cluster, one of its
+	// nodes, from which clients discover the others.
+	Addr string
+	// Image is the Docker image started for this environment. Empty means the
+	// server at Addr is managed externally.
+	Image string
+	// Cluster starts ClusterNodes primaries with cluster mode enabled instead
+	// of a standalone server.
+	Cluster      bool
+	ClusterNodes int
+	// MaxMemory and MaxMemoryPolicy, when set, are passed to the server at
+	// startup, e.g. "2gb" and "allkeys-lru".
+	MaxMemory       string
+	MaxMemoryPolicy string
+
+	// container is the Docker container name for managed environments.
+	container string
+	// ports are the host ports of the environment's nodes.
+	ports []int
+}
+
+// Options parameterize every managed environment.
+type Options struct {
+	// Cluster starts a cluster of ClusterNodes primaries. Zero nodes selects
+	// defaultClusterNodes.
+	Cluster      bool
+	ClusterNodes int
+	// MaxMemory and MaxMemoryPolicy are passed to every managed server.
+	MaxMemory       string
+	MaxMemoryPolicy string
+}
+
+// Host ports of managed environments. Standalone environments use
+// consecutive ports from firstPort, clusters consecutive blocks from
+// firstClusterPort, so they never collide with a developer's Redis on 6379.
+const (
+	firstPort           = 6390
+	firstClusterPort    = 7100
+	defaultClusterNodes = 3
+)
+
+// Parse turns comma-separated lists of Docker images and Redis versions into
+// managed environments; each version selects the redis:<version>-alpine
+// image. Empty lists yield the externally managed default at
+// implementations.DefaultAddr.
+func Parse(images, versions string, opts Options) ([]Environment, error) {
+	var all []string
+	for _, list := range []struct{ values, format string }{{images, "%s"}, {versions, "redis:%s-alpine"}} {
+		if list.values == "" {
+			continue
+		}
+		for _, v := range strings.Split(list.values, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				return nil, fmt.Errorf("empty entry in %q", list.values)
+			}
+			all = append(all, fmt.Sprintf(list.format, v))
+		}
+	}
+	if len(all) == 0 {
+		return []Environment{{Name: "default", Addr: implementations.DefaultAddr}}, nil
+	}
+
+	nodes := opts.ClusterNodes
+	if nodes <= 0 {
+		nodes = defaultClusterNodes
+	}
+	var envs []Environment
+	for i, image := range all {
+		e := Environment{
+			Name:            image,
+			Image:           image,
+			Cluster:         opts.Cluster,
+			MaxMemory:       opts.MaxMemory,
+			MaxMemoryPolicy: opts.MaxMemoryPolicy,
+			container:       fmt.Sprintf("caching-benchmark-env-%d", i),
+		}
+		if opts.Cluster {
+			e.Name += " (cluster)"
+			e.ClusterNodes = nodes
+			for n := 0; n < nodes; n++ {
+				e.ports = append(e.ports, firstClusterPort+i*nodes+n)
+			}
+		} else {
+			e.ports = []int{firstPort + i}
+		}
+		e.Addr = fmt.Sprintf("127.0.0.1:%d", e.ports[0])
+		envs = append(envs, e)
+	}
+	return envs, nil
+}
+
+// Start launches the environment's container, if managed, and waits until the
+// server answers PING and, for a cluster, reports all slots covered. The
+// returned function stops the container.
+func (e Environment) Start(ctx context.Context) (stop func(), err error) {
+	if e.Image == "" {
+		return func() {}, nil
+	}
+
+	exec.Command("docker", "rm", "-f", e.container).Run()
+	args := []string{"run", "--rm", "-d", "--name", e.container}
+	var serverArgs []string
+	if e.MaxMemory != "" {
+		serverArgs = append(serverArgs, "--maxmemory", e.MaxMemory)
+	}
+	if e.MaxMemoryPolicy != "" {
+		serverArgs = append(serverArgs, "--maxmemory-policy", e.MaxMemoryPolicy)
+	}
+	if e.Cluster {
+		for _, p := range e.ports {
+			args = append(args, "-p", fmt.Sprintf("%d:%d", p, p))
+		}
+		args = append(args, "--entrypoint", "sh", e.Image, "-c", clusterScript(e.ports, serverArgs))
+		log.Printf("Starting %s as %d-node cluster in container %s on ports %d-%d...", e.Image, len(e.ports), e.container, e.ports[0], e.ports[len(e.ports)-1])
+	} else {
+		args = append(args, "-p", fmt.Sprintf("%d:6379", e.ports[0]), e.Image)
+		// The official images' entrypoints prepend the server binary to
+		// arguments that start with a dash.
+		args = append(args, serverArgs...)
+		log.Printf("Starting %s as container %s on port %d...", e.Image, e.container, e.ports[0])
+	}
+	out, err := exec.CommandContext(ctx, "docker", args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("docker run %s: %v: %s", e.Image, err, strings.TrimSpace(string(out)))
+	}
+	stop = func() {
+		if err := exec.Command("docker", "stop", e.container).Run(); err != nil {
+			log.Printf("Failed to stop container %s: %v", e.container, err)
+		}
+	}
+
+	if err := WaitForRedis(ctx, e.Addr, 30*time.Second); err != nil {
+		stop()
+		return nil, err
+	}
+	if e.Cluster {
+		if err := waitForCluster(ctx, e.Addr, 30*time.Second); err != nil {
+			stop()
+			return nil, err
+		}
+	}
+	return stop, nil
+}
+
+// clusterScript starts one cluster-enabled server per port inside a single
+// container and joins them. Nodes announce 127.0.0.1 and listen on their
+// published host ports, so clients on the host can follow redirects.
+func clusterScript(ports []int, serverArgs []string) string {
+	var b strings.Builder
+	b.WriteString("S=$(command -v valkey-server || command -v redis-server); C=$(command -v valkey-cli || command -v redis-cli); ")
+	var nodes []string
+	for _, p := range ports {
+		port := strconv.Itoa(p)
+		fmt.Fprintf(&b, "$S --port %s --cluster-enabled yes --cluster-config-file /tmp/nodes-%s.conf --cluster-announce-ip 127.0.0.1 --save '' --appendonly no --daemonize yes %s; ",
+			port, port, strings.Join(serverArgs, " "))
+		nodes = append(nodes, "127.0.0.1:"+port)
+	}
+	fmt.Fprintf(&b, "sleep 1; $C --cluster create %s --cluster-replicas 0 --cluster-yes; exec tail -f /dev/null", strings.Join(nodes, " "))
+	return b.String()
+}
+
+// WaitForRedis polls addr with PING until it answers or timeout passes.
+func WaitForRedis(ctx context.Context, addr string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		client, err := rueidis.NewClient(rueidis.ClientOption{InitAddress: []string{addr}, DisableCache: true, ForceSingleClient: true})
+		if err == nil {
+			err = client.Do(ctx, client.B().Ping().Build()).Error()
+			client.Close()
+			if err == nil {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) || ctx.Err() != nil {
+			return fmt.Errorf("redis at %s not ready after %v: %w", addr, timeout, err)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+// waitForCluster polls CLUSTER INFO on addr until the cluster state is ok.
+func waitForCluster(ctx context.Context, addr string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		client, err := rueidis.NewClient(rueidis.ClientOption{InitAddress: []string{addr}, DisableCache: true, ForceSingleClient: true})
+		if err == nil {
+			var info string
+			info, err = client.Do(ctx, client.B().ClusterInfo().Build()).ToString()
+			client.Close()
+			if err == nil && implementations.ParseInfo(info)["cluster_state"] == "ok" {
+				return nil
+			}
+			if err == nil {
+				err = fmt.Errorf("cluster_state is not ok")
+			}
+		}
+		if time.Now().After(deadline) || ctx.Err() != nil {
+			return fmt.Errorf("cluster at %s not ready after %v: %w", addr, timeout, err)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+// ServerVersion reports the server version from INFO, preferring Valkey's own
+// version field over the Redis compatibility version it also advertises.
+func ServerVersion(ctx context.Context, addr string) string {
+	client, err := rueidis.NewClient(rueidis.ClientOption{InitAddress: []string{addr}, DisableCache: true})
+	if err != nil {
+		return "unknown"
+	}
+	defer client.Close()
+	info, err := client.Do(ctx, client.B().Info().Section("server").Build()).ToString()
+	if err != nil {
+		return "unknown"
+	}
+
+	fields := implementations.ParseInfo(info)
+	if v := fields["valkey_version"]; v != "" {
+		return "valkey " + v
+	}
+	if v := fields["redis_version"]; v != "" {
+		return "redis " + v
+	}
+	return "unknown"
+}
@@ -0,0 +1,519 @@
+package main
+
+import (
+	"caching-benchmark/benchmark"
+	"caching-benchmark/implementations"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// Manifest describes a multi-run experiment: a base scenario and the named
+// factors varied over it.
+//
+//	{
+//	  "name": "csc vs pubsub across networks",
+//	  "scenario": "Read-Heavy (90% Read, 64B Values)",
+//	  "factors": [
+//	    {"name": "strategy", "levels": ["rueidis-csc", "ristretto-pubsub"]},
+//	    {"name": "rtt", "levels": ["0s", "2ms"]},
+//	    {"name": "memory_budget", "levels": ["1048576", "1073741824"]},
+//	    {"name": "zipf_s", "levels": ["1.01", "1.5"]}
+//	  ],
+//	  "fraction": 2,
+//	  "replicates": 2
+//	}
+type Manifest struct {
+	Name string `json:"name"`
+	// Scenario names the default scenario used as the base configuration.
+	// Base, if set, is used instead.
+	Scenario string   `json:"scenario"`
+	Base     *Config  `json:"base"`
+	Factors  []Factor `json:"factors"`
+	// Fraction runs a regular 1/Fraction fraction of the full factorial: the
+	// points whose level indices sum to a multiple of Fraction. Every factor's
+	// levels stay equally represented as long as some other factor has a
+	// multiple of Fraction levels. Zero or one runs every point.
+	Fraction int `json:"fraction"`
+	// Replicates repeats every point with successive seeds. Zero runs once.
+	Replicates int `json:"replicates"`
+}
+
+// Factor is one named variable of an experiment and the levels it takes.
+//
+// The names strategy (a strategy spec), rtt, concurrency, num_keys,
+// value_size, read_ratio, zipf_s and serializer set the scenario field of
+// that name; any other name is a tuning knob applied to the strategy, as in
+// strategy specs.
+type Factor struct {
+	Name   string   `json:"name"`
+	Levels []string `json:"levels"`
+}
+
+// observation is the outcome of one experiment run.
+type observation struct {
+	levels []int
+	seed   int64
+	result benchmark.Result
+	p99    time.Duration
+}
+
+// experimentMetric is a response variable analyzed across factors.
+type experimentMetric struct {
+	name  string
+	value func(observation) float64
+	// lowerIsBetter orders the levels when naming the best one.
+	lowerIsBetter bool
+}
+
+var experimentMetrics = []experimentMetric{
+	{"Hit Rate (%)", func(o observation) float64 { return o.result.HitRate * 100 }, false},
+	{"P99 Latency (ms)", func(o observation) float64 { return ms(o.p99) }, true},
+	{"Ops/sec", func(o observation) float64 { return o.result.OpsPerSecond }, false},
+}
+
+// runExperiment runs every point of a manifest's design and reports which
+// factors explain the variance of each metric.
+func runExperiment(args []string) error {
+	fs := flag.NewFlagSet("experiment", flag.ExitOnError)
+	manifestPath := fs.String("manifest", "", "path to the experiment manifest (required)")
+	addr := fs.String("addr", implementations.DefaultAddr, "Redis address")
+	fs.Parse(args)
+	if *manifestPath == "" {
+		fs.Usage()
+		return errors.New("-manifest is required")
+	}
+
+	m, err := loadManifest(*manifestPath)
+	if err != nil {
+		return err
+	}
+	base, err := m.base()
+	if err != nil {
+		return err
+	}
+	base.Addr = *addr
+	clock := benchmark.MeasureClock()
+	base.Clock = &clock
+
+	factors := m.Factors
+	if !hasFactor(factors, "strategy") {
+		// Every run measures exactly one strategy, so the base scenario's
+		// strategies become a factor of their own.
+		factors = append([]Factor{{Name: "strategy", Levels: strategySpecs(base)}}, factors...)
+	}
+	points := designPoints(factors, m.Fraction)
+	replicates := max(m.Replicates, 1)
+	for _, point := range points {
+		if _, err := applyLevels(base, factors, point); err != nil {
+			return err
+		}
+	}
+	log.Printf("Experiment %q: %d factors, %d design points, %d replicates", m.Name, len(factors), len(points), replicates)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	baseSeed := base.Seed
+	if baseSeed == 0 {
+		baseSeed = defaultSeed
+	}
+	var obs []observation
+	for rep := 0; rep < replicates && ctx.Err() == nil; rep++ {
+		for _, point := range points {
+			cfg, _ := applyLevels(base, factors, point)
+			cfg.Seed = baseSeed + int64(rep)
+			cfg.Name = fmt.Sprintf("%s [%s]", m.Name, pointLabel(factors, point))
+			results, err := runScenario(ctx, cfg)
+			if ctx.Err() != nil {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			for _, r := range results {
+				if r.Incomplete {
+					continue
+				}
+				sortLatencies(r.Latencies)
+				obs = append(obs, observation{levels: point, seed: cfg.Seed, result: r, p99: nearestRank(r.Latencies, 99)})
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		log.Println("Interrupted: analyzing the runs completed so far.")
+	}
+	printExperimentReport(m.Name, factors, obs)
+	return nil
+}
+
+func loadManifest(path string) (Manifest, error) {
+	var m Manifest
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(m.Factors) == 0 {
+		return m, fmt.Errorf("%s: manifest has no factors", path)
+	}
+	seen := make(map[string]bool)
+	for _, f := range m.Factors {
+		if len(f.Levels) == 0 {
+			return m, fmt.Errorf("%s: factor %q has no levels", path, f.Name)
+		}
+		if seen[f.Name] {
+			return m, fmt.Errorf("%s: factor %q is listed twice", path, f.Name)
+		}
+		seen[f.Name] = true
+	}
+	if m.Name == "" {
+		m.Name = path
+	}
+	return m, nil
+}
+
+func (m Manifest) base() (Config, error) {
+	if m.Base != nil {
+		return *m.Base, nil
+	}
+	for _, cfg := range defaultScenarios() {
+		if cfg.Name == m.Scenario {
+			return cfg, nil
+		}
+	}
+	return Config{}, fmt.Errorf("manifest needs \"base\" or the name of a default scenario, got %q", m.Scenario)
+}
+
+func hasFactor(factors []Factor, name string) bool {
+	for _, f := range factors {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// designPoints expands the factors into level-index tuples, keeping the
+// points of the regular 1/fraction fraction.
+func designPoints(factors []Factor, fraction int) [][]int {
+	points := [][]int{{}}
+	for _, f := range factors {
+		var next [][]int
+		for _, p := range points {
+			for i := range f.Levels {
+				next = append(next, append(append([]int(nil), p...), i))
+			}
+		}
+		points = next
+	}
+	if fraction <= 1 {
+		return points
+	}
+	var kept [][]int
+	for _, p := range points {
+		sum := 0
+		for _, i := range p {
+			sum += i
+		}
+		if sum%fraction == 0 {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// applyLevels returns base with every factor set to its level in point.
+func applyLevels(base Config, factors []Factor, point []int) (Config, error) {
+	cfg := base
+	var knobs []string
+	var err error
+	for i, f := range factors {
+		level := f.Levels[point[i]]
+		switch f.Name {
+		case "strategy":
+			cfg.Strategies = []string{level}
+		case "rtt":
+			cfg.RTT, err = time.ParseDuration(level)
+		case "concurrency":
+			cfg.Concurrency, err = strconv.Atoi(level)
+		case "num_keys":
+			cfg.NumKeys, err = strconv.Atoi(level)
+		case "value_size":
+			cfg.ValueSizeBytes, err = strconv.Atoi(level)
+		case "read_ratio":
+			cfg.ReadWriteRatio, err = strconv.ParseFloat(level, 64)
+		case "zipf_s":
+			cfg.ZipfS, err = strconv.ParseFloat(level, 64)
+		case "serializer":
+			cfg.Serializer = level
+		default:
+			var p implementations.Params
+			err = p.Set(f.Name, level)
+			knobs = append(knobs, f.Name+"="+level)
+		}
+		if err != nil {
+			return cfg, fmt.Errorf("factor %s level %q: %w", f.Name, level, err)
+		}
+	}
+	if len(knobs) > 0 {
+		spec := cfg.Strategies[0]
+		sep := ":"
+		if strings.Contains(spec, ":") {
+			sep = ","
+		}
+		cfg.Strategies = []string{spec + sep + strings.Join(knobs, ",")}
+	}
+	return cfg, nil
+}
+
+func pointLabel(factors []Factor, point []int) string {
+	parts := make([]string, len(factors))
+	for i, f := range factors {
+		parts[i] = f.Name + "=" + f.Levels[point[i]]
+	}
+	return strings.Join(parts, " ")
+}
+
+// factorEffect is the main effect of one factor on one metric.
+type factorEffect struct {
+	factor int
+	// share is the fraction of the metric's total sum of squares explained
+	// by the factor's level means (eta squared).
+	share      float64
+	levelMeans []float64
+	levelRuns  []int
+}
+
+// mainEffects computes each factor's share of the metric's variance.
+func mainEffects(factors []Factor, obs []observation, value func(observation) float64) []factorEffect {
+	var grand float64
+	for _, o := range obs {
+		grand += value(o)
+	}
+	grand /= float64(len(obs))
+	var total float64
+	for _, o := range obs {
+		d := value(o) - grand
+		total += d * d
+	}
+
+	effects := make([]factorEffect, len(factors))
+	for fi, f := range factors {
+		e := factorEffect{factor: fi, levelMeans: make([]float64, len(f.Levels)), levelRuns: make([]int, len(f.Levels))}
+		for _, o := range obs {
+			e.levelMeans[o.levels[fi]] += value(o)
+			e.levelRuns[o.levels[fi]]++
+		}
+		var between float64
+		for li, n := range e.levelRuns {
+			if n == 0 {
+				continue
+			}
+			e.levelMeans[li] /= float64(n)
+			d := e.levelMeans[li] - grand
+			between += float64(n) * d * d
+		}
+		if total > 0 {
+			e.share = between / total
+		}
+		effects[fi] = e
+	}
+	sort.SliceStable(effects, func(i, j int) bool { return effects[i].share > effects[j].share })
+	return effects
+}
+
+// interactionEffect is the two-factor interaction of factors a and b on
+// one metric: the part of the cell means not explained by either main effect.
+type interactionEffect struct {
+	a, b  int
+	share float64
+}
+
+// interactions computes the share of the metric's variance explained by each
+// pairwise interaction. It is exact for balanced full factorials; in a
+// fractional design interactions are aliased with other terms and the
+// shares are indicative only.
+func interactions(factors []Factor, obs []observation, value func(observation) float64) []interactionEffect {
+	var grand, total float64
+	for _, o := range obs {
+		grand += value(o)
+	}
+	grand /= float64(len(obs))
+	for _, o := range obs {
+		d := value(o) - grand
+		total += d * d
+	}
+
+	// Marginal means by factor and level.
+	marginal := make([][]float64, len(factors))
+	for fi, f := range factors {
+		sums := make([]float64, len(f.Levels))
+		counts := make([]int, len(f.Levels))
+		for _, o := range obs {
+			sums[o.levels[fi]] += value(o)
+			counts[o.levels[fi]]++
+		}
+		for li := range sums {
+			if counts[li] > 0 {
+				sums[li] /= float64(counts[li])
+			}
+		}
+		marginal[fi] = sums
+	}
+
+	var effects []interactionEffect
+	for a := range factors {
+		for b := a + 1; b < len(factors); b++ {
+			type cell struct{ la, lb int }
+			sums := make(map[cell]float64)
+			counts := make(map[cell]int)
+			for _, o := range obs {
+				c := cell{o.levels[a], o.levels[b]}
+				sums[c] += value(o)
+				counts[c]++
+			}
+			var ss float64
+			for c, sum := range sums {
+				n := float64(counts[c])
+				d := sum/n - marginal[a][c.la] - marginal[b][c.lb] + grand
+				ss += n * d * d
+			}
+			e := interactionEffect{a: a, b: b}
+			if total > 0 {
+				e.share = ss / total
+			}
+			effects = append(effects, e)
+		}
+	}
+	sort.SliceStable(effects, func(i, j int) bool { return effects[i].share > effects[j].share })
+	return effects
+}
+
+// rankingFlips reports, for every other factor, the levels at which a
+// different strategy is best for the metric than at its other levels.
+func rankingFlips(factors []Factor, obs []observation, m experimentMetric) []string {
+	strategy := -1
+	for i, f := range factors {
+		if f.Name == "strategy" {
+			strategy = i
+		}
+	}
+	if strategy < 0 || len(factors[strategy].Levels) < 2 {
+		return nil
+	}
+
+	var flips []string
+	for fi, f := range factors {
+		if fi == strategy {
+			continue
+		}
+		var bests []string
+		distinct := make(map[string]bool)
+		for li, level := range f.Levels {
+			sums := make([]float64, len(factors[strategy].Levels))
+			counts := make([]int, len(sums))
+			for _, o := range obs {
+				if o.levels[fi] == li {
+					sums[o.levels[strategy]] += m.value(o)
+					counts[o.levels[strategy]]++
+				}
+			}
+			best := -1
+			for si := range sums {
+				if counts[si] == 0 {
+					continue
+				}
+				mean := sums[si] / float64(counts[si])
+				if best < 0 || (m.lowerIsBetter && mean < sums[best]/float64(counts[best])) || (!m.lowerIsBetter && mean > sums[best]/float64(counts[best])) {
+					best = si
+				}
+			}
+			if best < 0 {
+				continue
+			}
+			name := factors[strategy].Levels[best]
+			distinct[name] = true
+			bests = append(bests, level+" → "+name)
+		}
+		if len(distinct) > 1 {
+			flips = append(flips, fmt.Sprintf("best strategy flips across %s: %s", f.Name, strings.Join(bests, ", ")))
+		}
+	}
+	return flips
+}
+
+// printExperimentReport prints every run and, per metric, the factors
+// ranked by the share of variance they explain, the pairwise interactions,
+// and the conditions under which the best strategy changes.
+func printExperimentReport(name string, factors []Factor, obs []observation) {
+	log.Println("==========================================================")
+	log.Printf("--- Experiment Report: %s (%d runs) ---", name, len(obs))
+	if len(obs) == 0 {
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.AlignRight|tabwriter.Debug)
+	for _, f := range factors {
+		fmt.Fprintf(w, "%s\t", f.Name)
+	}
+	fmt.Fprintln(w, "Seed\tOps/sec\tHit Rate (%)\tP99 Latency (ms)\t")
+	for _, o := range obs {
+		for i, f := range factors {
+			fmt.Fprintf(w, "%s\t", f.Levels[o.levels[i]])
+		}
+		fmt.Fprintf(w, "%d\t%.2f\t%.2f\t%.4f\t\n", o.seed, o.result.OpsPerSecond, o.result.HitRate*100, ms(o.p99))
+	}
+	w.Flush()
+
+	for _, m := range experimentMetrics {
+		log.Printf("\n--- Factor Effects on %s ---", m.name)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.AlignRight|tabwriter.Debug)
+		fmt.Fprintln(w, "Factor\tVariance Explained (%)\tBest Level\tLevel Means\t")
+		var explained float64
+		for _, e := range mainEffects(factors, obs, m.value) {
+			f := factors[e.factor]
+			best := -1
+			var means []string
+			for li, mean := range e.levelMeans {
+				if e.levelRuns[li] == 0 {
+					continue
+				}
+				means = append(means, fmt.Sprintf("%s=%.4g", f.Levels[li], mean))
+				if best < 0 || (m.lowerIsBetter && mean < e.levelMeans[best]) || (!m.lowerIsBetter && mean > e.levelMeans[best]) {
+					best = li
+				}
+			}
+			fmt.Fprintf(w, "%s\t%.1f\t%s\t%s\t\n", f.Name, e.share*100, f.Levels[best], strings.Join(means, " "))
+			explained += e.share
+		}
+		w.Flush()
+
+		if len(factors) > 1 {
+			w = tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.AlignRight|tabwriter.Debug)
+			fmt.Fprintln(w, "Interaction\tVariance Explained (%)\t")
+			for _, e := range interactions(factors, obs, m.value) {
+				fmt.Fprintf(w, "%s × %s\t%.1f\t\n", factors[e.a].Name, factors[e.b].Name, e.share*100)
+				explained += e.share
+			}
+			fmt.Fprintf(w, "Residual\t%.1f\t\n", max(0, 1-explained)*100)
+			w.Flush()
+		}
+		for _, flip := range rankingFlips(factors, obs, m) {
+			log.Printf("Note: %s", flip)
+		}
+	}
+}
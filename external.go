@@ -0,0 +1,70 @@
+package main
+
+import (
+	"caching-benchmark/benchmark"
+	"caching-benchmark/implementations"
+	"caching-benchmark/workload"
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/redis/rueidis"
+)
+
+// defaultExternalWriters is the number of external writer goroutines when
+// a scenario does not set ExternalWriters.
+const defaultExternalWriters = 4
+
+// externalWriters configures the simulated external mutators of a run on
+// ns: a peer instance of the same strategy spec, or a rawWriter.
+func externalWriters(cfg Config, ns namedStrategy, deriver workload.KeyDeriver) benchmark.ExternalWriters {
+	ext := benchmark.ExternalWriters{
+		Rate:    cfg.ExternalWriteRate,
+		Workers: cmp.Or(cfg.ExternalWriters, defaultExternalWriters),
+		Keys:    make([]string, cfg.NumKeys),
+	}
+	for i := range ext.Keys {
+		key := fmt.Sprintf("key-%d", i)
+		if deriver != nil {
+			key = deriver.Derive(key)
+		}
+		ext.Keys[i] = key
+	}
+	if cfg.ExternalWritesRaw {
+		ext.Writer = &rawWriter{addr: cmp.Or(cfg.Addr, implementations.DefaultAddr)}
+		return ext
+	}
+	peer, err := buildStrategy(cfg, cmp.Or(ns.spec, ns.name))
+	if err != nil {
+		log.Printf("Warning: no external writers for %s: %v", ns.name, err)
+		return benchmark.ExternalWriters{}
+	}
+	ext.Writer = peer.strategy
+	return ext
+}
+
+// rawWriter writes with plain SETs from its own client, like an
+// application that updates Redis without publishing invalidations.
+type rawWriter struct {
+	addr   string
+	client rueidis.Client
+}
+
+func (w *rawWriter) Init(ctx context.Context) error {
+	client, err := rueidis.NewClient(rueidis.ClientOption{InitAddress: []string{w.addr}, DisableCache: true})
+	if err != nil {
+		return err
+	}
+	w.client = client
+	return nil
+}
+
+func (w *rawWriter) Write(ctx context.Context, key, value string) error {
+	return w.client.Do(ctx, w.client.B().Set().Key(key).Value(value).Build()).Error()
+}
+
+func (w *rawWriter) Close(ctx context.Context) error {
+	w.client.Close()
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"caching-benchmark/benchmark"
+	"caching-benchmark/implementations"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// FaultSpec schedules one fault in a scenario. Kind is one of:
+//   - "drop-connections": CLIENT KILL every normal and Pub/Sub connection
+//   - "kill-pubsub": CLIENT KILL only Pub/Sub connections, dropping subscriptions
+//   - "client-pause": CLIENT PAUSE all clients for Duration
+//   - "debug-sleep": DEBUG SLEEP the server for Duration (requires enable-debug-command)
+type FaultSpec struct {
+	At       time.Duration
+	Kind     string
+	Duration time.Duration
+}
+
+// buildFaults turns a scenario's fault specs into faults injected against addr.
+func buildFaults(specs []FaultSpec, addr string) ([]benchmark.Fault, error) {
+	if addr == "" {
+		addr = implementations.DefaultAddr
+	}
+	faults := make([]benchmark.Fault, 0, len(specs))
+	for _, spec := range specs {
+		var inject func(ctx context.Context, c rueidis.Client) error
+		name := spec.Kind
+		switch spec.Kind {
+		case "drop-connections":
+			inject = func(ctx context.Context, c rueidis.Client) error {
+				if err := c.Do(ctx, c.B().ClientKill().TypeNormal().SkipmeYes().Build()).Error(); err != nil {
+					return err
+				}
+				return c.Do(ctx, c.B().ClientKill().TypePubsub().SkipmeYes().Build()).Error()
+			}
+		case "kill-pubsub":
+			inject = func(ctx context.Context, c rueidis.Client) error {
+				return c.Do(ctx, c.B().ClientKill().TypePubsub().SkipmeYes().Build()).Error()
+			}
+		case "client-pause":
+			name = fmt.Sprintf("%s(%v)", spec.Kind, spec.Duration)
+			inject = func(ctx context.Context, c rueidis.Client) error {
+				return c.Do(ctx, c.B().ClientPause().Timeout(spec.Duration.Milliseconds()).All().Build()).Error()
+			}
+		case "debug-sleep":
+			name = fmt.Sprintf("%s(%v)", spec.Kind, spec.Duration)
+			inject = func(ctx context.Context, c rueidis.Client) error {
+				secs := strconv.FormatFloat(spec.Duration.Seconds(), 'f', -1, 64)
+				return c.Do(ctx, c.B().Arbitrary("DEBUG", "SLEEP").Args(secs).Build()).Error()
+			}
+		default:
+			return nil, fmt.Errorf("unknown fault kind %q", spec.Kind)
+		}
+
+		faults = append(faults, benchmark.Fault{
+			At:   spec.At,
+			Name: name,
+			// Each injection uses its own short-lived connection so it is
+			// unaffected by earlier faults.
+			Inject: func(ctx context.Context) error {
+				client, err := rueidis.NewClient(rueidis.ClientOption{InitAddress: []string{addr}, DisableCache: true})
+				if err != nil {
+					return err
+				}
+				defer client.Close()
+				return inject(ctx, client)
+			},
+		})
+	}
+	return faults, nil
+}
@@ -0,0 +1,183 @@
+package implementations
+
+import (
+	"caching-benchmark/benchmark"
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// backendCounter accumulates the traffic observed by countingClients.
+type backendCounter struct {
+	requests      atomic.Int64
+	commands      atomic.Int64
+	bytesSent     atomic.Int64
+	bytesReceived atomic.Int64
+	maxBatchSize  atomic.Int64
+}
+
+func (c *backendCounter) stats() benchmark.BackendStats {
+	return benchmark.BackendStats{
+		Requests:      c.requests.Load(),
+		Commands:      c.commands.Load(),
+		BytesSent:     c.bytesSent.Load(),
+		BytesReceived: c.bytesReceived.Load(),
+		MaxBatchSize:  c.maxBatchSize.Load(),
+	}
+}
+
+// recordBatch counts one round trip carrying n commands.
+func (c *backendCounter) recordBatch(n int, sent int64) {
+	c.requests.Add(1)
+	c.commands.Add(int64(n))
+	c.bytesSent.Add(sent)
+	for {
+		cur := c.maxBatchSize.Load()
+		if int64(n) <= cur || c.maxBatchSize.CompareAndSwap(cur, int64(n)) {
+			return
+		}
+	}
+}
+
+// recordReply counts the bytes of a reply that came back from Redis,
+// whatever its type; a reply lost to a network error counts none.
+func (c *backendCounter) recordReply(resp rueidis.RedisResult) {
+	if resp.NonRedisError() != nil {
+		return
+	}
+	msg, _ := resp.ToMessage()
+	c.bytesReceived.Add(replySize(&msg))
+}
+
+// replySize estimates the size of a reply on the wire in RESP3: the type
+// marker, length header and CRLF of every element, plus its payload.
+// Strings are sized as bulk strings, which simple strings are a few bytes
+// short of.
+func replySize(m *rueidis.RedisMessage) int64 {
+	switch {
+	case m.IsNil():
+		return 3 // "_\r\n"
+	case m.IsString():
+		s, _ := m.ToString()
+		return bulkSize(len(s))
+	case m.IsInt64():
+		v, _ := m.ToInt64()
+		return int64(3 + len(strconv.FormatInt(v, 10)))
+	case m.IsFloat64():
+		v, _ := m.ToFloat64()
+		return int64(3 + len(strconv.FormatFloat(v, 'g', -1, 64)))
+	case m.IsBool():
+		return 4 // "#t\r\n"
+	case m.IsArray():
+		values, _ := m.ToArray()
+		n := headerSize(len(values))
+		for i := range values {
+			n += replySize(&values[i])
+		}
+		return n
+	case m.IsMap():
+		fields, _ := m.ToMap()
+		n := headerSize(len(fields))
+		for k, v := range fields {
+			n += bulkSize(len(k)) + replySize(&v)
+		}
+		return n
+	}
+	if err := m.Error(); err != nil {
+		// "-<message>\r\n", less any "ERR " prefix rueidis strips.
+		return int64(3 + len(err.Error()))
+	}
+	return 0
+}
+
+// headerSize is the size of an aggregate's "*<n>\r\n" header.
+func headerSize(n int) int64 {
+	return int64(3 + len(strconv.Itoa(n)))
+}
+
+// bulkSize is the size of a bulk string of n bytes: "$<n>\r\n<data>\r\n".
+func bulkSize(n int) int64 {
+	return headerSize(n) + int64(n) + 2
+}
+
+// countingClient decorates a rueidis.Client and counts every command that
+// actually reaches Redis. Client-side cache hits are not counted.
+type countingClient struct {
+	rueidis.Client
+	counter *backendCounter
+}
+
+func newCountingClient(client rueidis.Client, counter *backendCounter) rueidis.Client {
+	return &countingClient{Client: client, counter: counter}
+}
+
+func argBytes(args []string) (n int64) {
+	for _, a := range args {
+		n += int64(len(a))
+	}
+	return n
+}
+
+func (c *countingClient) Do(ctx context.Context, cmd rueidis.Completed) rueidis.RedisResult {
+	// Commands are recycled by rueidis, so they must be measured up front.
+	c.counter.recordBatch(1, argBytes(cmd.Commands()))
+	resp := c.Client.Do(ctx, cmd)
+	c.counter.recordReply(resp)
+	return resp
+}
+
+func (c *countingClient) DoMulti(ctx context.Context, multi ...rueidis.Completed) []rueidis.RedisResult {
+	var sent int64
+	for i := range multi {
+		sent += argBytes(multi[i].Commands())
+	}
+	c.counter.recordBatch(len(multi), sent)
+	resps := c.Client.DoMulti(ctx, multi...)
+	for _, resp := range resps {
+		c.counter.recordReply(resp)
+	}
+	return resps
+}
+
+func (c *countingClient) DoCache(ctx context.Context, cmd rueidis.Cacheable, ttl time.Duration) rueidis.RedisResult {
+	sent := argBytes(cmd.Commands())
+	resp := c.Client.DoCache(ctx, cmd, ttl)
+	if !resp.IsCacheHit() {
+		c.counter.recordBatch(1, sent)
+		c.counter.recordReply(resp)
+	}
+	return resp
+}
+
+func (c *countingClient) DoMultiCache(ctx context.Context, multi ...rueidis.CacheableTTL) []rueidis.RedisResult {
+	sent := make([]int64, len(multi))
+	for i := range multi {
+		sent[i] = argBytes(multi[i].Cmd.Commands())
+	}
+	resps := c.Client.DoMultiCache(ctx, multi...)
+
+	var misses int
+	var missBytes int64
+	for i, resp := range resps {
+		if !resp.IsCacheHit() {
+			misses++
+			missBytes += sent[i]
+			c.counter.recordReply(resp)
+		}
+	}
+	if misses > 0 {
+		c.counter.recordBatch(misses, missBytes)
+	}
+	return resps
+}
+
+func (c *countingClient) Receive(ctx context.Context, subscribe rueidis.Completed, fn func(msg rueidis.PubSubMessage)) error {
+	c.counter.recordBatch(1, argBytes(subscribe.Commands()))
+	return c.Client.Receive(ctx, subscribe, func(msg rueidis.PubSubMessage) {
+		c.counter.bytesReceived.Add(int64(len(msg.Message)))
+		fn(msg)
+	})
+}
@@ -0,0 +1,186 @@
+package implementations
+
+import (
+	"caching-benchmark/benchmark"
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+const (
+	// failoverProbeInterval is how often the primary is health-checked.
+	failoverProbeInterval = 100 * time.Millisecond
+	// failoverProbeTimeout bounds each health-check PING.
+	failoverProbeTimeout = 200 * time.Millisecond
+	// failoverThreshold is the number of consecutive failed probes after
+	// which the client switches to the standby.
+	failoverThreshold = 3
+	// failoverOpTimeout bounds commands issued without a deadline, so
+	// commands stuck on a dead primary fail instead of hanging.
+	failoverOpTimeout = time.Second
+)
+
+// failoverClient sends commands to a primary until health checks declare it
+// dead, then switches to a warm standby for the rest of its life. There is
+// no failback, and writes made on the primary are not copied to the standby.
+type failoverClient struct {
+	primary  rueidis.Client
+	standby  rueidis.Client
+	switched atomic.Bool
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	mu            sync.Mutex
+	firstFailure  time.Time
+	switchedAt    time.Time
+	detectionTime time.Duration
+}
+
+func newFailoverClient(primary, standby rueidis.Client) *failoverClient {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &failoverClient{primary: primary, standby: standby, cancel: cancel, done: make(chan struct{})}
+	go c.probe(ctx)
+	return c
+}
+
+func (c *failoverClient) active() rueidis.Client {
+	if c.switched.Load() {
+		return c.standby
+	}
+	return c.primary
+}
+
+// probe PINGs the primary until it fails failoverThreshold times in a row.
+func (c *failoverClient) probe(ctx context.Context) {
+	defer close(c.done)
+	ticker := time.NewTicker(failoverProbeInterval)
+	defer ticker.Stop()
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		pingCtx, cancel := context.WithTimeout(ctx, failoverProbeTimeout)
+		err := c.primary.Do(pingCtx, c.primary.B().Ping().Build()).Error()
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			failures = 0
+			continue
+		}
+
+		failures++
+		c.mu.Lock()
+		if failures == 1 {
+			c.firstFailure = time.Now()
+		}
+		if failures >= failoverThreshold {
+			c.switchedAt = time.Now()
+			c.detectionTime = c.switchedAt.Sub(c.firstFailure)
+			c.switched.Store(true)
+			log.Printf("Primary failed %d health checks (%v); failing over to standby", failures, err)
+		}
+		c.mu.Unlock()
+		if c.switched.Load() {
+			return
+		}
+	}
+}
+
+// stats reports whether and when the client failed over.
+func (c *failoverClient) stats() benchmark.FailoverStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return benchmark.FailoverStats{
+		FailedOver:    c.switched.Load(),
+		SwitchedAt:    c.switchedAt,
+		DetectionTime: c.detectionTime,
+	}
+}
+
+func withOpTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, failoverOpTimeout)
+}
+
+func (c *failoverClient) B() rueidis.Builder {
+	return c.primary.B()
+}
+
+func (c *failoverClient) Do(ctx context.Context, cmd rueidis.Completed) rueidis.RedisResult {
+	ctx, cancel := withOpTimeout(ctx)
+	defer cancel()
+	return c.active().Do(ctx, cmd)
+}
+
+func (c *failoverClient) DoMulti(ctx context.Context, multi ...rueidis.Completed) []rueidis.RedisResult {
+	ctx, cancel := withOpTimeout(ctx)
+	defer cancel()
+	return c.active().DoMulti(ctx, multi...)
+}
+
+func (c *failoverClient) DoCache(ctx context.Context, cmd rueidis.Cacheable, ttl time.Duration) rueidis.RedisResult {
+	ctx, cancel := withOpTimeout(ctx)
+	defer cancel()
+	return c.active().DoCache(ctx, cmd, ttl)
+}
+
+func (c *failoverClient) DoMultiCache(ctx context.Context, multi ...rueidis.CacheableTTL) []rueidis.RedisResult {
+	ctx, cancel := withOpTimeout(ctx)
+	defer cancel()
+	return c.active().DoMultiCache(ctx, multi...)
+}
+
+func (c *failoverClient) DoStream(ctx context.Context, cmd rueidis.Completed) rueidis.RedisResultStream {
+	return c.active().DoStream(ctx, cmd)
+}
+
+func (c *failoverClient) DoMultiStream(ctx context.Context, multi ...rueidis.Completed) rueidis.MultiRedisResultStream {
+	return c.active().DoMultiStream(ctx, multi...)
+}
+
+// Receive subscribes on the active endpoint and, if the subscription ends
+// because of a failover, subscribes again on the standby.
+func (c *failoverClient) Receive(ctx context.Context, subscribe rueidis.Completed, fn func(msg rueidis.PubSubMessage)) error {
+	onStandby := c.switched.Load()
+	err := c.active().Receive(ctx, subscribe, fn)
+	for !onStandby && ctx.Err() == nil {
+		// Wait for the probe to decide whether the primary is gone.
+		time.Sleep(failoverThreshold * failoverProbeInterval)
+		if !c.switched.Load() {
+			break
+		}
+		onStandby = true
+		err = c.standby.Receive(ctx, subscribe, fn)
+	}
+	return err
+}
+
+func (c *failoverClient) Dedicated(fn func(rueidis.DedicatedClient) error) error {
+	return c.active().Dedicated(fn)
+}
+
+func (c *failoverClient) Dedicate() (rueidis.DedicatedClient, func()) {
+	return c.active().Dedicate()
+}
+
+func (c *failoverClient) Nodes() map[string]rueidis.Client {
+	return c.active().Nodes()
+}
+
+func (c *failoverClient) Close() {
+	c.cancel()
+	<-c.done
+	c.primary.Close()
+	c.standby.Close()
+}
@@ -0,0 +1,100 @@
+package implementations
+
+import (
+	"caching-benchmark/twolevel"
+	"context"
+	"strconv"
+
+	"github.com/redis/rueidis"
+)
+
+// Value structures selectable through Params.Structure.
+const (
+	// StructureString stores each value as a plain string with SET and GET.
+	StructureString = "string"
+	// StructureHash splits each value over HashFieldCount hash fields
+	// written with HSET and read back with HGETALL.
+	StructureHash = "hash"
+)
+
+// HashFieldCount is the number of fields a value is split over as a hash.
+const HashFieldCount = 4
+
+// hashStrategies lists the strategies that can read values stored as hashes.
+var hashStrategies = map[string]bool{
+	"rueidis-csc":        true,
+	"rueidis-csc-bcast":  true,
+	"ristretto-pubsub":   true,
+	"ristretto-spubsub":  true,
+	"ristretto-stream":   true,
+	"ristretto-keyspace": true,
+	"lru-pubsub":         true,
+	"twotier":            true,
+}
+
+// HashFields splits value into the field-value pairs HSET stores: fields
+// "f0" to "f3" holding consecutive, nearly equal parts of it.
+func HashFields(value string) []string {
+	pairs := make([]string, 0, 2*HashFieldCount)
+	for i := 0; i < HashFieldCount; i++ {
+		start, end := len(value)*i/HashFieldCount, len(value)*(i+1)/HashFieldCount
+		pairs = append(pairs, "f"+strconv.Itoa(i), value[start:end])
+	}
+	return pairs
+}
+
+// joinHash reassembles a value from the fields HGETALL returned. It reports
+// false for an absent key, for which HGETALL returns no fields.
+func joinHash(fields map[string]string) (string, bool) {
+	if len(fields) == 0 {
+		return "", false
+	}
+	var value string
+	for i := 0; i < HashFieldCount; i++ {
+		value += fields["f"+strconv.Itoa(i)]
+	}
+	return value, true
+}
+
+// hset builds the HSET storing value as a hash.
+func hset(b rueidis.Builder, key, value string) rueidis.Completed {
+	cmd := b.Hset().Key(key).FieldValue()
+	pairs := HashFields(value)
+	for i := 0; i < len(pairs); i += 2 {
+		cmd = cmd.FieldValue(pairs[i], pairs[i+1])
+	}
+	return cmd.Build()
+}
+
+// HashStore is the Redis L2 for values stored as hashes.
+type HashStore struct{}
+
+func (HashStore) Name() string { return "Redis Hash" }
+
+func (HashStore) NewL2(client rueidis.Client) twolevel.L2 {
+	return &hashL2{client: client}
+}
+
+type hashL2 struct {
+	client rueidis.Client
+}
+
+func (h *hashL2) Get(ctx context.Context, key string) (string, error) {
+	fields, err := h.client.Do(ctx, h.client.B().Hgetall().Key(key).Build()).AsStrMap()
+	if err != nil {
+		return "", err
+	}
+	value, ok := joinHash(fields)
+	if !ok {
+		return "", twolevel.ErrNotFound
+	}
+	return value, nil
+}
+
+func (h *hashL2) Set(ctx context.Context, key, value string) error {
+	return h.client.Do(ctx, hset(h.client.B(), key, value)).Error()
+}
+
+func (h *hashL2) Close() {
+	h.client.Close()
+}
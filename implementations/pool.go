@@ -0,0 +1,89 @@
+package implementations
+
+import (
+	"caching-benchmark/benchmark"
+	"context"
+	"fmt"
+	"math/bits"
+	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	"github.com/redis/rueidis"
+)
+
+// MaxConnections is the largest number of pipelined connections per node a
+// Pool accepts.
+const MaxConnections = 1 << rueidis.MaxPipelineMultiplex
+
+// Pool sizes the rueidis clients of a strategy, so client tuning can be held
+// equal, or swept, across strategy designs.
+type Pool struct {
+	// Connections is the number of connections per node commands are
+	// pipelined over, rounded down to a power of two. Zero keeps the rueidis
+	// default.
+	Connections int
+	// BlockingPoolSize caps the dedicated connections for blocking commands
+	// and transactions. Zero keeps the rueidis default.
+	BlockingPoolSize int
+	// name is set as the client name of every connection, so the server can
+	// count them. Empty leaves connections unnamed and uncounted.
+	name string
+}
+
+var poolSeq atomic.Int64
+
+// newPool returns the Pool described by p, named uniquely among the
+// strategies of this process.
+func newPool(p Params) Pool {
+	return Pool{
+		Connections:      p.Connections,
+		BlockingPoolSize: p.BlockingPoolSize,
+		name:             fmt.Sprintf("cachebench-%d-%d", os.Getpid(), poolSeq.Add(1)),
+	}
+}
+
+// option returns the client options for addr sized by the pool.
+func (p Pool) option(addr string) rueidis.ClientOption {
+	opt := rueidis.ClientOption{
+		InitAddress:      []string{addr},
+		BlockingPoolSize: p.BlockingPoolSize,
+		ClientName:       p.name,
+	}
+	switch {
+	case p.Connections == 1:
+		// Zero would select the default; a negative multiplex means one.
+		opt.PipelineMultiplex = -1
+	case p.Connections > 1:
+		opt.PipelineMultiplex = bits.Len(uint(p.Connections)) - 1
+	}
+	return opt
+}
+
+// pipelined returns the number of pipelined connections per node rueidis
+// opens for a standalone server.
+func (p Pool) pipelined() int {
+	if p.Connections > 0 {
+		return 1 << (bits.Len(uint(p.Connections)) - 1)
+	}
+	return 1 << min(2, bits.Len(uint(runtime.GOMAXPROCS(0)))-1)
+}
+
+// stats describes the pool, counting its open connections with CLIENT LIST
+// through client.
+func (p Pool) stats(ctx context.Context, client rueidis.Client) benchmark.ConnectionStats {
+	stats := benchmark.ConnectionStats{Pipelined: p.pipelined(), BlockingPoolSize: p.BlockingPoolSize, Open: -1}
+	if stats.BlockingPoolSize == 0 {
+		stats.BlockingPoolSize = rueidis.DefaultPoolSize
+	}
+	if p.name == "" || client == nil {
+		return stats
+	}
+	list, err := client.Do(ctx, client.B().ClientList().Build()).ToString()
+	if err != nil {
+		return stats
+	}
+	stats.Open = strings.Count(list, " name="+p.name+" ")
+	return stats
+}
@@ -0,0 +1,29 @@
+package implementations
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/redis/rueidis"
+)
+
+// ParseInfo parses the output of the INFO command into field/value pairs.
+func ParseInfo(info string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(info, "\r\n") {
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+// infoInt reads an integer INFO field from the given sections.
+func infoInt(ctx context.Context, client rueidis.Client, field string, sections ...string) (int64, error) {
+	info, err := client.Do(ctx, client.B().Info().Section(sections...).Build()).ToString()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(ParseInfo(info)[field], 10, 64)
+}
@@ -0,0 +1,214 @@
+package implementations
+
+import (
+	"caching-benchmark/benchmark"
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+func init() {
+	Register("redis-lua", func(p Params) benchmark.CachingStrategy {
+		return NewRedisScriptStrategy(redisScriptConfig(p, false))
+	})
+	Register("redis-function", func(p Params) benchmark.CachingStrategy {
+		return NewRedisScriptStrategy(redisScriptConfig(p, true))
+	})
+}
+
+func redisScriptConfig(p Params, function bool) RedisScriptConfig {
+	return RedisScriptConfig{
+		Addr:         p.Addr,
+		UseFunction:  function,
+		HotThreshold: p.HotKeyThreshold,
+		HotWindow:    p.HotKeyWindow,
+		Pool:         newPool(p),
+	}
+}
+
+// Defaults for server-side hot-key detection.
+const (
+	defaultHotThreshold = 100
+	defaultHotWindow    = time.Second
+)
+
+// readThroughScript reads KEYS[1] and counts the access in KEYS[2], a
+// counter that expires ARGV[1] milliseconds after the window's first access.
+// It returns {count} for an absent key and {count, value} otherwise, since
+// Lua's false for a missing value converts differently under RESP2 and RESP3.
+// The counter key shares KEYS[1]'s hash slot, so the script is cluster-safe.
+const readThroughScript = `
+local v = redis.call('GET', KEYS[1])
+local n = redis.call('INCR', KEYS[2])
+if n == 1 then redis.call('PEXPIRE', KEYS[2], ARGV[1]) end
+if not v then return {n} end
+return {n, v}
+`
+
+// readThroughLibrary is readThroughScript as a Redis 7 function library.
+const readThroughLibrary = `#!lua name=cachebench
+redis.register_function('cachebench_read', function(keys, args)
+  local v = redis.call('GET', keys[1])
+  local n = redis.call('INCR', keys[2])
+  if n == 1 then redis.call('PEXPIRE', keys[2], args[1]) end
+  if not v then return {n} end
+  return {n, v}
+end)
+`
+
+// RedisScriptConfig holds the tuning knobs of RedisScriptStrategy.
+type RedisScriptConfig struct {
+	// Addr is the Redis address. Empty selects DefaultAddr.
+	Addr string
+	// UseFunction calls a FUNCTION LOADed library with FCALL (Redis 7+)
+	// instead of running an EVALSHA script.
+	UseFunction bool
+	// HotThreshold and HotWindow define a hot key: one read at least
+	// HotThreshold times within HotWindow. Zero selects the defaults.
+	HotThreshold int64
+	HotWindow    time.Duration
+	// Pool sizes the client.
+	Pool Pool
+}
+
+// RedisScriptStrategy has no client-side cache: every read runs a
+// server-side read-through that also keeps per-key access counters, the
+// "smart server" alternative to client-side caching. Every read is a miss.
+type RedisScriptStrategy struct {
+	client  rueidis.Client
+	cfg     RedisScriptConfig
+	script  *rueidis.Lua
+	window  string
+	backend backendCounter
+
+	hotReads atomic.Int64
+	hotMu    sync.Mutex
+	hotKeys  map[string]struct{}
+	hottest  string
+	maxCount int64
+}
+
+func NewRedisScriptStrategy(cfg RedisScriptConfig) benchmark.CachingStrategy {
+	if cfg.Addr == "" {
+		cfg.Addr = DefaultAddr
+	}
+	if cfg.HotThreshold <= 0 {
+		cfg.HotThreshold = defaultHotThreshold
+	}
+	if cfg.HotWindow <= 0 {
+		cfg.HotWindow = defaultHotWindow
+	}
+	return &RedisScriptStrategy{
+		cfg:     cfg,
+		script:  rueidis.NewLuaScript(readThroughScript),
+		window:  strconv.FormatInt(cfg.HotWindow.Milliseconds(), 10),
+		hotKeys: make(map[string]struct{}),
+	}
+}
+
+func (s *RedisScriptStrategy) Name() string {
+	if s.cfg.UseFunction {
+		return "Redis Function Read-Through (Server-Side Hot-Key Counters)"
+	}
+	return "Redis Lua Read-Through (Server-Side Hot-Key Counters)"
+}
+
+func (s *RedisScriptStrategy) Init(ctx context.Context) error {
+	opt := s.cfg.Pool.option(s.cfg.Addr)
+	opt.DisableCache = true
+	client, err := rueidis.NewClient(opt)
+	if err != nil {
+		return err
+	}
+	if s.cfg.UseFunction {
+		if err := client.Do(ctx, client.B().FunctionLoad().Replace().FunctionCode(readThroughLibrary).Build()).Error(); err != nil {
+			client.Close()
+			return err
+		}
+	}
+	s.client = newCountingClient(client, &s.backend)
+	return nil
+}
+
+// hotCounterKey returns the access counter of key. It starts with key, so it
+// shares the benchmark's key prefix, and its hash tag puts it in key's slot.
+func hotCounterKey(key string) string {
+	return key + ":hits{" + key + "}"
+}
+
+func (s *RedisScriptStrategy) Read(ctx context.Context, key string) (string, bool, error) {
+	keys := []string{key, hotCounterKey(key)}
+	var resp rueidis.RedisResult
+	if s.cfg.UseFunction {
+		resp = s.client.Do(ctx, s.client.B().Fcall().Function("cachebench_read").Numkeys(2).Key(keys...).Arg(s.window).Build())
+	} else {
+		resp = s.script.Exec(ctx, s.client, keys, []string{s.window})
+	}
+	reply, err := resp.ToArray()
+	if err != nil {
+		return "", false, err
+	}
+	if count, err := reply[0].AsInt64(); err == nil && count >= s.cfg.HotThreshold {
+		s.recordHot(key, count)
+	}
+	if len(reply) < 2 {
+		return "", false, benchmark.ErrNotFound
+	}
+	value, err := reply[1].ToString()
+	return value, false, err
+}
+
+func (s *RedisScriptStrategy) recordHot(key string, count int64) {
+	s.hotReads.Add(1)
+	s.hotMu.Lock()
+	defer s.hotMu.Unlock()
+	s.hotKeys[key] = struct{}{}
+	if count > s.maxCount {
+		s.hottest, s.maxCount = key, count
+	}
+}
+
+func (s *RedisScriptStrategy) Write(ctx context.Context, key, value string) error {
+	return s.client.Do(ctx, s.client.B().Set().Key(key).Value(value).Build()).Error()
+}
+
+func (s *RedisScriptStrategy) Scan(ctx context.Context, prefix string, limit int) (map[string]string, int, error) {
+	return scanGet(ctx, s.client, prefix, limit)
+}
+
+func (s *RedisScriptStrategy) ReadModifyWrite(ctx context.Context, key string, modify func(string) string) (int, error) {
+	return watchRMW(ctx, s.client, &s.backend, key, modify)
+}
+
+func (s *RedisScriptStrategy) HotKeyStats() benchmark.HotKeyStats {
+	s.hotMu.Lock()
+	defer s.hotMu.Unlock()
+	return benchmark.HotKeyStats{
+		HotReads:        s.hotReads.Load(),
+		DistinctHotKeys: int64(len(s.hotKeys)),
+		HottestKey:      s.hottest,
+		HottestCount:    s.maxCount,
+	}
+}
+
+func (s *RedisScriptStrategy) ConnectionStats(ctx context.Context) benchmark.ConnectionStats {
+	return s.cfg.Pool.stats(ctx, s.client)
+}
+
+func (s *RedisScriptStrategy) BackendStats() benchmark.BackendStats {
+	return s.backend.stats()
+}
+
+// Drain is a no-op: writes go straight to Redis.
+func (s *RedisScriptStrategy) Drain(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (s *RedisScriptStrategy) Close(ctx context.Context) error {
+	s.client.Close()
+	return nil
+}
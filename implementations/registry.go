@@ -0,0 +1,239 @@
+package implementations
+
+import (
+	"caching-benchmark/benchmark"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultAddr is the Redis address used when none is configured.
+const DefaultAddr = "127.0.0.1:6379"
+
+// Params carries the scenario-level settings a factory may use to size a
+// strategy. Tuning knobs left at zero keep the strategy's default.
+type Params struct {
+	// Addr is the Redis address. Empty selects DefaultAddr.
+	Addr string
+	// StandbyAddr is a warm-standby Redis address for strategies that
+	// support client-side failover. Empty disables failover.
+	StandbyAddr string
+	// MemoryBudgetBytes is the L1 memory budget available to the strategy.
+	MemoryBudgetBytes int64
+	// ValueSizeBytes is the size of the values used in the scenario.
+	ValueSizeBytes int
+	// KeyPrefix namespaces the benchmark's keys; strategies apply it to the
+	// keys they create themselves.
+	KeyPrefix string
+	// Structure is how values are stored in Redis: StructureString (the
+	// default when empty) or StructureHash, which only the strategies
+	// listed in hashStrategies can read.
+	Structure string
+
+	// Ristretto knobs.
+	NumCounters int64
+	BufferItems int64
+	// L1Shards splits the Ristretto or LRU L1 into this many independent caches.
+	L1Shards int
+	// Tiers of the twotier strategy, by the names listed by TierNames;
+	// empty selects ristretto, redis and pubsub.
+	LocalCache  string
+	RemoteStore string
+	Invalidator string
+	// L1 expiry and invalidation knobs for the L1+L2 strategies.
+	L1TTL               time.Duration
+	DisableInvalidation bool
+	// WritePolicy is "invalidate" (default), "through" or "behind".
+	WritePolicy   string
+	FlushInterval time.Duration
+	// NegativeTTL caches misses for absent keys; zero disables negative caching.
+	NegativeTTL time.Duration
+	// Invalidation batching for the Pub/Sub strategies; a zero interval
+	// publishes once per write.
+	InvalidationBatchInterval time.Duration
+	InvalidationBatchSize     int
+	// FlushOnResubscribe clears L1 after a lost invalidation subscription recovers.
+	FlushOnResubscribe bool
+	// PubSubChannels is the number of sharded Pub/Sub invalidation
+	// channels; zero selects the default.
+	PubSubChannels int
+	// Compression compresses values in the L1+L2 strategies: "snappy" or
+	// "zstd"; empty stores them as written.
+	Compression string
+	// Hot-key detection for the server-side script strategies: a key read at
+	// least HotKeyThreshold times within HotKeyWindow is hot.
+	HotKeyThreshold int64
+	HotKeyWindow    time.Duration
+	// Rueidis client-side caching knobs.
+	CacheSizeEachConn int
+	CacheTTL          time.Duration
+	BroadcastPrefixes []string
+	// Client pool sizing of the strategies that connect to Redis; see Pool.
+	Connections      int
+	BlockingPoolSize int
+	// MaxInFlight caps concurrent calls into the strategy. The runner
+	// enforces it, so it applies to every strategy alike; zero leaves the
+	// scenario's cap.
+	MaxInFlight int
+	// TrackL1Keys makes the L1+L2 strategies remember their L1 keys so the
+	// L1 can be snapshotted with benchmark.Warmable.
+	TrackL1Keys bool
+}
+
+// Set assigns a tuning knob by name from its string form, so knobs can be
+// driven from sweeps and other generic configuration.
+func (p *Params) Set(knob, value string) error {
+	var err error
+	switch knob {
+	case "memory_budget":
+		p.MemoryBudgetBytes, err = strconv.ParseInt(value, 10, 64)
+	case "num_counters":
+		p.NumCounters, err = strconv.ParseInt(value, 10, 64)
+	case "buffer_items":
+		p.BufferItems, err = strconv.ParseInt(value, 10, 64)
+	case "l1_shards":
+		p.L1Shards, err = strconv.Atoi(value)
+	case "l1":
+		l1, _, _ := TierNames()
+		p.LocalCache, err = oneOf(value, l1)
+	case "l2":
+		_, l2, _ := TierNames()
+		p.RemoteStore, err = oneOf(value, l2)
+	case "invalidator":
+		_, _, invalidators := TierNames()
+		p.Invalidator, err = oneOf(value, invalidators)
+	case "cache_size_each_conn":
+		p.CacheSizeEachConn, err = strconv.Atoi(value)
+	case "l1_ttl":
+		p.L1TTL, err = time.ParseDuration(value)
+	case "invalidation":
+		var enabled bool
+		enabled, err = strconv.ParseBool(value)
+		p.DisableInvalidation = !enabled
+	case "write_policy":
+		switch value {
+		case "invalidate", "through", "behind":
+			p.WritePolicy = value
+		default:
+			err = fmt.Errorf("want invalidate, through or behind")
+		}
+	case "flush_interval":
+		p.FlushInterval, err = time.ParseDuration(value)
+	case "negative_ttl":
+		p.NegativeTTL, err = time.ParseDuration(value)
+	case "inval_batch_interval":
+		p.InvalidationBatchInterval, err = time.ParseDuration(value)
+	case "inval_batch_size":
+		p.InvalidationBatchSize, err = strconv.Atoi(value)
+	case "flush_on_resubscribe":
+		p.FlushOnResubscribe, err = strconv.ParseBool(value)
+	case "pubsub_channels":
+		if p.PubSubChannels, err = strconv.Atoi(value); err == nil && p.PubSubChannels < 1 {
+			err = fmt.Errorf("want at least 1")
+		}
+	case "compression":
+		if codec, ok := compressionCodecs[value]; ok {
+			p.Compression = codec
+		} else {
+			err = fmt.Errorf("want snappy, zstd or none")
+		}
+	case "hot_threshold":
+		p.HotKeyThreshold, err = strconv.ParseInt(value, 10, 64)
+	case "hot_window":
+		p.HotKeyWindow, err = time.ParseDuration(value)
+	case "csc_ttl":
+		p.CacheTTL, err = time.ParseDuration(value)
+	case "connections":
+		if p.Connections, err = strconv.Atoi(value); err == nil && (p.Connections < 1 || p.Connections > MaxConnections) {
+			err = fmt.Errorf("want 1 to %d", MaxConnections)
+		}
+	case "blocking_pool":
+		if p.BlockingPoolSize, err = strconv.Atoi(value); err == nil && p.BlockingPoolSize < 1 {
+			err = fmt.Errorf("want at least 1")
+		}
+	case "max_in_flight":
+		if p.MaxInFlight, err = strconv.Atoi(value); err == nil && p.MaxInFlight < 0 {
+			err = fmt.Errorf("want at least 0")
+		}
+	case "bcast_prefixes":
+		// '|' separates prefixes because ',' separates knobs in strategy specs.
+		p.BroadcastPrefixes = strings.Split(value, "|")
+	default:
+		return fmt.Errorf("unknown tuning knob %q", knob)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value %q for knob %s: %w", value, knob, err)
+	}
+	return nil
+}
+
+// compressionCodecs maps each accepted compression value to the codec name
+// stored in Params; "none" disables compression.
+var compressionCodecs = map[string]string{
+	"none":   "",
+	"snappy": "snappy",
+	"zstd":   "zstd",
+}
+
+// oneOf returns value if it is one of names.
+func oneOf(value string, names []string) (string, error) {
+	for _, name := range names {
+		if value == name {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("want one of %s", strings.Join(names, ", "))
+}
+
+// Factory builds a new, uninitialized strategy instance.
+type Factory func(p Params) benchmark.CachingStrategy
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a strategy available under name. It is intended to be called
+// from init functions and panics if name is registered twice.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[name]; dup {
+		panic("implementations: Register called twice for strategy " + name)
+	}
+	registry[name] = factory
+}
+
+// New builds the strategy registered under name.
+func New(name string, p Params) (benchmark.CachingStrategy, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy %q (registered: %v)", name, Names())
+	}
+	if p.Structure == StructureHash && !hashStrategies[name] {
+		return nil, fmt.Errorf("strategy %q cannot read values stored as hashes", name)
+	}
+	// Hashes are only stored through HashStore, which the twotier
+	// composition would otherwise put in place of the CSC L2 asked for.
+	if p.Structure == StructureHash && p.RemoteStore == "csc" {
+		return nil, fmt.Errorf("strategy %q: the csc L2 cannot read values stored as hashes", name)
+	}
+	return factory(p), nil
+}
+
+// Names returns the registered strategy names in sorted order.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
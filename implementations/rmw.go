@@ -0,0 +1,55 @@
+package implementations
+
+import (
+	"caching-benchmark/benchmark"
+	"context"
+
+	"github.com/redis/rueidis"
+)
+
+// watchRMW replaces key's value with modify applied to it using optimistic
+// locking on a dedicated connection: WATCH and GET, then MULTI, SET and
+// EXEC, retrying while EXEC aborts because another client wrote key in
+// between. Dedicated connections bypass countingClient, so the round trips
+// are recorded in counter directly.
+func watchRMW(ctx context.Context, client rueidis.Client, counter *backendCounter, key string, modify func(string) string) (retries int, err error) {
+	err = client.Dedicated(func(c rueidis.DedicatedClient) error {
+		do := func(cmds ...rueidis.Completed) []rueidis.RedisResult {
+			var sent int64
+			for i := range cmds {
+				sent += argBytes(cmds[i].Commands())
+			}
+			counter.recordBatch(len(cmds), sent)
+			resps := c.DoMulti(ctx, cmds...)
+			for _, resp := range resps {
+				counter.recordReply(resp)
+			}
+			return resps
+		}
+		for {
+			resps := do(c.B().Watch().Key(key).Build(), c.B().Get().Key(key).Build())
+			old, err := resps[1].ToString()
+			if rueidis.IsRedisNil(err) {
+				old, err = "", nil
+			}
+			if err == nil {
+				err = resps[0].Error()
+			}
+			if err != nil {
+				c.Do(ctx, c.B().Unwatch().Build())
+				return err
+			}
+			resps = do(c.B().Multi().Build(), c.B().Set().Key(key).Value(modify(old)).Build(), c.B().Exec().Build())
+			err = resps[2].Error()
+			if !rueidis.IsRedisNil(err) {
+				return err
+			}
+			// EXEC aborted: key changed since WATCH.
+			if retries == benchmark.MaxRMWRetries {
+				return benchmark.ErrRMWConflict
+			}
+			retries++
+		}
+	})
+	return retries, err
+}
@@ -2,53 +2,252 @@ package implementations
 
 import (
 	"caching-benchmark/benchmark"
+	"caching-benchmark/tracing"
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/redis/rueidis"
 )
 
+func init() {
+	Register("rueidis-csc", func(p Params) benchmark.CachingStrategy {
+		cfg := RueidisCSCConfig{
+			Addr:              p.Addr,
+			CacheSizeEachConn: p.CacheSizeEachConn,
+			TTL:               p.CacheTTL,
+			Hash:              p.Structure == StructureHash,
+			Pool:              newPool(p),
+		}
+		if cfg.CacheSizeEachConn == 0 {
+			// Estimate the key count from the memory budget. This is a rough
+			// estimation and a weakness of the key-count approach.
+			cfg.CacheSizeEachConn = int(p.MemoryBudgetBytes / int64(p.ValueSizeBytes+50)) // 50 bytes overhead per key
+		}
+		return NewRueidisCSCStrategy(cfg)
+	})
+	Register("rueidis-csc-bcast", func(p Params) benchmark.CachingStrategy {
+		prefixes := p.BroadcastPrefixes
+		if len(prefixes) == 0 {
+			for _, prefix := range defaultBroadcastPrefixes {
+				prefixes = append(prefixes, p.KeyPrefix+prefix)
+			}
+		}
+		return NewRueidisCSCStrategy(RueidisCSCConfig{
+			Addr:              p.Addr,
+			CacheSizeEachConn: int(p.MemoryBudgetBytes / int64(p.ValueSizeBytes+50)),
+			TTL:               p.CacheTTL,
+			BroadcastPrefixes: prefixes,
+			Hash:              p.Structure == StructureHash,
+			Pool:              newPool(p),
+		})
+	})
+}
+
+// defaultBroadcastPrefixes cover every key the harness populates or reads.
+var defaultBroadcastPrefixes = []string{"key-", "missing-"}
+
+// defaultCSCTTL is the client-side TTL passed to DoCache when none is configured.
+const defaultCSCTTL = 10 * time.Minute
+
+// RueidisCSCConfig holds the tuning knobs of RueidisCSCStrategy.
+type RueidisCSCConfig struct {
+	// Addr is the Redis address. Empty selects DefaultAddr.
+	Addr string
+	// CacheSizeEachConn is passed to rueidis.ClientOption.
+	CacheSizeEachConn int
+	// TTL is the client-side TTL for cached reads. Zero selects defaultCSCTTL.
+	TTL time.Duration
+	// BroadcastPrefixes switches tracking to broadcast mode for these key
+	// prefixes: the server keeps no per-key state and instead invalidates every
+	// client on any write under a prefix. Empty uses default per-key tracking.
+	BroadcastPrefixes []string
+	// Hash reads and writes values stored as hashes, caching HGETALL
+	// replies instead of GETs.
+	Hash bool
+	// Pool sizes the client.
+	Pool Pool
+}
+
 type RueidisCSCStrategy struct {
-	client        rueidis.Client
-	keyCountLimit int
+	client   rueidis.Client
+	cfg      RueidisCSCConfig
+	fetchRec benchmark.FetchRecorder
+	invRec   benchmark.InvalidationRecorder
+	backend  backendCounter
+	tracking *trackingMonitor
 }
 
-func NewRueidisCSCStrategy(keyCountLimit int) benchmark.CachingStrategy {
-	return &RueidisCSCStrategy{keyCountLimit: keyCountLimit}
+func NewRueidisCSCStrategy(cfg RueidisCSCConfig) benchmark.CachingStrategy {
+	if cfg.Addr == "" {
+		cfg.Addr = DefaultAddr
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultCSCTTL
+	}
+	return &RueidisCSCStrategy{cfg: cfg}
 }
 
 func (s *RueidisCSCStrategy) Name() string {
-	return "Rueidis Client-Side Caching"
+	name := "Rueidis Client-Side Caching"
+	if len(s.cfg.BroadcastPrefixes) > 0 {
+		name = fmt.Sprintf("Rueidis Client-Side Caching (Broadcast: %s)", strings.Join(s.cfg.BroadcastPrefixes, ", "))
+	}
+	if s.cfg.Hash {
+		name += " [hash values]"
+	}
+	return name
 }
 
 func (s *RueidisCSCStrategy) Init(ctx context.Context) error {
-	var err error
-	s.client, err = rueidis.NewClient(rueidis.ClientOption{
-		InitAddress:       []string{"127.0.0.1:6379"},
-		CacheSizeEachConn: s.keyCountLimit,
-	})
-	return err
+	s.tracking = newTrackingMonitor()
+	s.tracking.recorder = s.invRec
+	opt := s.cfg.Pool.option(s.cfg.Addr)
+	opt.CacheSizeEachConn = s.cfg.CacheSizeEachConn
+	opt.OnInvalidations = s.tracking.onInvalidations
+	if len(s.cfg.BroadcastPrefixes) > 0 {
+		opt.ClientTrackingOptions = []string{"BCAST"}
+		for _, prefix := range s.cfg.BroadcastPrefixes {
+			opt.ClientTrackingOptions = append(opt.ClientTrackingOptions, "PREFIX", prefix)
+		}
+	}
+	client, err := rueidis.NewClient(opt)
+	if err != nil {
+		return err
+	}
+	s.tracking.start(client)
+	s.client = newCountingClient(client, &s.backend)
+	return nil
+}
+
+func (s *RueidisCSCStrategy) ConnectionStats(ctx context.Context) benchmark.ConnectionStats {
+	return s.cfg.Pool.stats(ctx, s.client)
+}
+
+func (s *RueidisCSCStrategy) TrackingStats() benchmark.TrackingStats {
+	return s.tracking.stats()
+}
+
+// Metrics reports the invalidations rueidis delivered and the size of its
+// per-connection cache, which it does not expose the occupancy of.
+func (s *RueidisCSCStrategy) Metrics() map[string]int64 {
+	size := s.cfg.CacheSizeEachConn
+	if size <= 0 {
+		size = rueidis.DefaultCacheBytes
+	}
+	return map[string]int64{
+		"csc.invalidations":        s.tracking.invalidations.Load(),
+		"csc.cache_bytes_per_conn": int64(size),
+	}
 }
 
 func (s *RueidisCSCStrategy) Read(ctx context.Context, key string) (value string, hit bool, err error) {
 	// Use .Cache() to create a cacheable command and pass a time.Duration for the TTL.
 	cacheableCmd := s.client.B().Get().Key(key).Cache()
-	resp := s.client.DoCache(ctx, cacheableCmd, 10*time.Minute)
+	if s.cfg.Hash {
+		cacheableCmd = s.client.B().Hgetall().Key(key).Cache()
+	}
+	// rueidis serves hits and misses in one call, so a traced read is one
+	// span marked with whether it reached Redis.
+	span := tracing.FromContext(ctx).Child("csc.get")
+	start := time.Now()
+	resp := s.client.DoCache(ctx, cacheableCmd, s.cfg.TTL)
+	span.SetAttribute("cache.hit", resp.IsCacheHit())
+	span.End(resp.NonRedisError())
+	if s.fetchRec != nil && !resp.IsCacheHit() {
+		// A miss means rueidis went to Redis for this call.
+		s.fetchRec.Record(key, start, time.Now())
+	}
 
 	err = resp.Error()
-	if err == nil {
+	if err == nil && s.cfg.Hash {
+		var fields map[string]string
+		if fields, err = resp.AsStrMap(); err == nil {
+			var found bool
+			if value, found = joinHash(fields); !found {
+				err = benchmark.ErrNotFound
+			}
+		}
+	} else if err == nil {
 		value, err = resp.ToString()
 	}
+	if rueidis.IsRedisNil(err) {
+		// rueidis caches nil replies like any other, so absent keys are
+		// negatively cached for the client-side TTL.
+		err = benchmark.ErrNotFound
+	}
 
 	// IsCacheHit() is a method on the RedisResult.
 	return value, resp.IsCacheHit(), err
 }
 
 func (s *RueidisCSCStrategy) Write(ctx context.Context, key, value string) error {
-	return s.client.Do(ctx, s.client.B().Set().Key(key).Value(value).Build()).Error()
+	// Redis itself pushes the invalidations, so a write is a single L2 span.
+	span := tracing.FromContext(ctx).Child("l2.set")
+	var err error
+	if s.cfg.Hash {
+		err = s.client.Do(ctx, hset(s.client.B(), key, value)).Error()
+	} else {
+		err = s.client.Do(ctx, s.client.B().Set().Key(key).Value(value).Build()).Error()
+	}
+	span.End(err)
+	return err
+}
+
+// Scan lists keys with SCAN, which cannot be cached, and reads their values
+// through the client-side cache.
+func (s *RueidisCSCStrategy) Scan(ctx context.Context, prefix string, limit int) (map[string]string, int, error) {
+	keys, err := scanKeys(ctx, s.client, prefix, limit)
+	if err != nil || len(keys) == 0 {
+		return nil, 0, err
+	}
+	cmds := make([]rueidis.CacheableTTL, len(keys))
+	for i, key := range keys {
+		cmds[i] = rueidis.CT(s.client.B().Get().Key(key).Cache(), s.cfg.TTL)
+	}
+	values := make(map[string]string, len(keys))
+	hits := 0
+	for i, resp := range s.client.DoMultiCache(ctx, cmds...) {
+		value, err := resp.ToString()
+		if rueidis.IsRedisNil(err) {
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		values[keys[i]] = value
+		if resp.IsCacheHit() {
+			hits++
+		}
+	}
+	return values, hits, nil
+}
+
+func (s *RueidisCSCStrategy) ReadModifyWrite(ctx context.Context, key string, modify func(string) string) (int, error) {
+	return watchRMW(ctx, s.client, &s.backend, key, modify)
+}
+
+func (s *RueidisCSCStrategy) BackendStats() benchmark.BackendStats {
+	return s.backend.stats()
+}
+
+func (s *RueidisCSCStrategy) SetFetchRecorder(rec benchmark.FetchRecorder) {
+	s.fetchRec = rec
+}
+
+func (s *RueidisCSCStrategy) SetInvalidationRecorder(rec benchmark.InvalidationRecorder) {
+	s.invRec = rec
+}
+
+// Drain is a no-op: writes go straight to Redis and invalidations are applied
+// by rueidis as they arrive.
+func (s *RueidisCSCStrategy) Drain(ctx context.Context) (int64, error) {
+	return 0, nil
 }
 
 func (s *RueidisCSCStrategy) Close(ctx context.Context) error {
+	s.tracking.stop()
 	s.client.Close()
 	return nil
 }
@@ -0,0 +1,55 @@
+package implementations
+
+import (
+	"context"
+	"strings"
+
+	"github.com/redis/rueidis"
+)
+
+// scanCount is the COUNT hint of each SCAN call.
+const scanCount = 1000
+
+// scanKeys returns up to limit keys starting with prefix. SCAN walks the
+// whole keyspace, so a scan matching fewer than limit keys costs a round
+// trip per scanCount keys in the database.
+func scanKeys(ctx context.Context, client rueidis.Client, prefix string, limit int) ([]string, error) {
+	pattern := strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`).Replace(prefix) + "*"
+	var keys []string
+	var cursor uint64
+	for {
+		entry, err := client.Do(ctx, client.B().Scan().Cursor(cursor).Match(pattern).Count(scanCount).Build()).AsScanEntry()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, entry.Elements...)
+		if len(keys) >= limit {
+			return keys[:limit], nil
+		}
+		cursor = entry.Cursor
+		if cursor == 0 {
+			return keys, nil
+		}
+	}
+}
+
+// scanGet scans with SCAN and fetches the values with one MGET, caching
+// nothing.
+func scanGet(ctx context.Context, client rueidis.Client, prefix string, limit int) (map[string]string, int, error) {
+	keys, err := scanKeys(ctx, client, prefix, limit)
+	if err != nil || len(keys) == 0 {
+		return nil, 0, err
+	}
+	values, err := client.Do(ctx, client.B().Mget().Key(keys...).Build()).ToArray()
+	if err != nil {
+		return nil, 0, err
+	}
+	found := make(map[string]string, len(keys))
+	for i, v := range values {
+		// Keys deleted since the SCAN come back nil.
+		if s, err := v.ToString(); err == nil {
+			found[keys[i]] = s
+		}
+	}
+	return found, 0, nil
+}
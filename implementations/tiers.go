@@ -0,0 +1,201 @@
+package implementations
+
+import (
+	"caching-benchmark/twolevel"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/ristretto"
+	"github.com/redis/rueidis"
+)
+
+// LocalCache builds the in-process tier of a TwoTier strategy.
+type LocalCache interface {
+	Name() string
+	NewL1(size L1Size) (twolevel.L1, error)
+}
+
+// L1Size sizes a LocalCache. Caches ignore the knobs they have no use for.
+type L1Size struct {
+	NumCounters int64
+	MaxCost     int64
+	BufferItems int64
+	// Shards splits the cache into this many instances selected by key
+	// hash, each with its share of the budget.
+	Shards int
+	// TTL expires entries after this long. Zero disables expiry.
+	TTL time.Duration
+}
+
+// RemoteStore builds the shared tier of a TwoTier strategy over a Redis
+// client.
+type RemoteStore interface {
+	Name() string
+	NewL2(client rueidis.Client) twolevel.L2
+}
+
+// Invalidator builds the transport that carries invalidations between the
+// L1s of a TwoTier strategy. pub may be shared with the L2; sub is
+// dedicated to receiving.
+type Invalidator interface {
+	Name() string
+	NewTransport(ctx context.Context, pub, sub rueidis.Client, keyPrefix string) (twolevel.Transport, error)
+}
+
+// Tiers selectable by name through the l1, l2 and invalidator knobs.
+var (
+	localCaches = map[string]LocalCache{
+		"ristretto": RistrettoCache{},
+		"lru":       LRUCache{},
+	}
+	remoteStores = map[string]RemoteStore{
+		"redis": RedisStore{},
+		"csc":   CSCStore{},
+	}
+	invalidators = map[string]Invalidator{
+		TransportPubSub:        PubSubInvalidator{},
+		TransportShardedPubSub: ShardedPubSubInvalidator{},
+		TransportStream:        StreamInvalidator{},
+		TransportKeyspace:      KeyspaceInvalidator{},
+	}
+)
+
+// RistrettoCache is the Ristretto L1.
+type RistrettoCache struct{}
+
+func (RistrettoCache) Name() string { return "Ristretto L1" }
+
+func (RistrettoCache) NewL1(size L1Size) (twolevel.L1, error) {
+	n := max(size.Shards, 1)
+	shards := make([]twolevel.L1, 0, n)
+	for range n {
+		l1, err := twolevel.NewRistrettoL1(&ristretto.Config{
+			NumCounters: max(size.NumCounters/int64(n), 1),
+			MaxCost:     max(size.MaxCost/int64(n), 1),
+			BufferItems: size.BufferItems,
+			Metrics:     true,
+		}, size.TTL)
+		if err != nil {
+			for _, shard := range shards {
+				shard.Close()
+			}
+			return nil, err
+		}
+		shards = append(shards, l1)
+	}
+	if n == 1 {
+		return shards[0], nil
+	}
+	return twolevel.NewShardedL1(shards), nil
+}
+
+// LRUCache is a map of fixed-capacity LRUs, each behind its own RWMutex, as
+// a baseline for Ristretto's lock-free buffers.
+type LRUCache struct{}
+
+// defaultLRUShards is the shard count of the LRU L1 when Shards is unset.
+const defaultLRUShards = 64
+
+func (LRUCache) Name() string { return "Sharded LRU L1" }
+
+func (LRUCache) NewL1(size L1Size) (twolevel.L1, error) {
+	return twolevel.NewShardedLRUL1(max(size.Shards, 1), size.MaxCost, size.TTL), nil
+}
+
+// RedisStore reads and writes Redis with GET and SET.
+type RedisStore struct{}
+
+func (RedisStore) Name() string { return "Redis" }
+
+func (RedisStore) NewL2(client rueidis.Client) twolevel.L2 {
+	return twolevel.NewRedisL2(client)
+}
+
+// CSCStore reads Redis through rueidis client-side caching, so L1 misses
+// for keys still tracked by the server are served without a round trip.
+type CSCStore struct {
+	// TTL is the client-side TTL. Zero selects defaultCSCTTL.
+	TTL time.Duration
+}
+
+func (CSCStore) Name() string { return "Redis CSC" }
+
+func (s CSCStore) NewL2(client rueidis.Client) twolevel.L2 {
+	ttl := s.TTL
+	if ttl <= 0 {
+		ttl = defaultCSCTTL
+	}
+	return twolevel.NewCachedRedisL2(client, ttl)
+}
+
+// PubSubInvalidator publishes invalidations on InvalidationChannel; they are
+// fire-and-forget.
+type PubSubInvalidator struct{}
+
+func (PubSubInvalidator) Name() string { return "Redis Pub/Sub" }
+
+func (PubSubInvalidator) NewTransport(ctx context.Context, pub, sub rueidis.Client, keyPrefix string) (twolevel.Transport, error) {
+	return twolevel.NewPubSubTransport(pub, sub, InvalidationChannel), nil
+}
+
+// ShardedPubSubInvalidator publishes invalidations with SPUBLISH on
+// Channels sharded channels derived from InvalidationChannel, picked by
+// each key's hash slot. It needs Redis 7.
+type ShardedPubSubInvalidator struct {
+	// Channels is the number of channels. Zero selects defaultPubSubChannels.
+	Channels int
+}
+
+// defaultPubSubChannels spreads sharded invalidation channels over the
+// shards of clusters of up to that many primaries.
+const defaultPubSubChannels = 16
+
+func (s ShardedPubSubInvalidator) channels() int {
+	if s.Channels <= 0 {
+		return defaultPubSubChannels
+	}
+	return s.Channels
+}
+
+func (s ShardedPubSubInvalidator) Name() string {
+	return fmt.Sprintf("Redis Sharded Pub/Sub (%d channels)", s.channels())
+}
+
+func (s ShardedPubSubInvalidator) NewTransport(ctx context.Context, pub, sub rueidis.Client, keyPrefix string) (twolevel.Transport, error) {
+	// An empty message is ignored by subscribers; it only proves the
+	// server knows SPUBLISH.
+	if err := pub.Do(ctx, pub.B().Spublish().Channel(InvalidationChannel).Message("").Build()).Error(); err != nil {
+		return nil, fmt.Errorf("sharded Pub/Sub needs Redis 7: %w", err)
+	}
+	return twolevel.NewShardedPubSubTransport(pub, sub, InvalidationChannel, s.channels()), nil
+}
+
+// StreamInvalidator appends invalidations to a stream read through a
+// consumer group of its own, replaying those missed during a reconnect.
+type StreamInvalidator struct{}
+
+func (StreamInvalidator) Name() string { return "Redis Streams" }
+
+func (StreamInvalidator) NewTransport(ctx context.Context, pub, sub rueidis.Client, keyPrefix string) (twolevel.Transport, error) {
+	group := "l1-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	transport, err := twolevel.NewStreamTransport(ctx, pub, sub, keyPrefix+InvalidationStream, group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invalidation stream group: %w", err)
+	}
+	return transport, nil
+}
+
+// KeyspaceInvalidator relies on the server's keyspace notifications.
+type KeyspaceInvalidator struct{}
+
+func (KeyspaceInvalidator) Name() string { return "Keyspace Notifications" }
+
+func (KeyspaceInvalidator) NewTransport(ctx context.Context, pub, sub rueidis.Client, keyPrefix string) (twolevel.Transport, error) {
+	transport, err := twolevel.NewKeyspaceTransport(ctx, pub, sub, keyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable keyspace notifications: %w", err)
+	}
+	return transport, nil
+}
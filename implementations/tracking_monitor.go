@@ -0,0 +1,110 @@
+package implementations
+
+import (
+	"caching-benchmark/benchmark"
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+const trackingSampleInterval = time.Second
+
+// trackingMonitor samples the server-side CLIENT TRACKING state while a
+// client-side caching strategy runs and counts the invalidations it receives.
+type trackingMonitor struct {
+	client        rueidis.Client
+	peakKeys      atomic.Int64
+	peakClients   atomic.Int64
+	maxKeys       int64
+	invalidations atomic.Int64
+	// recorder, when set, also receives every invalidation.
+	recorder benchmark.InvalidationRecorder
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// newTrackingMonitor creates a monitor; call onInvalidations from the client's
+// OnInvalidations hook and start once the client exists. client must not be
+// instrumented, so that monitoring traffic is not counted as strategy load.
+func newTrackingMonitor() *trackingMonitor {
+	return &trackingMonitor{done: make(chan struct{})}
+}
+
+func (m *trackingMonitor) onInvalidations(msgs []rueidis.RedisMessage) {
+	if msgs == nil {
+		// A nil slice is a full flush of the client-side cache.
+		m.invalidations.Add(1)
+		if m.recorder != nil {
+			m.recorder.RecordInvalidations(1)
+		}
+		return
+	}
+	m.invalidations.Add(int64(len(msgs)))
+	if m.recorder != nil {
+		m.recorder.RecordInvalidations(len(msgs))
+	}
+}
+
+func (m *trackingMonitor) start(client rueidis.Client) {
+	m.client = client
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	if cfg, err := client.Do(ctx, client.B().ConfigGet().Parameter("tracking-table-max-keys").Build()).AsStrMap(); err == nil {
+		m.maxKeys, _ = strconv.ParseInt(cfg["tracking-table-max-keys"], 10, 64)
+	}
+
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(trackingSampleInterval)
+		defer ticker.Stop()
+		for {
+			m.sample(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (m *trackingMonitor) sample(ctx context.Context) {
+	if keys, err := infoInt(ctx, m.client, "tracking_total_keys", "stats"); err == nil {
+		storeMax(&m.peakKeys, keys)
+	}
+	if clients, err := infoInt(ctx, m.client, "tracking_clients", "clients"); err == nil {
+		storeMax(&m.peakClients, clients)
+	}
+}
+
+// stop takes a final sample and stops the sampling goroutine.
+func (m *trackingMonitor) stop() {
+	if m.cancel == nil {
+		return
+	}
+	m.sample(context.Background())
+	m.cancel()
+	<-m.done
+}
+
+func (m *trackingMonitor) stats() benchmark.TrackingStats {
+	return benchmark.TrackingStats{
+		PeakTrackedKeys:       m.peakKeys.Load(),
+		PeakTrackingClients:   m.peakClients.Load(),
+		TrackingTableMaxKeys:  m.maxKeys,
+		InvalidationsReceived: m.invalidations.Load(),
+	}
+}
+
+func storeMax(v *atomic.Int64, n int64) {
+	for {
+		cur := v.Load()
+		if n <= cur || v.CompareAndSwap(cur, n) {
+			return
+		}
+	}
+}
@@ -0,0 +1,472 @@
+package implementations
+
+import (
+	"caching-benchmark/benchmark"
+	"caching-benchmark/twolevel"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+const (
+	InvalidationChannel = "cache-invalidation"
+	InvalidationStream  = "cache-invalidation-stream"
+)
+
+// Invalidation transports, the names of the built-in Invalidators.
+const (
+	TransportPubSub        = "pubsub"
+	TransportShardedPubSub = "spubsub"
+	TransportStream        = "stream"
+	TransportKeyspace      = "keyspace"
+)
+
+func init() {
+	Register("ristretto-pubsub", func(p Params) benchmark.CachingStrategy {
+		return NewTwoTierStrategy(twoTierConfig(p, RistrettoCache{}, PubSubInvalidator{}))
+	})
+	Register("ristretto-spubsub", func(p Params) benchmark.CachingStrategy {
+		return NewTwoTierStrategy(twoTierConfig(p, RistrettoCache{}, ShardedPubSubInvalidator{}))
+	})
+	Register("ristretto-stream", func(p Params) benchmark.CachingStrategy {
+		return NewTwoTierStrategy(twoTierConfig(p, RistrettoCache{}, StreamInvalidator{}))
+	})
+	Register("ristretto-keyspace", func(p Params) benchmark.CachingStrategy {
+		return NewTwoTierStrategy(twoTierConfig(p, RistrettoCache{}, KeyspaceInvalidator{}))
+	})
+	Register("lru-pubsub", func(p Params) benchmark.CachingStrategy {
+		return NewTwoTierStrategy(twoTierConfig(p, LRUCache{}, PubSubInvalidator{}))
+	})
+	// twotier assembles any combination of tiers from the l1, l2 and
+	// invalidator knobs; their zero values give ristretto-pubsub.
+	Register("twotier", func(p Params) benchmark.CachingStrategy {
+		l1 := localCaches[p.LocalCache]
+		if l1 == nil {
+			l1 = RistrettoCache{}
+		}
+		inv := invalidators[p.Invalidator]
+		if inv == nil {
+			inv = PubSubInvalidator{}
+		}
+		cfg := twoTierConfig(p, l1, inv)
+		if _, ok := remoteStores[p.RemoteStore].(CSCStore); ok {
+			cfg.L2 = CSCStore{TTL: p.CacheTTL}
+		}
+		return NewTwoTierStrategy(cfg)
+	})
+}
+
+// twoTierConfig maps Params onto a TwoTierConfig with the given L1 and
+// invalidator over a plain Redis L2. Disabling invalidation drops inv.
+func twoTierConfig(p Params, l1 LocalCache, inv Invalidator) TwoTierConfig {
+	if p.DisableInvalidation {
+		inv = nil
+	}
+	if s, ok := inv.(ShardedPubSubInvalidator); ok && s.Channels == 0 {
+		s.Channels = p.PubSubChannels
+		inv = s
+	}
+	cfg := TwoTierConfig{
+		Addr:        p.Addr,
+		L1:          l1,
+		L2:          RedisStore{},
+		Invalidator: inv,
+		NumCounters: p.NumCounters,
+		MaxCost:     p.MemoryBudgetBytes,
+		BufferItems: p.BufferItems,
+		Shards:      p.L1Shards,
+		TTL:         p.L1TTL,
+		StandbyAddr: p.StandbyAddr,
+		KeyPrefix:   p.KeyPrefix,
+		Compression: p.Compression,
+		Pool:        newPool(p),
+		TrackL1Keys: p.TrackL1Keys,
+		Write: twolevel.Options{
+			WritePolicy:        writePolicies[p.WritePolicy],
+			FlushInterval:      p.FlushInterval,
+			NegativeTTL:        p.NegativeTTL,
+			BatchInterval:      p.InvalidationBatchInterval,
+			BatchSize:          p.InvalidationBatchSize,
+			FlushOnResubscribe: p.FlushOnResubscribe,
+		},
+	}
+	// Strategies reading hashes do so through HashStore, named in the
+	// strategy's name as its L2.
+	if p.Structure == StructureHash {
+		cfg.L2 = HashStore{}
+	}
+	if _, ok := l1.(LRUCache); ok && cfg.Shards == 0 {
+		cfg.Shards = defaultLRUShards
+	}
+	return cfg
+}
+
+// TierNames lists the names the l1, l2 and invalidator knobs accept.
+func TierNames() (l1, l2, invalidator []string) {
+	for name := range localCaches {
+		l1 = append(l1, name)
+	}
+	for name := range remoteStores {
+		l2 = append(l2, name)
+	}
+	for name := range invalidators {
+		invalidator = append(invalidator, name)
+	}
+	sort.Strings(l1)
+	sort.Strings(l2)
+	sort.Strings(invalidator)
+	return l1, l2, invalidator
+}
+
+// TwoTierConfig holds the tiers of a TwoTierStrategy and their tuning
+// knobs. Zero fields select the defaults.
+type TwoTierConfig struct {
+	// Addr is the Redis address. Empty selects DefaultAddr.
+	Addr string
+	// L1 and L2 are the in-process and shared tiers; nil selects
+	// RistrettoCache and RedisStore.
+	L1 LocalCache
+	L2 RemoteStore
+	// Invalidator carries invalidations between L1s. Nil leaves freshness
+	// to TTL alone; with both set, the strategy is the common TTL+Pub/Sub
+	// hybrid.
+	Invalidator Invalidator
+	// L1 sizing, passed to L1 as an L1Size.
+	NumCounters int64
+	MaxCost     int64
+	BufferItems int64
+	// Shards splits L1 into this many instances selected by key hash, each
+	// with its share of NumCounters and MaxCost. Zero or one keeps a single
+	// instance.
+	Shards int
+	// TTL expires L1 entries after this long. Zero disables expiry.
+	TTL time.Duration
+	// StandbyAddr, when set, enables client-side failover: the strategy
+	// health-checks Addr and switches every connection to StandbyAddr once
+	// the primary stops responding.
+	StandbyAddr string
+	// KeyPrefix namespaces the invalidation stream key.
+	KeyPrefix string
+	// Compression names the compressor applied to cached values ("snappy"
+	// or "zstd"); empty stores them uncompressed.
+	Compression string
+	// Write selects the write policy applied by the two-tier cache.
+	Write twolevel.Options
+	// Pool sizes the Redis clients.
+	Pool Pool
+	// TrackL1Keys remembers the keys set in L1, at some cost to every L1
+	// fill, so its contents can be dumped as a snapshot.
+	TrackL1Keys bool
+}
+
+// writePolicies maps Params.WritePolicy names to policies; "" is the default.
+var writePolicies = map[string]twolevel.WritePolicy{
+	"":           twolevel.WriteInvalidate,
+	"invalidate": twolevel.WriteInvalidate,
+	"through":    twolevel.WriteThrough,
+	"behind":     twolevel.WriteBehind,
+}
+
+// TwoTierStrategy serves reads from an in-process L1 in front of a shared
+// L2, keeping L1s fresh through an Invalidator.
+type TwoTierStrategy struct {
+	cache    *twolevel.Cache
+	client   rueidis.Client
+	cfg      TwoTierConfig
+	fetchRec benchmark.FetchRecorder
+	invRec   benchmark.InvalidationRecorder
+	backend  backendCounter
+	// failover is the data-path client when StandbyAddr is set.
+	failover *failoverClient
+}
+
+// TwoTier composes a strategy from one implementation of each tier with
+// default sizing; invalidation may be nil. Use NewTwoTierStrategy to tune it.
+func TwoTier(l1 LocalCache, l2 RemoteStore, invalidation Invalidator) benchmark.CachingStrategy {
+	return NewTwoTierStrategy(TwoTierConfig{L1: l1, L2: l2, Invalidator: invalidation})
+}
+
+func NewTwoTierStrategy(cfg TwoTierConfig) benchmark.CachingStrategy {
+	if cfg.Addr == "" {
+		cfg.Addr = DefaultAddr
+	}
+	if cfg.L1 == nil {
+		cfg.L1 = RistrettoCache{}
+	}
+	if cfg.L2 == nil {
+		cfg.L2 = RedisStore{}
+	}
+	if cfg.NumCounters == 0 {
+		cfg.NumCounters = 1e6
+	}
+	if cfg.MaxCost == 0 {
+		cfg.MaxCost = 1 << 30
+	}
+	if cfg.BufferItems == 0 {
+		cfg.BufferItems = 64
+	}
+	return &TwoTierStrategy{cfg: cfg}
+}
+
+func (s *TwoTierStrategy) Name() string {
+	var name string
+	switch {
+	case s.cfg.TTL > 0 && s.cfg.Invalidator == nil:
+		name = fmt.Sprintf("%s (TTL %v, no invalidation)", s.cfg.L1.Name(), s.cfg.TTL)
+	case s.cfg.TTL > 0:
+		name = fmt.Sprintf("%s (TTL %v) + %s", s.cfg.L1.Name(), s.cfg.TTL, s.cfg.Invalidator.Name())
+	case s.cfg.Invalidator == nil:
+		name = s.cfg.L1.Name() + " (no invalidation)"
+	default:
+		name = s.cfg.L1.Name() + " + " + s.cfg.Invalidator.Name()
+	}
+	if _, plain := s.cfg.L2.(RedisStore); !plain {
+		name += " [" + s.cfg.L2.Name() + " L2]"
+	}
+	if s.cfg.Write.WritePolicy != twolevel.WriteInvalidate {
+		name += " [" + s.cfg.Write.WritePolicy.String() + "]"
+	}
+	if s.cfg.Write.NegativeTTL > 0 {
+		name += fmt.Sprintf(" [negative TTL %v]", s.cfg.Write.NegativeTTL)
+	}
+	if s.cfg.Shards > 1 {
+		name += fmt.Sprintf(" [%d L1 shards]", s.cfg.Shards)
+	}
+	if s.cfg.Compression != "" {
+		name += " [" + s.cfg.Compression + "]"
+	}
+	if s.cfg.StandbyAddr != "" {
+		name += " [warm standby]"
+	}
+	if s.cfg.Invalidator != nil && s.cfg.Write.FlushOnResubscribe {
+		name += " [flush on resubscribe]"
+	}
+	if s.cfg.Invalidator != nil && s.cfg.Write.BatchInterval > 0 {
+		name += fmt.Sprintf(" [batched %v]", s.cfg.Write.BatchInterval)
+	}
+	return name
+}
+
+func (s *TwoTierStrategy) Init(ctx context.Context) error {
+	// 1. Initialize the L1
+	l1, err := s.cfg.L1.NewL1(L1Size{
+		NumCounters: s.cfg.NumCounters,
+		MaxCost:     s.cfg.MaxCost,
+		BufferItems: s.cfg.BufferItems,
+		Shards:      s.cfg.Shards,
+		TTL:         s.cfg.TTL,
+	})
+	if err != nil {
+		return err
+	}
+	if s.cfg.TrackL1Keys {
+		l1 = twolevel.TrackKeys(l1)
+	}
+
+	// 2. Initialize Redis client
+	redisClient, err := s.newClient(true)
+	if err != nil {
+		l1.Close()
+		return err
+	}
+	redisClient = newCountingClient(redisClient, &s.backend)
+	s.client = redisClient
+
+	l2 := s.cfg.L2.NewL2(redisClient)
+	if s.fetchRec != nil {
+		l2 = twolevel.NewInstrumentedL2(l2, s.fetchRec)
+	}
+
+	// 3. Initialize the invalidation transport, unless freshness is left to TTL alone
+	var transport twolevel.Transport
+	if s.cfg.Invalidator != nil {
+		subClient, err := s.newClient(false)
+		if err != nil {
+			redisClient.Close()
+			l1.Close()
+			return err
+		}
+		subClient = newCountingClient(subClient, &s.backend)
+		transport, err = s.cfg.Invalidator.NewTransport(ctx, redisClient, subClient, s.cfg.KeyPrefix)
+		if err != nil {
+			subClient.Close()
+			redisClient.Close()
+			l1.Close()
+			return err
+		}
+	}
+
+	// 4. Assemble the two-tier cache, which starts the invalidation listener
+	opts := s.cfg.Write
+	if s.cfg.Compression != "" {
+		if opts.Compressor, err = twolevel.NewCompressor(s.cfg.Compression); err != nil {
+			if transport != nil {
+				transport.Close()
+			}
+			redisClient.Close()
+			l1.Close()
+			return err
+		}
+	}
+	if s.invRec != nil {
+		opts.OnInvalidate = s.invRec.RecordInvalidations
+	}
+	s.cache = twolevel.New(l1, l2, transport, opts)
+	return nil
+}
+
+// newClient connects to Addr or, with StandbyAddr set, to both endpoints
+// behind a failoverClient. The data-path client is kept for FailoverStats.
+func (s *TwoTierStrategy) newClient(dataPath bool) (rueidis.Client, error) {
+	primary, err := rueidis.NewClient(s.cfg.Pool.option(s.cfg.Addr))
+	if err != nil || s.cfg.StandbyAddr == "" {
+		return primary, err
+	}
+	standby, err := rueidis.NewClient(s.cfg.Pool.option(s.cfg.StandbyAddr))
+	if err != nil {
+		primary.Close()
+		return nil, err
+	}
+	fc := newFailoverClient(primary, standby)
+	if dataPath {
+		s.failover = fc
+	}
+	return fc, nil
+}
+
+// DumpL1 returns the keys held in L1; it is empty unless TrackL1Keys is set.
+func (s *TwoTierStrategy) DumpL1(ctx context.Context) ([]string, error) {
+	return s.cache.L1Keys(), nil
+}
+
+// LoadL1 fills L1 with keys read through from L2.
+func (s *TwoTierStrategy) LoadL1(ctx context.Context, keys []string) (int, error) {
+	return s.cache.Warm(ctx, keys)
+}
+
+// ResizeL1 changes the L1 budget in place; cold empties the L1 first.
+func (s *TwoTierStrategy) ResizeL1(budget int64, cold bool) error {
+	return s.cache.ResizeL1(budget, cold)
+}
+
+// ConnectionStats counts the data-path and invalidation clients'
+// connections to the primary; those to a standby are not counted.
+func (s *TwoTierStrategy) ConnectionStats(ctx context.Context) benchmark.ConnectionStats {
+	return s.cfg.Pool.stats(ctx, s.client)
+}
+
+func (s *TwoTierStrategy) FailoverStats() benchmark.FailoverStats {
+	if s.failover == nil {
+		return benchmark.FailoverStats{}
+	}
+	return s.failover.stats()
+}
+
+func (s *TwoTierStrategy) ListenerStats() benchmark.ListenerStats {
+	stats := s.cache.ListenerStats()
+	return benchmark.ListenerStats{
+		Disconnects: stats.Disconnects,
+		DownTime:    stats.DownTime,
+		MaxDownTime: stats.MaxDownTime,
+		L1Flushes:   stats.L1Flushes,
+	}
+}
+
+func (s *TwoTierStrategy) CompressionStats() benchmark.CompressionStats {
+	stats := s.cache.CompressionStats()
+	return benchmark.CompressionStats{
+		Algorithm:       stats.Algorithm,
+		RawBytes:        stats.RawBytes,
+		CompressedBytes: stats.CompressedBytes,
+		Compressions:    stats.Compressions,
+		Decompressions:  stats.Decompressions,
+		CompressTime:    stats.CompressTime,
+		DecompressTime:  stats.DecompressTime,
+	}
+}
+
+func (s *TwoTierStrategy) Metrics() map[string]int64 {
+	return s.cache.Metrics()
+}
+
+func (s *TwoTierStrategy) BackendStats() benchmark.BackendStats {
+	return s.backend.stats()
+}
+
+func (s *TwoTierStrategy) SetFetchRecorder(rec benchmark.FetchRecorder) {
+	s.fetchRec = rec
+}
+
+func (s *TwoTierStrategy) SetInvalidationRecorder(rec benchmark.InvalidationRecorder) {
+	s.invRec = rec
+}
+
+func (s *TwoTierStrategy) Read(ctx context.Context, key string) (value string, hit bool, err error) {
+	value, hit, err = s.cache.Get(ctx, key)
+	if errors.Is(err, twolevel.ErrNotFound) {
+		err = benchmark.ErrNotFound
+	}
+	return value, hit, err
+}
+
+func (s *TwoTierStrategy) Write(ctx context.Context, key, value string) error {
+	return s.cache.Set(ctx, key, value)
+}
+
+// Scan lists keys in L2 with SCAN and reads each value through the two
+// tiers, so values already in L1 cost no round trip.
+func (s *TwoTierStrategy) Scan(ctx context.Context, prefix string, limit int) (map[string]string, int, error) {
+	keys, err := scanKeys(ctx, s.client, prefix, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	values := make(map[string]string, len(keys))
+	hits := 0
+	for _, key := range keys {
+		value, hit, err := s.cache.Get(ctx, key)
+		if errors.Is(err, twolevel.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		values[key] = value
+		if hit {
+			hits++
+		}
+	}
+	return values, hits, nil
+}
+
+// ReadModifyWrite reads through the two tiers and writes the result back
+// under the write policy. It is not atomic: the read may be served by a
+// stale L1 and concurrent updates can overwrite each other, so it never
+// retries, and lost updates go undetected.
+func (s *TwoTierStrategy) ReadModifyWrite(ctx context.Context, key string, modify func(string) string) (int, error) {
+	old, _, err := s.cache.Get(ctx, key)
+	if errors.Is(err, twolevel.ErrNotFound) {
+		old, err = "", nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 0, s.cache.Set(ctx, key, modify(old))
+}
+
+// InvalidateAll clears every instance's L1 via a flush-all broadcast.
+func (s *TwoTierStrategy) InvalidateAll(ctx context.Context) error {
+	return s.cache.InvalidateAll(ctx)
+}
+
+func (s *TwoTierStrategy) Drain(ctx context.Context) (int64, error) {
+	return s.cache.Drain(ctx)
+}
+
+func (s *TwoTierStrategy) Close(ctx context.Context) error {
+	s.cache.Close()
+	return nil
+}
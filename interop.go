@@ -0,0 +1,49 @@
+package main
+
+import (
+	"caching-benchmark/benchmark"
+	"caching-benchmark/workload"
+	"context"
+	"log"
+	"sync"
+)
+
+// runInterop runs every strategy concurrently against the same keyspace, as
+// happens mid-migration when old and new clients share one Redis. Operations
+// are dealt round-robin and workers split evenly, and a shared staleness
+// tracker counts reads that miss another client's completed write.
+func runInterop(ctx context.Context, cfg Config, w []workload.Operation, seed int64, strategies []namedStrategy) []benchmark.Result {
+	log.Printf("\n--- Running %d strategies concurrently (interop) ---", len(strategies))
+
+	shares := make([][]workload.Operation, len(strategies))
+	for i, op := range w {
+		shares[i%len(strategies)] = append(shares[i%len(strategies)], op)
+	}
+
+	tracker := benchmark.NewStalenessTracker()
+	results := make([]benchmark.Result, len(strategies))
+	var wg sync.WaitGroup
+	for i, ns := range strategies {
+		opts := runnerOptions(cfg, ns, seed+int64(i)*int64(cfg.Concurrency))
+		opts.Concurrency = max(1, cfg.Concurrency/len(strategies))
+		opts.Staleness = tracker
+		if i > 0 {
+			// Profiles cover the whole process, so the first runner's
+			// include every concurrent runner.
+			opts.ProfileDir = ""
+		}
+
+		wg.Add(1)
+		go func(i int, ns namedStrategy, opts benchmark.Options) {
+			defer wg.Done()
+			result, err := benchmark.NewRunner(ns.strategy, shares[i], opts).Run(ctx)
+			if err != nil {
+				log.Printf("Error running interop strategy %s: %v", ns.strategy.Name(), err)
+			}
+			result.StrategyName += " (interop)"
+			results[i] = result
+		}(i, ns, opts)
+	}
+	wg.Wait()
+	return results
+}
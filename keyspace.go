@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"caching-benchmark/implementations"
+	"caching-benchmark/workload"
+
+	"github.com/redis/rueidis"
+)
+
+// keyDeriver returns the deriver from logical workload keys to the keys
+// stored in Redis: the scenario's key derivation or template, if any, under
+// KeyPrefix. It returns nil when keys are stored as generated.
+func keyDeriver(cfg Config) (workload.KeyDeriver, error) {
+	var d workload.KeyDeriver
+	templated := cfg.KeyTemplate != "" || cfg.KeyBytes > 0
+	switch {
+	case cfg.KeyDerivation != "" && templated:
+		return nil, fmt.Errorf("key derivation %s cannot be combined with a key template or key size", cfg.KeyDerivation)
+	case cfg.KeyDerivation != "":
+		var err error
+		if d, err = workload.NewKeyDeriver(cfg.KeyDerivation, cfg.KeyParamBytes); err != nil {
+			return nil, err
+		}
+	case templated:
+		var err error
+		if d, err = workload.NewKeyTemplate(keyTemplate(cfg), cfg.KeyBytes, cfg.NumKeys); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.KeyPrefix != "" {
+		d = workload.WithPrefix(d, cfg.KeyPrefix)
+	}
+	return d, nil
+}
+
+// keyTemplate returns the scenario's key template, defaulting to the
+// generator's own naming.
+func keyTemplate(cfg Config) string {
+	if cfg.KeyTemplate == "" {
+		return workload.DefaultKeyTemplate
+	}
+	return cfg.KeyTemplate
+}
+
+// clearBenchmarkKeys removes the keys a previous run left behind: every key
+// under prefix when noFlush is set, or the whole datastore otherwise.
+func clearBenchmarkKeys(ctx context.Context, client rueidis.Client, prefix string, noFlush bool) error {
+	if !noFlush {
+		if err := client.Do(ctx, client.B().Flushall().Build()).Error(); err != nil {
+			return fmt.Errorf("failed to flush datastore: %w", err)
+		}
+		return nil
+	}
+	n, err := unlinkPrefix(ctx, client, prefix)
+	if err != nil {
+		return fmt.Errorf("failed to clean up keys under %q: %w", prefix, err)
+	}
+	if n > 0 {
+		log.Printf("Removed %d keys under %q", n, prefix)
+	}
+	return nil
+}
+
+// unlinkPrefix deletes every key matching prefix* with SCAN and UNLINK and
+// returns how many were deleted.
+func unlinkPrefix(ctx context.Context, client rueidis.Client, prefix string) (int64, error) {
+	pattern := escapeGlob(prefix) + "*"
+	var deleted int64
+	var cursor uint64
+	for {
+		entry, err := client.Do(ctx, client.B().Scan().Cursor(cursor).Match(pattern).Count(1000).Build()).AsScanEntry()
+		if err != nil {
+			return deleted, err
+		}
+		if len(entry.Elements) > 0 {
+			n, err := client.Do(ctx, client.B().Unlink().Key(entry.Elements...).Build()).AsInt64()
+			if err != nil {
+				return deleted, err
+			}
+			deleted += n
+		}
+		cursor = entry.Cursor
+		if cursor == 0 {
+			return deleted, nil
+		}
+	}
+}
+
+// cleanupKeyPrefix deletes the keys left under prefix at addr after the
+// campaign, including when it was interrupted.
+func cleanupKeyPrefix(addr, prefix string) {
+	client, err := rueidis.NewClient(rueidis.ClientOption{InitAddress: []string{addr}, DisableCache: true})
+	if err != nil {
+		log.Printf("Failed to clean up keys under %q: %v", prefix, err)
+		return
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	n, err := unlinkPrefix(ctx, client, prefix)
+	if err != nil {
+		log.Printf("Failed to clean up keys under %q: %v", prefix, err)
+		return
+	}
+	log.Printf("Cleaned up %d keys under %q at %s", n, prefix, addr)
+}
+
+// escapeGlob escapes the characters SCAN MATCH treats as glob syntax.
+func escapeGlob(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`).Replace(s)
+}
+
+// confirmFlush guards FLUSHALL against a server that is not dedicated to the
+// benchmark: if the datastore at addr already holds keys, the user must
+// confirm on the terminal unless assumeYes is set. Without a terminal the
+// flush is refused.
+func confirmFlush(ctx context.Context, addr string, assumeYes bool) error {
+	if assumeYes {
+		return nil
+	}
+	if addr == "" {
+		addr = implementations.DefaultAddr
+	}
+	client, err := rueidis.NewClient(rueidis.ClientOption{InitAddress: []string{addr}, DisableCache: true})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	size, err := client.Do(ctx, client.B().Dbsize().Build()).AsInt64()
+	if err != nil {
+		return err
+	}
+	if size == 0 {
+		return nil
+	}
+
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return fmt.Errorf("redis at %s holds %d keys that FLUSHALL would delete; use -no-flush with -key-prefix on shared servers, or -yes to flush anyway", addr, size)
+	}
+	fmt.Fprintf(os.Stderr, "Redis at %s holds %d keys. Every run starts with FLUSHALL, deleting all of them.\nUse -no-flush with -key-prefix to touch only benchmark keys. Type \"flush\" to continue: ", addr, size)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if strings.TrimSpace(answer) != "flush" {
+		return fmt.Errorf("flush of %s not confirmed", addr)
+	}
+	return nil
+}
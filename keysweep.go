@@ -0,0 +1,155 @@
+package main
+
+import (
+	"caching-benchmark/benchmark"
+	"caching-benchmark/workload"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+)
+
+// workingSetPoint is one run of a working-set sweep.
+type workingSetPoint struct {
+	numKeys int
+	// workingSetBytes is the total size of the values of every key.
+	workingSetBytes int64
+	hitRate         float64
+	backendPer1k    float64
+	opsPerSec       float64
+}
+
+// workingSetCurve is the hit-rate-vs-working-set curve of one strategy.
+type workingSetCurve struct {
+	strategy string
+	points   []workingSetPoint
+}
+
+// runKeySweep runs every strategy once per cfg.KeyCounts, each time on a
+// workload regenerated over that many keys.
+func runKeySweep(ctx context.Context, cfg Config, seed int64) ([]benchmark.Result, error) {
+	var results []benchmark.Result
+	var curves []workingSetCurve
+	defer func() {
+		printWorkingSetCurves(curves, l1Budget(cfg))
+		if cfg.CurveDir != "" && len(curves) > 0 {
+			if err := writeWorkingSetCurves(cfg.CurveDir, cfg.Name, curves, l1Budget(cfg)); err != nil {
+				log.Printf("Failed to write working-set curves: %v", err)
+			}
+		}
+	}()
+
+	workloads := make(map[int][]workload.Operation, len(cfg.KeyCounts))
+	for _, spec := range strategySpecs(cfg) {
+		var curve workingSetCurve
+		for _, numKeys := range cfg.KeyCounts {
+			levelCfg := cfg
+			levelCfg.NumKeys = numKeys
+			w, ok := workloads[numKeys]
+			if !ok {
+				w = generateWorkload(levelCfg, seed)
+				workloads[numKeys] = w
+			}
+
+			// Every run gets a fresh strategy: strategies are not reusable after Close.
+			ns, err := buildStrategy(levelCfg, spec)
+			if err != nil {
+				return results, err
+			}
+			workingSet := int64(numKeys) * int64(cfg.ValueSizeBytes)
+			log.Printf("\n--- Running Strategy: %s over %d keys (working set %.1f%% of L1 budget) ---",
+				ns.strategy.Name(), numKeys, float64(workingSet)/float64(l1Budget(cfg))*100)
+			prep, err := prepareData(ctx, levelCfg, seed)
+			if err != nil {
+				return results, fmt.Errorf("failed to prepare data for strategy %s: %w", ns.strategy.Name(), err)
+			}
+			result, err := benchmark.NewRunner(ns.strategy, w, runnerOptions(levelCfg, ns, seed)).Run(ctx)
+			if err != nil {
+				log.Printf("Error running strategy %s over %d keys: %v", ns.strategy.Name(), numKeys, err)
+				continue
+			}
+			result.Lifecycle.DataPrep = prep
+
+			curve.strategy = result.StrategyName
+			curve.points = append(curve.points, workingSetPoint{
+				numKeys:         numKeys,
+				workingSetBytes: workingSet,
+				hitRate:         result.HitRate,
+				backendPer1k:    result.BackendRequestsPer1kOps,
+				opsPerSec:       result.OpsPerSecond,
+			})
+
+			result.StrategyName = fmt.Sprintf("%s [%d keys]", result.StrategyName, numKeys)
+			results = append(results, result)
+			if result.Incomplete {
+				curves = append(curves, curve)
+				return results, nil
+			}
+		}
+		if len(curve.points) > 0 {
+			curves = append(curves, curve)
+		}
+	}
+	return results, nil
+}
+
+// printWorkingSetCurves prints one table per strategy with a row per key count.
+func printWorkingSetCurves(curves []workingSetCurve, budget int64) {
+	for _, c := range curves {
+		log.Printf("\n--- Hit Rate vs Working Set: %s ---", c.strategy)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.AlignRight|tabwriter.Debug)
+		fmt.Fprintln(w, "Keys\tWorking Set (MB)\tOf L1 Budget (%)\tHit Rate (%)\tBackend Req/1k Ops\tOps/sec\t")
+		for _, p := range c.points {
+			fmt.Fprintf(w, "%d\t%.1f\t%.1f\t%.2f\t%.1f\t%.2f\t\n",
+				p.numKeys,
+				float64(p.workingSetBytes)/(1<<20),
+				float64(p.workingSetBytes)/float64(budget)*100,
+				p.hitRate*100,
+				p.backendPer1k,
+				p.opsPerSec,
+			)
+		}
+		w.Flush()
+	}
+}
+
+// writeWorkingSetCurves writes the scenario's curves to dir as CSV.
+func writeWorkingSetCurves(dir, scenario string, curves []workingSetCurve, budget int64) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, slug(scenario)+".csv")
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(f)
+	cw.Write([]string{"strategy", "keys", "working_set_bytes", "l1_budget_fraction", "hit_rate", "backend_requests_per_1k_ops", "ops_per_second"})
+	for _, c := range curves {
+		for _, p := range c.points {
+			cw.Write([]string{
+				c.strategy,
+				strconv.Itoa(p.numKeys),
+				strconv.FormatInt(p.workingSetBytes, 10),
+				formatFloat(float64(p.workingSetBytes) / float64(budget)),
+				formatFloat(p.hitRate),
+				formatFloat(p.backendPer1k),
+				formatFloat(p.opsPerSec),
+			})
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	log.Printf("Working-set curves written to %s", path)
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// l1Snapshots holds the L1 key sets saved during this process, by
+// l1SnapshotKey.
+var (
+	l1SnapshotsMu sync.Mutex
+	l1Snapshots   = make(map[string][]string)
+)
+
+func l1SnapshotKey(cfg Config, strategyName string) string {
+	return cfg.L1Snapshot + "\x00" + strategyName
+}
+
+// l1SnapshotPath is where a strategy's snapshot is persisted under
+// cfg.L1SnapshotDir.
+func l1SnapshotPath(cfg Config, strategyName string) string {
+	return filepath.Join(cfg.L1SnapshotDir, slug(cfg.L1Snapshot), slug(strategyName)+".keys")
+}
+
+// loadL1Snapshot returns the keys last saved to the scenario's snapshot by
+// the named strategy, in this process or, with an L1SnapshotDir, an earlier
+// one; nil when there are none.
+func loadL1Snapshot(cfg Config, strategyName string) []string {
+	l1SnapshotsMu.Lock()
+	keys, ok := l1Snapshots[l1SnapshotKey(cfg, strategyName)]
+	l1SnapshotsMu.Unlock()
+	if ok || cfg.L1SnapshotDir == "" {
+		return keys
+	}
+	data, err := os.ReadFile(l1SnapshotPath(cfg, strategyName))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Failed to read L1 snapshot: %v", err)
+		}
+		return nil
+	}
+	return strings.Fields(string(data))
+}
+
+// saveL1Snapshot saves a strategy's L1 keys to the scenario's snapshot.
+func saveL1Snapshot(cfg Config, strategyName string, keys []string) {
+	l1SnapshotsMu.Lock()
+	l1Snapshots[l1SnapshotKey(cfg, strategyName)] = keys
+	l1SnapshotsMu.Unlock()
+	if cfg.L1SnapshotDir == "" {
+		return
+	}
+	if err := writeL1Snapshot(l1SnapshotPath(cfg, strategyName), keys); err != nil {
+		log.Printf("Failed to write L1 snapshot: %v", err)
+	}
+}
+
+// writeL1Snapshot writes keys to path, one per line.
+func writeL1Snapshot(path string, keys []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, key := range keys {
+		w.WriteString(key)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
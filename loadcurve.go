@@ -0,0 +1,260 @@
+package main
+
+import (
+	"caching-benchmark/benchmark"
+	"caching-benchmark/workload"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// curvePoint is one open-loop run of a load sweep.
+type curvePoint struct {
+	fraction     float64
+	offered      float64
+	achieved     float64
+	avgLatency   time.Duration
+	p95Latency   time.Duration
+	avgQueueWait time.Duration
+	// p95Response is the p95 response time from the intended start.
+	p95Response   time.Duration
+	operationsRun int64
+}
+
+// loadCurve is the latency-vs-throughput curve of one strategy.
+type loadCurve struct {
+	strategy string
+	capacity float64
+	points   []curvePoint
+}
+
+// runLoadCurve discovers each strategy's closed-loop capacity and then offers
+// it open-loop load at each of cfg.LoadFractions of that capacity.
+func runLoadCurve(ctx context.Context, cfg Config, w []workload.Operation, seed int64) ([]benchmark.Result, error) {
+	var results []benchmark.Result
+	var curves []loadCurve
+	defer func() {
+		printLoadCurves(curves)
+		if cfg.CurveDir != "" && len(curves) > 0 {
+			if err := writeLoadCurves(cfg.CurveDir, cfg.Name, curves); err != nil {
+				log.Printf("Failed to write load curves: %v", err)
+			}
+		}
+	}()
+
+	for _, spec := range strategySpecs(cfg) {
+		// Every run gets a fresh strategy: strategies are not reusable after Close.
+		ns, err := buildStrategy(cfg, spec)
+		if err != nil {
+			return results, err
+		}
+		log.Printf("\n--- Discovering capacity: %s ---", ns.strategy.Name())
+		prep, err := prepareData(ctx, cfg, seed)
+		if err != nil {
+			return results, fmt.Errorf("failed to prepare data for strategy %s: %w", ns.strategy.Name(), err)
+		}
+		capRun, err := benchmark.NewRunner(ns.strategy, w, runnerOptions(cfg, ns, seed)).Run(ctx)
+		if err != nil {
+			log.Printf("Error discovering capacity for strategy %s: %v", ns.strategy.Name(), err)
+			continue
+		}
+		capRun.Lifecycle.DataPrep = prep
+		curve := loadCurve{strategy: capRun.StrategyName, capacity: capRun.OpsPerSecond}
+		capRun.StrategyName += " [closed-loop capacity]"
+		results = append(results, capRun)
+		if capRun.Incomplete || curve.capacity <= 0 {
+			curves = append(curves, curve)
+			return results, nil
+		}
+
+		for _, fraction := range cfg.LoadFractions {
+			ns, err := buildStrategy(cfg, spec)
+			if err != nil {
+				return results, err
+			}
+			rate := fraction * curve.capacity
+			log.Printf("\n--- Running Strategy: %s at %.0f%% of capacity (%.0f ops/sec) ---", ns.strategy.Name(), fraction*100, rate)
+			prep, err := prepareData(ctx, cfg, seed)
+			if err != nil {
+				return results, fmt.Errorf("failed to prepare data for strategy %s: %w", ns.strategy.Name(), err)
+			}
+			opts := runnerOptions(cfg, ns, seed)
+			opts.TargetRate = rate
+			result, err := benchmark.NewRunner(ns.strategy, w, opts).Run(ctx)
+			if err != nil {
+				log.Printf("Error running strategy %s at %.0f%% load: %v", ns.strategy.Name(), fraction*100, err)
+				continue
+			}
+			result.Lifecycle.DataPrep = prep
+
+			point := curvePoint{
+				fraction:      fraction,
+				offered:       rate,
+				achieved:      result.OpsPerSecond,
+				operationsRun: result.TotalOperations,
+			}
+			point.avgLatency, point.p95Latency = latencyStats(result.Latencies)
+			if result.TotalOperations > 0 {
+				point.avgQueueWait = result.TotalQueueWait / time.Duration(result.TotalOperations)
+			}
+			_, point.p95Response = latencyStats(result.ResponseTimes)
+			curve.points = append(curve.points, point)
+
+			result.StrategyName = fmt.Sprintf("%s [%.0f%% load]", result.StrategyName, fraction*100)
+			results = append(results, result)
+			if result.Incomplete {
+				curves = append(curves, curve)
+				return results, nil
+			}
+		}
+		curves = append(curves, curve)
+	}
+	return results, nil
+}
+
+// printLoadCurves prints one table per strategy with a row per load level.
+func printLoadCurves(curves []loadCurve) {
+	for _, c := range curves {
+		log.Printf("\n--- Latency vs Throughput: %s (capacity %.2f ops/sec) ---", c.strategy, c.capacity)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.AlignRight|tabwriter.Debug)
+		fmt.Fprintln(w, "Load (%)\tOffered Ops/sec\tAchieved Ops/sec\tAvg Latency (ms)\tP95 Latency (ms)\tAvg Queue Wait (ms)\tP95 Response (ms)\t")
+		for _, p := range c.points {
+			fmt.Fprintf(w, "%.0f\t%.2f\t%.2f\t%.4f\t%.4f\t%.4f\t%.4f\t\n",
+				p.fraction*100,
+				p.offered,
+				p.achieved,
+				ms(p.avgLatency),
+				ms(p.p95Latency),
+				ms(p.avgQueueWait),
+				ms(p.p95Response),
+			)
+		}
+		w.Flush()
+	}
+}
+
+// writeLoadCurves writes the scenario's curves to dir as a CSV data file and
+// an SVG chart of p95 latency against achieved throughput.
+func writeLoadCurves(dir, scenario string, curves []loadCurve) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	base := filepath.Join(dir, slug(scenario))
+
+	f, err := os.Create(base + ".csv")
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(f)
+	cw.Write([]string{"strategy", "capacity_ops", "load_fraction", "offered_ops", "achieved_ops", "avg_latency_ms", "p95_latency_ms", "avg_queue_wait_ms", "p95_response_ms", "operations"})
+	for _, c := range curves {
+		for _, p := range c.points {
+			cw.Write([]string{
+				c.strategy,
+				formatFloat(c.capacity),
+				formatFloat(p.fraction),
+				formatFloat(p.offered),
+				formatFloat(p.achieved),
+				formatFloat(ms(p.avgLatency)),
+				formatFloat(ms(p.p95Latency)),
+				formatFloat(ms(p.avgQueueWait)),
+				formatFloat(ms(p.p95Response)),
+				strconv.FormatInt(p.operationsRun, 10),
+			})
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(base+".svg", []byte(curveSVG(scenario, curves)), 0o644); err != nil {
+		return err
+	}
+	log.Printf("Load curves written to %s.{csv,svg}", base)
+	return nil
+}
+
+var curveColors = []string{"#1f77b4", "#d62728", "#2ca02c", "#ff7f0e", "#9467bd", "#8c564b"}
+
+// curveSVG renders p95 latency against achieved throughput, one line per strategy.
+func curveSVG(title string, curves []loadCurve) string {
+	const width, height, margin = 800.0, 500.0, 70.0
+	var maxX, maxY float64
+	for _, c := range curves {
+		for _, p := range c.points {
+			maxX = max(maxX, p.achieved)
+			maxY = max(maxY, ms(p.p95Latency))
+		}
+	}
+	maxX, maxY = max(maxX*1.05, 1), max(maxY*1.1, 0.001)
+	x := func(v float64) float64 { return margin + v/maxX*(width-2*margin) }
+	y := func(v float64) float64 { return height - margin - v/maxY*(height-2*margin) }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" font-family="sans-serif" font-size="12">`+"\n", width, height)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="white"/>`+"\n")
+	fmt.Fprintf(&b, `<text x="%.0f" y="25" text-anchor="middle" font-size="14">%s</text>`+"\n", width/2, svgEscape(title))
+	fmt.Fprintf(&b, `<line x1="%.0f" y1="%.0f" x2="%.0f" y2="%.0f" stroke="black"/>`+"\n", margin, height-margin, width-margin, height-margin)
+	fmt.Fprintf(&b, `<line x1="%.0f" y1="%.0f" x2="%.0f" y2="%.0f" stroke="black"/>`+"\n", margin, margin, margin, height-margin)
+	for i := 0; i <= 5; i++ {
+		vx, vy := maxX*float64(i)/5, maxY*float64(i)/5
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.0f" text-anchor="middle">%.0f</text>`+"\n", x(vx), height-margin+18, vx)
+		fmt.Fprintf(&b, `<text x="%.0f" y="%.1f" text-anchor="end">%.3g</text>`+"\n", margin-6, y(vy)+4, vy)
+	}
+	fmt.Fprintf(&b, `<text x="%.0f" y="%.0f" text-anchor="middle">Achieved throughput (ops/sec)</text>`+"\n", width/2, height-20)
+	fmt.Fprintf(&b, `<text transform="translate(18,%.0f) rotate(-90)" text-anchor="middle">P95 latency (ms)</text>`+"\n", height/2)
+
+	for i, c := range curves {
+		color := curveColors[i%len(curveColors)]
+		var pts []string
+		for _, p := range c.points {
+			pts = append(pts, fmt.Sprintf("%.1f,%.1f", x(p.achieved), y(ms(p.p95Latency))))
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`+"\n", color, strings.Join(pts, " "))
+		for _, pt := range pts {
+			cx, cy, _ := strings.Cut(pt, ",")
+			fmt.Fprintf(&b, `<circle cx="%s" cy="%s" r="3" fill="%s"/>`+"\n", cx, cy, color)
+		}
+		ly := margin + float64(i)*18
+		fmt.Fprintf(&b, `<rect x="%.0f" y="%.0f" width="12" height="12" fill="%s"/>`+"\n", margin+10, ly, color)
+		fmt.Fprintf(&b, `<text x="%.0f" y="%.0f">%s</text>`+"\n", margin+28, ly+10, svgEscape(c.strategy))
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+func svgEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// slug turns a scenario name into a file name.
+func slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
@@ -2,14 +2,28 @@ package main
 
 import (
 	"caching-benchmark/benchmark"
+	"caching-benchmark/codec"
+	"caching-benchmark/env"
 	"caching-benchmark/implementations"
+	"caching-benchmark/netproxy"
+	"caching-benchmark/serialize"
+	"caching-benchmark/tracing"
 	"caching-benchmark/workload"
+	"cmp"
 	"context"
-	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
+	"math/rand"
 	"os"
-	"sort"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"text/tabwriter"
 	"time"
 
@@ -24,188 +38,1304 @@ type Config struct {
 	ReadWriteRatio float64
 	Concurrency    int
 	ValueSizeBytes int
-	ZipfS          float64
-	ZipfV          float64
+	// Tags group scenarios for -tags and -skip-tags, e.g. "quick" for the
+	// fast smoke subset or "large-values"; see scenarioTags.
+	Tags []string
+	// Payload is the kind of value populated and written ("hex", "text",
+	// "json" or "binary"); empty selects hex. VaryValues writes a new value
+	// on every write instead of one per worker.
+	Payload    string
+	VaryValues bool
+	// Serializer caches values as Go objects serialized with "json",
+	// "msgpack" or "protobuf", timing encode and decode separately. Empty
+	// caches raw strings.
+	Serializer string
+	ZipfS      float64
+	ZipfV      float64
+	// L1BudgetBytes replaces memoryBudgetBytes as the L1 memory budget
+	// given to every strategy, for scenarios that need an L1 small next to
+	// their keys. Zero selects memoryBudgetBytes.
+	L1BudgetBytes int64
+	// Seed makes the workload and write payloads reproducible. Zero selects defaultSeed.
+	Seed int64
+	// Strategies lists the strategies to run as "name[:knob=value,...]" specs
+	// of registered strategies. Empty selects defaultStrategies.
+	Strategies []string
+	// StampedeInterval turns the scenario into a cache-stampede test: every
+	// operation reads a single hot key which is invalidated at this interval.
+	StampedeInterval time.Duration
+	// ExternalWriteRate, when positive, has ExternalWriters goroutines
+	// (default defaultExternalWriters) write this many values per second
+	// in total to random populated keys during each run, simulating other
+	// application instances: through a second instance of the strategy,
+	// which publishes invalidations as a peer would, or with
+	// ExternalWritesRaw as plain SETs from a bare client, which only
+	// Redis-driven invalidation (CSC, keyspace events) notices. Raw writes
+	// store strings, so they do not suit hash-value strategies.
+	ExternalWriteRate float64
+	ExternalWriters   int
+	ExternalWritesRaw bool
+	// MaxInFlight caps concurrent strategy calls per strategy name, modeling a
+	// connection-pool limit independent of Concurrency. Missing entries are uncapped.
+	// A spec's max_in_flight knob overrides its strategy's entry.
+	MaxInFlight map[string]int
+	// Sweeps replace the scenario's strategy list with parameter sweeps that
+	// run each strategy across a grid of tuning knobs.
+	Sweeps []Sweep
+	// TrackStaleness stamps writes and counts reads that return data older
+	// than the latest completed write.
+	TrackStaleness bool
+	// ReadYourWrites has every worker read each key back after its own
+	// write and counts the reads that miss it; it also tracks staleness.
+	ReadYourWrites bool
+	// RestartAt restarts each strategy this long into its run, closing and
+	// re-initializing it while Redis keeps the data, and reports its
+	// recovery. RestartPrewarmKeys reads that many of the most popular keys
+	// through the restarted strategy before traffic resumes.
+	RestartAt          time.Duration
+	RestartPrewarmKeys int
+	// L1Snapshot names a snapshot that strategies able to dump their L1
+	// (benchmark.Warmable) save their L1 key set to at the end of each run.
+	// With PrewarmL1, they first load the key set saved by the same
+	// strategy's previous run before measurement; without one they start
+	// cold.
+	L1Snapshot string
+	PrewarmL1  bool
+	// L1Schedule changes the L1 budget of strategies that can resize their
+	// L1 during each run, modeling containers whose memory limit is
+	// adjusted or shared with the application heap; L1ResizeCold empties
+	// the L1 at every change, as if it were recreated instead.
+	L1Schedule   []benchmark.L1Resize
+	L1ResizeCold bool
+	// L1SnapshotDir, filled in from -l1-snapshot-dir, persists L1 snapshots
+	// across benchmark processes.
+	L1SnapshotDir string
+	// Timeline records each run's per-second latency timeline, annotating
+	// p99 spikes with the invalidations and GC pauses in the same second.
+	// -timeline-dir enables it for every scenario.
+	Timeline bool
+	// AbsentReadFraction redirects this fraction of reads to keys that do not
+	// exist, exercising negative caching.
+	AbsentReadFraction float64
+	// RangeReadFraction turns this fraction of reads into reads of only
+	// RangeReadBytes at a random offset of the value. Strategies implementing
+	// benchmark.RangeReader read the fragment on the server; the others fetch
+	// whole values. It cannot be combined with Codec.
+	RangeReadFraction float64
+	RangeReadBytes    int
+	// RMWFraction turns this fraction of writes into read-modify-writes
+	// that increment a counter prefixed to the value. It cannot be combined
+	// with Codec or Serializer.
+	RMWFraction float64
+	// ScanFraction turns this fraction of reads into scans of up to
+	// ScanLimit keys prefixed by the key read, served by strategies
+	// implementing benchmark.Scanner. It cannot be combined with
+	// KeyDerivation, which does not preserve prefixes.
+	ScanFraction float64
+	ScanLimit    int
+	// Structure stores values as plain strings (implementations.StructureString,
+	// the default) or split over the fields of hashes
+	// (implementations.StructureHash), which only some strategies can read.
+	// Hashes cannot be combined with range reads, read-modify-writes or scans.
+	Structure string
+	// CSCTTL is the client-side TTL of values cached through rueidis
+	// client-side caching, by the CSC strategies and the CSC L2; zero keeps
+	// their 10-minute default. csc_ttl knobs override it.
+	CSCTTL time.Duration
+	// KeyDerivation derives every cache key from a structured request inside
+	// the measured path ("url-raw", "url-fnv" or "url-sha256"); empty uses keys as-is.
+	KeyDerivation string
+	// KeyParamBytes pads the derived request URL to model larger requests.
+	KeyParamBytes int
+	// KeyTemplate names keys the way an application would, e.g.
+	// "user:{id}:profile" or "item:{id:08}" (see workload.NewKeyTemplate),
+	// and KeyBytes pads every key to this length, since key size drives
+	// CSC tracking memory, L1 cost accounting and network bytes. Either one
+	// renders keys inside the measured path like KeyDerivation, which they
+	// cannot be combined with; KeyBytes alone pads the default "key-{id}".
+	KeyTemplate string
+	KeyBytes    int
+	// TargetRate runs every strategy open-loop at this many ops/sec, so the
+	// run lasts NumOperations/TargetRate regardless of strategy speed.
+	// Zero runs closed-loop.
+	TargetRate float64
+	// Codec encodes every written value in an on-wire layout ("raw" or "v1")
+	// and validates every read against it. Empty stores payloads unencoded.
+	Codec string
+	// OpTimeout bounds every Read and Write; operations exceeding it count
+	// as timeouts. Zero leaves operations unbounded.
+	OpTimeout time.Duration
+	// Retry retries operations failing with timeouts or connection errors
+	// in the runner, with backoff, instead of leaving retries to each
+	// strategy's client; retried time counts toward response times only.
+	Retry benchmark.RetryPolicy
+	// Faults are injected into the server at fixed offsets during each run.
+	Faults []FaultSpec
+	// LoadFractions turns the scenario into a load sweep: each strategy is
+	// first run closed-loop to discover its capacity, then open-loop at each
+	// of these fractions of it, tracing its latency-vs-throughput curve.
+	LoadFractions []float64
+	// ConcurrencyLevels turns the scenario into a concurrency sweep: each
+	// strategy is run closed-loop at each of these worker counts in place of
+	// Concurrency, and its saturation knee is reported.
+	ConcurrencyLevels []int
+	// KeyCounts turns the scenario into a working-set sweep: each strategy is
+	// run with a workload regenerated over each of these key counts in place
+	// of NumKeys, tracing hit rate against working-set size.
+	KeyCounts []int
+	// ZipfSkews turns the scenario into a skew sweep: each strategy is run
+	// with a workload regenerated with each of these Zipf s in place of
+	// ZipfS, and its hit rate compared with the hit rates theory predicts
+	// for an L1 of the scenario's budget.
+	ZipfSkews []float64
+	// StepRates turns the scenario into a step-load test: each strategy is
+	// offered each of these increasing rates in ops/sec for StepDuration,
+	// NumOperations being ignored, until its p99 response time exceeds
+	// StepP99Limit; the highest rate it sustained is reported as its max
+	// sustainable throughput.
+	StepRates    []float64
+	StepDuration time.Duration
+	StepP99Limit time.Duration
+	// TTLClasses gives populated keys TTLs drawn from these classes, so runs
+	// include natural expirations in Redis; hit rate is also reported per
+	// class. Writes during the run replace keys without a TTL.
+	TTLClasses []TTLClass
+	// Mix replaces the single Zipf or uniform distribution with a composite
+	// workload whose components draw from consecutive ranges of the keys,
+	// each with its own distribution and read/write ratio; hit rate is also
+	// reported per component. ReadWriteRatio is then derived from the
+	// components'. It cannot be combined with KeyCounts, ZipfSkews or
+	// StampedeInterval.
+	Mix []workload.Component
+	// Tenants partitions the keys into this many tenants, each a Zipf
+	// workload over its own keys, with tenant i receiving traffic in
+	// proportion to 1/(i+1)^TenantSkew; hit rate and p99 latency are also
+	// reported per tenant. It cannot be combined with Mix.
+	Tenants    int
+	TenantSkew float64
+	// SLOs are thresholds every strategy's run must meet, such as
+	// "p99 < 2ms" or "hit-rate > 80%" (see SLO). Misses are reported in
+	// the comparison table and fail the process with sloExitCode.
+	SLOs []string
+	// MaxMemory and MaxMemoryPolicy are applied to the server with CONFIG SET
+	// for the duration of the scenario, e.g. "64mb" and "allkeys-lru", so
+	// strategies run while L2 itself evicts keys. Empty leaves the setting
+	// unchanged; previous values are restored afterwards.
+	MaxMemory       string
+	MaxMemoryPolicy string
+	// KeyPrefix, filled in from -key-prefix, namespaces every key the
+	// benchmark stores in Redis.
+	KeyPrefix string
+	// NoFlush, filled in from -no-flush, replaces FLUSHALL before each run
+	// with deleting only the keys under KeyPrefix, for shared servers.
+	NoFlush bool
+	// PopulateBatch and PopulateWorkers, filled in from -populate-batch and
+	// -populate-workers, size the pipelines that seed keys before each run
+	// and how many run in parallel. Zero selects defaults.
+	PopulateBatch   int
+	PopulateWorkers int
+	// PopulateResume, filled in from -populate-resume, keeps keys that
+	// already hold a value of the scenario's size instead of clearing them,
+	// so an interrupted multi-GB population continues where it stopped.
+	// Values written by a previous run are kept as well.
+	PopulateResume bool
+	// NoDataReuse, filled in from -no-data-reuse, flushes and repopulates
+	// before every strategy run instead of verifying and reusing the data
+	// the scenario's previous run populated.
+	NoDataReuse bool
+	// DisableBufferPool allocates the runner's value buffers on every use
+	// instead of reusing pooled ones; -no-buffer-pool sets it everywhere.
+	DisableBufferPool bool
+	// RTT routes all traffic to Redis through a local proxy that adds this
+	// round-trip time, modeling e.g. a cross-AZ network. Zero connects directly.
+	RTT time.Duration
+	// RTTJitter adds up to this much uniform random delay to each round trip.
+	RTTJitter time.Duration
+	// Addr is the Redis endpoint, filled in from the environment being run.
+	Addr string
+	// Failover runs strategies with client-side failover to StandbyAddr, which
+	// is populated with the same data as Addr before each run.
+	Failover bool
+	// StandbyAddr, filled in from -standby-addr, is the warm-standby endpoint.
+	StandbyAddr string
+	// Clock is the host timer self-check, measured once at startup.
+	Clock *benchmark.ClockCheck
+	// SlowestN, filled in from -slowest-ops, is the number of slowest
+	// operations reported per run.
+	SlowestN int
+	// CurveDir, filled in from -curve-dir, receives the data and charts of
+	// load, concurrency and working-set sweeps.
+	CurveDir string
+	// ProfileDir, filled in from -profile-dir, receives CPU, heap and mutex
+	// profiles of every strategy run, one subdirectory per scenario.
+	ProfileDir string
+	// ProgressInterval, filled in from -progress, is how often running
+	// strategies report progress; zero disables the reports.
+	ProgressInterval time.Duration
+	// WorkloadCache, filled in from -workload-cache, is a directory that
+	// generated workloads are saved to and reused from across runs.
+	WorkloadCache string
+	// Tracer, created from -otlp-endpoint, exports spans of TraceSampleRate
+	// (-trace-sample) of every run's operations; nil disables tracing.
+	Tracer          *tracing.Exporter
+	TraceSampleRate float64
+	// Interop runs all of the scenario's strategies at the same time against
+	// the same keys, splitting workers and operations between them, and
+	// counts stale reads caused by the heterogeneous clients.
+	Interop bool
+	// CanaryFraction splits the workload between exactly two strategies
+	// running at the same time: this fraction of operations, and of workers,
+	// goes to the second (candidate) strategy and the rest to the first
+	// (control), and the two cohorts are compared side by side.
+	CanaryFraction float64
 }
 
+// namedStrategy pairs a strategy with the registry name it was built from.
+type namedStrategy struct {
+	name     string
+	strategy benchmark.CachingStrategy
+	// spec is the spec the strategy was built from, knobs included; empty
+	// for strategies built by name alone.
+	spec string
+	// maxInFlight is the spec's max_in_flight knob, which overrides the
+	// scenario's MaxInFlight for the strategy; zero when not set.
+	maxInFlight int
+}
+
+// hotKey is the key targeted by stampede scenarios.
+const hotKey = "key-0"
+
+// defaultStrategies are run for scenarios that do not list their own.
+var defaultStrategies = []string{"rueidis-csc", "ristretto-pubsub"}
+
+// memoryBudgetBytes is the L1 memory budget given to every strategy.
+const memoryBudgetBytes = 1 << 30
+
+// l1Budget returns the L1 memory budget of the scenario's strategies.
+func l1Budget(cfg Config) int64 {
+	return cmp.Or(cfg.L1BudgetBytes, memoryBudgetBytes)
+}
+
+// defaultSeed is used for scenarios that do not set an explicit seed, so
+// repeated invocations of the benchmark replay the same operations.
+const defaultSeed = 42
+
 func main() {
-	// Define the different benchmark scenarios
-	testConfigs := []Config{
-		{
-			Name:           "Read-Heavy (90% Read, 64B Values)",
-			NumOperations:  100000,
-			NumKeys:        10000,
-			ReadWriteRatio: 0.9,
-			Concurrency:    64,
-			ValueSizeBytes: 64,
-			ZipfS:          1.01,
-			ZipfV:          1,
-		},
-		{
-			Name:           "Write-Heavy (50% Read, 64B Values)",
-			NumOperations:  100000,
-			NumKeys:        10000,
-			ReadWriteRatio: 0.5,
-			Concurrency:    64,
-			ValueSizeBytes: 64,
-			ZipfS:          1.01,
-			ZipfV:          1,
-		},
-		{
-			Name:           "Uniform Workload (Worst-Case, 90% Read)",
-			NumOperations:  100000,
-			NumKeys:        10000,
-			ReadWriteRatio: 0.9,
-			Concurrency:    64,
-			ValueSizeBytes: 64,
-			ZipfS:          0, // Zipf parameters are ignored for uniform
-			ZipfV:          0,
-		},
-		{
-			Name:           "Memory-Intensive (90% Read, 1KB Values)",
-			NumOperations:  50000, // Reduced ops to keep test duration reasonable
-			NumKeys:        10000,
-			ReadWriteRatio: 0.9,
-			Concurrency:    64,
-			ValueSizeBytes: 1024,
-			ZipfS:          1.01,
-			ZipfV:          1,
-		},
-		{
-			Name:           "Large Value Scenario (90% Read, 2MB Values)",
-			NumOperations:  2000, // Drastically reduced ops due to large payload size
-			NumKeys:        100,  // Reduced keys to keep data prep manageable
-			ReadWriteRatio: 0.9,
-			Concurrency:    64, // Reduced concurrency to avoid overwhelming network
-			ValueSizeBytes: 2 * 1024 * 1024,
-			ZipfS:          1.01,
-			ZipfV:          1,
-		},
-		{
-			Name:           "Write-Heavy & Large Value (50% Read, 1MB Values)",
-			NumOperations:  2000,
-			NumKeys:        100, // Reduced keys to keep data prep manageable
-			ReadWriteRatio: 0.5,
-			Concurrency:    64,
-			ValueSizeBytes: 2 * 1024 * 1024,
-			ZipfS:          1.01,
-			ZipfV:          1,
-		},
-	}
-
-	ctx := context.Background()
-	allResults := make(map[string][]benchmark.Result)
-
-	for _, cfg := range testConfigs {
-		log.Println("==========================================================")
-		log.Printf("--- Starting Scenario: %s ---", cfg.Name)
-		log.Printf("Preparing benchmark with %d operations on %d keys.", cfg.NumOperations, cfg.NumKeys)
-		log.Printf("Concurrency: %d, Read/Write Ratio: %.2f, Value Size: %dB", cfg.Concurrency, cfg.ReadWriteRatio, cfg.ValueSizeBytes)
-
-		var w []workload.Operation
-		if cfg.Name == "Uniform Workload (Worst-Case, 90% Read)" {
-			w = workload.GenerateUniform(cfg.NumOperations, cfg.NumKeys, cfg.ReadWriteRatio)
-		} else {
-			w = workload.Generate(cfg.NumOperations, cfg.NumKeys, cfg.ReadWriteRatio, cfg.ZipfS, cfg.ZipfV)
-		}
-
-		// Estimate key count for rueidis based on a 1GB memory budget
-		// This is a rough estimation and a weakness of the key-count approach.
-		estimatedKeyCount := (1 << 30) / (cfg.ValueSizeBytes + 50) // 50 bytes overhead per key
-
-		strategies := []benchmark.CachingStrategy{
-			implementations.NewRueidisCSCStrategy(estimatedKeyCount),
-			implementations.NewRistrettoPubSubStrategy(1 << 30), // 1GB memory budget
-		}
-
-		for _, s := range strategies {
-			log.Printf("\n--- Running Strategy: %s ---", s.Name())
-			if err := prepareData(ctx, cfg.NumKeys, cfg.ValueSizeBytes); err != nil {
-				log.Fatalf("Failed to prepare data for strategy %s: %v", s.Name(), err)
-			}
-
-			runner := benchmark.NewRunner(s, w, cfg.Concurrency, cfg.ValueSizeBytes)
-			result, err := runner.Run(ctx)
-			if err != nil {
-				log.Printf("Error running benchmark for strategy %s: %v", s.Name(), err)
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		switch os.Args[1] {
+		case "migrate-analysis":
+			if err := runMigrateAnalysis(os.Args[2:]); err != nil {
+				log.Fatalf("migrate-analysis: %v", err)
+			}
+			return
+		case "experiment":
+			if err := runExperiment(os.Args[2:]); err != nil {
+				log.Fatalf("experiment: %v", err)
+			}
+			return
+		case "serve-api":
+			if err := runServeAPI(os.Args[2:]); err != nil {
+				log.Fatalf("serve-api: %v", err)
+			}
+			return
+		case "history":
+			if err := runHistory(os.Args[2:]); err != nil {
+				log.Fatalf("history: %v", err)
+			}
+			return
+		default:
+			log.Fatalf("unknown command %q (available: migrate-analysis, experiment, serve-api, history)", os.Args[1])
+		}
+	}
+
+	envImages := flag.String("env-images", "", "comma-separated Docker images (e.g. redis:6.2-alpine,valkey/valkey:8-alpine) to run every scenario against in turn; empty uses the server at "+implementations.DefaultAddr)
+	envVersions := flag.String("env-redis-versions", "", "comma-separated Redis versions (e.g. 6.2,7.4) to run against as redis:<version>-alpine containers, after -env-images")
+	envCluster := flag.Bool("env-cluster", false, "start managed environments as Redis clusters instead of standalone servers")
+	envClusterNodes := flag.Int("env-cluster-nodes", 3, "number of primaries in managed clusters")
+	envMaxMemory := flag.String("env-maxmemory", "", "maxmemory of managed servers (e.g. 2gb); empty keeps the image default")
+	envMaxMemoryPolicy := flag.String("env-maxmemory-policy", "", "maxmemory-policy of managed servers (e.g. allkeys-lru); empty keeps the image default")
+	artifactsDir := flag.String("artifacts-dir", "", "write compressed per-run time series and latencies to this directory")
+	artifactChunkMB := flag.Int64("artifact-chunk-mb", 64, "uncompressed size in MB at which artifact chunks are rotated")
+	standbyAddr := flag.String("standby-addr", "", "warm-standby Redis address for failover scenarios; they are skipped when empty")
+	slowestN := flag.Int("slowest-ops", 10, "number of slowest operations to report per run with key, type, layer and error")
+	percentileList := flag.String("percentiles", defaultPercentiles, "comma-separated latency percentiles to report; 100 is the maximum")
+	cdfDir := flag.String("latency-cdf-dir", "", "write each run's full latency CDF to this directory as CSV")
+	keyPrefix := flag.String("key-prefix", "", "namespace every benchmark key under this prefix, e.g. cachebench:")
+	noFlush := flag.Bool("no-flush", false, "never FLUSHALL: delete only keys under -key-prefix before each run and at exit, for shared servers")
+	assumeYes := flag.Bool("yes", false, "FLUSHALL a non-empty externally managed server without asking")
+	noBufferPool := flag.Bool("no-buffer-pool", false, "allocate value buffers on every use instead of reusing pooled ones, to measure their allocation and GC cost")
+	populateBatch := flag.Int("populate-batch", 0, "keys per pipelined batch when pre-populating; 0 sizes batches to about 64MB of values")
+	populateWorkers := flag.Int("populate-workers", defaultPopulateWorkers, "number of pre-population batches in flight at once")
+	populateResume := flag.Bool("populate-resume", false, "do not clear keys before pre-populating; only set keys that do not already hold a value of the scenario's size")
+	noDataReuse := flag.Bool("no-data-reuse", false, "flush and repopulate before every strategy run instead of reusing the scenario's verified data")
+	summaryFile := flag.String("summary-file", "", "also write the narrative summary of the campaign's findings to this file as Markdown")
+	payload := flag.String("payload", "", "override every scenario's value kind: "+strings.Join(benchmark.PayloadKinds, ", "))
+	serializer := flag.String("serializer", "", "override every scenario's serialization: json, msgpack or protobuf")
+	varyValues := flag.Bool("vary-values", false, "write a newly generated value on every write in every scenario")
+	progress := flag.Duration("progress", 10*time.Second, "log completed operations, throughput, hit rate and ETA this often during each run; 0 disables")
+	profileDir := flag.String("profile-dir", "", "write CPU, heap and mutex pprof profiles of every strategy run to this directory")
+	resultsDB := flag.String("results-db", "", "append every run, with its scenario config, git commit and server version, to this SQLite results database for the history subcommand")
+	influxURL := flag.String("influx-url", "", "push final metrics to this InfluxDB write endpoint, e.g. http://influx:8086/api/v2/write?org=o&bucket=b")
+	influxToken := flag.String("influx-token", "", "API token for -influx-url")
+	pushSeries := flag.Bool("push-series", false, "also push each run's per-second throughput, hit rate and load to -influx-url")
+	pushgatewayURL := flag.String("pushgateway-url", "", "push final metrics to this Prometheus Pushgateway, grouped by scenario and strategy")
+	pushJob := flag.String("push-job", pushMeasurement, "Pushgateway job name")
+	l1SnapshotDir := flag.String("l1-snapshot-dir", "", "persist the L1 key sets of snapshotting scenarios to this directory, so later runs can pre-warm from them")
+	workloadCache := flag.String("workload-cache", "", "save generated workloads to this directory and reuse them in later runs with the same settings and seed")
+	otlpEndpoint := flag.String("otlp-endpoint", "", "export spans of sampled operations to this OpenTelemetry collector's OTLP/HTTP endpoint, e.g. http://localhost:4318")
+	traceSample := flag.Float64("trace-sample", 0.001, "fraction of operations traced when -otlp-endpoint is set")
+	timelineDir := flag.String("timeline-dir", "", "record every run's per-second latency timeline, attributing p99 spikes to invalidation storms, GC or Redis, and write it to this directory as CSV")
+	retries := flag.Int("retries", 0, "retry operations failing with timeouts or connection errors up to this many times in every scenario, counting retries in response times but not service times")
+	retryBackoff := flag.Duration("retry-backoff", 5*time.Millisecond, "wait before the first -retries retry, doubling on each further one")
+	retryMaxBackoff := flag.Duration("retry-max-backoff", 100*time.Millisecond, "cap on the -retries backoff")
+	externalRate := flag.Float64("external-write-rate", 0, "in every scenario, write this many values per second from simulated other application instances, outside the measured workers")
+	externalRaw := flag.Bool("external-writes-raw", false, "make -external-write-rate writes plain SETs from a bare client instead of writes through a peer instance of the strategy")
+	tagList := flag.String("tags", "", "only run scenarios with one of these comma-separated tags, e.g. quick for a fast smoke subset")
+	skipTagList := flag.String("skip-tags", "", "skip scenarios with any of these comma-separated tags, e.g. large-values,sweep")
+	curveDir := flag.String("curve-dir", "", "write curves from load-sweep (CSV and SVG), concurrency-sweep and working-set-sweep (CSV) scenarios to this directory")
+	flag.Parse()
+	percentiles, err := parsePercentiles(*percentileList)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tags, err := parseTags(*tagList)
+	if err != nil {
+		log.Fatalf("-tags: %v", err)
+	}
+	skipTags, err := parseTags(*skipTagList)
+	if err != nil {
+		log.Fatalf("-skip-tags: %v", err)
+	}
+
+	// Ctrl-C cancels ctx; workers stop, strategies are closed and the results
+	// collected so far are still reported.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var allResults []scenarioResults
+	started := time.Now()
+
+	clock := benchmark.MeasureClock()
+	log.Printf("Host %s (%s): clock resolution %v, timing overhead %v", clock.Platform, clock.GoVersion, clock.Resolution, clock.Overhead)
+	if clock.Resolution > time.Microsecond {
+		log.Printf("WARNING: clock resolution is coarser than 1µs; L1-hit latencies will be quantized and not comparable with other hosts")
+	}
+
+	var tracer *tracing.Exporter
+	if *otlpEndpoint != "" {
+		if *traceSample <= 0 || *traceSample > 1 {
+			log.Fatalf("-trace-sample must be in (0, 1], got %v", *traceSample)
+		}
+		tracer = tracing.NewExporter(*otlpEndpoint, "caching-benchmark")
+		log.Printf("Tracing %.3g%% of operations to %s", *traceSample*100, *otlpEndpoint)
+	}
+
+	environments, err := env.Parse(*envImages, *envVersions, env.Options{
+		Cluster:         *envCluster,
+		ClusterNodes:    *envClusterNodes,
+		MaxMemory:       *envMaxMemory,
+		MaxMemoryPolicy: *envMaxMemoryPolicy,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *noFlush && *keyPrefix == "" {
+		log.Fatal("-no-flush needs a -key-prefix to clean up by")
+	}
+	for _, e := range environments {
+		// Managed containers are dedicated to the benchmark; only a server
+		// someone else runs needs protecting.
+		if e.Image == "" && !*noFlush {
+			if err := confirmFlush(ctx, e.Addr, *assumeYes); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+campaign:
+	for _, e := range environments {
+		stopEnv, err := e.Start(ctx)
+		if err != nil {
+			log.Fatalf("Failed to start environment %s: %v", e.Name, err)
+		}
+		version := env.ServerVersion(ctx, e.Addr)
+		log.Printf("Environment %s at %s (server version %s)", e.Name, e.Addr, version)
+
+		for _, cfg := range defaultScenarios() {
+			if !selected(cfg, tags, skipTags) {
 				continue
 			}
-			allResults[cfg.Name] = append(allResults[cfg.Name], result)
+			cfg.Addr = e.Addr
+			cfg.KeyPrefix = *keyPrefix
+			cfg.NoFlush = *noFlush
+			cfg.PopulateBatch = *populateBatch
+			cfg.PopulateWorkers = *populateWorkers
+			cfg.PopulateResume = *populateResume
+			cfg.NoDataReuse = *noDataReuse
+			if *payload != "" {
+				cfg.Payload = *payload
+			}
+			cfg.VaryValues = cfg.VaryValues || *varyValues
+			if *serializer != "" {
+				cfg.Serializer = *serializer
+			}
+			cfg.StandbyAddr = *standbyAddr
+			cfg.SlowestN = *slowestN
+			cfg.Clock = &clock
+			cfg.CurveDir = *curveDir
+			cfg.DisableBufferPool = cfg.DisableBufferPool || *noBufferPool
+			if *curveDir != "" && len(environments) > 1 {
+				cfg.CurveDir = filepath.Join(*curveDir, slug(e.Name))
+			}
+			cfg.ProfileDir = *profileDir
+			cfg.ProgressInterval = *progress
+			cfg.WorkloadCache = *workloadCache
+			cfg.Tracer = tracer
+			cfg.TraceSampleRate = *traceSample
+			cfg.L1SnapshotDir = *l1SnapshotDir
+			cfg.Timeline = cfg.Timeline || *timelineDir != ""
+			if *externalRate > 0 {
+				cfg.ExternalWriteRate = *externalRate
+				cfg.ExternalWritesRaw = *externalRaw
+			}
+			if *retries > 0 {
+				cfg.Retry = benchmark.RetryPolicy{MaxRetries: *retries, Backoff: *retryBackoff, MaxBackoff: *retryMaxBackoff}
+			}
+			if len(cfg.Mix) > 0 {
+				cfg.ReadWriteRatio = workload.MixReadRatio(cfg.Mix)
+			}
+			if *profileDir != "" && len(environments) > 1 {
+				cfg.ProfileDir = filepath.Join(*profileDir, slug(e.Name))
+			}
+			results, err := runScenario(ctx, cfg)
+			for i := range results {
+				results[i].Environment = e.Name
+				results[i].ServerVersion = version
+			}
+			name := cfg.Name
+			if len(environments) > 1 {
+				name += " @ " + e.Name
+			}
+			// Validated by runScenario before any strategy ran.
+			slos, _ := parseSLOs(cfg.SLOs)
+			allResults = append(allResults, scenarioResults{name: name, results: results, readRatio: cfg.ReadWriteRatio, slos: slos, config: cfg})
+			if ctx.Err() != nil {
+				stopEnv()
+				break campaign
+			}
+			if err != nil {
+				stopEnv()
+				log.Fatalf("Scenario %s failed: %v", cfg.Name, err)
+			}
+		}
+		if *noFlush && e.Image == "" {
+			cleanupKeyPrefix(e.Addr, *keyPrefix)
+		}
+		stopEnv()
+	}
+
+	if ctx.Err() != nil {
+		log.Println("Interrupted: reporting partial results.")
+	}
+	if tracer != nil {
+		tracer.Close()
+	}
+	printFinalComparison(allResults, percentiles)
+	if err := printNarrativeSummary(allResults, *summaryFile); err != nil {
+		log.Fatalf("Failed to write summary: %v", err)
+	}
+	if *cdfDir != "" {
+		if err := writeLatencyCDFs(*cdfDir, allResults); err != nil {
+			log.Fatalf("Failed to write latency CDFs: %v", err)
+		}
+	}
+	if *timelineDir != "" {
+		if err := writeTimelines(*timelineDir, allResults); err != nil {
+			log.Fatalf("Failed to write latency timelines: %v", err)
+		}
+	}
+	if *artifactsDir != "" {
+		if err := writeArtifacts(*artifactsDir, *artifactChunkMB<<20, allResults); err != nil {
+			log.Fatalf("Failed to write artifacts: %v", err)
+		}
+	}
+	if *influxURL != "" || *pushgatewayURL != "" {
+		push := pushOptions{influxURL: *influxURL, influxToken: *influxToken, series: *pushSeries, pushgatewayURL: *pushgatewayURL, job: *pushJob}
+		if err := pushResults(push, newRunID(started), allResults); err != nil {
+			log.Printf("Failed to push results: %v", err)
+		}
+	}
+	if *resultsDB != "" {
+		if err := recordRuns(*resultsDB, newRunID(started), gitCommit(), allResults); err != nil {
+			log.Fatalf("Failed to record runs: %v", err)
 		}
+		log.Printf("Runs recorded in %s", *resultsDB)
+	}
+	if failed := reportSLOs(allResults); failed > 0 {
+		log.Printf("%d runs missed their SLOs", failed)
+		os.Exit(sloExitCode)
 	}
+}
 
-	printFinalComparison(allResults)
+// generateWorkload generates the scenario's operations, or reuses them from
+// the workload cache.
+func generateWorkload(cfg Config, seed int64) []workload.Operation {
+	if cfg.WorkloadCache != "" {
+		return cachedWorkload(cfg, seed)
+	}
+	return buildWorkload(cfg, seed)
 }
 
-func prepareData(ctx context.Context, numKeys, valueSizeBytes int) error {
-	log.Println("Preparing datastore for benchmark...")
-	// TODO: For very large data pre-population, consider a context with a longer timeout.
-	client, err := rueidis.NewClient(rueidis.ClientOption{InitAddress: []string{"127.0.0.1:6379"}})
+// buildWorkload generates the scenario's operations.
+func buildWorkload(cfg Config, seed int64) []workload.Operation {
+	var w []workload.Operation
+	if cfg.StampedeInterval > 0 {
+		w = workload.GenerateHotKey(cfg.NumOperations, hotKey)
+	} else if len(cfg.Mix) > 0 {
+		w = workload.GenerateMix(cfg.NumOperations, cfg.Mix, seed)
+	} else if cfg.Name == uniformScenario {
+		w = workload.GenerateUniform(cfg.NumOperations, cfg.NumKeys, cfg.ReadWriteRatio, seed)
+	} else {
+		w = workload.Generate(cfg.NumOperations, cfg.NumKeys, cfg.ReadWriteRatio, cfg.ZipfS, cfg.ZipfV, seed)
+	}
+	if cfg.AbsentReadFraction > 0 {
+		w = workload.WithAbsentReads(w, cfg.AbsentReadFraction, max(1, cfg.NumKeys/10), seed)
+	}
+	if cfg.RangeReadFraction > 0 {
+		w = workload.WithRangeReads(w, cfg.RangeReadFraction, cfg.ValueSizeBytes, cfg.RangeReadBytes, seed)
+	}
+	if cfg.RMWFraction > 0 {
+		w = workload.WithRMW(w, cfg.RMWFraction, seed)
+	}
+	if cfg.ScanFraction > 0 {
+		w = workload.WithScans(w, cfg.ScanFraction, cfg.ScanLimit, seed)
+	}
+	return w
+}
+
+// scenarioResults holds the results of one scenario, in run order.
+type scenarioResults struct {
+	name    string
+	results []benchmark.Result
+	// readRatio is the scenario's ReadWriteRatio.
+	readRatio float64
+	// slos are the scenario's SLOs.
+	slos []SLO
+	// config is the scenario as run.
+	config Config
+}
+
+// hostMetadata is collected once per process.
+var hostMetadata = sync.OnceValue(benchmark.HostMetadata)
+
+// runScenario runs cfg and attaches the run metadata to its results.
+func runScenario(ctx context.Context, cfg Config) ([]benchmark.Result, error) {
+	results, err := executeScenario(ctx, cfg)
+	if len(results) > 0 {
+		md := hostMetadata()
+		addr := cfg.Addr
+		if addr == "" {
+			addr = implementations.DefaultAddr
+		}
+		md.RedisVersion = env.ServerVersion(ctx, addr)
+		md.ConfigHash = configHash(cfg)
+		for i := range results {
+			results[i].Metadata = &md
+		}
+	}
+	return results, err
+}
+
+// configHash is a short hash of the scenario settings, leaving out the
+// endpoints, output directories and host measurements that differ between
+// invocations of the same scenario.
+func configHash(cfg Config) string {
+	cfg.Addr, cfg.StandbyAddr = "", ""
+	cfg.Tags = nil
+	cfg.Clock, cfg.Tracer = nil, nil
+	cfg.CurveDir, cfg.ProfileDir, cfg.WorkloadCache, cfg.L1SnapshotDir = "", "", "", ""
+	b, err := json.Marshal(cfg)
 	if err != nil {
-		return err
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
+
+// executeScenario generates the scenario's workload once and runs every
+// strategy against it. On cancellation it returns the results collected so
+// far.
+func executeScenario(ctx context.Context, cfg Config) ([]benchmark.Result, error) {
+	log.Println("==========================================================")
+	log.Printf("--- Starting Scenario: %s ---", cfg.Name)
+	log.Printf("Preparing benchmark with %d operations on %d keys.", cfg.NumOperations, cfg.NumKeys)
+	log.Printf("Concurrency: %d, Read/Write Ratio: %.2f, Value Size: %dB", cfg.Concurrency, cfg.ReadWriteRatio, cfg.ValueSizeBytes)
+
+	if cfg.Failover && cfg.StandbyAddr == "" {
+		log.Println("Skipping scenario: it needs a standby endpoint (-standby-addr).")
+		return nil, nil
+	}
+
+	if cfg.MaxMemory != "" || cfg.MaxMemoryPolicy != "" {
+		restore, err := applyMaxMemory(ctx, cfg.Addr, cfg.MaxMemory, cfg.MaxMemoryPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set server memory limits: %w", err)
+		}
+		defer restore()
+	}
+
+	if cfg.RTT > 0 {
+		target := cfg.Addr
+		if target == "" {
+			target = implementations.DefaultAddr
+		}
+		proxy, err := netproxy.Start(target, cfg.RTT, cfg.RTTJitter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start latency proxy: %w", err)
+		}
+		defer proxy.Close()
+		cfg.Addr = proxy.Addr()
+		log.Printf("Simulating %v RTT (jitter %v) via proxy %s -> %s", cfg.RTT, cfg.RTTJitter, cfg.Addr, target)
+	}
+
+	if err := benchmark.ValidPayload(cfg.Payload); err != nil {
+		return nil, err
+	}
+	if cfg.Serializer != "" {
+		if _, err := serialize.ByName(cfg.Serializer); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.RangeReadFraction > 0 {
+		if cfg.RangeReadBytes <= 0 || cfg.RangeReadBytes > cfg.ValueSizeBytes {
+			return nil, fmt.Errorf("range reads of %d bytes do not fit %d-byte values", cfg.RangeReadBytes, cfg.ValueSizeBytes)
+		}
+		if cfg.Codec != "" {
+			return nil, fmt.Errorf("range reads cannot be validated by the %s codec", cfg.Codec)
+		}
+		if cfg.Serializer != "" {
+			return nil, fmt.Errorf("range reads cannot decode %s records", cfg.Serializer)
+		}
+	}
+	if cfg.RMWFraction > 0 && (cfg.Codec != "" || cfg.Serializer != "") {
+		return nil, fmt.Errorf("read-modify-writes cannot be combined with a codec or serializer")
+	}
+	if cfg.ScanFraction > 0 {
+		if cfg.ScanLimit <= 0 {
+			return nil, fmt.Errorf("scans need a positive key limit, got %d", cfg.ScanLimit)
+		}
+		if cfg.KeyDerivation != "" {
+			return nil, fmt.Errorf("scans cannot match prefixes of %s derived keys", cfg.KeyDerivation)
+		}
+		if cfg.KeyTemplate != "" || cfg.KeyBytes > 0 {
+			return nil, fmt.Errorf("scans cannot match prefixes of templated keys")
+		}
+	}
+	switch cfg.Structure {
+	case "", implementations.StructureString:
+	case implementations.StructureHash:
+		if cfg.RangeReadFraction > 0 || cfg.RMWFraction > 0 || cfg.ScanFraction > 0 {
+			return nil, fmt.Errorf("range reads, read-modify-writes and scans cannot read values stored as hashes")
+		}
+	default:
+		return nil, fmt.Errorf("unknown value structure %q", cfg.Structure)
+	}
+	if cfg.Tenants > 0 {
+		if len(cfg.Mix) > 0 {
+			return nil, fmt.Errorf("tenants cannot be combined with a workload mix")
+		}
+		var err error
+		if cfg.Mix, err = tenantMix(cfg); err != nil {
+			return nil, err
+		}
+	}
+	if len(cfg.Mix) > 0 {
+		if len(cfg.KeyCounts) > 0 || len(cfg.ZipfSkews) > 0 || cfg.StampedeInterval > 0 {
+			return nil, fmt.Errorf("a workload mix cannot be combined with key-count or skew sweeps or stampedes")
+		}
+		if err := workload.ValidateMix(cfg.Mix, cfg.NumKeys); err != nil {
+			return nil, err
+		}
+	}
+
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = defaultSeed
+	}
+	log.Printf("Seed: %d", seed)
+
+	// The workload is generated once so every strategy replays the identical sequence.
+	w := generateWorkload(cfg, seed)
+
+	strategies, err := buildStrategies(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid strategies: %w", err)
+	}
+	if _, err := keyDeriver(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.NoFlush && cfg.KeyPrefix == "" {
+		return nil, fmt.Errorf("-no-flush needs a -key-prefix to clean up by")
+	}
+	if _, err := buildFaults(cfg.Faults, cfg.Addr); err != nil {
+		return nil, err
+	}
+	if cfg.Codec != "" {
+		if _, err := codec.ByName(cfg.Codec); err != nil {
+			return nil, err
+		}
+	}
+	if len(cfg.TTLClasses) > 0 {
+		if err := validateTTLClasses(cfg.TTLClasses); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := parseSLOs(cfg.SLOs); err != nil {
+		return nil, err
+	}
+	if len(cfg.StepRates) > 0 {
+		if err := validateStepLoad(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(cfg.Sweeps) > 0 {
+		var all []benchmark.Result
+		for _, sw := range cfg.Sweeps {
+			results, err := runSweep(ctx, cfg, w, seed, sw)
+			all = append(all, results...)
+			if err != nil || ctx.Err() != nil {
+				return all, err
+			}
+		}
+		return all, nil
+	}
+
+	if len(cfg.LoadFractions) > 0 {
+		return runLoadCurve(ctx, cfg, w, seed)
+	}
+
+	if len(cfg.ConcurrencyLevels) > 0 {
+		return runConcurrencySweep(ctx, cfg, w, seed)
+	}
+
+	if len(cfg.KeyCounts) > 0 {
+		return runKeySweep(ctx, cfg, seed)
+	}
+
+	if len(cfg.ZipfSkews) > 0 {
+		return runSkewSweep(ctx, cfg, seed)
 	}
-	defer client.Close()
 
-	if err := client.Do(ctx, client.B().Flushall().Build()).Error(); err != nil {
-		return fmt.Errorf("failed to flush datastore: %w", err)
+	if len(cfg.StepRates) > 0 {
+		return runStepLoad(ctx, cfg, seed)
 	}
 
-	log.Printf("Pre-populating with %d keys of size %dB...", numKeys, valueSizeBytes)
-	cmds := make(rueidis.Commands, 0, numKeys)
-	value := generateValue(valueSizeBytes)
-	for i := 0; i < numKeys; i++ {
+	if cfg.CanaryFraction > 0 {
+		if len(strategies) != 2 || cfg.CanaryFraction >= 1 || cfg.Concurrency < 2 {
+			return nil, fmt.Errorf("a canary needs a control and a candidate strategy, a fraction below 1 and at least 2 workers")
+		}
+		if _, err := prepareData(ctx, cfg, seed); err != nil {
+			return nil, fmt.Errorf("failed to prepare data: %w", err)
+		}
+		return runCanary(ctx, cfg, w, seed, strategies), nil
+	}
+
+	if cfg.Interop {
+		if _, err := prepareData(ctx, cfg, seed); err != nil {
+			return nil, fmt.Errorf("failed to prepare data: %w", err)
+		}
+		return runInterop(ctx, cfg, w, seed, strategies), nil
+	}
+
+	var results []benchmark.Result
+	for _, ns := range strategies {
+		s := ns.strategy
+		log.Printf("\n--- Running Strategy: %s ---", s.Name())
+		prep, err := prepareData(ctx, cfg, seed)
+		if err != nil {
+			return results, fmt.Errorf("failed to prepare data for strategy %s: %w", s.Name(), err)
+		}
+
+		runner := benchmark.NewRunner(s, w, runnerOptions(cfg, ns, seed))
+		result, err := runner.Run(ctx)
+		if err != nil {
+			log.Printf("Error running benchmark for strategy %s: %v", s.Name(), err)
+			continue
+		}
+		result.Lifecycle.DataPrep = prep
+		if result.L1Snapshot != nil {
+			saveL1Snapshot(cfg, s.Name(), result.L1Snapshot)
+		}
+		results = append(results, result)
+		if result.Incomplete {
+			break
+		}
+	}
+	return results, nil
+}
+
+// runnerOptions derives the runner configuration for one strategy in a scenario.
+func runnerOptions(cfg Config, ns namedStrategy, seed int64) benchmark.Options {
+	opts := benchmark.Options{
+		Concurrency:       cfg.Concurrency,
+		ValueSizeBytes:    cfg.ValueSizeBytes,
+		Seed:              seed,
+		MaxInFlight:       cfg.MaxInFlight[ns.name],
+		TargetRate:        cfg.TargetRate,
+		SlowestN:          cfg.SlowestN,
+		Clock:             cfg.Clock,
+		OpTimeout:         cfg.OpTimeout,
+		Retry:             cfg.Retry,
+		DisableBufferPool: cfg.DisableBufferPool,
+		Payload:           cfg.Payload,
+		VaryValues:        cfg.VaryValues,
+		ProgressInterval:  cfg.ProgressInterval,
+	}
+	if ns.maxInFlight > 0 {
+		opts.MaxInFlight = ns.maxInFlight
+	}
+	if cfg.Serializer != "" {
+		// Validated by runScenario before any runner is built.
+		opts.Serializer, _ = serialize.ByName(cfg.Serializer)
+	}
+	if cfg.TrackStaleness || cfg.ReadYourWrites {
+		// Read-your-writes checks find the worker's write by its stamp.
+		opts.Staleness = benchmark.NewStalenessTracker()
+	}
+	opts.ReadYourWrites = cfg.ReadYourWrites
+	opts.RestartAt = cfg.RestartAt
+	opts.Timeline = cfg.Timeline
+	opts.L1Schedule = cfg.L1Schedule
+	opts.L1ResizeCold = cfg.L1ResizeCold
+	opts.Tracer = cfg.Tracer
+	opts.TraceSampleRate = cfg.TraceSampleRate
+	if cfg.L1Snapshot != "" {
+		opts.DumpL1 = true
+		if cfg.PrewarmL1 {
+			opts.L1Snapshot = loadL1Snapshot(cfg, ns.strategy.Name())
+		}
+	}
+	// Validated by runScenario before any runner is built.
+	opts.KeyDeriver, _ = keyDeriver(cfg)
+	opts.Faults, _ = buildFaults(cfg.Faults, cfg.Addr)
+	if cfg.Codec != "" {
+		opts.Codec, _ = codec.ByName(cfg.Codec)
+		opts.WriterID = codec.WriterID(ns.name)
+	}
+	if cfg.ExternalWriteRate > 0 {
+		opts.External = externalWriters(cfg, ns, opts.KeyDeriver)
+	}
+	if cfg.StampedeInterval > 0 {
+		opts.InvalidateKey = hotKey
+		if opts.KeyDeriver != nil {
+			opts.InvalidateKey = opts.KeyDeriver.Derive(hotKey)
+		}
+		opts.InvalidateInterval = cfg.StampedeInterval
+		opts.TrackFetches = true
+	}
+	for i := range min(cfg.RestartPrewarmKeys, cfg.NumKeys) {
+		// Zipf workloads rank key-0 the most popular.
 		key := fmt.Sprintf("key-%d", i)
-		cmds = append(cmds, client.B().Set().Key(key).Value(value).Build())
+		if opts.KeyDeriver != nil {
+			key = opts.KeyDeriver.Derive(key)
+		}
+		opts.RestartPrewarmKeys = append(opts.RestartPrewarmKeys, key)
+	}
+	opts.KeyClasses = keyClassNames(cfg, seed)
+	opts.RankedKeys = cfg.NumKeys
+	if cfg.ProfileDir != "" {
+		opts.ProfileDir = filepath.Join(cfg.ProfileDir, slug(cfg.Name))
+		opts.ProfileName = slug(ns.name)
+	}
+	return opts
+}
+
+// baseParams returns the strategy parameters shared by every strategy in a scenario.
+func baseParams(cfg Config) implementations.Params {
+	p := implementations.Params{
+		Addr:              cfg.Addr,
+		MemoryBudgetBytes: l1Budget(cfg),
+		ValueSizeBytes:    cfg.ValueSizeBytes,
+	}
+	if cfg.Failover {
+		p.StandbyAddr = cfg.StandbyAddr
 	}
+	p.KeyPrefix = cfg.KeyPrefix
+	p.Structure = cfg.Structure
+	p.CacheTTL = cfg.CSCTTL
+	p.TrackL1Keys = cfg.L1Snapshot != ""
+	if cfg.KeyTemplate != "" {
+		// Broadcast tracking must cover the keys the template renders,
+		// absent ones included.
+		p.BroadcastPrefixes = []string{cfg.KeyPrefix + workload.TemplatePrefix(cfg.KeyTemplate)}
+	}
+	return p
+}
 
-	for _, resp := range client.DoMulti(ctx, cmds...) {
-		if err := resp.Error(); err != nil {
+// strategySpecs returns the scenario's strategy specs.
+func strategySpecs(cfg Config) []string {
+	if len(cfg.Strategies) == 0 {
+		return defaultStrategies
+	}
+	return cfg.Strategies
+}
+
+func buildStrategies(cfg Config) ([]namedStrategy, error) {
+	specs := strategySpecs(cfg)
+	strategies := make([]namedStrategy, 0, len(specs))
+	for _, spec := range specs {
+		ns, err := buildStrategy(cfg, spec)
+		if err != nil {
+			return nil, err
+		}
+		strategies = append(strategies, ns)
+	}
+	return strategies, nil
+}
+
+// buildStrategy builds a fresh, uninitialized strategy from one spec.
+func buildStrategy(cfg Config, spec string) (namedStrategy, error) {
+	name, params, err := parseStrategySpec(spec, baseParams(cfg))
+	if err != nil {
+		return namedStrategy{}, err
+	}
+	s, err := implementations.New(name, params)
+	if err != nil {
+		return namedStrategy{}, err
+	}
+	return namedStrategy{name: name, strategy: s, spec: spec, maxInFlight: params.MaxInFlight}, nil
+}
+
+// parseStrategySpec parses "name" or "name:knob=value,knob=value" into a
+// registry name and Params derived from base.
+func parseStrategySpec(spec string, base implementations.Params) (string, implementations.Params, error) {
+	name, knobs, _ := strings.Cut(spec, ":")
+	params := base
+	if knobs == "" {
+		return name, params, nil
+	}
+	for _, kv := range strings.Split(knobs, ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return "", params, fmt.Errorf("invalid knob %q in %q, want knob=value", kv, spec)
+		}
+		if err := params.Set(k, v); err != nil {
+			return "", params, err
+		}
+	}
+	return name, params, nil
+}
+
+// prepareData populates the datastore for one run, returning how long it
+// took.
+func prepareData(ctx context.Context, cfg Config, seed int64) (time.Duration, error) {
+	start := time.Now()
+	err := prepareDataset(ctx, cfg, seed)
+	return time.Since(start), err
+}
+
+func prepareDataset(ctx context.Context, cfg Config, seed int64) error {
+	keys := make([]string, cfg.NumKeys)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	deriver, err := keyDeriver(cfg)
+	if err != nil {
+		return err
+	}
+	if deriver != nil {
+		for i := range keys {
+			keys[i] = deriver.Derive(keys[i])
+		}
+	}
+	value := benchmark.GeneratePayload(cfg.Payload, rand.New(rand.NewSource(seed)), cfg.ValueSizeBytes)
+	ttls := keyTTLs(cfg, seed)
+	// Identified before encoding, whose header carries the time.
+	id := datasetID(cfg.keyspace(), keys, cfg.Serializer+"\x00"+cfg.Codec+"\x00"+value, ttls)
+	if cfg.Serializer != "" {
+		s, _ := serialize.ByName(cfg.Serializer)
+		rec := benchmark.SampleRecord(rand.New(rand.NewSource(seed)), value)
+		b, err := s.Marshal(&rec)
+		if err != nil {
 			return err
 		}
+		value = string(b)
+	}
+	if cfg.Codec != "" {
+		// Validated by runScenario before any data is prepared.
+		c, _ := codec.ByName(cfg.Codec)
+		value = string(c.Encode(codec.Header{WriterID: codec.WriterID("prepare"), Timestamp: time.Now()}, []byte(value)))
+	}
+	if cfg.Failover {
+		// The standby is warm: it starts with the same data as the primary.
+		if err := prepareKeys(ctx, cfg.StandbyAddr, cfg.keyspace(), cfg.populateOptions(), keys, value, ttls); err != nil {
+			return fmt.Errorf("failed to prepare standby: %w", err)
+		}
+	}
+	// Remembered datasets are strings, so hashes are always repopulated.
+	reuse := !cfg.NoDataReuse && !cfg.Failover && cfg.Structure != implementations.StructureHash
+	if reuse {
+		if reused, err := reuseData(ctx, cfg, seed, id, keys, value, ttls); reused || err != nil {
+			return err
+		}
+	}
+	if err := prepareKeys(ctx, cfg.Addr, cfg.keyspace(), cfg.populateOptions(), keys, value, ttls); err != nil {
+		return err
+	}
+	if reuse {
+		rememberData(ctx, cfg, seed, id)
 	}
-	log.Println("Data preparation complete.")
 	return nil
 }
 
-func generateValue(size int) string {
-	b := make([]byte, size)
-	rand.Read(b)
-	return fmt.Sprintf("%x", b)
+// keyspace says which keys of a server the benchmark owns.
+type keyspace struct {
+	// prefix namespaces every key the benchmark stores.
+	prefix string
+	// noFlush replaces FLUSHALL with deleting only the keys under prefix.
+	noFlush bool
+}
+
+func (cfg Config) keyspace() keyspace {
+	return keyspace{prefix: cfg.KeyPrefix, noFlush: cfg.NoFlush}
+}
+
+// prepareKeys clears the benchmark's keys from the datastore at addr and
+// populates it with the given keys, all set to value. If ttls is not nil,
+// keys[i] expires after ttls[i] unless that is zero. When resuming, keys are
+// not cleared and only those not yet holding a value are set.
+func prepareKeys(ctx context.Context, addr string, ks keyspace, pop populateOptions, keys []string, value string, ttls []time.Duration) error {
+	log.Println("Preparing datastore for benchmark...")
+	if addr == "" {
+		addr = implementations.DefaultAddr
+	}
+	client, err := rueidis.NewClient(rueidis.ClientOption{InitAddress: []string{addr}})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if !pop.resume {
+		if err := clearBenchmarkKeys(ctx, client, ks.prefix, ks.noFlush); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Pre-populating with %d keys of size %dB...", len(keys), len(value))
+	if err := populate(ctx, client, pop, keys, value, ttls); err != nil {
+		return err
+	}
+	log.Println("Data preparation complete.")
+	return nil
 }
 
-func printFinalComparison(allResults map[string][]benchmark.Result) {
+func printFinalComparison(allResults []scenarioResults, percentiles []float64) {
 	log.Println("\n\n--- Final Benchmark Comparison ---")
 
-	for scenarioName, results := range allResults {
-		log.Printf("\n--- Scenario: %s ---", scenarioName)
+	for _, sr := range allResults {
+		results := sr.results
+		log.Printf("\n--- Scenario: %s ---", sr.name)
+		if len(results) > 0 && results[0].ServerVersion != "" {
+			log.Printf("Server: %s", results[0].ServerVersion)
+		}
+		if len(results) > 0 && results[0].Metadata != nil {
+			md := results[0].Metadata
+			log.Printf("Host: %s, %s, %d CPUs (GOMAXPROCS %d), %.1f GB; %s; config %s",
+				md.OS, cmp.Or(md.CPUModel, "unknown CPU"), md.NumCPU, md.GOMAXPROCS, float64(md.MemoryBytes)/(1<<30), md.GoVersion, md.ConfigHash)
+		}
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.AlignRight|tabwriter.Debug)
-		fmt.Fprintln(w, "Strategy\tOps/sec\tHit Rate (%)\tAvg Latency (ms)\tP95 Latency (ms)\t")
+		fmt.Fprint(w, "Strategy\tOps/sec\tHit Rate (%)\tAvg Latency (ms)\t")
+		for _, p := range percentiles {
+			fmt.Fprintf(w, "%s Latency (ms)\t", percentileLabel(p))
+		}
+		fmt.Fprintln(w, "Backend Req/1k Ops\tStale Reads\tLost Writes\tAlloc/Op (KB)\tAllocs/Op\tGC Pause (ms)\tHeap Growth (MB)\tSLO\t")
 
 		for _, r := range results {
-			sort.Slice(r.Latencies, func(i, j int) bool {
-				return r.Latencies[i] < r.Latencies[j]
-			})
+			name := r.StrategyName
+			if r.Incomplete {
+				name += " (INCOMPLETE)"
+			}
+			if len(r.Latencies) == 0 {
+				fmt.Fprintf(w, "%s\t%s\n", name, strings.Repeat("-\t", 11+len(percentiles)))
+				continue
+			}
 
-			var p95Latency time.Duration
-			if len(r.Latencies) > 20 {
-				p95Index := int(float64(len(r.Latencies)) * 0.95)
-				p95Latency = r.Latencies[p95Index]
+			staleReads := "-"
+			if r.StalenessTracked {
+				staleReads = fmt.Sprint(r.StaleReads)
 			}
+			sortLatencies(r.Latencies)
+			avgLatency, _ := latencyStats(r.Latencies)
+			fmt.Fprintf(w, "%s\t%.2f\t%.2f\t%.4f\t", name, r.OpsPerSecond, r.HitRate*100, ms(avgLatency))
+			for _, p := range percentiles {
+				fmt.Fprintf(w, "%.4f\t", ms(nearestRank(r.Latencies, p)))
+			}
+			slo, _ := checkSLOs(sr.slos, r)
+			fmt.Fprintf(w, "%.1f\t%s\t%d\t%.1f\t%.1f\t%.2f\t%+.1f\t%s\t\n", r.BackendRequestsPer1kOps, staleReads, r.LostWrites,
+				r.Memory.BytesPerOp/1024, r.Memory.AllocsPerOp, ms(r.Memory.GCPause), float64(r.Memory.HeapGrowth())/(1<<20), slo)
+		}
+		w.Flush()
+		printResponseTimeSummary(results, percentiles)
+		printKeyClassSummary(results)
+		printSerializationSummary(results)
+		printFaultSummary(results)
+		printLifecycleSummary(results)
+		printConnectionSummary(results)
+		printReadYourWritesSummary(results)
+		printRestartSummary(results)
+		printTimelineSummary(results)
+		printL1PhaseSummary(results)
+		printFairnessSummary(results)
+	}
+}
+
+// printResponseTimeSummary lists, per open-loop run, its response-time
+// percentiles from the intended start, which the table's service times
+// understate when operations queue behind a stall, and per run with
+// retries the retries behind the difference.
+func printResponseTimeSummary(results []benchmark.Result, percentiles []float64) {
+	for _, r := range results {
+		if len(r.ResponseTimes) == 0 {
+			continue
+		}
+		sortLatencies(r.ResponseTimes)
+		parts := make([]string, len(percentiles))
+		for i, p := range percentiles {
+			parts[i] = fmt.Sprintf("%s %.4f ms", percentileLabel(p), ms(nearestRank(r.ResponseTimes, p)))
+		}
+		retries := ""
+		if r.RetriedOps > 0 {
+			retries = fmt.Sprintf(" (%d retries over %d ops, %d recovered)", r.Retries, r.RetriedOps, r.RetryRecoveries)
+		}
+		log.Printf("  %s: response time %s%s", r.StrategyName, strings.Join(parts, ", "), retries)
+	}
+}
 
-			var totalLatency time.Duration
-			for _, lat := range r.Latencies {
-				totalLatency += lat
+// printKeyClassSummary lists, per strategy, the hit rate and p99 latency of
+// each key class, such as each tenant's keys.
+func printKeyClassSummary(results []benchmark.Result) {
+	for _, r := range results {
+		if len(r.KeyClasses) == 0 {
+			continue
+		}
+		parts := make([]string, len(r.KeyClasses))
+		for i, c := range r.KeyClasses {
+			parts[i] = fmt.Sprintf("%s %.2f%% hits, p99 %.4f ms", c.Name, c.HitRate*100, ms(c.P99Latency))
+		}
+		log.Printf("  %s: %s", r.StrategyName, strings.Join(parts, "; "))
+	}
+}
+
+// printSerializationSummary breaks out, per strategy, the part of its
+// latency spent serializing records.
+func printSerializationSummary(results []benchmark.Result) {
+	for _, r := range results {
+		if s := r.Serialization; s != nil {
+			log.Printf("  %s: %s encode %v, decode %v on average", r.StrategyName, s.Format, s.AvgEncode(), s.AvgDecode())
+		}
+	}
+}
+
+// printFaultSummary lists, per strategy, the errors, recovery time and stale
+// reads that followed each injected fault.
+func printFaultSummary(results []benchmark.Result) {
+	for _, r := range results {
+		for _, f := range r.Faults {
+			if f.Err != nil {
+				log.Printf("  %s: fault %s not injected: %v", r.StrategyName, f.Name, f.Err)
+				continue
 			}
-			avgLatency := totalLatency / time.Duration(len(r.Latencies))
+			log.Printf("  %s: after %s at %v: %d errors, recovered in %v, %d stale reads",
+				r.StrategyName, f.Name, f.At.Round(time.Millisecond), f.Errors, f.RecoveryTime.Round(time.Millisecond), f.StaleReads)
+		}
+	}
+}
 
-			fmt.Fprintf(w, "%s\t%.2f\t%.2f\t%.4f\t%.4f\t\n",
-				r.StrategyName,
-				r.OpsPerSecond,
-				r.HitRate*100,
-				float64(avgLatency.Microseconds())/1000.0,
-				float64(p95Latency.Microseconds())/1000.0,
-			)
+// printLifecycleSummary lists, per strategy, the time spent outside the
+// steady state the table measures: preparing data, Init, warming up,
+// draining and Close.
+func printLifecycleSummary(results []benchmark.Result) {
+	for _, r := range results {
+		l := r.Lifecycle
+		prewarm := ""
+		if r.PrewarmedL1Keys > 0 {
+			prewarm = fmt.Sprintf(", L1 pre-warm %v (%d keys)", l.Prewarm.Round(time.Millisecond), r.PrewarmedL1Keys)
 		}
-		w.Flush()
+		log.Printf("  %s: data prep %v, init %v%s, warm-up %v, drain %v, close %v", r.StrategyName,
+			l.DataPrep.Round(time.Millisecond), l.Init.Round(time.Millisecond), prewarm, l.Warmup.Round(time.Millisecond),
+			r.DrainDuration.Round(time.Millisecond), l.Close.Round(time.Millisecond))
+	}
+}
+
+// printConnectionSummary lists, per strategy, the Redis connections and
+// in-flight cap it ran with, so differences in client tuning are visible
+// next to the comparison.
+func printConnectionSummary(results []benchmark.Result) {
+	for _, r := range results {
+		if c := r.Connections; c != nil {
+			inFlight := "uncapped"
+			if r.MaxInFlight > 0 {
+				inFlight = fmt.Sprint(r.MaxInFlight)
+			}
+			log.Printf("  %s: %s open connections, %d pipelined per node, blocking pool %d, max in flight %s",
+				r.StrategyName, c.OpenString(), c.Pipelined, c.BlockingPoolSize, inFlight)
+		}
+	}
+}
+
+// printReadYourWritesSummary lists, per strategy that was checked, how
+// often a worker failed to read its own write back.
+func printReadYourWritesSummary(results []benchmark.Result) {
+	for _, r := range results {
+		if r.ReadYourWritesChecks > 0 {
+			log.Printf("  %s: %d read-your-writes violations in %d checks (%.3f%%)", r.StrategyName,
+				r.ReadYourWritesViolations, r.ReadYourWritesChecks,
+				float64(r.ReadYourWritesViolations)/float64(r.ReadYourWritesChecks)*100)
+		}
+	}
+}
+
+// printRestartSummary lists, per strategy restarted mid-run, its downtime,
+// hit-rate recovery and the backend traffic of its cold start.
+func printRestartSummary(results []benchmark.Result) {
+	for _, r := range results {
+		if rs := r.Restart; rs != nil {
+			log.Printf("  %s: restart %s", r.StrategyName, rs)
+		}
+	}
+}
+
+// printL1PhaseSummary lists, per run with an L1 budget schedule, the hit
+// rate and p99 of each phase.
+func printL1PhaseSummary(results []benchmark.Result) {
+	for _, r := range results {
+		for _, p := range r.L1Phases {
+			log.Printf("  %s: L1 phase %s", r.StrategyName, p)
+		}
+	}
+}
+
+// printFairnessSummary lists the runs whose workers were served unevenly,
+// which their aggregate throughput hides.
+func printFairnessSummary(results []benchmark.Result) {
+	for _, r := range results {
+		if r.Fairness.Starved() {
+			log.Printf("  %s: workers starved, fairness %s", r.StrategyName, r.Fairness)
+		}
+	}
+}
+
+// latencyStats returns the mean and p95 of latencies, which it sorts in
+// place to find the p95.
+func latencyStats(latencies []time.Duration) (avg, p95 time.Duration) {
+	if len(latencies) == 0 {
+		return 0, 0
+	}
+	sortLatencies(latencies)
+
+	var total time.Duration
+	for _, lat := range latencies {
+		total += lat
 	}
+	return total / time.Duration(len(latencies)), nearestRank(latencies, 95)
 }
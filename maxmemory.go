@@ -0,0 +1,90 @@
+package main
+
+import (
+	"caching-benchmark/implementations"
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/redis/rueidis"
+)
+
+// applyMaxMemory sets maxmemory and maxmemory-policy on the server at addr
+// with CONFIG SET, leaving either unchanged when empty. The returned function
+// restores the previous values and logs how many keys the server evicted in
+// between.
+func applyMaxMemory(ctx context.Context, addr, maxMemory, policy string) (restore func(), err error) {
+	if addr == "" {
+		addr = implementations.DefaultAddr
+	}
+	client, err := rueidis.NewClient(rueidis.ClientOption{InitAddress: []string{addr}, DisableCache: true})
+	if err != nil {
+		return nil, err
+	}
+
+	settings := map[string]string{"maxmemory": maxMemory, "maxmemory-policy": policy}
+	previous := make(map[string]string)
+	for param, value := range settings {
+		if value == "" {
+			continue
+		}
+		old, err := client.Do(ctx, client.B().ConfigGet().Parameter(param).Build()).AsStrMap()
+		if err != nil {
+			client.Close()
+			return nil, fmt.Errorf("CONFIG GET %s: %w", param, err)
+		}
+		previous[param] = old[param]
+	}
+	set := func(values map[string]string) error {
+		for param, value := range values {
+			if err := client.Do(ctx, client.B().ConfigSet().ParameterValue().ParameterValue(param, value).Build()).Error(); err != nil {
+				return fmt.Errorf("CONFIG SET %s %s: %w", param, value, err)
+			}
+		}
+		return nil
+	}
+	applied := make(map[string]string)
+	for param := range previous {
+		applied[param] = settings[param]
+	}
+	evictedBefore := evictedKeys(ctx, client)
+	if err := set(applied); err != nil {
+		set(previous)
+		client.Close()
+		return nil, err
+	}
+	log.Printf("Server memory limits: maxmemory %s, maxmemory-policy %s", displayOr(maxMemory, previous["maxmemory"]), displayOr(policy, previous["maxmemory-policy"]))
+
+	return func() {
+		// Restoring must not be skipped because the scenario was interrupted.
+		ctx := context.WithoutCancel(ctx)
+		if n := evictedKeys(ctx, client); n >= 0 && evictedBefore >= 0 {
+			log.Printf("Server evicted %d keys during the scenario", n-evictedBefore)
+		}
+		if err := set(previous); err != nil {
+			log.Printf("Failed to restore server memory limits: %v", err)
+		}
+		client.Close()
+	}, nil
+}
+
+// evictedKeys returns the server's evicted_keys counter, or -1 if unavailable.
+func evictedKeys(ctx context.Context, client rueidis.Client) int64 {
+	info, err := client.Do(ctx, client.B().Info().Section("stats").Build()).ToString()
+	if err != nil {
+		return -1
+	}
+	n, err := strconv.ParseInt(implementations.ParseInfo(info)["evicted_keys"], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func displayOr(value, fallback string) string {
+	if value == "" {
+		return fallback + " (unchanged)"
+	}
+	return value
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"caching-benchmark/benchmark"
+	"caching-benchmark/implementations"
+	"caching-benchmark/workload"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// runMigrateAnalysis replays a production trace against the current and the
+// proposed strategy and writes a before/after report in Markdown.
+func runMigrateAnalysis(args []string) error {
+	fs := flag.NewFlagSet("migrate-analysis", flag.ExitOnError)
+	tracePath := fs.String("trace", "", "path to the operation trace (required)")
+	current := fs.String("current", "", "current strategy spec: name[:knob=value,...] (required)")
+	proposed := fs.String("proposed", "", "proposed strategy spec: name[:knob=value,...] (required)")
+	addr := fs.String("addr", implementations.DefaultAddr, "Redis address")
+	concurrency := fs.Int("concurrency", 64, "number of concurrent workers")
+	valueSize := fs.Int("value-size", 64, "value size in bytes used to pre-populate and write")
+	outPath := fs.String("out", "", "write the report to this file instead of stdout")
+	keyPrefix := fs.String("key-prefix", "", "namespace every benchmark key under this prefix")
+	noFlush := fs.Bool("no-flush", false, "delete only keys under -key-prefix instead of running FLUSHALL")
+	yes := fs.Bool("yes", false, "FLUSHALL a non-empty server without asking")
+	fs.Parse(args)
+	if *tracePath == "" || *current == "" || *proposed == "" {
+		fs.Usage()
+		return fmt.Errorf("-trace, -current and -proposed are required")
+	}
+
+	f, err := os.Open(*tracePath)
+	if err != nil {
+		return err
+	}
+	ops, err := workload.LoadTrace(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	keys := workload.Keys(ops)
+	log.Printf("Loaded trace with %d operations on %d keys", len(ops), len(keys))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg := Config{Name: "migrate-analysis", Addr: *addr, Concurrency: *concurrency, ValueSizeBytes: *valueSize, KeyPrefix: *keyPrefix, NoFlush: *noFlush}
+	if *noFlush && *keyPrefix == "" {
+		return fmt.Errorf("-no-flush needs a -key-prefix to clean up by")
+	}
+	if !*noFlush {
+		if err := confirmFlush(ctx, *addr, *yes); err != nil {
+			return err
+		}
+	}
+	if cfg.KeyPrefix != "" {
+		deriver := workload.WithPrefix(nil, cfg.KeyPrefix)
+		for i := range keys {
+			keys[i] = deriver.Derive(keys[i])
+		}
+	}
+	var results [2]benchmark.Result
+	for i, spec := range []string{*current, *proposed} {
+		name, params, err := parseStrategySpec(spec, baseParams(cfg))
+		if err != nil {
+			return err
+		}
+		s, err := implementations.New(name, params)
+		if err != nil {
+			return err
+		}
+		value := benchmark.GeneratePayload(benchmark.PayloadHex, rand.New(rand.NewSource(defaultSeed)), *valueSize)
+		if err := prepareKeys(ctx, cfg.Addr, cfg.keyspace(), cfg.populateOptions(), keys, value, nil); err != nil {
+			return err
+		}
+		opts := runnerOptions(cfg, namedStrategy{name: name, strategy: s, maxInFlight: params.MaxInFlight}, defaultSeed)
+		opts.Staleness = benchmark.NewStalenessTracker()
+		results[i], err = benchmark.NewRunner(s, ops, opts).Run(ctx)
+		if err != nil {
+			return err
+		}
+		results[i].StrategyName = spec
+	}
+
+	out := io.Writer(os.Stdout)
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	writeMigrationReport(out, *tracePath, len(ops), len(keys), results[0], results[1])
+	return nil
+}
+
+func writeMigrationReport(out io.Writer, trace string, numOps, numKeys int, before, after benchmark.Result) {
+	avgBefore, p95Before := latencyStats(before.Latencies)
+	avgAfter, p95After := latencyStats(after.Latencies)
+
+	fmt.Fprintf(out, "# Caching migration analysis\n\n")
+	fmt.Fprintf(out, "Trace `%s`: %d operations over %d keys.\n\n", trace, numOps, numKeys)
+	fmt.Fprintf(out, "- **Current:** `%s`\n- **Proposed:** `%s`\n\n", before.StrategyName, after.StrategyName)
+	if before.Incomplete || after.Incomplete {
+		fmt.Fprintf(out, "> **Note:** at least one run was interrupted; figures are partial.\n\n")
+	}
+
+	fmt.Fprintf(out, "| Metric | Current | Proposed | Change |\n|---|---:|---:|---:|\n")
+	fmt.Fprintf(out, "| L1 hit rate | %.2f%% | %.2f%% | %+.2f pts |\n", before.HitRate*100, after.HitRate*100, (after.HitRate-before.HitRate)*100)
+	fmt.Fprintf(out, "| Redis requests / 1k ops | %.1f | %.1f | %s |\n", before.BackendRequestsPer1kOps, after.BackendRequestsPer1kOps, percentChange(before.BackendRequestsPer1kOps, after.BackendRequestsPer1kOps))
+	fmt.Fprintf(out, "| Throughput (ops/sec) | %.0f | %.0f | %s |\n", before.OpsPerSecond, after.OpsPerSecond, percentChange(before.OpsPerSecond, after.OpsPerSecond))
+	fmt.Fprintf(out, "| Avg latency (ms) | %.4f | %.4f | %s |\n", ms(avgBefore), ms(avgAfter), percentChange(ms(avgBefore), ms(avgAfter)))
+	fmt.Fprintf(out, "| P95 latency (ms) | %.4f | %.4f | %s |\n", ms(p95Before), ms(p95After), percentChange(ms(p95Before), ms(p95After)))
+	fmt.Fprintf(out, "| Stale reads | %d | %d | %+d |\n", before.StaleReads, after.StaleReads, after.StaleReads-before.StaleReads)
+	fmt.Fprintf(out, "| Errors | %d | %d | %+d |\n\n", before.TotalErrors, after.TotalErrors, after.TotalErrors-before.TotalErrors)
+
+	fmt.Fprintf(out, "## Summary\n\n")
+	if before.BackendRequestsPer1kOps > 0 {
+		fmt.Fprintf(out, "- Redis load changes by %s with the proposed strategy.\n", percentChange(before.BackendRequestsPer1kOps, after.BackendRequestsPer1kOps))
+	}
+	switch {
+	case after.StaleReads > before.StaleReads:
+		fmt.Fprintf(out, "- **Staleness risk increases:** %d more reads returned data older than a completed write.\n", after.StaleReads-before.StaleReads)
+	case after.StaleReads < before.StaleReads:
+		fmt.Fprintf(out, "- Staleness risk decreases: %d fewer stale reads.\n", before.StaleReads-after.StaleReads)
+	default:
+		fmt.Fprintf(out, "- No change in observed stale reads.\n")
+	}
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000.0
+}
+
+func percentChange(before, after float64) string {
+	if before == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%+.1f%%", (after-before)/before*100)
+}
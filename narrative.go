@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"caching-benchmark/benchmark"
+)
+
+// readHeavyRatio is the read ratio from which a scenario counts as
+// read-heavy in the narrative summary; below it, as write-heavy.
+const readHeavyRatio = 0.9
+
+// narrativeMinRatio is the smallest difference, as a ratio, the summary
+// calls out; smaller ones are reported as comparable.
+const narrativeMinRatio = 1.1
+
+// scenarioGroup names the class of scenarios a result belongs to.
+func scenarioGroup(sr scenarioResults) string {
+	if sr.readRatio >= readHeavyRatio {
+		return "read-heavy scenarios"
+	}
+	return "write-heavy scenarios"
+}
+
+// narrativeSummary describes the campaign's results in a few sentences,
+// templated from the computed metrics: a headline comparing each pair of
+// strategies that ran side by side, by scenario class, followed by one
+// finding per scenario and any correctness problems.
+func narrativeSummary(allResults []scenarioResults) []string {
+	var lines []string
+	lines = append(lines, pairFindings(allResults)...)
+	for _, sr := range allResults {
+		if line, ok := scenarioFinding(sr); ok {
+			lines = append(lines, line)
+		}
+	}
+	for _, sr := range allResults {
+		for _, r := range sr.results {
+			if r.StaleReads > 0 {
+				lines = append(lines, fmt.Sprintf("%s returned %d stale reads in %s.", r.StrategyName, r.StaleReads, sr.name))
+			}
+			if rate := errorRate(r); rate >= 0.005 {
+				lines = append(lines, fmt.Sprintf("%s failed %.1f%% of operations in %s.", r.StrategyName, rate*100, sr.name))
+			}
+			if r.LostWrites > 0 {
+				lines = append(lines, fmt.Sprintf("%s lost %d writes in %s.", r.StrategyName, r.LostWrites, sr.name))
+			}
+		}
+	}
+	return lines
+}
+
+// pairRatios accumulates the log-ratios of one strategy pair's metrics
+// across the scenarios of one group.
+type pairRatios struct {
+	scenarios      int
+	logOps, logP99 float64
+	first, second  string
+	group          string
+}
+
+// pairFindings compares every pair of strategies that ran in the same
+// scenarios, by the geometric mean of their throughput and p99 ratios per
+// scenario group.
+func pairFindings(allResults []scenarioResults) []string {
+	pairs := make(map[[2]string]*pairRatios)
+	var order [][2]string
+	for _, sr := range allResults {
+		results := completedResults(sr.results)
+		for i := range results {
+			for j := i + 1; j < len(results); j++ {
+				a, b := results[i], results[j]
+				if a.StrategyName > b.StrategyName {
+					a, b = b, a
+				}
+				p99a, p99b := nearestRank(a.Latencies, 99), nearestRank(b.Latencies, 99)
+				if a.OpsPerSecond <= 0 || b.OpsPerSecond <= 0 || p99a <= 0 || p99b <= 0 {
+					continue
+				}
+				group := scenarioGroup(sr)
+				key := [2]string{a.StrategyName + "\x00" + b.StrategyName, group}
+				p, ok := pairs[key]
+				if !ok {
+					p = &pairRatios{first: a.StrategyName, second: b.StrategyName, group: group}
+					pairs[key] = p
+					order = append(order, key)
+				}
+				p.scenarios++
+				p.logOps += math.Log(a.OpsPerSecond / b.OpsPerSecond)
+				p.logP99 += math.Log(float64(p99a) / float64(p99b))
+			}
+		}
+	}
+
+	var lines []string
+	for _, key := range order {
+		p := pairs[key]
+		ops := math.Exp(p.logOps / float64(p.scenarios))
+		p99 := math.Exp(p.logP99 / float64(p.scenarios))
+		// Lead with the faster strategy.
+		fast, slow := p.first, p.second
+		if ops < 1 {
+			fast, slow, ops, p99 = slow, fast, 1/ops, 1/p99
+		}
+		in := fmt.Sprintf("in %s", p.group)
+		if p.scenarios == 1 {
+			in = fmt.Sprintf("in the one %s they shared", strings.TrimSuffix(p.group, "s"))
+		}
+		var b strings.Builder
+		if ops < narrativeMinRatio {
+			fmt.Fprintf(&b, "%s and %s delivered comparable throughput %s", fast, slow, in)
+			switch {
+			case p99 >= narrativeMinRatio:
+				fmt.Fprintf(&b, ", with %s showing %.1f× lower p99", slow, p99)
+			case 1/p99 >= narrativeMinRatio:
+				fmt.Fprintf(&b, ", with %s showing %.1f× lower p99", fast, 1/p99)
+			default:
+				b.WriteString(" and p99")
+			}
+		} else {
+			fmt.Fprintf(&b, "%s delivered %.1f× the throughput of %s %s", fast, ops, slow, in)
+			switch {
+			case p99 >= narrativeMinRatio:
+				fmt.Fprintf(&b, " but showed %.1f× higher p99", p99)
+			case 1/p99 >= narrativeMinRatio:
+				fmt.Fprintf(&b, " with %.1f× lower p99", 1/p99)
+			default:
+				b.WriteString(" at a similar p99")
+			}
+		}
+		if p.scenarios > 1 {
+			fmt.Fprintf(&b, " (geometric mean over %d scenarios)", p.scenarios)
+		}
+		b.WriteString(".")
+		lines = append(lines, b.String())
+	}
+	return lines
+}
+
+// scenarioFinding names the scenario's throughput and p99 leaders.
+func scenarioFinding(sr scenarioResults) (string, bool) {
+	results := completedResults(sr.results)
+	if len(results) < 2 {
+		return "", false
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].OpsPerSecond > results[j].OpsPerSecond })
+	best, next := results[0], results[1]
+	line := fmt.Sprintf("In %s, %s led at %.0f ops/sec", sr.name, best.StrategyName, best.OpsPerSecond)
+	if next.OpsPerSecond > 0 {
+		line += fmt.Sprintf(" (%.1f× %s)", best.OpsPerSecond/next.OpsPerSecond, next.StrategyName)
+	}
+	lowest := best
+	for _, r := range results {
+		if nearestRank(r.Latencies, 99) < nearestRank(lowest.Latencies, 99) {
+			lowest = r
+		}
+	}
+	if lowest.StrategyName == best.StrategyName {
+		line += fmt.Sprintf(" and also had the lowest p99 (%.3f ms).", ms(nearestRank(best.Latencies, 99)))
+	} else {
+		line += fmt.Sprintf(", while %s had the lowest p99 (%.3f ms).", lowest.StrategyName, ms(nearestRank(lowest.Latencies, 99)))
+	}
+	return line, true
+}
+
+// completedResults returns the results of runs that finished their
+// workload, with their latencies sorted in place.
+func completedResults(results []benchmark.Result) []benchmark.Result {
+	var done []benchmark.Result
+	for _, r := range results {
+		if !r.Incomplete && len(r.Latencies) > 0 {
+			sortLatencies(r.Latencies)
+			done = append(done, r)
+		}
+	}
+	return done
+}
+
+// printNarrativeSummary logs the narrative summary and, if path is set,
+// writes it there as Markdown.
+func printNarrativeSummary(allResults []scenarioResults, path string) error {
+	lines := narrativeSummary(allResults)
+	if len(lines) == 0 {
+		return nil
+	}
+	log.Println("\n--- Summary ---")
+	for _, line := range lines {
+		log.Println(line)
+	}
+	if path == "" {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("## Summary\n\n")
+	for _, line := range lines {
+		b.WriteString("- " + line + "\n")
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
@@ -0,0 +1,168 @@
+// Package netproxy provides a local TCP proxy that delays traffic, so
+// benchmarks against a local Redis can model a network round trip.
+package netproxy
+
+import (
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// Proxy forwards connections to a target address, delaying every chunk of
+// data in each direction by half the configured round-trip time plus jitter.
+// Delays never reorder data within a connection.
+type Proxy struct {
+	listener net.Listener
+	target   string
+	oneWay   time.Duration
+	jitter   time.Duration
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// Start listens on a free local port and proxies to target with the given
+// round-trip time. Each direction adds up to jitter/2 of uniform random delay.
+func Start(target string, rtt, jitter time.Duration) (*Proxy, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	p := &Proxy{
+		listener: l,
+		target:   target,
+		oneWay:   rtt / 2,
+		jitter:   jitter / 2,
+		conns:    make(map[net.Conn]struct{}),
+	}
+	p.wg.Add(1)
+	go p.accept()
+	return p, nil
+}
+
+// Addr returns the address clients should connect to.
+func (p *Proxy) Addr() string {
+	return p.listener.Addr().String()
+}
+
+// Close stops accepting connections, closes the open ones and waits for
+// their forwarding goroutines to exit.
+func (p *Proxy) Close() {
+	p.mu.Lock()
+	p.closed = true
+	for c := range p.conns {
+		c.Close()
+	}
+	p.mu.Unlock()
+	p.listener.Close()
+	p.wg.Wait()
+}
+
+func (p *Proxy) accept() {
+	defer p.wg.Done()
+	for {
+		client, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		server, err := net.Dial("tcp", p.target)
+		if err != nil {
+			log.Printf("netproxy: failed to dial %s: %v", p.target, err)
+			client.Close()
+			continue
+		}
+		if !p.track(client, server) {
+			return
+		}
+		p.wg.Add(2)
+		go p.pipe(server, client)
+		go p.pipe(client, server)
+	}
+}
+
+// track registers a connection pair, or closes it if the proxy is closing.
+func (p *Proxy) track(conns ...net.Conn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		for _, c := range conns {
+			c.Close()
+		}
+		return false
+	}
+	for _, c := range conns {
+		p.conns[c] = struct{}{}
+	}
+	return true
+}
+
+// chunk is data read from one side, to be written to the other at due.
+type chunk struct {
+	data []byte
+	due  time.Time
+}
+
+// pipe copies src to dst, holding each chunk until its due time. On EOF or
+// error both connections are closed, which also ends the opposite pipe.
+func (p *Proxy) pipe(dst, src net.Conn) {
+	defer p.wg.Done()
+	queue := make(chan chunk, 1024)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		failed := false
+		for c := range queue {
+			if failed {
+				continue // drain so the reader never blocks
+			}
+			time.Sleep(time.Until(c.due))
+			if _, err := dst.Write(c.data); err != nil {
+				failed = true
+				src.Close()
+			}
+		}
+	}()
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var last time.Time
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			due := time.Now().Add(p.oneWay)
+			if p.jitter > 0 {
+				due = due.Add(time.Duration(rng.Int63n(int64(p.jitter))))
+			}
+			// Jitter must not reorder bytes within the stream.
+			if due.Before(last) {
+				due = last
+			}
+			last = due
+			queue <- chunk{data: append([]byte(nil), buf[:n]...), due: due}
+		}
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				log.Printf("netproxy: read failed: %v", err)
+			}
+			break
+		}
+	}
+	close(queue)
+	<-done
+	p.untrack(dst, src)
+}
+
+func (p *Proxy) untrack(conns ...net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range conns {
+		c.Close()
+		delete(p.conns, c)
+	}
+}
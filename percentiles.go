@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultPercentiles are the latency percentiles reported when -percentiles
+// is not set; 100 reports the maximum.
+const defaultPercentiles = "50,90,95,99,99.9,100"
+
+// parsePercentiles parses a comma-separated list of percentiles in (0, 100].
+func parsePercentiles(s string) ([]float64, error) {
+	var ps []float64
+	for _, field := range strings.Split(s, ",") {
+		p, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil || p <= 0 || p > 100 {
+			return nil, fmt.Errorf("invalid percentile %q, want a number in (0, 100]", field)
+		}
+		ps = append(ps, p)
+	}
+	return ps, nil
+}
+
+// percentileLabel names a percentile for table headers, e.g. "P99.9" or "Max".
+func percentileLabel(p float64) string {
+	if p == 100 {
+		return "Max"
+	}
+	return "P" + strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+// sortLatencies sorts latencies in place, as nearestRank requires.
+func sortLatencies(latencies []time.Duration) {
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+}
+
+// nearestRank returns the p-th percentile of sorted using the nearest-rank
+// method: the smallest sample such that at least p% of samples are <= it.
+// Callers sort with sortLatencies first.
+func nearestRank(sorted []time.Duration, p float64) time.Duration {
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	// The epsilon absorbs float error, e.g. 99.9*1000/100 slightly above 999.
+	rank := int(math.Ceil(p*float64(n)/100 - 1e-9))
+	rank = min(max(rank, 1), n)
+	return sorted[rank-1]
+}
+
+// writeLatencyCDFs writes each result's full latency distribution to
+// dir/<scenario>/<strategy>.csv as (latency_ms, cumulative_fraction) pairs,
+// one per distinct latency. Open-loop runs also get their response-time
+// distribution in <strategy>.response.csv.
+func writeLatencyCDFs(dir string, allResults []scenarioResults) error {
+	for _, sr := range allResults {
+		scenarioDir := filepath.Join(dir, slug(sr.name))
+		if err := os.MkdirAll(scenarioDir, 0o755); err != nil {
+			return err
+		}
+		for _, r := range sr.results {
+			if len(r.Latencies) == 0 {
+				continue
+			}
+			if err := writeCDF(filepath.Join(scenarioDir, slug(r.StrategyName)+".csv"), r.Latencies); err != nil {
+				return err
+			}
+			if len(r.ResponseTimes) > 0 {
+				if err := writeCDF(filepath.Join(scenarioDir, slug(r.StrategyName)+".response.csv"), r.ResponseTimes); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	log.Printf("Latency CDFs written to %s", dir)
+	return nil
+}
+
+func writeCDF(path string, latencies []time.Duration) error {
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "latency_ms,cumulative_fraction")
+	n := float64(len(sorted))
+	for i, lat := range sorted {
+		// Only the last of equal latencies carries the cumulative fraction.
+		if i+1 < len(sorted) && sorted[i+1] == lat {
+			continue
+		}
+		fmt.Fprintf(w, "%s,%s\n", formatFloat(float64(lat)/float64(time.Millisecond)), formatFloat(float64(i+1)/n))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
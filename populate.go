@@ -0,0 +1,208 @@
+package main
+
+import (
+	"caching-benchmark/implementations"
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// populateOptions tunes how prepareKeys seeds the datastore.
+type populateOptions struct {
+	// batch is the number of SETs pipelined per DoMulti; zero sizes
+	// batches to about populateBatchBytes of values.
+	batch int
+	// workers is the number of batches in flight at once; zero selects
+	// defaultPopulateWorkers.
+	workers int
+	// resume keeps the keys an interrupted population already wrote
+	// instead of clearing the datastore, and only sets the rest.
+	resume bool
+	// hash stores values as hashes with HSET instead of strings with SET.
+	hash bool
+}
+
+func (cfg Config) populateOptions() populateOptions {
+	return populateOptions{
+		batch:   cfg.PopulateBatch,
+		workers: cfg.PopulateWorkers,
+		resume:  cfg.PopulateResume,
+		hash:    cfg.Structure == implementations.StructureHash,
+	}
+}
+
+const (
+	// populateBatchBytes bounds the values buffered per automatic batch, so
+	// 2MB-value scenarios pipeline a few dozen SETs at a time instead of
+	// building every command at once.
+	populateBatchBytes     = 64 << 20
+	maxPopulateBatch       = 1000
+	defaultPopulateWorkers = 4
+	// populateProgressEvery is how often population progress is logged.
+	populateProgressEvery = 2 * time.Second
+)
+
+// batchSize returns the number of keys pipelined per batch for values of
+// valueSize bytes.
+func (o populateOptions) batchSize(valueSize int) int {
+	if o.batch > 0 {
+		return o.batch
+	}
+	return max(1, min(maxPopulateBatch, populateBatchBytes/max(1, valueSize)))
+}
+
+// populate sets keys[i] to value, expiring after ttls[i] when ttls is not
+// nil and that is not zero, in pipelined batches written by parallel
+// workers. It logs progress while it runs.
+func populate(ctx context.Context, client rueidis.Client, opts populateOptions, keys []string, value string, ttls []time.Duration) error {
+	todo := make([]int, 0, len(keys))
+	if opts.resume {
+		var err error
+		if todo, err = missingKeys(ctx, client, opts, keys, len(value)); err != nil {
+			return err
+		}
+		if skipped := len(keys) - len(todo); skipped > 0 {
+			log.Printf("Resuming population: %d of %d keys already present", skipped, len(keys))
+		}
+	} else {
+		for i := range keys {
+			todo = append(todo, i)
+		}
+	}
+	if len(todo) == 0 {
+		return nil
+	}
+
+	size := opts.batchSize(len(value))
+	workers := opts.workers
+	if workers <= 0 {
+		workers = defaultPopulateWorkers
+	}
+	// Every key holds the same value, so its hash fields are split once.
+	fields := implementations.HashFields(value)
+	batches := make(chan []int)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var done atomic.Int64
+	var once sync.Once
+	var firstErr error
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				cmds := make(rueidis.Commands, 0, len(batch))
+				for _, i := range batch {
+					if opts.hash {
+						hset := client.B().Hset().Key(keys[i]).FieldValue()
+						for f := 0; f < len(fields); f += 2 {
+							hset = hset.FieldValue(fields[f], fields[f+1])
+						}
+						cmds = append(cmds, hset.Build())
+						if ttls != nil && ttls[i] > 0 {
+							cmds = append(cmds, client.B().Pexpire().Key(keys[i]).Milliseconds(ttls[i].Milliseconds()).Build())
+						}
+						continue
+					}
+					if ttls != nil && ttls[i] > 0 {
+						cmds = append(cmds, client.B().Set().Key(keys[i]).Value(value).Px(ttls[i]).Build())
+						continue
+					}
+					cmds = append(cmds, client.B().Set().Key(keys[i]).Value(value).Build())
+				}
+				for _, resp := range client.DoMulti(ctx, cmds...) {
+					if err := resp.Error(); err != nil {
+						once.Do(func() { firstErr = err; cancel() })
+						break
+					}
+				}
+				done.Add(int64(len(batch)))
+			}
+		}()
+	}
+
+	stopProgress := logPopulateProgress(&done, len(todo), len(value))
+	for start := 0; start < len(todo) && ctx.Err() == nil; start += size {
+		select {
+		case batches <- todo[start:min(start+size, len(todo))]:
+		case <-ctx.Done():
+		}
+	}
+	close(batches)
+	wg.Wait()
+	stopProgress()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// missingKeys returns the indexes of keys that do not hold a value of
+// valueSize bytes, checking them with pipelined STRLENs, or that do not
+// hold every field of a hash, checking them with HLENs.
+func missingKeys(ctx context.Context, client rueidis.Client, opts populateOptions, keys []string, valueSize int) ([]int, error) {
+	var missing []int
+	batch := opts.batchSize(0)
+	want := int64(valueSize)
+	if opts.hash {
+		want = implementations.HashFieldCount
+	}
+	for start := 0; start < len(keys); start += batch {
+		end := min(start+batch, len(keys))
+		cmds := make(rueidis.Commands, 0, end-start)
+		for _, key := range keys[start:end] {
+			if opts.hash {
+				cmds = append(cmds, client.B().Hlen().Key(key).Build())
+				continue
+			}
+			cmds = append(cmds, client.B().Strlen().Key(key).Build())
+		}
+		for i, resp := range client.DoMulti(ctx, cmds...) {
+			n, err := resp.AsInt64()
+			if err != nil {
+				return nil, err
+			}
+			if n != want {
+				missing = append(missing, start+i)
+			}
+		}
+	}
+	return missing, nil
+}
+
+// logPopulateProgress logs the number of keys populated out of total
+// until the returned function is called, which logs the final count.
+func logPopulateProgress(done *atomic.Int64, total, valueSize int) (stop func()) {
+	start := time.Now()
+	report := func() {
+		n := done.Load()
+		elapsed := time.Since(start)
+		log.Printf("Populated %d/%d keys (%.0f%%, %.1f MB, %.0f keys/s)",
+			n, total, float64(n)*100/float64(total), float64(n)*float64(valueSize)/(1<<20), float64(n)/max(elapsed.Seconds(), 1e-9))
+	}
+	quit := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(populateProgressEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+				report()
+			}
+		}
+	}()
+	return func() {
+		close(quit)
+		<-exited
+		report()
+	}
+}
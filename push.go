@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"caching-benchmark/benchmark"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// pushOptions says where pushResults sends the final metrics.
+type pushOptions struct {
+	// influxURL is an InfluxDB write endpoint, e.g.
+	// http://influx:8086/api/v2/write?org=o&bucket=b or
+	// http://influx:8086/write?db=d; influxToken, when set, is sent as an
+	// API token.
+	influxURL   string
+	influxToken string
+	// series also writes each run's per-second time series to InfluxDB.
+	series bool
+	// pushgatewayURL is the base URL of a Prometheus Pushgateway; metrics
+	// are pushed under job with the scenario and strategy as grouping labels.
+	pushgatewayURL string
+	job            string
+}
+
+// pushMeasurement and pushSeriesMeasurement name the InfluxDB measurements
+// and, as a prefix, the Prometheus metrics.
+const (
+	pushMeasurement       = "cache_benchmark"
+	pushSeriesMeasurement = "cache_benchmark_series"
+)
+
+// pushTimeout bounds each request to InfluxDB or the Pushgateway.
+const pushTimeout = 30 * time.Second
+
+// pushResults sends every completed run's final metrics to the configured
+// InfluxDB and Pushgateway, labelled by scenario, strategy, environment and
+// run ID.
+func pushResults(opts pushOptions, runID string, allResults []scenarioResults) error {
+	client := &http.Client{Timeout: pushTimeout}
+	if opts.influxURL != "" {
+		var body bytes.Buffer
+		for _, sr := range allResults {
+			for _, r := range sr.results {
+				if len(r.Latencies) == 0 {
+					continue
+				}
+				writeInfluxRun(&body, sr.name, runID, r, opts.series)
+			}
+		}
+		req, err := http.NewRequest(http.MethodPost, opts.influxURL, &body)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+		if opts.influxToken != "" {
+			req.Header.Set("Authorization", "Token "+opts.influxToken)
+		}
+		if err := doPush(client, req); err != nil {
+			return fmt.Errorf("InfluxDB: %w", err)
+		}
+		log.Printf("Results pushed to InfluxDB")
+	}
+	if opts.pushgatewayURL != "" {
+		for _, sr := range allResults {
+			for _, r := range sr.results {
+				if len(r.Latencies) == 0 {
+					continue
+				}
+				if err := pushGatewayRun(client, opts, sr.name, runID, r); err != nil {
+					return fmt.Errorf("Pushgateway: %w", err)
+				}
+			}
+		}
+		log.Printf("Results pushed to the Pushgateway")
+	}
+	return nil
+}
+
+// pushMetric is one final metric of a run, in the unit of its name's
+// suffix.
+type pushMetric struct {
+	name  string
+	value float64
+}
+
+// runMetrics returns the final metrics of r.
+func runMetrics(r benchmark.Result) []pushMetric {
+	sortLatencies(r.Latencies)
+	return []pushMetric{
+		{"ops_per_second", r.OpsPerSecond},
+		{"hit_rate", r.HitRate},
+		{"p50_latency_ms", ms(nearestRank(r.Latencies, 50))},
+		{"p95_latency_ms", ms(nearestRank(r.Latencies, 95))},
+		{"p99_latency_ms", ms(nearestRank(r.Latencies, 99))},
+		{"operations", float64(r.TotalOperations)},
+		{"errors", float64(r.TotalErrors)},
+		{"stale_reads", float64(r.StaleReads)},
+		{"lost_writes", float64(r.LostWrites)},
+		{"backend_requests_per_1k_ops", r.BackendRequestsPer1kOps},
+		{"allocs_per_op", r.Memory.AllocsPerOp},
+	}
+}
+
+// writeInfluxRun appends r's final metrics as one line-protocol point and,
+// when series is set, one point per second of its samples.
+func writeInfluxRun(w io.Writer, scenario, runID string, r benchmark.Result, series bool) {
+	tags := fmt.Sprintf("scenario=%s,strategy=%s,run_id=%s", influxEscape(scenario), influxEscape(r.StrategyName), influxEscape(runID))
+	if r.Environment != "" {
+		tags += ",environment=" + influxEscape(r.Environment)
+	}
+	var fields []string
+	for _, m := range runMetrics(r) {
+		fields = append(fields, fmt.Sprintf("%s=%g", m.name, m.value))
+	}
+	end := r.StartTime.Add(r.TotalDuration)
+	fmt.Fprintf(w, "%s,%s %s %d\n", pushMeasurement, tags, strings.Join(fields, ","), end.UnixNano())
+	if !series {
+		return
+	}
+	for _, p := range perSecond(r.Samples) {
+		fmt.Fprintf(w, "%s,%s ops_per_second=%g,hit_rate=%g,in_flight=%di,queued=%di,errors=%di,heap_bytes=%di %d\n",
+			pushSeriesMeasurement, tags, p.opsPerSecond, p.hitRate, p.sample.InFlight, p.sample.Queued, p.sample.Errors,
+			p.sample.HeapBytes, r.StartTime.Add(p.sample.Elapsed).UnixNano())
+	}
+}
+
+// secondPoint is the last sample of one second of a run with the
+// throughput and hit rate over that second.
+type secondPoint struct {
+	sample       benchmark.Sample
+	opsPerSecond float64
+	hitRate      float64
+}
+
+// perSecond reduces samples to the last one of each second of the run.
+func perSecond(samples []benchmark.Sample) []secondPoint {
+	var points []secondPoint
+	var prev benchmark.Sample
+	for i, s := range samples {
+		if i+1 < len(samples) && samples[i+1].Elapsed/time.Second == s.Elapsed/time.Second {
+			continue
+		}
+		p := secondPoint{sample: s}
+		if d := (s.Elapsed - prev.Elapsed).Seconds(); d > 0 {
+			p.opsPerSecond = float64(s.Completed-prev.Completed) / d
+		}
+		if reads := s.Hits - prev.Hits + s.Misses - prev.Misses; reads > 0 {
+			p.hitRate = float64(s.Hits-prev.Hits) / float64(reads)
+		}
+		points = append(points, p)
+		prev = s
+	}
+	return points
+}
+
+// influxEscape escapes a line-protocol tag value.
+func influxEscape(s string) string {
+	return strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`).Replace(s)
+}
+
+// pushGatewayRun replaces the metrics of r's group, keyed by job, scenario
+// and strategy, with its final metrics.
+func pushGatewayRun(client *http.Client, opts pushOptions, scenario, runID string, r benchmark.Result) error {
+	job := opts.job
+	if job == "" {
+		job = pushMeasurement
+	}
+	// Base64 grouping-key values may contain slashes.
+	b64 := base64.RawURLEncoding.EncodeToString
+	target := fmt.Sprintf("%s/metrics/job/%s/scenario@base64/%s/strategy@base64/%s",
+		strings.TrimRight(opts.pushgatewayURL, "/"), url.PathEscape(job), b64([]byte(scenario)), b64([]byte(r.StrategyName)))
+	if r.Environment != "" {
+		target += "/environment@base64/" + b64([]byte(r.Environment))
+	}
+
+	var body bytes.Buffer
+	for _, m := range runMetrics(r) {
+		name := pushMeasurement + "_" + m.name
+		fmt.Fprintf(&body, "# TYPE %s gauge\n%s{run_id=%q} %g\n", name, name, runID, m.value)
+	}
+	req, err := http.NewRequest(http.MethodPut, target, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	return doPush(client, req)
+}
+
+func doPush(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", req.URL.Redacted(), resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"caching-benchmark/benchmark"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime/debug"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// gitCommit identifies the code a run measured: the VCS revision stamped
+// into the binary, or else the HEAD of the working directory's repository.
+// It is empty when neither is available.
+func gitCommit() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		var revision string
+		modified := false
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+		if revision != "" {
+			if modified {
+				revision += "-dirty"
+			}
+			return revision
+		}
+	}
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// resultsSchema is the results database's schema: a row per strategy run,
+// with the scenario config and run metadata as JSON.
+const resultsSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	run_id                      TEXT NOT NULL,
+	recorded_at                 TEXT NOT NULL,
+	scenario                    TEXT NOT NULL,
+	strategy                    TEXT NOT NULL,
+	git_commit                  TEXT NOT NULL DEFAULT '',
+	environment                 TEXT NOT NULL DEFAULT '',
+	server_version              TEXT NOT NULL DEFAULT '',
+	config                      TEXT,
+	metadata                    TEXT,
+	incomplete                  INTEGER NOT NULL DEFAULT 0,
+	operations                  INTEGER NOT NULL,
+	ops_per_second              REAL NOT NULL,
+	hit_rate                    REAL NOT NULL,
+	avg_latency_ms              REAL NOT NULL,
+	p95_latency_ms              REAL NOT NULL,
+	errors                      INTEGER NOT NULL,
+	timeouts                    INTEGER NOT NULL,
+	stale_reads                 INTEGER NOT NULL,
+	lost_writes                 INTEGER NOT NULL,
+	backend_requests_per_1k_ops REAL NOT NULL,
+	staleness_tracked           INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS runs_by_run ON runs (run_id);
+CREATE INDEX IF NOT EXISTS runs_by_scenario ON runs (scenario, strategy, recorded_at);
+`
+
+// openResultsDB opens the SQLite results database at path, creating it and
+// its schema if needed.
+func openResultsDB(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(resultsSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return db, nil
+}
+
+// recordRuns inserts every result of the invocation into the results
+// database at path, one row per strategy run, in a single transaction.
+func recordRuns(path, runID, commit string, scenarios []scenarioResults) error {
+	db, err := openResultsDB(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	insert, err := tx.Prepare(`INSERT INTO runs (run_id, recorded_at, scenario, strategy, git_commit, environment,
+		server_version, config, metadata, incomplete, operations, ops_per_second, hit_rate, avg_latency_ms,
+		p95_latency_ms, errors, timeouts, stale_reads, lost_writes, backend_requests_per_1k_ops, staleness_tracked)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	for _, sr := range scenarios {
+		config, err := json.Marshal(sr.config)
+		if err != nil {
+			return err
+		}
+		for _, r := range sr.results {
+			s := summarize(r)
+			var metadata []byte
+			if s.Metadata != nil {
+				if metadata, err = json.Marshal(s.Metadata); err != nil {
+					return err
+				}
+			}
+			_, err := insert.Exec(runID, now, sr.name, s.Strategy, commit, r.Environment,
+				r.ServerVersion, string(config), nullString(metadata), s.Incomplete, s.Operations, s.OpsPerSecond, s.HitRate,
+				s.AvgLatencyMs, s.P95LatencyMs, s.Errors, s.Timeouts, s.StaleReads, s.LostWrites, s.BackendReqPer1k,
+				s.StalenessTracked)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// nullString stores empty JSON as NULL.
+func nullString(b []byte) sql.NullString {
+	return sql.NullString{String: string(b), Valid: len(b) > 0}
+}
+
+// historyFilter selects the runs the history subcommand lists.
+type historyFilter struct {
+	scenario, strategy string
+	since              time.Time
+}
+
+// queryRuns reads the runs of the results database at path matching f,
+// oldest first.
+func queryRuns(path string, f historyFilter) ([]historyRecord, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	db, err := openResultsDB(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query(`SELECT run_id, recorded_at, scenario, strategy, git_commit, environment, server_version,
+		config, metadata, incomplete, operations, ops_per_second, hit_rate, avg_latency_ms, p95_latency_ms, errors,
+		timeouts, stale_reads, lost_writes, backend_requests_per_1k_ops, staleness_tracked
+		FROM runs
+		WHERE instr(scenario, ?) > 0 AND instr(strategy, ?) > 0 AND recorded_at >= ?
+		ORDER BY recorded_at, rowid`,
+		f.scenario, f.strategy, f.since.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var records []historyRecord
+	for rows.Next() {
+		var r historyRecord
+		var recorded string
+		var config, metadata sql.NullString
+		err := rows.Scan(&r.RunID, &recorded, &r.Scenario, &r.Strategy, &r.GitCommit, &r.Environment,
+			&r.ServerVersion, &config, &metadata, &r.Incomplete, &r.Operations, &r.OpsPerSecond, &r.HitRate,
+			&r.AvgLatencyMs, &r.P95LatencyMs, &r.Errors, &r.Timeouts, &r.StaleReads, &r.LostWrites,
+			&r.BackendReqPer1k, &r.StalenessTracked)
+		if err != nil {
+			return nil, err
+		}
+		if r.Time, err = time.Parse(time.RFC3339Nano, recorded); err != nil {
+			return nil, fmt.Errorf("run %s: %w", r.RunID, err)
+		}
+		if config.Valid {
+			r.Config = json.RawMessage(config.String)
+		}
+		if metadata.Valid {
+			r.Metadata = new(benchmark.Metadata)
+			if err := json.Unmarshal([]byte(metadata.String), r.Metadata); err != nil {
+				return nil, fmt.Errorf("run %s: %w", r.RunID, err)
+			}
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// runHistory implements the history subcommand: it lists the runs recorded
+// in a results database, optionally filtered, or compares two of them.
+func runHistory(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	db := fs.String("db", "", "SQLite results database written by -results-db (required)")
+	scenario := fs.String("scenario", "", "only list runs of scenarios whose name contains this")
+	strategy := fs.String("strategy", "", "only list runs of strategies whose name contains this")
+	since := fs.Duration("since", 0, "only list runs recorded within this long; 0 lists all")
+	compare := fs.String("compare", "", "compare two runs given as <base-run-id>,<run-id> strategy by strategy")
+	fs.Parse(args)
+	if *db == "" {
+		fs.Usage()
+		return errors.New("-db is required")
+	}
+	filter := historyFilter{scenario: *scenario, strategy: *strategy}
+	if *since > 0 {
+		filter.since = time.Now().Add(-*since)
+	}
+	selected, err := queryRuns(*db, filter)
+	if err != nil {
+		return err
+	}
+	if *compare != "" {
+		ids := strings.Split(*compare, ",")
+		if len(ids) != 2 {
+			return fmt.Errorf("-compare wants two run IDs separated by a comma, got %q", *compare)
+		}
+		return compareRuns(selected, ids[0], ids[1])
+	}
+	printHistory(selected)
+	return nil
+}
+
+// printHistory lists records, which queryRuns returns oldest first.
+func printHistory(records []historyRecord) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.Debug)
+	fmt.Fprintln(w, "Time\tRun\tCommit\tServer\tScenario\tStrategy\tOps/sec\tHit Rate (%)\tP95 Latency (ms)\tErrors\t")
+	for _, r := range records {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%.2f\t%.2f\t%.4f\t%d\t\n",
+			r.Time.Format(time.RFC3339), r.RunID, shortCommit(r.GitCommit), r.ServerVersion,
+			r.Scenario, r.Strategy, r.OpsPerSecond, r.HitRate*100, r.P95LatencyMs, r.Errors)
+	}
+	w.Flush()
+}
+
+// compareRuns prints, for every scenario and strategy recorded in both
+// runs, the change of its headline metrics from base to cur.
+func compareRuns(records []historyRecord, base, cur string) error {
+	type key struct{ scenario, strategy string }
+	baseRuns := make(map[key]historyRecord)
+	for _, r := range records {
+		if r.RunID == base {
+			baseRuns[key{r.Scenario, r.Strategy}] = r
+		}
+	}
+	if len(baseRuns) == 0 {
+		return fmt.Errorf("no recorded results for run %q", base)
+	}
+	change := func(prev, now float64) string {
+		if prev == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%+.1f%%", (now-prev)/prev*100)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.Debug)
+	fmt.Fprintf(w, "Scenario\tStrategy\tOps/sec\tHit Rate (%%)\tP95 Latency (ms)\tBackend Req/1k Ops\t\n")
+	matched := 0
+	for _, r := range records {
+		b, ok := baseRuns[key{r.Scenario, r.Strategy}]
+		if r.RunID != cur || !ok {
+			continue
+		}
+		matched++
+		fmt.Fprintf(w, "%s\t%s\t%.2f (%s)\t%.2f (%s)\t%.4f (%s)\t%.1f (%s)\t\n", r.Scenario, r.Strategy,
+			r.OpsPerSecond, change(b.OpsPerSecond, r.OpsPerSecond),
+			r.HitRate*100, change(b.HitRate, r.HitRate),
+			r.P95LatencyMs, change(b.P95LatencyMs, r.P95LatencyMs),
+			r.BackendReqPer1k, change(b.BackendReqPer1k, r.BackendReqPer1k))
+	}
+	w.Flush()
+	if matched == 0 {
+		return fmt.Errorf("run %q shares no scenario and strategy with run %q", cur, base)
+	}
+	return nil
+}
+
+// shortCommit abbreviates a commit hash for tables.
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+// newRunID names one invocation of the benchmark in the results database.
+func newRunID(start time.Time) string {
+	return "run-" + start.UTC().Format("20060102-150405")
+}
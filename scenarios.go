@@ -0,0 +1,941 @@
+package main
+
+import (
+	"caching-benchmark/benchmark"
+	"caching-benchmark/workload"
+	"time"
+)
+
+// uniformScenario is the scenario whose keys are drawn uniformly instead of
+// from a Zipf distribution.
+const uniformScenario = "Uniform Workload (Worst-Case, 90% Read)"
+
+// defaultScenarios defines the benchmark scenarios run by default.
+func defaultScenarios() []Config {
+	return []Config{
+		{
+			Name:           "Read-Heavy (90% Read, 64B Values)",
+			Tags:           []string{"quick"},
+			NumOperations:  100000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			SLOs:           []string{"p99 < 5ms", "hit-rate > 50%", "error-rate < 0.1%"},
+		},
+		{
+			Name:           "Write-Heavy (50% Read, 64B Values)",
+			Tags:           []string{"quick"},
+			NumOperations:  100000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.5,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+		},
+		{
+			Name:           uniformScenario,
+			NumOperations:  100000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          0, // Zipf parameters are ignored for uniform
+			ZipfV:          0,
+		},
+		{
+			Name:           "Memory-Intensive (90% Read, 1KB Values)",
+			NumOperations:  50000, // Reduced ops to keep test duration reasonable
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 1024,
+			ZipfS:          1.01,
+			ZipfV:          1,
+		},
+		{
+			Name:           "Large Value Scenario (90% Read, 2MB Values)",
+			Tags:           []string{"large-values"},
+			NumOperations:  2000, // Drastically reduced ops due to large payload size
+			NumKeys:        100,  // Reduced keys to keep data prep manageable
+			ReadWriteRatio: 0.9,
+			Concurrency:    64, // Reduced concurrency to avoid overwhelming network
+			ValueSizeBytes: 2 * 1024 * 1024,
+			ZipfS:          1.01,
+			ZipfV:          1,
+		},
+		{
+			Name:           "Write-Heavy & Large Value (50% Read, 1MB Values)",
+			Tags:           []string{"large-values"},
+			NumOperations:  2000,
+			NumKeys:        100, // Reduced keys to keep data prep manageable
+			ReadWriteRatio: 0.5,
+			Concurrency:    64,
+			ValueSizeBytes: 2 * 1024 * 1024,
+			ZipfS:          1.01,
+			ZipfV:          1,
+		},
+		{
+			// Encoded, stamped 2MB values exercise every pooled buffer in the
+			// runner; the pair reports the allocation and GC-pause difference.
+			Name:           "Large Value Buffer Reuse (90% Read, 2MB v1 Values, Pooled)",
+			Tags:           []string{"large-values"},
+			NumOperations:  2000,
+			NumKeys:        100,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 2 * 1024 * 1024,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			Codec:          "v1",
+			TrackStaleness: true,
+		},
+		{
+			Name:              "Large Value Buffer Reuse (90% Read, 2MB v1 Values, Unpooled)",
+			Tags:              []string{"large-values"},
+			NumOperations:     2000,
+			NumKeys:           100,
+			ReadWriteRatio:    0.9,
+			Concurrency:       64,
+			ValueSizeBytes:    2 * 1024 * 1024,
+			ZipfS:             1.01,
+			ZipfV:             1,
+			Codec:             "v1",
+			TrackStaleness:    true,
+			DisableBufferPool: true,
+		},
+		{
+			// Every read needs only 4KB of a 2MB value: whole-object client
+			// caching against fetching the fragment with GETRANGE each time.
+			Name:              "Partial Reads (90% Read, 2MB Values, 4KB Ranges)",
+			Tags:              []string{"large-values"},
+			NumOperations:     2000,
+			NumKeys:           100,
+			ReadWriteRatio:    0.9,
+			Concurrency:       64,
+			ValueSizeBytes:    2 * 1024 * 1024,
+			ZipfS:             1.01,
+			ZipfV:             1,
+			RangeReadFraction: 1,
+			RangeReadBytes:    4096,
+			Strategies:        []string{"redis-getrange", "rueidis-csc", "ristretto-pubsub"},
+		},
+		{
+			// Large values held in-process: compare heap growth, peak heap
+			// and GC pauses rather than throughput alone.
+			Name:           "GC Pressure: Large Values in L1 (90% Read, 256KB)",
+			Tags:           []string{"large-values", "l1"},
+			NumOperations:  20000,
+			NumKeys:        2000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 256 * 1024,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			Strategies:     []string{"rueidis-csc", "ristretto-pubsub", "lru-pubsub"},
+		},
+		{
+			// A 1GB working set of compressible values against a 256MB L1:
+			// compression trades CPU per hit for fitting more of it in L1.
+			Name:           "Compressed L1+L2 (90% Read, 1MB Text Values, 256MB L1)",
+			Tags:           []string{"large-values", "serialization"},
+			NumOperations:  5000,
+			NumKeys:        1000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    32,
+			ValueSizeBytes: 1024 * 1024,
+			Payload:        "text",
+			ZipfS:          1.01,
+			ZipfV:          1,
+			Strategies: []string{
+				"ristretto-pubsub:memory_budget=268435456",
+				"ristretto-pubsub:memory_budget=268435456,compression=snappy",
+				"ristretto-pubsub:memory_budget=268435456,compression=zstd",
+			},
+		},
+		{
+			Name:           "JSON Documents (80% Read, 16KB, New Value per Write)",
+			Tags:           []string{"serialization"},
+			NumOperations:  100000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.8,
+			Concurrency:    64,
+			ValueSizeBytes: 16 * 1024,
+			Payload:        "json",
+			VaryValues:     true,
+			ZipfS:          1.01,
+			ZipfV:          1,
+		},
+		{
+			Name:           "Serialized Objects (90% Read, 4KB msgpack Records)",
+			Tags:           []string{"serialization"},
+			NumOperations:  100000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 4096,
+			Payload:        "text",
+			Serializer:     "msgpack",
+			ZipfS:          1.01,
+			ZipfV:          1,
+		},
+		{
+			Name:             "Cache Stampede (Hot Key Invalidated Every 10ms)",
+			Tags:             []string{"invalidation"},
+			NumOperations:    100000,
+			NumKeys:          1,
+			Concurrency:      256,
+			ValueSizeBytes:   1024,
+			StampedeInterval: 10 * time.Millisecond,
+		},
+		{
+			Name:           "Pool-Limited (256 Workers, 16 In-Flight, 90% Read)",
+			NumOperations:  100000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    256,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			MaxInFlight:    map[string]int{"rueidis-csc": 16, "ristretto-pubsub": 16},
+		},
+		{
+			// The same 4 pipelined connections and 32 in-flight calls for
+			// every design, then CSC at 1 and 16 connections, so client
+			// tuning is not mistaken for strategy design.
+			Name:           "Equal Client Pools (4 Connections, 32 In-Flight, 90% Read)",
+			NumOperations:  100000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    128,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			Strategies: []string{
+				"rueidis-csc:connections=4,max_in_flight=32",
+				"ristretto-pubsub:connections=4,max_in_flight=32",
+				"redis-lua:connections=4,max_in_flight=32",
+				"rueidis-csc:connections=1,max_in_flight=32",
+				"rueidis-csc:connections=16,max_in_flight=32",
+			},
+		},
+		{
+			// Each worker reads its own writes back: does the strategy's
+			// read path see them at once, or only after an invalidation
+			// arrives?
+			Name:           "Read-Your-Writes: CSC vs Pub/Sub Invalidation (50% Read)",
+			Tags:           []string{"quick", "invalidation"},
+			NumOperations:  100000,
+			NumKeys:        1000,
+			ReadWriteRatio: 0.5,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			ReadYourWrites: true,
+			Strategies:     []string{"rueidis-csc", "rueidis-csc-bcast", "ristretto-pubsub", "ristretto-stream"},
+		},
+		{
+			// The application restarts 5s in: every L1 starts cold while
+			// Redis keeps the data, and the L2 takes the herd of misses.
+			Name:           "Hot Restart: Cold L1 at 5s (90% Read, 20k Ops/sec)",
+			Tags:           []string{"faults", "l1"},
+			NumOperations:  300000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 1024,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			TargetRate:     20000,
+			RestartAt:      5 * time.Second,
+		},
+		{
+			Name:               "Hot Restart: L1 Pre-Warmed With 1000 Hot Keys at 5s (90% Read, 20k Ops/sec)",
+			Tags:               []string{"faults", "l1"},
+			NumOperations:      300000,
+			NumKeys:            10000,
+			ReadWriteRatio:     0.9,
+			Concurrency:        64,
+			ValueSizeBytes:     1024,
+			ZipfS:              1.01,
+			ZipfV:              1,
+			TargetRate:         20000,
+			RestartAt:          5 * time.Second,
+			RestartPrewarmKeys: 1000,
+		},
+		{
+			// Saves each L1's key set at the end of the run for the next
+			// scenario; rueidis-csc cannot dump its cache and stays cold.
+			Name:           "L1 Snapshot: Cold Start, Saving the L1 Key Set (90% Read)",
+			Tags:           []string{"l1"},
+			NumOperations:  100000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 1024,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			L1Snapshot:     "zipf-10k",
+			Strategies:     []string{"ristretto-pubsub", "lru-pubsub", "rueidis-csc"},
+		},
+		{
+			Name:           "L1 Snapshot: Pre-Warmed From the Previous Run (90% Read)",
+			Tags:           []string{"l1"},
+			NumOperations:  100000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 1024,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			L1Snapshot:     "zipf-10k",
+			PrewarmL1:      true,
+			Strategies:     []string{"ristretto-pubsub", "lru-pubsub", "rueidis-csc"},
+		},
+		{
+			// Writes to hot keys fan out as invalidations; the timeline shows
+			// whether p99 spikes follow them, GC pauses, or neither.
+			Name:           "Latency Timeline: Invalidation Storms vs GC (70% Read, 20k Ops/sec)",
+			Tags:           []string{"invalidation"},
+			NumOperations:  400000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.7,
+			Concurrency:    64,
+			ValueSizeBytes: 1024,
+			ZipfS:          1.2,
+			ZipfV:          1,
+			TargetRate:     20000,
+			Timeline:       true,
+			Strategies:     []string{"rueidis-csc", "rueidis-csc-bcast", "ristretto-pubsub", "ristretto-stream"},
+		},
+		{
+			Name:           "Canary: 10% Pub/Sub Candidate Beside 90% CSC Control (90% Read)",
+			NumOperations:  100000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			Strategies:     []string{"rueidis-csc", "ristretto-pubsub"},
+			CanaryFraction: 0.1,
+			TrackStaleness: true,
+		},
+		{
+			Name:           "Interop: CSC + Pub/Sub Clients Sharing Keys (50% Read)",
+			Tags:           []string{"invalidation"},
+			NumOperations:  100000,
+			NumKeys:        1000,
+			ReadWriteRatio: 0.5,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			Interop:        true,
+			// Values carry the v1 header so other-language clients joining
+			// the experiment can validate them.
+			Codec: "v1",
+		},
+		{
+			Name:           "CSC Tracking Modes: Default vs Broadcast (90% Read)",
+			Tags:           []string{"invalidation"},
+			NumOperations:  100000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			Strategies:     []string{"rueidis-csc", "rueidis-csc-bcast"},
+		},
+		{
+			Name:           "Freshness: TTL vs Pub/Sub vs Hybrid (80% Read)",
+			Tags:           []string{"invalidation"},
+			NumOperations:  100000,
+			NumKeys:        1000,
+			ReadWriteRatio: 0.8,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			TrackStaleness: true,
+			Strategies: []string{
+				"ristretto-pubsub:l1_ttl=100ms,invalidation=false",
+				"ristretto-pubsub",
+				"ristretto-pubsub:l1_ttl=100ms",
+			},
+		},
+		{
+			Name:           "Write Policies: Invalidate vs Through vs Behind (50% Read)",
+			NumOperations:  100000,
+			NumKeys:        1000,
+			ReadWriteRatio: 0.5,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			TrackStaleness: true,
+			Strategies: []string{
+				"ristretto-pubsub:write_policy=invalidate",
+				"ristretto-pubsub:write_policy=through",
+				"ristretto-pubsub:write_policy=behind,flush_interval=10ms",
+			},
+		},
+		{
+			Name:               "Miss Storm (30% of Reads for Absent Keys)",
+			Tags:               []string{"quick"},
+			NumOperations:      100000,
+			NumKeys:            10000,
+			ReadWriteRatio:     0.9,
+			Concurrency:        64,
+			ValueSizeBytes:     64,
+			ZipfS:              1.01,
+			ZipfV:              1,
+			AbsentReadFraction: 0.3,
+			Strategies: []string{
+				"rueidis-csc",
+				"ristretto-pubsub",
+				"ristretto-pubsub:negative_ttl=1s",
+			},
+		},
+		{
+			Name:           "Invalidation Batching: Per-Write vs Batched PUBLISH (50% Read)",
+			Tags:           []string{"invalidation"},
+			NumOperations:  100000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.5,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			Strategies: []string{
+				"ristretto-pubsub",
+				"ristretto-pubsub:inval_batch_interval=2ms",
+				"ristretto-pubsub:inval_batch_interval=10ms,inval_batch_size=512",
+			},
+			TrackStaleness: true,
+		},
+		{
+			Name:           "Invalidation Transports: Pub/Sub vs Streams vs Keyspace Events (50% Read)",
+			Tags:           []string{"invalidation"},
+			NumOperations:  100000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.5,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			Strategies:     []string{"ristretto-pubsub", "ristretto-stream", "ristretto-keyspace"},
+			TrackStaleness: true,
+		},
+		{
+			// Every measured operation is a read; other instances write
+			// through their own copy of the strategy.
+			Name:              "External Mutators: Read-Only Workload, 500 Writes/sec From Peer Instances",
+			Tags:              []string{"invalidation"},
+			NumOperations:     200000,
+			NumKeys:           10000,
+			ReadWriteRatio:    1.0,
+			Concurrency:       64,
+			ValueSizeBytes:    64,
+			ZipfS:             1.01,
+			ZipfV:             1,
+			TargetRate:        20000,
+			ExternalWriteRate: 500,
+			Strategies:        []string{"rueidis-csc", "ristretto-pubsub", "ristretto-keyspace"},
+			TrackStaleness:    true,
+		},
+		{
+			// Plain SETs publish nothing, so only invalidation driven by
+			// Redis itself keeps the L1 fresh.
+			Name:              "External Mutators: Read-Only Workload, 500 Raw SETs/sec",
+			Tags:              []string{"invalidation"},
+			NumOperations:     200000,
+			NumKeys:           10000,
+			ReadWriteRatio:    1.0,
+			Concurrency:       64,
+			ValueSizeBytes:    64,
+			ZipfS:             1.01,
+			ZipfV:             1,
+			TargetRate:        20000,
+			ExternalWriteRate: 500,
+			ExternalWritesRaw: true,
+			Strategies:        []string{"rueidis-csc", "ristretto-pubsub", "ristretto-keyspace"},
+			TrackStaleness:    true,
+		},
+		{
+			// Point gets mixed with small prefix scans, as feed and session
+			// reads fetch a handful of related entries at once.
+			Name:           "Feeds: Point Gets with Prefix Scans (90% Read, 10% of Reads Scan 10 Keys)",
+			Tags:           []string{"quick"},
+			NumOperations:  50000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 256,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			ScanFraction:   0.1,
+			ScanLimit:      10,
+			Strategies:     []string{"redis-lua", "rueidis-csc", "ristretto-pubsub"},
+		},
+		{
+			// Counters updated in place on hot keys: WATCH/MULTI retries for
+			// the Redis-backed strategies, unsynchronized get-then-set for
+			// the L1+L2 one.
+			Name:           "Counters: Read-Modify-Write on Hot Keys (70% Read, All Writes RMW)",
+			Tags:           []string{"quick"},
+			NumOperations:  100000,
+			NumKeys:        1000,
+			ReadWriteRatio: 0.7,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.2,
+			ZipfV:          1,
+			RMWFraction:    1,
+			TrackStaleness: true,
+			Strategies:     []string{"redis-lua", "rueidis-csc", "ristretto-pubsub"},
+		},
+		{
+			// Real traffic is rarely one distribution: a hot Zipf head, a
+			// long uniform tail and a batch job sweeping its own keys once
+			// per pass, which pollutes caches that admit every read.
+			Name:           "Mixed Traffic: Hot Zipf + Cold Uniform + Background Scan",
+			NumOperations:  200000,
+			NumKeys:        20000,
+			Concurrency:    64,
+			ValueSizeBytes: 256,
+			Mix: []workload.Component{
+				{Name: "hot", Weight: 0.7, Distribution: workload.DistZipf, NumKeys: 2000, ReadWriteRatio: 0.9, ZipfS: 1.01, ZipfV: 1},
+				{Name: "cold", Weight: 0.2, Distribution: workload.DistUniform, NumKeys: 15000, ReadWriteRatio: 0.9},
+				{Name: "scan", Weight: 0.1, Distribution: workload.DistSequential, NumKeys: 3000, ReadWriteRatio: 1},
+			},
+		},
+		{
+			// Eight tenants share one L1 sized for a fraction of their hot
+			// keys; tenant-0 sends about half of the traffic. The
+			// per-tenant hit rates show whether its hot keys evict the
+			// quieter tenants' entries.
+			Name:           "Multi-Tenant: Noisy Tenant in a Shared L1 (8 Tenants, 4MB L1)",
+			Tags:           []string{"l1"},
+			NumOperations:  200000,
+			NumKeys:        80000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 1024,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			Tenants:        8,
+			TenantSkew:     1.5,
+			Strategies:     []string{"ristretto-pubsub:memory_budget=4194304", "lru-pubsub:memory_budget=4194304"},
+		},
+		{
+			// Values split over hash fields: CSC caches the HGETALL reply
+			// instead of the GET one, and the L1+L2 stack misses to HGETALL.
+			Name:           "Hash Values: HGETALL vs GET Caching (90% Read, 1KB)",
+			NumOperations:  100000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 1024,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			Structure:      "hash",
+			Strategies:     []string{"rueidis-csc", "ristretto-pubsub"},
+		},
+		{
+			// Tier combinations assembled by the twotier strategy's knobs.
+			Name:           "Two-Tier Combinations (90% Read)",
+			Tags:           []string{"quick"},
+			NumOperations:  100000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			Strategies: []string{
+				"twotier:l1=ristretto,invalidator=stream",
+				"twotier:l1=lru,invalidator=keyspace",
+				"twotier:l1=lru,l2=csc",
+			},
+			TrackStaleness: true,
+		},
+		{
+			Name:           "Fault Injection: Connection Drop, Pub/Sub Kill, Server Pause (90% Read)",
+			Tags:           []string{"faults"},
+			NumOperations:  200000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			TargetRate:     20000, // 10s per strategy, so faults land at the same points
+			OpTimeout:      100 * time.Millisecond,
+			Faults: []FaultSpec{
+				{At: 2 * time.Second, Kind: "drop-connections"},
+				{At: 5 * time.Second, Kind: "kill-pubsub"},
+				{At: 8 * time.Second, Kind: "client-pause", Duration: 500 * time.Millisecond},
+			},
+			Strategies:     []string{"rueidis-csc", "ristretto-pubsub", "ristretto-pubsub:flush_on_resubscribe=true"},
+			TrackStaleness: true,
+		},
+		{
+			Name:           "Fault Injection with Runner Retries (3 Retries, 5ms Backoff, 90% Read)",
+			Tags:           []string{"faults"},
+			NumOperations:  200000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			TargetRate:     20000,
+			OpTimeout:      100 * time.Millisecond,
+			Retry:          benchmark.RetryPolicy{MaxRetries: 3, Backoff: 5 * time.Millisecond, MaxBackoff: 50 * time.Millisecond},
+			Faults: []FaultSpec{
+				{At: 2 * time.Second, Kind: "drop-connections"},
+				{At: 5 * time.Second, Kind: "kill-pubsub"},
+				{At: 8 * time.Second, Kind: "client-pause", Duration: 500 * time.Millisecond},
+			},
+			Strategies:     []string{"rueidis-csc", "ristretto-pubsub"},
+			TrackStaleness: true,
+		},
+		{
+			Name:           "Warm-Standby Failover: Primary Unresponsive for 3s (90% Read)",
+			Tags:           []string{"faults", "needs-standby"},
+			NumOperations:  200000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			Strategies:     []string{"ristretto-pubsub"},
+			TargetRate:     20000,
+			Failover:       true,
+			// Dropping every connection and pausing all clients makes the
+			// primary look dead to both existing and reconnecting clients.
+			Faults: []FaultSpec{
+				{At: 3 * time.Second, Kind: "drop-connections"},
+				{At: 3 * time.Second, Kind: "client-pause", Duration: 3 * time.Second},
+			},
+			TrackStaleness: true,
+		},
+		{
+			Name:           "Cross-AZ Network (2ms RTT +/- 0.5ms, 90% Read)",
+			Tags:           []string{"network"},
+			NumOperations:  50000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			RTT:            2 * time.Millisecond,
+			RTTJitter:      500 * time.Microsecond,
+		},
+		{
+			Name:           "Latency vs Throughput (90% Read, 10%-120% of Capacity)",
+			Tags:           []string{"sweep"},
+			NumOperations:  50000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			LoadFractions:  []float64{0.1, 0.25, 0.5, 0.75, 0.9, 1.0, 1.1, 1.2},
+		},
+		{
+			// Absolute rates rather than fractions of a closed-loop
+			// capacity: the highest step each strategy holds under the p99
+			// limit is its max sustainable throughput.
+			Name:           "Step Load (90% Read, 10k-80k Ops/sec, p99 < 2ms)",
+			Tags:           []string{"sweep"},
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			StepRates:      []float64{10000, 20000, 40000, 80000},
+			StepDuration:   30 * time.Second,
+			StepP99Limit:   2 * time.Millisecond,
+		},
+		{
+			Name:              "Concurrency Sweep (90% Read, 1-256 Workers)",
+			Tags:              []string{"sweep"},
+			NumOperations:     50000,
+			NumKeys:           10000,
+			ReadWriteRatio:    0.9,
+			Concurrency:       64,
+			ValueSizeBytes:    64,
+			ZipfS:             1.01,
+			ZipfV:             1,
+			ConcurrencyLevels: []int{1, 8, 32, 64, 128, 256},
+		},
+		{
+			// 4KB values put the 1GB L1 budget at 262144 keys, so the sweep
+			// spans working sets from an eighth of the budget to twice it.
+			Name:           "Working-Set Sweep (90% Read, 4KB Values, 32K-512K Keys)",
+			Tags:           []string{"sweep"},
+			NumOperations:  500000,
+			NumKeys:        32768,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 4096,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			KeyCounts:      []int{32768, 65536, 131072, 262144, 524288},
+		},
+		{
+			// A 16MB L1 holds about 15.6K of the 1KB values, an eighth of the
+			// keys, so the hit rate rests on how well each L1 keeps the most
+			// popular ones as the skew varies. s at or below 1 is too flat
+			// for most of the popularity to fit.
+			Name:           "Zipf Skew Sweep (90% Read, 16MB L1, s 0.8-1.4)",
+			Tags:           []string{"sweep", "l1"},
+			NumOperations:  1000000,
+			NumKeys:        125000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 1024,
+			ZipfS:          1.1,
+			ZipfV:          1,
+			L1BudgetBytes:  16 << 20,
+			ZipfSkews:      []float64{0.8, 0.9, 1.0, 1.1, 1.2, 1.3, 1.4},
+			Strategies: []string{
+				"rueidis-csc",
+				"ristretto-pubsub",
+				"twotier:l1=lru,invalidator=pubsub",
+			},
+		},
+		{
+			// 512B payloads are stored hex-encoded as 1KB values, so 100K keys
+			// need about 100MB and the server evicts most of them.
+			Name:            "L2 Eviction (32MB maxmemory, allkeys-lru, 90% Read)",
+			Tags:            []string{"server-config"},
+			NumOperations:   200000,
+			NumKeys:         100000,
+			ReadWriteRatio:  0.9,
+			Concurrency:     64,
+			ValueSizeBytes:  512,
+			ZipfS:           1.01,
+			ZipfV:           1,
+			MaxMemory:       "32mb",
+			MaxMemoryPolicy: "allkeys-lru",
+		},
+		{
+			// redis-function needs Redis 7+; on older servers its run fails
+			// at Init and the other strategies still run.
+			Name:           "Server-Side Scripting vs Client-Side Caching (95% Read)",
+			NumOperations:  200000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.95,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.2,
+			ZipfV:          1,
+			Strategies:     []string{"rueidis-csc", "ristretto-pubsub", "redis-lua", "redis-function"},
+		},
+		{
+			// Open-loop at 20000 ops/sec the run lasts 20s, so every
+			// short-lived key expires in Redis several times over.
+			Name:           "TTL Mix (10% 5s / 90% 1h TTL, 90% Read)",
+			NumOperations:  400000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			TargetRate:     20000,
+			TTLClasses: []TTLClass{
+				{Name: "short-lived", Fraction: 0.1, TTL: 5 * time.Second},
+				{Name: "long-lived", Fraction: 0.9, TTL: time.Hour},
+			},
+		},
+		{
+			Name:           "Key Construction Cost (SHA-256 of 1KB Request URL, 90% Read)",
+			NumOperations:  100000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			KeyDerivation:  "url-sha256",
+			KeyParamBytes:  1024,
+		},
+		{
+			// The 1GB L1 budget, which holds the 100MB working set, is cut
+			// to 4MB at 10s, as when a container's limit is lowered or its
+			// heap grows, and restored at 20s. Ristretto evicts lazily on
+			// later Sets; the LRU at once.
+			Name:           "Adaptive L1: Budget Cut to 4MB and Restored (90% Read, 20k Ops/sec)",
+			Tags:           []string{"l1"},
+			NumOperations:  600000,
+			NumKeys:        100000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 1024,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			TargetRate:     20000,
+			L1Schedule: []benchmark.L1Resize{
+				{At: 10 * time.Second, Budget: 4 << 20},
+				{At: 20 * time.Second, Budget: memoryBudgetBytes},
+			},
+			Strategies: []string{"ristretto-pubsub", "lru-pubsub"},
+		},
+		{
+			Name:           "Adaptive L1: Recreated at 4MB and Restored (90% Read, 20k Ops/sec)",
+			Tags:           []string{"l1"},
+			NumOperations:  600000,
+			NumKeys:        100000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 1024,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			TargetRate:     20000,
+			L1Schedule: []benchmark.L1Resize{
+				{At: 10 * time.Second, Budget: 4 << 20},
+				{At: 20 * time.Second, Budget: memoryBudgetBytes},
+			},
+			L1ResizeCold: true,
+			Strategies:   []string{"ristretto-pubsub", "lru-pubsub"},
+		},
+		{
+			// Run with -env-cluster to see sharded channels keep each
+			// invalidation within one shard while PUBLISH reaches every node.
+			Name:           "Sharded vs Global Pub/Sub Invalidation (70% Read)",
+			Tags:           []string{"invalidation", "redis7"},
+			NumOperations:  200000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.7,
+			Concurrency:    64,
+			ValueSizeBytes: 256,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			Strategies:     []string{"ristretto-pubsub", "ristretto-spubsub", "ristretto-spubsub:pubsub_channels=128"},
+		},
+		{
+			Name:           "Composite Keys (user:{id:08}:profile, 90% Read)",
+			Tags:           []string{"quick"},
+			NumOperations:  100000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			KeyTemplate:    "user:{id:08}:profile",
+			Strategies:     []string{"rueidis-csc", "rueidis-csc-bcast", "ristretto-pubsub", "lru-pubsub"},
+		},
+		{
+			// Long keys cost CSC tracking-table memory, L1 cost and bytes on
+			// the wire on every operation, which small values make visible.
+			Name:           "Key Size: 256B Keys (90% Read, 64B Values)",
+			NumOperations:  100000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			KeyTemplate:    "tenant:acme:{pad}:item:{id}",
+			KeyBytes:       256,
+			Strategies:     []string{"rueidis-csc", "rueidis-csc-bcast", "ristretto-pubsub", "lru-pubsub"},
+		},
+		{
+			Name:           "Tuning Sweep (90% Read, 64B Values)",
+			Tags:           []string{"sweep"},
+			NumOperations:  100000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			Sweeps: []Sweep{
+				{Strategy: "ristretto-pubsub", Knobs: []Knob{
+					{Name: "num_counters", Values: []string{"100000", "1000000"}},
+					{Name: "memory_budget", Values: []string{"1048576", "1073741824"}},
+					{Name: "buffer_items", Values: []string{"16", "64"}},
+				}},
+				{Strategy: "rueidis-csc", Knobs: []Knob{
+					{Name: "cache_size_each_conn", Values: []string{"1000", "100000"}},
+					{Name: "csc_ttl", Values: []string{"1s", "10m"}},
+				}},
+			},
+		},
+		{
+			// The client-side TTL bounds how long a value survives in the
+			// CSC cache without being read again, trading backend traffic
+			// against memory and against staleness should an invalidation
+			// be lost. Open-loop, every point runs for the same 90 seconds.
+			Name:           "CSC TTL Sweep (90% Read, 1s-600s)",
+			Tags:           []string{"sweep"},
+			NumOperations:  900000,
+			NumKeys:        100000,
+			ReadWriteRatio: 0.9,
+			Concurrency:    64,
+			ValueSizeBytes: 256,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			TargetRate:     10000,
+			TrackStaleness: true,
+			Sweeps: []Sweep{
+				{Strategy: "rueidis-csc", Knobs: []Knob{
+					{Name: "csc_ttl", Values: []string{"1s", "10s", "60s", "600s"}},
+				}},
+				{Strategy: "rueidis-csc-bcast", Knobs: []Knob{
+					{Name: "csc_ttl", Values: []string{"1s", "10s", "60s", "600s"}},
+				}},
+			},
+		},
+		{
+			Name:           "L1 Sharding Sweep (95% Read, 256 Workers)",
+			Tags:           []string{"sweep", "l1"},
+			NumOperations:  500000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.95,
+			Concurrency:    256,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			Sweeps: []Sweep{
+				{Strategy: "ristretto-pubsub", Knobs: []Knob{
+					{Name: "l1_shards", Values: []string{"1", "4", "16", "64"}},
+				}},
+			},
+		},
+		{
+			// What Ristretto's lock-free buffers buy over plain locking: the
+			// same L1+L2 stack with a sharded LRU, from one lock to 256.
+			Name:           "L1 Locking: Ristretto vs Sharded LRU (95% Read, 256 Workers)",
+			Tags:           []string{"l1"},
+			NumOperations:  500000,
+			NumKeys:        10000,
+			ReadWriteRatio: 0.95,
+			Concurrency:    256,
+			ValueSizeBytes: 64,
+			ZipfS:          1.01,
+			ZipfV:          1,
+			Strategies: []string{
+				"ristretto-pubsub",
+				"lru-pubsub:l1_shards=1",
+				"lru-pubsub:l1_shards=16",
+				"lru-pubsub",
+				"lru-pubsub:l1_shards=256",
+			},
+		},
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{"* * * * *", false},
+		{"0 3 * * 1-5", false},
+		{"*/15 0-23/6 1,15 * 0", false},
+		{"59 23 31 12 6", false},
+		{"60 * * * *", true},
+		{"* 24 * * *", true},
+		{"* * 0 * *", true},
+		{"* * 32 * *", true},
+		{"* * * 0 *", true},
+		{"* * * 13 *", true},
+		{"* * * * 7", true},
+		{"5-1 * * * *", true},
+		{"*/0 * * * *", true},
+		{"*/x * * * *", true},
+		{"a * * * *", true},
+		{"* * * *", true},
+	}
+	for _, tt := range tests {
+		_, err := parseCron(tt.expr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCron(%q) error = %v, want error %v", tt.expr, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	kolkata := time.FixedZone("+05:30", 5*3600+1800)
+	chatham := time.FixedZone("+12:45", 12*3600+45*60)
+	tests := []struct {
+		expr     string
+		from     time.Time
+		want     time.Time
+		describe string
+	}{
+		{"* * * * *", time.Date(2026, 1, 1, 10, 0, 30, 0, time.UTC), time.Date(2026, 1, 1, 10, 1, 0, 0, time.UTC), "next whole minute"},
+		{"*/15 * * * *", time.Date(2026, 1, 1, 10, 16, 0, 0, time.UTC), time.Date(2026, 1, 1, 10, 30, 0, 0, time.UTC), "minute step"},
+		{"10-40/15 * * * *", time.Date(2026, 1, 1, 10, 26, 0, 0, time.UTC), time.Date(2026, 1, 1, 10, 40, 0, 0, time.UTC), "stepped range"},
+		{"0 */6 * * *", time.Date(2026, 1, 1, 7, 0, 0, 0, time.UTC), time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC), "hour step"},
+		{"0 3 * * 1", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 5, 3, 0, 0, 0, time.UTC), "weekday"},
+		{"0 0 1 3 *", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2027, 3, 1, 0, 0, 0, 0, time.UTC), "month wraps the year"},
+		{"0 0 13 * 5", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), "either restricted day field"},
+		{"0 0 30 2 *", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}, "never fires"},
+		{"0 9 * * *", time.Date(2026, 1, 1, 7, 10, 0, 0, kolkata), time.Date(2026, 1, 1, 9, 0, 0, 0, kolkata), "half-hour offset"},
+		{"0 */2 * * *", time.Date(2026, 1, 1, 9, 20, 0, 0, chatham), time.Date(2026, 1, 1, 10, 0, 0, 0, chatham), "three-quarter-hour offset"},
+	}
+	for _, tt := range tests {
+		c, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", tt.expr, err)
+		}
+		if got := c.next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%s: %q next after %s = %s, want %s", tt.describe, tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
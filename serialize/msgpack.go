@@ -0,0 +1,206 @@
+package serialize
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Msgpack encodes a Record as a MessagePack map keyed by its JSON field names.
+type Msgpack struct{}
+
+func (Msgpack) Name() string { return "msgpack" }
+
+func (Msgpack) Marshal(r *Record) ([]byte, error) {
+	b := make([]byte, 0, 64+len(r.Name)+len(r.Body)+16*len(r.Tags))
+	b = append(b, 0x86) // fixmap, 6 entries
+	b = mpString(b, "id")
+	b = mpInt(b, r.ID)
+	b = mpString(b, "name")
+	b = mpString(b, r.Name)
+	b = mpString(b, "active")
+	if r.Active {
+		b = append(b, 0xc3)
+	} else {
+		b = append(b, 0xc2)
+	}
+	b = mpString(b, "score")
+	b = append(b, 0xcb)
+	b = binary.BigEndian.AppendUint64(b, math.Float64bits(r.Score))
+	b = mpString(b, "tags")
+	b = mpArrayHeader(b, len(r.Tags))
+	for _, tag := range r.Tags {
+		b = mpString(b, tag)
+	}
+	b = mpString(b, "body")
+	b = mpString(b, r.Body)
+	return b, nil
+}
+
+func (Msgpack) Unmarshal(b []byte, r *Record) error {
+	*r = Record{}
+	d := mpDecoder{b: b}
+	n := d.mapHeader()
+	for i := 0; i < n && d.err == nil; i++ {
+		switch d.str() {
+		case "id":
+			r.ID = d.int()
+		case "name":
+			r.Name = d.str()
+		case "active":
+			r.Active = d.bool()
+		case "score":
+			r.Score = d.float()
+		case "tags":
+			tags := make([]string, d.arrayHeader())
+			for j := range tags {
+				tags[j] = d.str()
+			}
+			r.Tags = tags
+		case "body":
+			r.Body = d.str()
+		default:
+			d.fail("unknown field")
+		}
+	}
+	return d.err
+}
+
+func mpString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xda)
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b = append(b, 0xdb)
+		b = binary.BigEndian.AppendUint32(b, uint32(n))
+	}
+	return append(b, s...)
+}
+
+func mpInt(b []byte, v int64) []byte {
+	if v >= 0 && v < 128 {
+		return append(b, byte(v))
+	}
+	b = append(b, 0xd3)
+	return binary.BigEndian.AppendUint64(b, uint64(v))
+}
+
+func mpArrayHeader(b []byte, n int) []byte {
+	if n < 16 {
+		return append(b, 0x90|byte(n))
+	}
+	b = append(b, 0xdd)
+	return binary.BigEndian.AppendUint32(b, uint32(n))
+}
+
+// mpDecoder reads the subset of MessagePack that Msgpack.Marshal writes.
+// The first error sticks and later reads return zero values. Lengths read
+// from the input are checked against the bytes left before anything is
+// allocated for them, so corrupt input cannot demand huge allocations.
+type mpDecoder struct {
+	b   []byte
+	err error
+}
+
+// mpZero backs the reads of fixed-size fields past a failure.
+var mpZero [8]byte
+
+func (d *mpDecoder) fail(what string) {
+	if d.err == nil {
+		d.err = fmt.Errorf("%w: msgpack %s", ErrMalformed, what)
+	}
+	d.b = nil
+}
+
+// next consumes n bytes; past a failure it returns zeros for the n <= 8
+// bytes of fixed-size fields, and nothing for longer reads.
+func (d *mpDecoder) next(n int) []byte {
+	if d.err != nil || len(d.b) < n {
+		d.fail("truncated")
+		return mpZero[:min(n, len(mpZero))]
+	}
+	p := d.b[:n]
+	d.b = d.b[n:]
+	return p
+}
+
+func (d *mpDecoder) mapHeader() int {
+	t := d.next(1)[0]
+	if t&0xf0 != 0x80 {
+		d.fail("expected map")
+	}
+	return int(t & 0x0f)
+}
+
+func (d *mpDecoder) arrayHeader() int {
+	switch t := d.next(1)[0]; {
+	case t&0xf0 == 0x90:
+		return int(t & 0x0f)
+	case t == 0xdd:
+		// Every element takes at least a byte.
+		if n := int(binary.BigEndian.Uint32(d.next(4))); n <= len(d.b) {
+			return n
+		}
+		d.fail("truncated")
+		return 0
+	}
+	d.fail("expected array")
+	return 0
+}
+
+func (d *mpDecoder) str() string {
+	var n int
+	switch t := d.next(1)[0]; {
+	case t&0xe0 == 0xa0:
+		n = int(t & 0x1f)
+	case t == 0xd9:
+		n = int(d.next(1)[0])
+	case t == 0xda:
+		n = int(binary.BigEndian.Uint16(d.next(2)))
+	case t == 0xdb:
+		n = int(binary.BigEndian.Uint32(d.next(4)))
+	default:
+		d.fail("expected string")
+		return ""
+	}
+	if n > len(d.b) {
+		d.fail("truncated")
+		return ""
+	}
+	return string(d.next(n))
+}
+
+func (d *mpDecoder) int() int64 {
+	switch t := d.next(1)[0]; {
+	case t < 0x80:
+		return int64(t)
+	case t == 0xd3:
+		return int64(binary.BigEndian.Uint64(d.next(8)))
+	}
+	d.fail("expected int")
+	return 0
+}
+
+func (d *mpDecoder) bool() bool {
+	switch d.next(1)[0] {
+	case 0xc3:
+		return true
+	case 0xc2:
+		return false
+	}
+	d.fail("expected bool")
+	return false
+}
+
+func (d *mpDecoder) float() float64 {
+	if d.next(1)[0] != 0xcb {
+		d.fail("expected float64")
+		return 0
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(d.next(8)))
+}
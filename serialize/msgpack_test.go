@@ -0,0 +1,74 @@
+package serialize
+
+import (
+	"errors"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	records := []Record{
+		{},
+		{ID: 42, Name: "short", Active: true, Score: 3.5, Tags: []string{"a", "b"}, Body: "body"},
+		{ID: -1, Name: strings.Repeat("n", 200), Score: -0.25, Tags: []string{}, Body: strings.Repeat("b", 1000)},
+		{ID: 1 << 40, Name: strings.Repeat("n", 70000), Tags: make([]string, 20), Body: strings.Repeat("x", 31)},
+	}
+	for i, want := range records {
+		b, err := Msgpack{}.Marshal(&want)
+		if err != nil {
+			t.Fatalf("record %d: Marshal: %v", i, err)
+		}
+		// Decoding into a used record must not keep its old fields.
+		got := Record{ID: 7, Name: "stale", Tags: []string{"stale"}}
+		if err := (Msgpack{}).Unmarshal(b, &got); err != nil {
+			t.Fatalf("record %d: Unmarshal: %v", i, err)
+		}
+		if want.Tags == nil {
+			want.Tags = []string{}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("record %d: round trip = %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestMsgpackMalformed(t *testing.T) {
+	valid, err := Msgpack{}.Marshal(&Record{ID: 300, Name: "name", Active: true, Score: 1, Tags: []string{"t"}, Body: "body"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{"empty", nil},
+		{"not a map", []byte{0x90}},
+		{"unknown field", []byte{0x81, 0xa1, 'x', 0x00}},
+		{"wrong type", []byte{0x81, 0xa2, 'i', 'd', 0xa0}},
+		{"huge string", []byte{0x81, 0xa4, 'b', 'o', 'd', 'y', 0xdb, 0xff, 0xff, 0xff, 0xff}},
+		{"huge array", []byte{0x81, 0xa4, 't', 'a', 'g', 's', 0xdd, 0xff, 0xff, 0xff, 0xff}},
+		{"array longer than input", []byte{0x81, 0xa4, 't', 'a', 'g', 's', 0xdd, 0x00, 0x00, 0x00, 0x10, 0xa0}},
+		{"string longer than input", []byte{0x81, 0xa4, 'n', 'a', 'm', 'e', 0xd9, 0x05, 'a'}},
+	}
+	for n := range len(valid) {
+		tests = append(tests, struct {
+			name string
+			b    []byte
+		}{"truncated", valid[:n]})
+	}
+	for _, tt := range tests {
+		var r Record
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		err := Msgpack{}.Unmarshal(tt.b, &r)
+		runtime.ReadMemStats(&after)
+		if !errors.Is(err, ErrMalformed) {
+			t.Errorf("%s %x: error = %v, want ErrMalformed", tt.name, tt.b, err)
+		}
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+			t.Errorf("%s %x: allocated %d bytes", tt.name, tt.b, allocated)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package serialize
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Protobuf encodes a Record in the protocol buffers wire format of the
+// schema in the package documentation.
+type Protobuf struct{}
+
+func (Protobuf) Name() string { return "protobuf" }
+
+// Wire types.
+const (
+	pbVarint  = 0
+	pbFixed64 = 1
+	pbBytes   = 2
+)
+
+func (Protobuf) Marshal(r *Record) ([]byte, error) {
+	b := make([]byte, 0, 32+len(r.Name)+len(r.Body)+16*len(r.Tags))
+	if r.ID != 0 {
+		b = binary.AppendUvarint(b, 1<<3|pbVarint)
+		b = binary.AppendUvarint(b, uint64(r.ID))
+	}
+	b = pbString(b, 2, r.Name)
+	if r.Active {
+		b = append(b, 3<<3|pbVarint, 1)
+	}
+	if r.Score != 0 {
+		b = append(b, 4<<3|pbFixed64)
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(r.Score))
+	}
+	for _, tag := range r.Tags {
+		b = binary.AppendUvarint(b, 5<<3|pbBytes)
+		b = binary.AppendUvarint(b, uint64(len(tag)))
+		b = append(b, tag...)
+	}
+	b = pbString(b, 6, r.Body)
+	return b, nil
+}
+
+// pbString appends a non-empty string field; proto3 omits empty ones.
+func pbString(b []byte, field uint64, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = binary.AppendUvarint(b, field<<3|pbBytes)
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func (Protobuf) Unmarshal(b []byte, r *Record) error {
+	*r = Record{}
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return fmt.Errorf("%w: protobuf field key", ErrMalformed)
+		}
+		b = b[n:]
+		field, wire := key>>3, key&7
+		switch wire {
+		case pbVarint:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return fmt.Errorf("%w: protobuf varint", ErrMalformed)
+			}
+			b = b[n:]
+			switch field {
+			case 1:
+				r.ID = int64(v)
+			case 3:
+				r.Active = v != 0
+			}
+		case pbFixed64:
+			if len(b) < 8 {
+				return fmt.Errorf("%w: protobuf fixed64", ErrMalformed)
+			}
+			if field == 4 {
+				r.Score = math.Float64frombits(binary.LittleEndian.Uint64(b))
+			}
+			b = b[8:]
+		case pbBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return fmt.Errorf("%w: protobuf length", ErrMalformed)
+			}
+			s := string(b[n : n+int(l)])
+			b = b[n+int(l):]
+			switch field {
+			case 2:
+				r.Name = s
+			case 5:
+				r.Tags = append(r.Tags, s)
+			case 6:
+				r.Body = s
+			}
+		default:
+			return fmt.Errorf("%w: protobuf wire type %d", ErrMalformed, wire)
+		}
+	}
+	return nil
+}
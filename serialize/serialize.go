@@ -0,0 +1,65 @@
+// Package serialize turns a sample Go object into bytes and back in several
+// formats, so benchmarks can measure the end-to-end "cache a Go object" path
+// rather than raw string round-trips.
+//
+// The msgpack and protobuf formats are hand-written for Record, producing
+// exactly what a generated or reflection-based encoder would for the same
+// schema, without adding code generation or dependencies:
+//
+//	message Record {
+//	  int64 id = 1;
+//	  string name = 2;
+//	  bool active = 3;
+//	  double score = 4;
+//	  repeated string tags = 5;
+//	  string body = 6;
+//	}
+package serialize
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Record is the sample object cached by serialized runs.
+type Record struct {
+	ID     int64    `json:"id"`
+	Name   string   `json:"name"`
+	Active bool     `json:"active"`
+	Score  float64  `json:"score"`
+	Tags   []string `json:"tags"`
+	Body   string   `json:"body"`
+}
+
+// Serializer marshals Records in one format.
+type Serializer interface {
+	Name() string
+	Marshal(r *Record) ([]byte, error)
+	Unmarshal(b []byte, r *Record) error
+}
+
+// ErrMalformed is returned for bytes that do not decode as a Record.
+var ErrMalformed = errors.New("serialize: malformed record")
+
+// ByName returns the serializer registered as "json", "msgpack" or "protobuf".
+func ByName(name string) (Serializer, error) {
+	switch name {
+	case "json":
+		return JSON{}, nil
+	case "msgpack":
+		return Msgpack{}, nil
+	case "protobuf":
+		return Protobuf{}, nil
+	}
+	return nil, fmt.Errorf("unknown serializer %q (want json, msgpack or protobuf)", name)
+}
+
+// JSON uses encoding/json.
+type JSON struct{}
+
+func (JSON) Name() string { return "json" }
+
+func (JSON) Marshal(r *Record) ([]byte, error) { return json.Marshal(r) }
+
+func (JSON) Unmarshal(b []byte, r *Record) error { return json.Unmarshal(b, r) }
@@ -0,0 +1,490 @@
+package main
+
+import (
+	"bufio"
+	"caching-benchmark/benchmark"
+	"caching-benchmark/implementations"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// runServeAPI runs a long-lived HTTP server that accepts scenario runs,
+// executes them one at a time against the configured Redis, and serves their
+// progress and results:
+//
+//	POST   /runs              submit {"scenario": "<default scenario name>"} or {"config": {...}}
+//	GET    /runs              list runs
+//	GET    /runs/{id}         run status and results
+//	GET    /runs/{id}/events  stream the run's log as server-sent events
+//	DELETE /runs/{id}         cancel a queued or running run
+//	GET    /scenarios         list the default scenario names
+//
+// Durations in a submitted config are integers in nanoseconds.
+//
+// With -schedule, the scenarios named by -campaign are also queued on that
+// cron schedule; their results are recorded in the -results-db SQLite
+// database and compared week over week, and drifts beyond -drift-threshold are posted to -alert-webhook.
+func runServeAPI(args []string) error {
+	fs := flag.NewFlagSet("serve-api", flag.ExitOnError)
+	listen := fs.String("listen", ":8080", "HTTP listen address")
+	addr := fs.String("addr", implementations.DefaultAddr, "Redis address runs are executed against")
+	standbyAddr := fs.String("standby-addr", "", "warm-standby Redis address for failover scenarios")
+	schedule := fs.String("schedule", "", "cron expression (minute hour dom month dow) for recurring campaign runs")
+	campaignList := fs.String("campaign", "Read-Heavy (90% Read, 64B Values)", "comma-separated default scenario names run on -schedule")
+	resultsDB := fs.String("results-db", "", "SQLite results database campaign results are recorded in and trended from")
+	webhook := fs.String("alert-webhook", "", "URL that receives a JSON POST when campaign metrics drift")
+	threshold := fs.Float64("drift-threshold", 0.1, "relative week-over-week change that triggers a drift alert")
+	keyPrefix := fs.String("key-prefix", "", "namespace every benchmark key under this prefix")
+	noFlush := fs.Bool("no-flush", false, "delete only keys under -key-prefix before each run instead of running FLUSHALL")
+	assumeYes := fs.Bool("yes", false, "FLUSHALL a non-empty server without asking")
+	fs.Parse(args)
+	if *noFlush && *keyPrefix == "" {
+		return errors.New("-no-flush needs a -key-prefix to clean up by")
+	}
+
+	var camp *campaign
+	if *schedule != "" {
+		var err error
+		if camp, err = newCampaign(*schedule, *campaignList, *resultsDB, *webhook, *threshold); err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if !*noFlush {
+		if err := confirmFlush(ctx, *addr, *assumeYes); err != nil {
+			return err
+		}
+	}
+
+	clock := benchmark.MeasureClock()
+	s := &apiServer{
+		addr:        *addr,
+		standbyAddr: *standbyAddr,
+		keyPrefix:   *keyPrefix,
+		noFlush:     *noFlush,
+		clock:       clock,
+		runs:        make(map[string]*apiRun),
+		queue:       make(chan *apiRun, 64),
+		campaign:    camp,
+	}
+	go s.execute(ctx)
+	if camp != nil {
+		go camp.run(ctx, s)
+	}
+
+	srv := &http.Server{Addr: *listen, Handler: s.routes()}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	log.Printf("Serving benchmark API on %s against Redis at %s", *listen, *addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Run states reported by the API.
+const (
+	runQueued    = "queued"
+	runRunning   = "running"
+	runDone      = "done"
+	runFailed    = "failed"
+	runCancelled = "cancelled"
+)
+
+type apiServer struct {
+	addr        string
+	standbyAddr string
+	keyPrefix   string
+	noFlush     bool
+	clock       benchmark.ClockCheck
+	queue       chan *apiRun
+	campaign    *campaign
+
+	mu     sync.Mutex
+	runs   map[string]*apiRun
+	order  []string
+	nextID int
+}
+
+// apiRun is one submitted scenario. Its exported fields are its JSON form.
+type apiRun struct {
+	ID        string          `json:"id"`
+	Scenario  string          `json:"scenario"`
+	Scheduled bool            `json:"scheduled,omitempty"`
+	Status    string          `json:"status"`
+	Error     string          `json:"error,omitempty"`
+	Submitted time.Time       `json:"submitted"`
+	Started   *time.Time      `json:"started,omitempty"`
+	Finished  *time.Time      `json:"finished,omitempty"`
+	Results   []resultSummary `json:"results,omitempty"`
+
+	cfg    Config
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// lines is the run's captured log; changed is closed and replaced
+	// whenever a line is added or the run finishes.
+	lines   []string
+	changed chan struct{}
+}
+
+// resultSummary is the API form of a benchmark.Result, without raw latencies.
+type resultSummary struct {
+	Strategy         string  `json:"strategy"`
+	Incomplete       bool    `json:"incomplete,omitempty"`
+	Operations       int64   `json:"operations"`
+	OpsPerSecond     float64 `json:"ops_per_second"`
+	HitRate          float64 `json:"hit_rate"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms"`
+	P95LatencyMs     float64 `json:"p95_latency_ms"`
+	Errors           int64   `json:"errors"`
+	Timeouts         int64   `json:"timeouts"`
+	StaleReads       int64   `json:"stale_reads"`
+	LostWrites       int64   `json:"lost_writes"`
+	BackendReqPer1k  float64 `json:"backend_requests_per_1k_ops"`
+	StalenessTracked bool    `json:"staleness_tracked"`
+	// Metadata describes the host, software and configuration of the run.
+	Metadata *benchmark.Metadata `json:"metadata,omitempty"`
+}
+
+func summarize(r benchmark.Result) resultSummary {
+	avg, p95 := latencyStats(r.Latencies)
+	return resultSummary{
+		Strategy:         r.StrategyName,
+		Incomplete:       r.Incomplete,
+		Operations:       r.TotalOperations,
+		OpsPerSecond:     r.OpsPerSecond,
+		HitRate:          r.HitRate,
+		AvgLatencyMs:     ms(avg),
+		P95LatencyMs:     ms(p95),
+		Errors:           r.TotalErrors,
+		Timeouts:         r.TotalTimeouts,
+		StaleReads:       r.StaleReads,
+		LostWrites:       r.LostWrites,
+		BackendReqPer1k:  r.BackendRequestsPer1kOps,
+		StalenessTracked: r.StalenessTracked,
+		Metadata:         r.Metadata,
+	}
+}
+
+func (s *apiServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /runs", s.submit)
+	mux.HandleFunc("GET /runs", s.list)
+	mux.HandleFunc("GET /runs/{id}", s.get)
+	mux.HandleFunc("GET /runs/{id}/events", s.events)
+	mux.HandleFunc("DELETE /runs/{id}", s.cancel)
+	mux.HandleFunc("GET /scenarios", func(w http.ResponseWriter, r *http.Request) {
+		var names []string
+		for _, cfg := range defaultScenarios() {
+			names = append(names, cfg.Name)
+		}
+		writeJSON(w, http.StatusOK, names)
+	})
+	return mux
+}
+
+func (s *apiServer) submit(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Scenario string  `json:"scenario"`
+		Config   *Config `json:"config"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var cfg Config
+	switch {
+	case req.Config != nil:
+		cfg = *req.Config
+	case req.Scenario != "":
+		found := false
+		for _, c := range defaultScenarios() {
+			if c.Name == req.Scenario {
+				cfg, found = c, true
+				break
+			}
+		}
+		if !found {
+			http.Error(w, fmt.Sprintf("unknown scenario %q", req.Scenario), http.StatusNotFound)
+			return
+		}
+	default:
+		http.Error(w, `request needs "scenario" or "config"`, http.StatusBadRequest)
+		return
+	}
+	if cfg.NumOperations <= 0 || cfg.NumKeys <= 0 || cfg.Concurrency <= 0 {
+		http.Error(w, "config needs positive NumOperations, NumKeys and Concurrency", http.StatusBadRequest)
+		return
+	}
+	if _, err := buildStrategies(cfg); err != nil {
+		http.Error(w, "invalid strategies: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	run, err := s.enqueue(cfg, false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, s.snapshot(run))
+}
+
+// enqueue registers a run for cfg and queues it for execution.
+func (s *apiServer) enqueue(cfg Config, scheduled bool) (*apiRun, error) {
+	// Endpoints and host facts belong to the server, not the submitter,
+	// and submitters may not have the server write files or export spans.
+	cfg.Addr = s.addr
+	cfg.StandbyAddr = s.standbyAddr
+	cfg.KeyPrefix = s.keyPrefix
+	cfg.NoFlush = s.noFlush
+	cfg.Clock = &s.clock
+	cfg.CurveDir, cfg.ProfileDir, cfg.WorkloadCache, cfg.L1SnapshotDir = "", "", "", ""
+	cfg.Tracer, cfg.TraceSampleRate = nil, 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.nextID++
+	run := &apiRun{
+		ID:        strconv.Itoa(s.nextID),
+		Scenario:  cfg.Name,
+		Scheduled: scheduled,
+		Status:    runQueued,
+		Submitted: time.Now(),
+		cfg:       cfg,
+		ctx:       ctx,
+		cancel:    cancel,
+		changed:   make(chan struct{}),
+	}
+	s.runs[run.ID] = run
+	s.order = append(s.order, run.ID)
+	s.mu.Unlock()
+
+	select {
+	case s.queue <- run:
+	default:
+		err := errors.New("run queue is full")
+		s.finish(run, runFailed, err)
+		return nil, err
+	}
+	return run, nil
+}
+
+func (s *apiServer) list(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	runs := make([]apiRun, 0, len(s.order))
+	for _, id := range s.order {
+		run := *s.runs[id]
+		run.Results = nil
+		runs = append(runs, run)
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, runs)
+}
+
+func (s *apiServer) get(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.lookup(w, r)
+	if ok {
+		writeJSON(w, http.StatusOK, s.snapshot(run))
+	}
+}
+
+func (s *apiServer) cancel(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	run.cancel()
+	writeJSON(w, http.StatusAccepted, s.snapshot(run))
+}
+
+// events streams the run's log lines as server-sent events until the run
+// finishes or the client disconnects.
+func (s *apiServer) events(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	sent := 0
+	for {
+		s.mu.Lock()
+		lines := run.lines[sent:]
+		changed := run.changed
+		status := run.Status
+		s.mu.Unlock()
+
+		for _, line := range lines {
+			fmt.Fprintf(w, "event: log\ndata: %s\n\n", line)
+		}
+		sent += len(lines)
+		if status != runQueued && status != runRunning {
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", status, run.ID)
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *apiServer) lookup(w http.ResponseWriter, r *http.Request) (*apiRun, bool) {
+	s.mu.Lock()
+	run, ok := s.runs[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "no such run", http.StatusNotFound)
+	}
+	return run, ok
+}
+
+// snapshot copies a run's JSON fields under the lock.
+func (s *apiServer) snapshot(run *apiRun) apiRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap := *run
+	snap.Results = append([]resultSummary(nil), run.Results...)
+	return snap
+}
+
+// execute runs queued scenarios one at a time, since they share the backend.
+func (s *apiServer) execute(ctx context.Context) {
+	for {
+		var run *apiRun
+		select {
+		case <-ctx.Done():
+			return
+		case run = <-s.queue:
+		}
+		if run.ctx.Err() != nil {
+			s.finish(run, runCancelled, nil)
+			continue
+		}
+
+		started := time.Now()
+		s.mu.Lock()
+		run.Status = runRunning
+		run.Started = &started
+		s.notify(run)
+		s.mu.Unlock()
+
+		// Scenarios report progress through the standard logger, so it is
+		// captured for the run while it executes.
+		runCtx, stop := context.WithCancel(run.ctx)
+		go func() {
+			select {
+			case <-ctx.Done():
+				stop()
+			case <-runCtx.Done():
+			}
+		}()
+		pr, pw := io.Pipe()
+		captured := make(chan struct{})
+		go s.capture(run, pr, captured)
+		log.SetOutput(io.MultiWriter(os.Stderr, pw))
+		results, err := runScenario(runCtx, run.cfg)
+		log.SetOutput(os.Stderr)
+		pw.Close()
+		<-captured
+		stop()
+
+		s.mu.Lock()
+		for _, r := range results {
+			run.Results = append(run.Results, summarize(r))
+		}
+		s.mu.Unlock()
+		switch {
+		case run.ctx.Err() != nil || ctx.Err() != nil:
+			s.finish(run, runCancelled, err)
+		case err != nil:
+			s.finish(run, runFailed, err)
+		default:
+			s.finish(run, runDone, nil)
+			if run.Scheduled && s.campaign != nil {
+				s.campaign.record(newRunID(started)+"-"+run.ID, scenarioResults{name: run.cfg.Name, results: results, config: run.cfg})
+			}
+		}
+	}
+}
+
+// maxLogLine is the longest log line captured for a run.
+const maxLogLine = 1 << 20
+
+// capture appends each log line read from r to the run. It reads r to the
+// end even if a line is too long to capture, since the process-wide logger
+// writes to r until the run finishes.
+func (s *apiServer) capture(run *apiRun, r io.Reader, done chan<- struct{}) {
+	defer close(done)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLogLine)
+	for scanner.Scan() {
+		s.mu.Lock()
+		run.lines = append(run.lines, scanner.Text())
+		s.notify(run)
+		s.mu.Unlock()
+	}
+	if err := scanner.Err(); err != nil {
+		s.mu.Lock()
+		run.lines = append(run.lines, "log capture stopped: "+err.Error())
+		s.notify(run)
+		s.mu.Unlock()
+		io.Copy(io.Discard, r)
+	}
+}
+
+func (s *apiServer) finish(run *apiRun, status string, err error) {
+	finished := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run.Status = status
+	run.Finished = &finished
+	if err != nil {
+		run.Error = err.Error()
+	}
+	run.cancel()
+	s.notify(run)
+}
+
+// notify wakes event streams waiting on run. The caller holds s.mu.
+func (s *apiServer) notify(run *apiRun) {
+	close(run.changed)
+	run.changed = make(chan struct{})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
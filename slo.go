@@ -0,0 +1,152 @@
+package main
+
+import (
+	"caching-benchmark/benchmark"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sloExitCode is the process exit code when a run missed one of its
+// scenario's SLOs.
+const sloExitCode = 3
+
+// SLO is a threshold every strategy run in a scenario must meet, parsed
+// from "<metric> <op> <value>", e.g. "p99 < 2ms" or "hit-rate > 80%".
+// Metrics are latency percentiles ("p50", "p99.9"), "avg" latency,
+// "hit-rate" and "error-rate" in percent, "ops/sec", "stale-reads",
+// "lost-writes" and "ryw-violations"; ops are <, <=, > and >=.
+type SLO struct {
+	metric string
+	op     string
+	limit  float64
+}
+
+func parseSLO(s string) (SLO, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 3 {
+		return SLO{}, fmt.Errorf("SLO %q: want \"<metric> <op> <value>\"", s)
+	}
+	slo := SLO{metric: strings.ToLower(fields[0]), op: fields[1]}
+	switch slo.op {
+	case "<", "<=", ">", ">=":
+	default:
+		return SLO{}, fmt.Errorf("SLO %q: unknown comparison %q", s, slo.op)
+	}
+	value := fields[2]
+	var err error
+	switch {
+	case slo.metric == "avg" || isPercentileMetric(slo.metric):
+		var d time.Duration
+		d, err = time.ParseDuration(value)
+		slo.limit = ms(d)
+	case slo.metric == "hit-rate" || slo.metric == "error-rate":
+		slo.limit, err = strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	case slo.metric == "ops/sec" || slo.metric == "stale-reads" || slo.metric == "lost-writes" || slo.metric == "ryw-violations":
+		slo.limit, err = strconv.ParseFloat(value, 64)
+	default:
+		return SLO{}, fmt.Errorf("SLO %q: unknown metric %q", s, fields[0])
+	}
+	if err != nil {
+		return SLO{}, fmt.Errorf("SLO %q: invalid value %q", s, value)
+	}
+	return slo, nil
+}
+
+func parseSLOs(specs []string) ([]SLO, error) {
+	slos := make([]SLO, 0, len(specs))
+	for _, spec := range specs {
+		slo, err := parseSLO(spec)
+		if err != nil {
+			return nil, err
+		}
+		slos = append(slos, slo)
+	}
+	return slos, nil
+}
+
+// isPercentileMetric reports whether metric names a latency percentile,
+// such as "p99".
+func isPercentileMetric(metric string) bool {
+	if !strings.HasPrefix(metric, "p") {
+		return false
+	}
+	p, err := strconv.ParseFloat(metric[1:], 64)
+	return err == nil && p > 0 && p <= 100
+}
+
+// measure returns the SLO's metric for r, in milliseconds for latencies
+// and percent for rates. r's latencies must be sorted.
+func (s SLO) measure(r benchmark.Result) float64 {
+	switch s.metric {
+	case "avg":
+		avg, _ := latencyStats(r.Latencies)
+		return ms(avg)
+	case "hit-rate":
+		return r.HitRate * 100
+	case "error-rate":
+		if r.TotalOperations == 0 {
+			return 0
+		}
+		return float64(r.TotalErrors) / float64(r.TotalOperations) * 100
+	case "ops/sec":
+		return r.OpsPerSecond
+	case "stale-reads":
+		return float64(r.StaleReads)
+	case "lost-writes":
+		return float64(r.LostWrites)
+	case "ryw-violations":
+		return float64(r.ReadYourWritesViolations)
+	}
+	p, _ := strconv.ParseFloat(s.metric[1:], 64)
+	sortLatencies(r.Latencies)
+	return ms(nearestRank(r.Latencies, p))
+}
+
+func (s SLO) met(v float64) bool {
+	switch s.op {
+	case "<":
+		return v < s.limit
+	case "<=":
+		return v <= s.limit
+	case ">":
+		return v > s.limit
+	}
+	return v >= s.limit
+}
+
+// checkSLOs returns the table cell summarizing r against slos, e.g. "PASS"
+// or "FAIL: p99 = 3.10", and whether r missed any. Incomplete runs and
+// runs without latencies are not checked.
+func checkSLOs(slos []SLO, r benchmark.Result) (cell string, failed bool) {
+	if len(slos) == 0 || len(r.Latencies) == 0 || r.Incomplete {
+		return "-", false
+	}
+	var misses []string
+	for _, s := range slos {
+		if v := s.measure(r); !s.met(v) {
+			misses = append(misses, fmt.Sprintf("%s = %.2f", s.metric, v))
+		}
+	}
+	if len(misses) == 0 {
+		return "PASS", false
+	}
+	return "FAIL: " + strings.Join(misses, ", "), true
+}
+
+// reportSLOs logs every run that missed an SLO of its scenario and returns
+// how many did.
+func reportSLOs(allResults []scenarioResults) int {
+	failed := 0
+	for _, sr := range allResults {
+		for _, r := range sr.results {
+			if cell, miss := checkSLOs(sr.slos, r); miss {
+				log.Printf("SLO missed in %s by %s (%s)", sr.name, r.StrategyName, cell)
+				failed++
+			}
+		}
+	}
+	return failed
+}
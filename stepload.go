@@ -0,0 +1,184 @@
+package main
+
+import (
+	"caching-benchmark/benchmark"
+	"caching-benchmark/workload"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// stepSustainFraction is the share of the offered rate a step must achieve,
+// besides meeting the p99 limit, to count as sustained.
+const stepSustainFraction = 0.95
+
+// stepPoint is one open-loop run of a step-load test.
+type stepPoint struct {
+	offered     float64
+	achieved    float64
+	p99Service  time.Duration
+	p99Response time.Duration
+	sustained   bool
+}
+
+// stepCurve is the step-load ramp of one strategy.
+type stepCurve struct {
+	strategy string
+	limit    time.Duration
+	points   []stepPoint
+}
+
+// maxSustainable returns the highest offered rate sustained before the
+// first step that was not, or zero if the first step already failed.
+func (c stepCurve) maxSustainable() float64 {
+	var best float64
+	for _, p := range c.points {
+		if !p.sustained {
+			break
+		}
+		best = p.offered
+	}
+	return best
+}
+
+func validateStepLoad(cfg Config) error {
+	if cfg.StepDuration <= 0 || cfg.StepP99Limit <= 0 {
+		return fmt.Errorf("a step-load test needs a positive step duration and p99 limit")
+	}
+	for i, rate := range cfg.StepRates {
+		if rate <= 0 || (i > 0 && rate <= cfg.StepRates[i-1]) {
+			return fmt.Errorf("step rates must be positive and increasing, got %v", cfg.StepRates)
+		}
+	}
+	return nil
+}
+
+// runStepLoad offers each strategy cfg.StepRates in turn, each for
+// cfg.StepDuration, stopping after the first step whose p99 response time
+// exceeds cfg.StepP99Limit or whose achieved rate falls short of the
+// offered one.
+func runStepLoad(ctx context.Context, cfg Config, seed int64) ([]benchmark.Result, error) {
+	var results []benchmark.Result
+	var curves []stepCurve
+	defer func() {
+		printStepCurves(curves)
+		if cfg.CurveDir != "" && len(curves) > 0 {
+			if err := writeStepCurves(cfg.CurveDir, cfg.Name, curves); err != nil {
+				log.Printf("Failed to write step-load curves: %v", err)
+			}
+		}
+	}()
+
+	workloads := make(map[int][]workload.Operation, len(cfg.StepRates))
+	for _, spec := range strategySpecs(cfg) {
+		curve := stepCurve{limit: cfg.StepP99Limit}
+		for _, rate := range cfg.StepRates {
+			stepCfg := cfg
+			stepCfg.NumOperations = max(1, int(rate*cfg.StepDuration.Seconds()))
+			stepCfg.TargetRate = rate
+			w, ok := workloads[stepCfg.NumOperations]
+			if !ok {
+				w = generateWorkload(stepCfg, seed)
+				workloads[stepCfg.NumOperations] = w
+			}
+
+			// Every run gets a fresh strategy: strategies are not reusable after Close.
+			ns, err := buildStrategy(stepCfg, spec)
+			if err != nil {
+				return results, err
+			}
+			log.Printf("\n--- Running Strategy: %s at %.0f ops/sec for %v ---", ns.strategy.Name(), rate, cfg.StepDuration)
+			prep, err := prepareData(ctx, stepCfg, seed)
+			if err != nil {
+				return results, fmt.Errorf("failed to prepare data for strategy %s: %w", ns.strategy.Name(), err)
+			}
+			result, err := benchmark.NewRunner(ns.strategy, w, runnerOptions(stepCfg, ns, seed)).Run(ctx)
+			if err != nil {
+				log.Printf("Error running strategy %s at %.0f ops/sec: %v", ns.strategy.Name(), rate, err)
+				break
+			}
+			result.Lifecycle.DataPrep = prep
+
+			curve.strategy = result.StrategyName
+			point := stepPoint{offered: rate, achieved: result.OpsPerSecond}
+			sortLatencies(result.Latencies)
+			sortLatencies(result.ResponseTimes)
+			point.p99Service = nearestRank(result.Latencies, 99)
+			point.p99Response = nearestRank(result.ResponseTimes, 99)
+			point.sustained = !result.Incomplete && point.p99Response <= cfg.StepP99Limit &&
+				point.achieved >= stepSustainFraction*rate
+			curve.points = append(curve.points, point)
+
+			result.StrategyName = fmt.Sprintf("%s [%.0f ops/sec offered]", result.StrategyName, rate)
+			results = append(results, result)
+			if result.Incomplete {
+				curves = append(curves, curve)
+				return results, nil
+			}
+			if !point.sustained {
+				break
+			}
+		}
+		if len(curve.points) > 0 {
+			curves = append(curves, curve)
+		}
+	}
+	return results, nil
+}
+
+// printStepCurves prints one table per strategy with a row per step and its
+// max sustainable throughput.
+func printStepCurves(curves []stepCurve) {
+	for _, c := range curves {
+		log.Printf("\n--- Step Load: %s (max sustainable %.0f ops/sec at p99 <= %v) ---", c.strategy, c.maxSustainable(), c.limit)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.AlignRight|tabwriter.Debug)
+		fmt.Fprintln(w, "Offered Ops/sec\tAchieved Ops/sec\tP99 Service (ms)\tP99 Response (ms)\tSustained\t")
+		for _, p := range c.points {
+			fmt.Fprintf(w, "%.0f\t%.2f\t%.4f\t%.4f\t%t\t\n", p.offered, p.achieved, ms(p.p99Service), ms(p.p99Response), p.sustained)
+		}
+		w.Flush()
+	}
+}
+
+// writeStepCurves writes the scenario's step-load ramps to dir as CSV.
+func writeStepCurves(dir, scenario string, curves []stepCurve) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, slug(scenario)+".csv")
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(f)
+	cw.Write([]string{"strategy", "offered_ops", "achieved_ops", "p99_service_ms", "p99_response_ms", "sustained", "max_sustainable_ops"})
+	for _, c := range curves {
+		for _, p := range c.points {
+			cw.Write([]string{
+				c.strategy,
+				formatFloat(p.offered),
+				formatFloat(p.achieved),
+				formatFloat(ms(p.p99Service)),
+				formatFloat(ms(p.p99Response)),
+				strconv.FormatBool(p.sustained),
+				formatFloat(c.maxSustainable()),
+			})
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	log.Printf("Step-load curves written to %s", path)
+	return nil
+}
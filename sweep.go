@@ -0,0 +1,131 @@
+package main
+
+import (
+	"caching-benchmark/benchmark"
+	"caching-benchmark/implementations"
+	"caching-benchmark/workload"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// Knob is one axis of a sweep grid: an implementations.Params knob name and
+// the values to try for it.
+type Knob struct {
+	Name   string
+	Values []string
+}
+
+// Sweep runs one strategy at every point of the cartesian product of its knobs.
+type Sweep struct {
+	Strategy string
+	Knobs    []Knob
+}
+
+// grid expands the knobs into every combination of values, each given as
+// one value per knob in knob order.
+func (sw Sweep) grid() [][]string {
+	points := [][]string{{}}
+	for _, k := range sw.Knobs {
+		var next [][]string
+		for _, p := range points {
+			for _, v := range k.Values {
+				next = append(next, append(append([]string(nil), p...), v))
+			}
+		}
+		points = next
+	}
+	return points
+}
+
+// runSweep runs the workload once per grid point and prints a matrix report.
+func runSweep(ctx context.Context, cfg Config, w []workload.Operation, seed int64, sw Sweep) ([]benchmark.Result, error) {
+	points := sw.grid()
+	log.Printf("\n--- Sweeping %s over %d configurations ---", sw.Strategy, len(points))
+
+	var results []benchmark.Result
+	var ranPoints [][]string
+	for _, point := range points {
+		params := baseParams(cfg)
+		settings := make([]string, len(point))
+		for i, v := range point {
+			if err := params.Set(sw.Knobs[i].Name, v); err != nil {
+				return results, err
+			}
+			settings[i] = sw.Knobs[i].Name + "=" + v
+		}
+		s, err := implementations.New(sw.Strategy, params)
+		if err != nil {
+			return results, err
+		}
+
+		log.Printf("\n--- Running Strategy: %s [%s] ---", s.Name(), strings.Join(settings, " "))
+		prep, err := prepareData(ctx, cfg, seed)
+		if err != nil {
+			return results, err
+		}
+		result, err := benchmark.NewRunner(s, w, runnerOptions(cfg, namedStrategy{name: sw.Strategy, strategy: s, maxInFlight: params.MaxInFlight}, seed)).Run(ctx)
+		if err != nil {
+			log.Printf("Error running sweep point %v: %v", settings, err)
+			continue
+		}
+		result.Lifecycle.DataPrep = prep
+		result.StrategyName = fmt.Sprintf("%s [%s]", result.StrategyName, strings.Join(settings, " "))
+		results = append(results, result)
+		ranPoints = append(ranPoints, point)
+		if result.Incomplete {
+			break
+		}
+	}
+
+	printSweepMatrix(sw, ranPoints, results)
+	return results, nil
+}
+
+// printSweepMatrix prints one row per grid point, with the backend traffic
+// and stale reads each configuration traded for its hit rate, and names the
+// best configuration by throughput and by hit rate.
+func printSweepMatrix(sw Sweep, points [][]string, results []benchmark.Result) {
+	if len(results) == 0 {
+		return
+	}
+	log.Printf("\n--- Sweep Matrix: %s ---", sw.Strategy)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.AlignRight|tabwriter.Debug)
+	for _, k := range sw.Knobs {
+		fmt.Fprintf(w, "%s\t", k.Name)
+	}
+	fmt.Fprintln(w, "Ops/sec\tHit Rate (%)\tAvg Latency (ms)\tP95 Latency (ms)\tBackend Req/1k Ops\tStale Reads\t")
+
+	bestOps, bestHit := 0, 0
+	for i, r := range results {
+		avg, p95 := latencyStats(r.Latencies)
+		for _, v := range points[i] {
+			fmt.Fprintf(w, "%s\t", v)
+		}
+		staleReads := "-"
+		if r.StalenessTracked {
+			staleReads = fmt.Sprint(r.StaleReads)
+		}
+		fmt.Fprintf(w, "%.2f\t%.2f\t%.4f\t%.4f\t%.1f\t%s\t\n",
+			r.OpsPerSecond,
+			r.HitRate*100,
+			float64(avg.Microseconds())/1000.0,
+			float64(p95.Microseconds())/1000.0,
+			r.BackendRequestsPer1kOps,
+			staleReads,
+		)
+		if r.OpsPerSecond > results[bestOps].OpsPerSecond {
+			bestOps = i
+		}
+		if r.HitRate > results[bestHit].HitRate {
+			bestHit = i
+		}
+	}
+	w.Flush()
+
+	log.Printf("Best throughput: %s (%.2f ops/sec)", results[bestOps].StrategyName, results[bestOps].OpsPerSecond)
+	log.Printf("Best hit rate: %s (%.2f%%)", results[bestHit].StrategyName, results[bestHit].HitRate*100)
+}
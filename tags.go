@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// scenarioTags describes the tags default scenarios are grouped by.
+var scenarioTags = map[string]string{
+	"quick":         "short scenarios covering the main strategies, for CI smoke runs",
+	"large-values":  "values of 256KB and more",
+	"serialization": "structured, serialized or compressed values",
+	"invalidation":  "invalidation delivery, freshness and consistency",
+	"l1":            "L1 sizing, sharding, snapshots and restarts",
+	"faults":        "injected faults, restarts and failover",
+	"sweep":         "multi-run sweeps over load, concurrency, keys or settings",
+	"network":       "simulated network latency",
+	"needs-standby": "needs a standby server (-standby-addr)",
+	"redis7":        "needs Redis 7 or later",
+	"server-config": "reconfigures the server, e.g. its maxmemory",
+}
+
+// parseTags splits a comma-separated tag list, rejecting tags no scenario
+// is grouped by so that a typo does not silently select nothing.
+func parseTags(list string) ([]string, error) {
+	var tags []string
+	for _, tag := range strings.Split(list, ",") {
+		if tag = strings.TrimSpace(tag); tag == "" {
+			continue
+		}
+		if _, ok := scenarioTags[tag]; !ok {
+			known := make([]string, 0, len(scenarioTags))
+			for t := range scenarioTags {
+				known = append(known, t)
+			}
+			sort.Strings(known)
+			return nil, fmt.Errorf("unknown scenario tag %q (known: %s)", tag, strings.Join(known, ", "))
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// selected reports whether cfg runs under the -tags and -skip-tags
+// filters: it must have one of tags, unless tags is empty, and none of skip.
+func selected(cfg Config, tags, skip []string) bool {
+	has := func(tag string) bool { return slices.Contains(cfg.Tags, tag) }
+	if len(tags) > 0 && !slices.ContainsFunc(tags, has) {
+		return false
+	}
+	return !slices.ContainsFunc(skip, has)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"caching-benchmark/workload"
+	"fmt"
+	"math"
+)
+
+// tenantMix partitions the scenario's keys into cfg.Tenants equal
+// namespaces, "tenant-0" to "tenant-<N-1>", each a Zipf workload over its
+// own keys. Tenant i receives a share of the traffic proportional to
+// 1/(i+1)^TenantSkew, so tenant-0 is the noisiest.
+func tenantMix(cfg Config) ([]workload.Component, error) {
+	if cfg.Tenants > cfg.NumKeys || cfg.TenantSkew < 0 {
+		return nil, fmt.Errorf("%d tenants need at least as many keys (have %d) and a non-negative skew", cfg.Tenants, cfg.NumKeys)
+	}
+	mix := make([]workload.Component, cfg.Tenants)
+	for i := range mix {
+		mix[i] = workload.Component{
+			Name:           fmt.Sprintf("tenant-%d", i),
+			Weight:         1 / math.Pow(float64(i+1), cfg.TenantSkew),
+			Distribution:   workload.DistZipf,
+			NumKeys:        cfg.NumKeys / cfg.Tenants,
+			ReadWriteRatio: cfg.ReadWriteRatio,
+			ZipfS:          cfg.ZipfS,
+			ZipfV:          cfg.ZipfV,
+		}
+	}
+	return mix, nil
+}
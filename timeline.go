@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"caching-benchmark/benchmark"
+)
+
+// writeTimelines writes each run's annotated per-second latency timeline to
+// <dir>/<scenario>/<strategy>.timeline.csv.
+func writeTimelines(dir string, allResults []scenarioResults) error {
+	for _, sr := range allResults {
+		scenarioDir := filepath.Join(dir, slug(sr.name))
+		if err := os.MkdirAll(scenarioDir, 0o755); err != nil {
+			return err
+		}
+		for _, r := range sr.results {
+			if len(r.Timeline) == 0 {
+				continue
+			}
+			if err := writeTimeline(filepath.Join(scenarioDir, slug(r.StrategyName)+".timeline.csv"), r.Timeline); err != nil {
+				return err
+			}
+		}
+	}
+	log.Printf("Latency timelines written to %s", dir)
+	return nil
+}
+
+func writeTimeline(path string, seconds []benchmark.TimelineSecond) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "second,ops,p50_ms,p99_ms,invalidations,gc_cycles,gc_pause_ms,spike,cause")
+	for _, s := range seconds {
+		fmt.Fprintf(w, "%d,%d,%s,%s,%d,%d,%s,%t,%s\n", s.Second, s.Ops,
+			formatFloat(float64(s.P50)/float64(time.Millisecond)), formatFloat(float64(s.P99)/float64(time.Millisecond)),
+			s.Invalidations, s.GCCycles, formatFloat(float64(s.GCPause)/float64(time.Millisecond)), s.Spike, s.Cause)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// printTimelineSummary counts, per run with a timeline, its p99 spikes by
+// attributed cause.
+func printTimelineSummary(results []benchmark.Result) {
+	for _, r := range results {
+		if len(r.Timeline) == 0 {
+			continue
+		}
+		spikes := benchmark.Spikes(r.Timeline)
+		if len(spikes) == 0 {
+			log.Printf("  %s: no p99 spikes in %d seconds", r.StrategyName, len(r.Timeline))
+			continue
+		}
+		causes := make(map[string]int)
+		for _, s := range spikes {
+			causes[s.Cause]++
+		}
+		names := make([]string, 0, len(causes))
+		for cause := range causes {
+			names = append(names, cause)
+		}
+		sort.Strings(names)
+		parts := make([]string, len(names))
+		for i, cause := range names {
+			parts[i] = fmt.Sprintf("%d %s", causes[cause], cause)
+		}
+		log.Printf("  %s: %d p99 spikes in %d seconds: %s", r.StrategyName, len(spikes), len(r.Timeline), strings.Join(parts, ", "))
+	}
+}
@@ -0,0 +1,251 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// batchSize spans are exported at once, or whatever has ended every
+	// flushInterval.
+	batchSize     = 512
+	flushInterval = time.Second
+	// maxQueued bounds the spans waiting for export; spans ended while the
+	// queue is full are dropped rather than slowing the benchmark.
+	maxQueued = 64 * batchSize
+
+	exportTimeout = 10 * time.Second
+
+	// OTLP status and span kind codes.
+	statusError      = 2
+	spanKindInternal = 1
+)
+
+// Exporter batches ended spans and posts them to an OTLP/HTTP endpoint.
+type Exporter struct {
+	url     string
+	service string
+	client  *http.Client
+
+	mu      sync.Mutex
+	queue   []*Span
+	dropped int64
+	failed  int64
+
+	flush chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// NewExporter starts exporting spans to the OTLP/HTTP collector at
+// endpoint, e.g. http://localhost:4318, under the service name service.
+func NewExporter(endpoint, service string) *Exporter {
+	e := &Exporter{
+		url:     strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		service: service,
+		client:  &http.Client{Timeout: exportTimeout},
+		flush:   make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go e.loop()
+	return e
+}
+
+func (e *Exporter) add(s *Span) {
+	e.mu.Lock()
+	if len(e.queue) >= maxQueued {
+		e.dropped++
+		e.mu.Unlock()
+		return
+	}
+	e.queue = append(e.queue, s)
+	full := len(e.queue) >= batchSize
+	e.mu.Unlock()
+	if full {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (e *Exporter) loop() {
+	defer close(e.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.stop:
+			e.export()
+			return
+		case <-ticker.C:
+		case <-e.flush:
+		}
+		e.export()
+	}
+}
+
+// export posts every queued span, batchSize at a time.
+func (e *Exporter) export() {
+	for {
+		e.mu.Lock()
+		n := min(len(e.queue), batchSize)
+		batch := e.queue[:n:n]
+		e.queue = e.queue[n:]
+		e.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		if err := e.post(batch); err != nil {
+			e.mu.Lock()
+			if e.failed == 0 {
+				log.Printf("Warning: exporting spans to %s: %v", e.url, err)
+			}
+			e.failed += int64(n)
+			e.mu.Unlock()
+		}
+	}
+}
+
+// Close exports the remaining spans and reports how many were dropped or
+// failed to export.
+func (e *Exporter) Close() {
+	close(e.stop)
+	<-e.done
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.dropped+e.failed > 0 {
+		log.Printf("Tracing: %d spans dropped while the export queue was full, %d failed to export", e.dropped, e.failed)
+	}
+}
+
+func (e *Exporter) post(spans []*Span) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// The types below are the OTLP JSON encoding of an ExportTraceServiceRequest.
+// IDs are hex strings and 64-bit integers decimal strings.
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            *otlpStatus    `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func (e *Exporter) request(spans []*Span) otlpRequest {
+	out := make([]otlpSpan, len(spans))
+	for i, s := range spans {
+		o := otlpSpan{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		}
+		if s.parentID != [8]byte{} {
+			o.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		for _, a := range s.attrs {
+			o.Attributes = append(o.Attributes, keyValue(a.key, a.value))
+		}
+		if s.err != "" {
+			o.Status = &otlpStatus{Code: statusError, Message: s.err}
+		}
+		out[i] = o
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpKeyValue{keyValue("service.name", e.service)}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "caching-benchmark"}, Spans: out}},
+	}}}
+}
+
+func keyValue(key string, value any) otlpKeyValue {
+	var v otlpValue
+	switch x := value.(type) {
+	case bool:
+		v.BoolValue = &x
+	case int:
+		s := strconv.Itoa(x)
+		v.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(x, 10)
+		v.IntValue = &s
+	case float64:
+		v.DoubleValue = &x
+	case string:
+		v.StringValue = &x
+	default:
+		s := fmt.Sprint(x)
+		v.StringValue = &s
+	}
+	return otlpKeyValue{Key: key, Value: v}
+}
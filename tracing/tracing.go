@@ -0,0 +1,93 @@
+// Package tracing records spans of sampled benchmark operations and exports
+// them to an OpenTelemetry collector over OTLP/HTTP with JSON encoding, so
+// individual slow operations can be followed through every cache tier.
+//
+// A sampled operation carries its root span in its context; cache tiers get
+// it with FromContext and record their steps as children. Every Span method
+// is a no-op on a nil Span, so unsampled operations pay a single lookup.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Span is one timed step of a traced operation.
+type Span struct {
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	start    time.Time
+	end      time.Time
+	attrs    []attribute
+	err      string
+	exporter *Exporter
+}
+
+type attribute struct {
+	key   string
+	value any
+}
+
+type spanKey struct{}
+
+// Root starts a new trace with a root span named name, recorded by e when
+// it ends. It returns ctx unchanged and a nil Span when e is nil.
+func Root(ctx context.Context, e *Exporter, name string) (context.Context, *Span) {
+	if e == nil {
+		return ctx, nil
+	}
+	s := &Span{name: name, start: time.Now(), exporter: e}
+	rand.Read(s.traceID[:])
+	rand.Read(s.spanID[:])
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// FromContext returns the span of ctx, or nil when ctx is not being traced.
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// Child starts a span named name under s. It returns nil for a nil Span.
+func (s *Span) Child(name string) *Span {
+	if s == nil {
+		return nil
+	}
+	c := &Span{traceID: s.traceID, parentID: s.spanID, name: name, start: time.Now(), exporter: s.exporter}
+	rand.Read(c.spanID[:])
+	return c
+}
+
+// SetAttribute attaches a string, bool, integer or float attribute to s.
+// It is a no-op on a nil Span.
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.attrs = append(s.attrs, attribute{key, value})
+}
+
+// End finishes s, marking it failed when err is non-nil, and hands it to
+// its exporter. It is a no-op on a nil Span.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	if err != nil {
+		s.err = err.Error()
+	}
+	s.exporter.add(s)
+}
+
+// TraceID returns the hex trace ID, or "" for a nil Span.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"caching-benchmark/workload"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// TTLClass is a share of the populated keys that is given the same TTL.
+type TTLClass struct {
+	Name string
+	// Fraction of the keys in this class; the fractions of a scenario sum to 1.
+	Fraction float64
+	// TTL is the key expiry set at population. Zero never expires.
+	TTL time.Duration
+}
+
+func validateTTLClasses(classes []TTLClass) error {
+	var total float64
+	names := make(map[string]bool)
+	for _, c := range classes {
+		if c.Fraction <= 0 || c.TTL < 0 {
+			return fmt.Errorf("TTL class %q needs a positive fraction and a non-negative TTL", c.Name)
+		}
+		if names[c.Name] {
+			return fmt.Errorf("TTL class %q is listed twice", c.Name)
+		}
+		names[c.Name] = true
+		total += c.Fraction
+	}
+	if math.Abs(total-1) > 1e-9 {
+		return fmt.Errorf("TTL class fractions sum to %v, want 1", total)
+	}
+	return nil
+}
+
+// assignTTLClasses returns the class index of each of the scenario's
+// numKeys keys. The assignment depends only on the seed, so population and
+// per-class reporting agree.
+func assignTTLClasses(classes []TTLClass, numKeys int, seed int64) []int {
+	rng := rand.New(rand.NewSource(seed))
+	assigned := make([]int, numKeys)
+	for i := range assigned {
+		u := rng.Float64()
+		ci := len(classes) - 1
+		for j, c := range classes {
+			if u < c.Fraction {
+				ci = j
+				break
+			}
+			u -= c.Fraction
+		}
+		assigned[i] = ci
+	}
+	return assigned
+}
+
+// keyTTLs returns the TTL each populated key is set with, or nil when the
+// scenario has no TTL classes.
+func keyTTLs(cfg Config, seed int64) []time.Duration {
+	if len(cfg.TTLClasses) == 0 {
+		return nil
+	}
+	ttls := make([]time.Duration, cfg.NumKeys)
+	for i, ci := range assignTTLClasses(cfg.TTLClasses, cfg.NumKeys, seed) {
+		ttls[i] = cfg.TTLClasses[ci].TTL
+	}
+	return ttls
+}
+
+// keyClassNames maps each workload key to its TTL class name, prefixed by
+// the name of its workload mix component, for per-class hit-rate reporting.
+func keyClassNames(cfg Config, seed int64) map[string]string {
+	if len(cfg.TTLClasses) == 0 && len(cfg.Mix) == 0 {
+		return nil
+	}
+	names := make(map[string]string, cfg.NumKeys)
+	if len(cfg.TTLClasses) > 0 {
+		for i, ci := range assignTTLClasses(cfg.TTLClasses, cfg.NumKeys, seed) {
+			c := cfg.TTLClasses[ci]
+			names[fmt.Sprintf("key-%d", i)] = fmt.Sprintf("%s (TTL %v)", c.Name, c.TTL)
+		}
+	}
+	if len(cfg.Mix) > 0 {
+		for i, ci := range workload.MixComponentOf(cfg.Mix, cfg.NumKeys) {
+			if ci < 0 {
+				continue
+			}
+			key := fmt.Sprintf("key-%d", i)
+			if ttl, ok := names[key]; ok {
+				names[key] = cfg.Mix[ci].Name + ", " + ttl
+			} else {
+				names[key] = cfg.Mix[ci].Name
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,91 @@
+package twolevel
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const defaultBatchSize = 128
+
+// invalidationBatch collects invalidated keys between publishes. Repeated
+// invalidations of a key before a publish coalesce into one.
+type invalidationBatch struct {
+	mu      sync.Mutex
+	keys    []string
+	pending map[string]struct{}
+	size    int
+	// full is signalled when the batch reaches size keys.
+	full chan struct{}
+	// publishMu serializes publishes so Drain waits for one already in progress.
+	publishMu sync.Mutex
+}
+
+func newInvalidationBatch(size int) *invalidationBatch {
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+	return &invalidationBatch{
+		pending: make(map[string]struct{}),
+		size:    size,
+		full:    make(chan struct{}, 1),
+	}
+}
+
+func (b *invalidationBatch) add(key string) {
+	b.mu.Lock()
+	if _, dup := b.pending[key]; !dup {
+		b.pending[key] = struct{}{}
+		b.keys = append(b.keys, key)
+	}
+	full := len(b.keys) >= b.size
+	b.mu.Unlock()
+	if full {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// take removes and returns every pending key in invalidation order.
+func (b *invalidationBatch) take() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	keys := b.keys
+	b.keys = nil
+	clear(b.pending)
+	return keys
+}
+
+func (c *Cache) batchLoop(ctx context.Context) {
+	ticker := time.NewTicker(c.opts.BatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-c.batch.full:
+		}
+		if err := c.publishBatch(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to publish invalidation batch: %v", err)
+		}
+	}
+}
+
+// publishBatch publishes the pending keys in messages of at most the batch size.
+func (c *Cache) publishBatch(ctx context.Context) error {
+	c.batch.publishMu.Lock()
+	defer c.batch.publishMu.Unlock()
+	keys := c.batch.take()
+	for len(keys) > 0 {
+		n := min(len(keys), c.batch.size)
+		if err := c.transport.Publish(ctx, Message{Keys: keys[:n], Origin: c.id}); err != nil {
+			return err
+		}
+		keys = keys[n:]
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package twolevel
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressor compresses values stored by a Cache.
+type Compressor interface {
+	Name() string
+	Compress(src []byte) []byte
+	Decompress(src []byte) ([]byte, error)
+}
+
+// NewCompressor returns the compressor named "snappy" or "zstd".
+func NewCompressor(name string) (Compressor, error) {
+	switch name {
+	case "snappy":
+		return snappyCompressor{}, nil
+	case "zstd":
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+		if err != nil {
+			return nil, err
+		}
+		dec, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, err
+		}
+		return zstdCompressor{enc: enc, dec: dec}, nil
+	}
+	return nil, fmt.Errorf("unknown compression %q (want snappy or zstd)", name)
+}
+
+type snappyCompressor struct{}
+
+func (snappyCompressor) Name() string { return "snappy" }
+
+func (snappyCompressor) Compress(src []byte) []byte { return snappy.Encode(nil, src) }
+
+func (snappyCompressor) Decompress(src []byte) ([]byte, error) { return snappy.Decode(nil, src) }
+
+// zstdCompressor uses the stateless EncodeAll and DecodeAll, which are safe
+// for concurrent use.
+type zstdCompressor struct {
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+}
+
+func (zstdCompressor) Name() string { return "zstd" }
+
+func (z zstdCompressor) Compress(src []byte) []byte { return z.enc.EncodeAll(src, nil) }
+
+func (z zstdCompressor) Decompress(src []byte) ([]byte, error) { return z.dec.DecodeAll(src, nil) }
+
+// compressedMagic prefixes compressed values, so values written
+// uncompressed, such as pre-populated data or those of other clients, are
+// still read as-is.
+const compressedMagic = "\x00CZ\x01"
+
+// CompressionStats describes a Cache's value compression.
+type CompressionStats struct {
+	Algorithm string
+	// RawBytes and CompressedBytes total the values compressed.
+	RawBytes        int64
+	CompressedBytes int64
+	Compressions    int64
+	Decompressions  int64
+	CompressTime    time.Duration
+	DecompressTime  time.Duration
+}
+
+// compression applies a Compressor to the values a Cache stores, counting
+// its work.
+type compression struct {
+	c               Compressor
+	rawBytes        atomic.Int64
+	compressedBytes atomic.Int64
+	compressions    atomic.Int64
+	decompressions  atomic.Int64
+	compressNanos   atomic.Int64
+	decompressNanos atomic.Int64
+}
+
+// pack returns value compressed and marked as such.
+func (c *compression) pack(value string) string {
+	start := time.Now()
+	packed := compressedMagic + string(c.c.Compress([]byte(value)))
+	c.compressNanos.Add(int64(time.Since(start)))
+	c.compressions.Add(1)
+	c.rawBytes.Add(int64(len(value)))
+	c.compressedBytes.Add(int64(len(packed)))
+	return packed
+}
+
+// packed reports whether stored was written by pack.
+func packed(stored string) bool {
+	return strings.HasPrefix(stored, compressedMagic)
+}
+
+// unpack returns the value stored holds, decompressing it if it is packed.
+func (c *compression) unpack(stored string) (string, error) {
+	if !packed(stored) {
+		return stored, nil
+	}
+	start := time.Now()
+	b, err := c.c.Decompress([]byte(stored[len(compressedMagic):]))
+	c.decompressNanos.Add(int64(time.Since(start)))
+	if err != nil {
+		return "", fmt.Errorf("twolevel: %s decompression: %w", c.c.Name(), err)
+	}
+	c.decompressions.Add(1)
+	return string(b), nil
+}
+
+func (c *compression) stats() CompressionStats {
+	return CompressionStats{
+		Algorithm:       c.c.Name(),
+		RawBytes:        c.rawBytes.Load(),
+		CompressedBytes: c.compressedBytes.Load(),
+		Compressions:    c.compressions.Load(),
+		Decompressions:  c.decompressions.Load(),
+		CompressTime:    time.Duration(c.compressNanos.Load()),
+		DecompressTime:  time.Duration(c.decompressNanos.Load()),
+	}
+}
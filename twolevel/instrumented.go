@@ -0,0 +1,28 @@
+package twolevel
+
+import (
+	"context"
+	"time"
+)
+
+// FetchRecorder receives the time span of every L2 fetch.
+type FetchRecorder interface {
+	Record(key string, start, end time.Time)
+}
+
+// InstrumentedL2 decorates an L2 and reports each Get to a FetchRecorder.
+type InstrumentedL2 struct {
+	L2
+	rec FetchRecorder
+}
+
+func NewInstrumentedL2(l2 L2, rec FetchRecorder) *InstrumentedL2 {
+	return &InstrumentedL2{L2: l2, rec: rec}
+}
+
+func (i *InstrumentedL2) Get(ctx context.Context, key string) (string, error) {
+	start := time.Now()
+	value, err := i.L2.Get(ctx, key)
+	i.rec.Record(key, start, time.Now())
+	return value, err
+}
@@ -0,0 +1,49 @@
+package twolevel
+
+import "sync"
+
+// KeyTrackingL1 wraps an L1 to remember the keys set in it, which neither
+// Ristretto nor the LRU can enumerate, so its contents can be snapshotted.
+type KeyTrackingL1 struct {
+	L1
+	keys sync.Map // key -> struct{}
+}
+
+// TrackKeys wraps l1 to remember its keys.
+func TrackKeys(l1 L1) *KeyTrackingL1 {
+	return &KeyTrackingL1{L1: l1}
+}
+
+func (t *KeyTrackingL1) Set(key, value string) {
+	if _, ok := t.keys.Load(key); !ok {
+		t.keys.Store(key, struct{}{})
+	}
+	t.L1.Set(key, value)
+}
+
+func (t *KeyTrackingL1) Del(key string) {
+	t.keys.Delete(key)
+	t.L1.Del(key)
+}
+
+func (t *KeyTrackingL1) Clear() {
+	t.keys.Clear()
+	t.L1.Clear()
+}
+
+// Keys returns the remembered keys the L1 still holds. Keys it evicted or
+// expired on its own are found missing and forgotten.
+func (t *KeyTrackingL1) Keys() []string {
+	t.L1.Wait()
+	var keys []string
+	t.keys.Range(func(k, _ any) bool {
+		key := k.(string)
+		if _, ok := t.L1.Get(key); ok {
+			keys = append(keys, key)
+		} else {
+			t.keys.Delete(key)
+		}
+		return true
+	})
+	return keys
+}
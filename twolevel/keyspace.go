@@ -0,0 +1,164 @@
+package twolevel
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+const (
+	keyspacePattern = "__keyspace@*__:*"
+	// keyspaceEvents enables keyspace (K) notifications for string ($),
+	// generic (g, e.g. DEL), expired (x) and evicted (e) events.
+	keyspaceEvents = "K$gxe"
+	// drainKeyPrefix marks the keys Drain writes to round-trip a marker
+	// through the notification stream; it follows the transport's key
+	// prefix.
+	drainKeyPrefix = "twolevel:drain:"
+)
+
+// KeyspaceTransport invalidates from the server's keyspace notifications
+// instead of application messages: every change to a key is announced by
+// Redis itself, so Publish is a no-op for key invalidations. Notifications
+// carry no origin, so a writer also drops its own freshly written L1 entry.
+type KeyspaceTransport struct {
+	pub         rueidis.Client
+	sub         rueidis.Client
+	drainPrefix string
+	server      string
+}
+
+// NewKeyspaceTransport enables keyspace notifications on the server. The
+// setting is server-wide, so it is shared by the process's transports on
+// the same server: the first adds the events it needs to the current
+// setting and the last to close restores the original. Other processes are
+// not tracked; one restoring the setting while this one runs stops its
+// notifications. Drain markers are written under keyPrefix. As with
+// PubSubTransport, Close only closes sub, so pub may be shared with an L2.
+func NewKeyspaceTransport(ctx context.Context, pub, sub rueidis.Client, keyPrefix string) (*KeyspaceTransport, error) {
+	server := serverID(pub)
+	if err := keyspaceConfigs.acquire(ctx, pub, server); err != nil {
+		return nil, err
+	}
+	return &KeyspaceTransport{pub: pub, sub: sub, drainPrefix: keyPrefix + drainKeyPrefix, server: server}, nil
+}
+
+// keyspaceConfigs counts the transports relying on each server's
+// notify-keyspace-events setting.
+var keyspaceConfigs = keyspaceConfig{servers: make(map[string]*keyspaceUsers)}
+
+type keyspaceConfig struct {
+	mu      sync.Mutex
+	servers map[string]*keyspaceUsers
+}
+
+type keyspaceUsers struct {
+	refs     int
+	previous string
+}
+
+// acquire enables the notifications needed on server for one more
+// transport, remembering the setting found by the first.
+func (c *keyspaceConfig) acquire(ctx context.Context, pub rueidis.Client, server string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if u, ok := c.servers[server]; ok {
+		u.refs++
+		return nil
+	}
+	cfg, err := pub.Do(ctx, pub.B().ConfigGet().Parameter("notify-keyspace-events").Build()).AsStrMap()
+	if err != nil {
+		return err
+	}
+	previous := cfg["notify-keyspace-events"]
+	if events := withKeyspaceEvents(previous); events != previous {
+		if err := pub.Do(ctx, pub.B().ConfigSet().ParameterValue().ParameterValue("notify-keyspace-events", events).Build()).Error(); err != nil {
+			return err
+		}
+	}
+	c.servers[server] = &keyspaceUsers{refs: 1, previous: previous}
+	return nil
+}
+
+// release drops one transport's use of server's setting, restoring the
+// original once none is left.
+func (c *keyspaceConfig) release(ctx context.Context, pub rueidis.Client, server string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	u, ok := c.servers[server]
+	if !ok {
+		return nil
+	}
+	if u.refs--; u.refs > 0 {
+		return nil
+	}
+	delete(c.servers, server)
+	if withKeyspaceEvents(u.previous) == u.previous {
+		return nil
+	}
+	return pub.Do(ctx, pub.B().ConfigSet().ParameterValue().ParameterValue("notify-keyspace-events", u.previous).Build()).Error()
+}
+
+// withKeyspaceEvents returns the notify-keyspace-events setting that adds
+// the events the transport needs to current, or current when it has them
+// already. "A" stands for every event class.
+func withKeyspaceEvents(current string) string {
+	events := current
+	for _, e := range keyspaceEvents {
+		if !strings.ContainsRune(events, e) && (e == 'K' || !strings.ContainsRune(events, 'A')) {
+			events += string(e)
+		}
+	}
+	return events
+}
+
+// serverID identifies the server a client is connected to by its nodes'
+// addresses.
+func serverID(client rueidis.Client) string {
+	var nodes []string
+	for addr := range client.Nodes() {
+		nodes = append(nodes, addr)
+	}
+	sort.Strings(nodes)
+	return strings.Join(nodes, ",")
+}
+
+// Publish writes a short-lived marker key for drain messages; the server
+// announces key writes on its own.
+func (t *KeyspaceTransport) Publish(ctx context.Context, msg Message) error {
+	if msg.DrainToken == "" {
+		return nil
+	}
+	return t.pub.Do(ctx, t.pub.B().Set().Key(t.drainPrefix+msg.DrainToken).Value("1").Px(10*time.Second).Build()).Error()
+}
+
+func (t *KeyspaceTransport) Subscribe(ctx context.Context, fn func(Message)) error {
+	return t.sub.Receive(ctx, t.sub.B().Psubscribe().Pattern(keyspacePattern).Build(), func(m rueidis.PubSubMessage) {
+		_, key, ok := strings.Cut(m.Channel, "__:")
+		if !ok {
+			return
+		}
+		if token, ok := strings.CutPrefix(key, t.drainPrefix); ok {
+			// Only the write announces the marker; its expiry does not.
+			if m.Message == "set" {
+				fn(Message{DrainToken: token})
+			}
+			return
+		}
+		fn(Message{Key: key})
+	})
+}
+
+func (t *KeyspaceTransport) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := keyspaceConfigs.release(ctx, t.pub, t.server); err != nil {
+		log.Printf("Failed to restore notify-keyspace-events: %v", err)
+	}
+	t.sub.Close()
+}
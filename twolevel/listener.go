@@ -0,0 +1,131 @@
+package twolevel
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	minResubscribeBackoff = 10 * time.Millisecond
+	maxResubscribeBackoff = time.Second
+	// probeInterval is how often a resubscribing listener publishes a probe
+	// to learn when its subscription is live again.
+	probeInterval = 50 * time.Millisecond
+)
+
+// ListenerStats describes the invalidation listener's lost subscriptions.
+type ListenerStats struct {
+	Disconnects int64
+	// DownTime is the total time without a live subscription, including a
+	// window still open when the stats were taken; MaxDownTime is the longest.
+	DownTime    time.Duration
+	MaxDownTime time.Duration
+	// L1Flushes counts L1 clears made by FlushOnResubscribe.
+	L1Flushes int64
+}
+
+type listenerStats struct {
+	disconnects atomic.Int64
+	downNanos   atomic.Int64
+	maxDown     atomic.Int64
+	flushes     atomic.Int64
+	// downSince is the UnixNano start of the open down window, or zero.
+	downSince atomic.Int64
+}
+
+// ListenerStats returns the listener's disconnect and down-window metrics.
+func (c *Cache) ListenerStats() ListenerStats {
+	down := time.Duration(c.listener.downNanos.Load())
+	maxDown := time.Duration(c.listener.maxDown.Load())
+	if since := c.listener.downSince.Load(); since != 0 {
+		open := time.Since(time.Unix(0, since))
+		down += open
+		maxDown = max(maxDown, open)
+	}
+	return ListenerStats{
+		Disconnects: c.listener.disconnects.Load(),
+		DownTime:    down,
+		MaxDownTime: maxDown,
+		L1Flushes:   c.listener.flushes.Load(),
+	}
+}
+
+// listen keeps a subscription open for the life of the Cache. When the
+// subscription fails it resubscribes with exponential backoff, and the
+// listener counts as down until a probe round-trips through the new one.
+func (c *Cache) listen(ctx context.Context) {
+	backoff := minResubscribeBackoff
+	var downSince time.Time
+	for {
+		errc := make(chan error, 1)
+		go func() { errc <- c.transport.Subscribe(ctx, c.handle) }()
+
+		var err error
+		if downSince.IsZero() {
+			err = <-errc
+		} else if err = c.awaitSubscribed(ctx, errc); err == nil {
+			c.recordUp(downSince)
+			downSince = time.Time{}
+			backoff = minResubscribeBackoff
+			err = <-errc
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		if downSince.IsZero() {
+			downSince = time.Now()
+			c.listener.disconnects.Add(1)
+			c.listener.downSince.Store(downSince.UnixNano())
+			log.Printf("Invalidation listener lost its subscription, resubscribing: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxResubscribeBackoff)
+	}
+}
+
+// awaitSubscribed publishes probes until one is received, proving the new
+// subscription is live. It returns the subscription's error if it fails first.
+func (c *Cache) awaitSubscribed(ctx context.Context, errc <-chan error) error {
+	token := "probe-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	done := make(chan struct{})
+	c.drainWaiters.Store(token, done)
+	defer c.drainWaiters.Delete(token)
+
+	ticker := time.NewTicker(probeInterval)
+	defer ticker.Stop()
+	for {
+		// Publish failures are expected while the backend is unreachable.
+		c.transport.Publish(ctx, Message{DrainToken: token})
+		select {
+		case <-done:
+			return nil
+		case err := <-errc:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Cache) recordUp(downSince time.Time) {
+	down := time.Since(downSince)
+	c.listener.downSince.Store(0)
+	c.listener.downNanos.Add(int64(down))
+	if int64(down) > c.listener.maxDown.Load() {
+		c.listener.maxDown.Store(int64(down))
+	}
+	if c.opts.FlushOnResubscribe {
+		c.clearLocal()
+		c.listener.flushes.Add(1)
+	}
+	log.Printf("Invalidation listener resubscribed after %v", down.Round(time.Millisecond))
+}
@@ -0,0 +1,123 @@
+package twolevel
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRUL1 is a fixed-capacity LRU cache behind a single RWMutex. Like
+// RistrettoL1, entries are costed by their value length in bytes, and the
+// least recently used are evicted once the total exceeds the capacity.
+// Unlike Ristretto, every Set is applied immediately and admission is
+// unconditional.
+type LRUL1 struct {
+	mu      sync.RWMutex
+	items   map[string]*list.Element
+	order   list.List // front is most recently used
+	cost    int64
+	maxCost int64
+	ttl     time.Duration
+}
+
+type lruEntry struct {
+	key     string
+	value   string
+	expires time.Time
+}
+
+// NewLRUL1 creates an LRU holding up to maxCost bytes of values. A positive
+// ttl expires every entry that long after it was set.
+func NewLRUL1(maxCost int64, ttl time.Duration) *LRUL1 {
+	return &LRUL1{items: make(map[string]*list.Element), maxCost: maxCost, ttl: ttl}
+}
+
+// NewShardedLRUL1 spreads maxCost over n LRUs selected by key hash, so
+// each shard's lock only serialises its own keys.
+func NewShardedLRUL1(n int, maxCost int64, ttl time.Duration) *ShardedL1 {
+	shards := make([]L1, n)
+	for i := range shards {
+		shards[i] = NewLRUL1(max(maxCost/int64(n), 1), ttl)
+	}
+	return NewShardedL1(shards)
+}
+
+// Get looks the key up under the read lock and takes the write lock only to
+// move a hit to the front.
+func (l *LRUL1) Get(key string) (string, bool) {
+	l.mu.RLock()
+	elem, found := l.items[key]
+	var entry *lruEntry
+	if found {
+		entry = elem.Value.(*lruEntry)
+	}
+	front := found && l.order.Front() == elem
+	l.mu.RUnlock()
+	if !found {
+		return "", false
+	}
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		l.Del(key)
+		return "", false
+	}
+	if !front {
+		l.mu.Lock()
+		// A no-op if the entry was removed since it was read.
+		l.order.MoveToFront(elem)
+		l.mu.Unlock()
+	}
+	return entry.value, true
+}
+
+func (l *LRUL1) Set(key, value string) {
+	cost := int64(len(value))
+	if cost > l.maxCost {
+		l.Del(key)
+		return
+	}
+	entry := &lruEntry{key: key, value: value}
+	if l.ttl > 0 {
+		entry.expires = time.Now().Add(l.ttl)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if elem, found := l.items[key]; found {
+		l.cost -= int64(len(elem.Value.(*lruEntry).value))
+		elem.Value = entry
+		l.order.MoveToFront(elem)
+	} else {
+		l.items[key] = l.order.PushFront(entry)
+	}
+	l.cost += cost
+	for l.cost > l.maxCost {
+		l.remove(l.order.Back())
+	}
+}
+
+func (l *LRUL1) Del(key string) {
+	l.mu.Lock()
+	if elem, found := l.items[key]; found {
+		l.remove(elem)
+	}
+	l.mu.Unlock()
+}
+
+// remove drops elem; the caller holds the write lock.
+func (l *LRUL1) remove(elem *list.Element) {
+	entry := l.order.Remove(elem).(*lruEntry)
+	delete(l.items, entry.key)
+	l.cost -= int64(len(entry.value))
+}
+
+func (l *LRUL1) Clear() {
+	l.mu.Lock()
+	l.items = make(map[string]*list.Element)
+	l.order.Init()
+	l.cost = 0
+	l.mu.Unlock()
+}
+
+// Wait returns at once: Sets are applied synchronously.
+func (l *LRUL1) Wait() {}
+
+func (l *LRUL1) Close() {}
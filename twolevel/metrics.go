@@ -0,0 +1,80 @@
+package twolevel
+
+// MetricsL1 is implemented by L1s that expose internal counters, named
+// "l1.<counter>".
+type MetricsL1 interface {
+	Metrics() map[string]int64
+}
+
+// Metrics returns Ristretto's own counters; the cache must have been
+// created with Config.Metrics set, or it reports none.
+func (r *RistrettoL1) Metrics() map[string]int64 {
+	m := r.cache.Metrics
+	if m == nil {
+		return nil
+	}
+	return map[string]int64{
+		"l1.hits":          int64(m.Hits()),
+		"l1.misses":        int64(m.Misses()),
+		"l1.keys_added":    int64(m.KeysAdded()),
+		"l1.keys_updated":  int64(m.KeysUpdated()),
+		"l1.keys_evicted":  int64(m.KeysEvicted()),
+		"l1.cost_added":    int64(m.CostAdded()),
+		"l1.cost_evicted":  int64(m.CostEvicted()),
+		"l1.sets_dropped":  int64(m.SetsDropped()),
+		"l1.sets_rejected": int64(m.SetsRejected()),
+		"l1.gets_dropped":  int64(m.GetsDropped()),
+	}
+}
+
+// Metrics reports the LRU's current size; it keeps no counters.
+func (l *LRUL1) Metrics() map[string]int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return map[string]int64{
+		"l1.keys":       int64(len(l.items)),
+		"l1.cost_bytes": l.cost,
+	}
+}
+
+// Metrics sums the metrics of the shards that report them.
+func (s *ShardedL1) Metrics() map[string]int64 {
+	var sum map[string]int64
+	for _, l1 := range s.shards {
+		m, ok := l1.(MetricsL1)
+		if !ok {
+			continue
+		}
+		for name, v := range m.Metrics() {
+			if sum == nil {
+				sum = make(map[string]int64)
+			}
+			sum[name] += v
+		}
+	}
+	return sum
+}
+
+func (t *KeyTrackingL1) Metrics() map[string]int64 {
+	if m, ok := t.L1.(MetricsL1); ok {
+		return m.Metrics()
+	}
+	return nil
+}
+
+// Metrics returns the L1's counters, when it reports them, and the
+// invalidation messages received from other instances with the keys they
+// invalidated.
+func (c *Cache) Metrics() map[string]int64 {
+	metrics := make(map[string]int64)
+	if m, ok := c.l1.(MetricsL1); ok {
+		for name, v := range m.Metrics() {
+			metrics[name] = v
+		}
+	}
+	if c.transport != nil {
+		metrics["invalidation.messages"] = c.received.messages.Load()
+		metrics["invalidation.keys"] = c.received.keys.Load()
+	}
+	return metrics
+}
@@ -0,0 +1,45 @@
+package twolevel
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by L2.Get, and by Cache.Get, when the key does not exist.
+var ErrNotFound = errors.New("twolevel: key not found")
+
+// negativeCache remembers keys known to be absent from L2 for a short TTL,
+// so repeated reads of missing keys do not each reach the backend.
+type negativeCache struct {
+	ttl     time.Duration
+	entries sync.Map // key -> expiry time.Time
+}
+
+func newNegativeCache(ttl time.Duration) *negativeCache {
+	return &negativeCache{ttl: ttl}
+}
+
+func (n *negativeCache) has(key string) bool {
+	expiry, ok := n.entries.Load(key)
+	if !ok {
+		return false
+	}
+	if time.Now().After(expiry.(time.Time)) {
+		n.entries.Delete(key)
+		return false
+	}
+	return true
+}
+
+func (n *negativeCache) put(key string) {
+	n.entries.Store(key, time.Now().Add(n.ttl))
+}
+
+func (n *negativeCache) del(key string) {
+	n.entries.Delete(key)
+}
+
+func (n *negativeCache) clear() {
+	n.entries.Clear()
+}
@@ -0,0 +1,39 @@
+package twolevel
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/rueidis"
+)
+
+// PubSubTransport broadcasts invalidations as JSON over a Redis Pub/Sub channel.
+type PubSubTransport struct {
+	pub     rueidis.Client
+	sub     rueidis.Client
+	channel string
+}
+
+// NewPubSubTransport publishes with pub and subscribes with sub, which must be
+// a dedicated client. Close only closes sub, so pub may be shared with an L2.
+func NewPubSubTransport(pub, sub rueidis.Client, channel string) *PubSubTransport {
+	return &PubSubTransport{pub: pub, sub: sub, channel: channel}
+}
+
+func (t *PubSubTransport) Publish(ctx context.Context, msg Message) error {
+	payload, _ := json.Marshal(msg)
+	return t.pub.Do(ctx, t.pub.B().Publish().Channel(t.channel).Message(string(payload)).Build()).Error()
+}
+
+func (t *PubSubTransport) Subscribe(ctx context.Context, fn func(Message)) error {
+	return t.sub.Receive(ctx, t.sub.B().Subscribe().Channel(t.channel).Build(), func(m rueidis.PubSubMessage) {
+		var msg Message
+		if err := json.Unmarshal([]byte(m.Message), &msg); err == nil {
+			fn(msg)
+		}
+	})
+}
+
+func (t *PubSubTransport) Close() {
+	t.sub.Close()
+}
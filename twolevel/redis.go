@@ -0,0 +1,53 @@
+package twolevel
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// RedisL2 adapts a rueidis client to the L2 interface using GET and SET.
+type RedisL2 struct {
+	client rueidis.Client
+}
+
+func NewRedisL2(client rueidis.Client) *RedisL2 {
+	return &RedisL2{client: client}
+}
+
+func (r *RedisL2) Get(ctx context.Context, key string) (string, error) {
+	value, err := r.client.Do(ctx, r.client.B().Get().Key(key).Build()).ToString()
+	if rueidis.IsRedisNil(err) {
+		return "", ErrNotFound
+	}
+	return value, err
+}
+
+func (r *RedisL2) Set(ctx context.Context, key, value string) error {
+	return r.client.Do(ctx, r.client.B().Set().Key(key).Value(value).Build()).Error()
+}
+
+func (r *RedisL2) Close() {
+	r.client.Close()
+}
+
+// CachedRedisL2 reads through rueidis client-side caching, so L2 reads of
+// keys the server is already tracking for this client cost no round trip.
+type CachedRedisL2 struct {
+	RedisL2
+	ttl time.Duration
+}
+
+// NewCachedRedisL2 caches reads client-side for up to ttl.
+func NewCachedRedisL2(client rueidis.Client, ttl time.Duration) *CachedRedisL2 {
+	return &CachedRedisL2{RedisL2: RedisL2{client: client}, ttl: ttl}
+}
+
+func (r *CachedRedisL2) Get(ctx context.Context, key string) (string, error) {
+	value, err := r.client.DoCache(ctx, r.client.B().Get().Key(key).Cache(), r.ttl).ToString()
+	if rueidis.IsRedisNil(err) {
+		return "", ErrNotFound
+	}
+	return value, err
+}
@@ -0,0 +1,73 @@
+package twolevel
+
+import "errors"
+
+// ErrNotResizable is returned by Cache.ResizeL1 when the L1 cannot change
+// its capacity in place.
+var ErrNotResizable = errors.New("L1 cannot be resized")
+
+// Resizer is implemented by L1s whose capacity can change while in use.
+type Resizer interface {
+	// Resize sets the capacity to maxCost bytes of values. A cache may
+	// evict down to a smaller capacity lazily, as Ristretto does on later
+	// Sets, or at once, as the LRU does.
+	Resize(maxCost int64)
+}
+
+func (r *RistrettoL1) Resize(maxCost int64) {
+	r.cache.UpdateMaxCost(maxCost)
+}
+
+func (l *LRUL1) Resize(maxCost int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.maxCost = maxCost
+	for l.cost > l.maxCost {
+		l.remove(l.order.Back())
+	}
+}
+
+// Resize splits maxCost evenly over the shards, which must all be Resizers.
+func (s *ShardedL1) Resize(maxCost int64) {
+	for _, l1 := range s.shards {
+		l1.(Resizer).Resize(max(maxCost/int64(len(s.shards)), 1))
+	}
+}
+
+func (s *ShardedL1) resizable() bool {
+	for _, l1 := range s.shards {
+		if !resizable(l1) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *KeyTrackingL1) Resize(maxCost int64) {
+	t.L1.(Resizer).Resize(maxCost)
+}
+
+func resizable(l1 L1) bool {
+	switch l := l1.(type) {
+	case *ShardedL1:
+		return l.resizable()
+	case *KeyTrackingL1:
+		return resizable(l.L1)
+	}
+	_, ok := l1.(Resizer)
+	return ok
+}
+
+// ResizeL1 changes the L1's capacity to maxCost bytes while the cache is in
+// use. With cold set it is emptied first, modeling an instance recreated
+// with the new budget rather than resized in place.
+func (c *Cache) ResizeL1(maxCost int64, cold bool) error {
+	if !resizable(c.l1) {
+		return ErrNotResizable
+	}
+	if cold {
+		c.clearLocal()
+	}
+	c.l1.(Resizer).Resize(maxCost)
+	return nil
+}
@@ -0,0 +1,52 @@
+package twolevel
+
+import (
+	"time"
+
+	"github.com/dgraph-io/ristretto"
+)
+
+// RistrettoL1 adapts a Ristretto cache to the L1 interface.
+// Entries are costed by their value length in bytes.
+type RistrettoL1 struct {
+	cache *ristretto.Cache
+	ttl   time.Duration
+}
+
+// NewRistrettoL1 creates a Ristretto-backed L1 from config. A positive ttl
+// expires every entry that long after it was set; zero keeps entries until evicted.
+func NewRistrettoL1(config *ristretto.Config, ttl time.Duration) (*RistrettoL1, error) {
+	cache, err := ristretto.NewCache(config)
+	if err != nil {
+		return nil, err
+	}
+	return &RistrettoL1{cache: cache, ttl: ttl}, nil
+}
+
+func (r *RistrettoL1) Get(key string) (string, bool) {
+	val, found := r.cache.Get(key)
+	if !found {
+		return "", false
+	}
+	return val.(string), true
+}
+
+func (r *RistrettoL1) Set(key, value string) {
+	r.cache.SetWithTTL(key, value, int64(len(value)), r.ttl)
+}
+
+func (r *RistrettoL1) Del(key string) {
+	r.cache.Del(key)
+}
+
+func (r *RistrettoL1) Clear() {
+	r.cache.Clear()
+}
+
+func (r *RistrettoL1) Wait() {
+	r.cache.Wait()
+}
+
+func (r *RistrettoL1) Close() {
+	r.cache.Close()
+}
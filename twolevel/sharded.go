@@ -0,0 +1,50 @@
+package twolevel
+
+import "hash/maphash"
+
+// ShardedL1 spreads keys over independent L1 shards by hash, so that
+// concurrent operations on different keys contend on different locks and
+// buffers.
+type ShardedL1 struct {
+	seed   maphash.Seed
+	shards []L1
+}
+
+// NewShardedL1 shards keys over the given caches, which must be non-empty.
+func NewShardedL1(shards []L1) *ShardedL1 {
+	return &ShardedL1{seed: maphash.MakeSeed(), shards: shards}
+}
+
+func (s *ShardedL1) shard(key string) L1 {
+	return s.shards[maphash.String(s.seed, key)%uint64(len(s.shards))]
+}
+
+func (s *ShardedL1) Get(key string) (string, bool) {
+	return s.shard(key).Get(key)
+}
+
+func (s *ShardedL1) Set(key, value string) {
+	s.shard(key).Set(key, value)
+}
+
+func (s *ShardedL1) Del(key string) {
+	s.shard(key).Del(key)
+}
+
+func (s *ShardedL1) Clear() {
+	for _, l1 := range s.shards {
+		l1.Clear()
+	}
+}
+
+func (s *ShardedL1) Wait() {
+	for _, l1 := range s.shards {
+		l1.Wait()
+	}
+}
+
+func (s *ShardedL1) Close() {
+	for _, l1 := range s.shards {
+		l1.Close()
+	}
+}
@@ -0,0 +1,158 @@
+package twolevel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/redis/rueidis"
+)
+
+// ShardedPubSubTransport spreads invalidations over sharded Pub/Sub
+// channels (Redis 7 SPUBLISH and SSUBSCRIBE). In a cluster a sharded
+// channel lives on the shard owning its hash slot, so each message is
+// delivered within that shard instead of being broadcast to every node over
+// the cluster bus as PUBLISH is. A key's invalidations go to the channel
+// chosen by the key's hash slot; flushes go to the first channel, and drain
+// markers go to every channel and are delivered once all of them have
+// carried theirs, so that per-channel ordering still proves every earlier
+// invalidation was received.
+type ShardedPubSubTransport struct {
+	pub      rueidis.Client
+	sub      rueidis.Client
+	channels []string
+
+	mu sync.Mutex
+	// markers records, per drain token, the channels its copies arrived on.
+	markers map[string]map[int]bool
+}
+
+// NewShardedPubSubTransport publishes with pub and subscribes with sub,
+// which must be a dedicated client, over n channels named
+// "<channel>:{<i>}". Close only closes sub, so pub may be shared with an L2.
+func NewShardedPubSubTransport(pub, sub rueidis.Client, channel string, n int) *ShardedPubSubTransport {
+	t := &ShardedPubSubTransport{pub: pub, sub: sub, markers: make(map[string]map[int]bool)}
+	for i := range max(n, 1) {
+		t.channels = append(t.channels, fmt.Sprintf("%s:{%d}", channel, i))
+	}
+	return t
+}
+
+func (t *ShardedPubSubTransport) Publish(ctx context.Context, msg Message) error {
+	switch {
+	case msg.DrainToken != "":
+		for i := range t.channels {
+			if err := t.publish(ctx, i, msg); err != nil {
+				return err
+			}
+		}
+		return nil
+	case msg.All:
+		return t.publish(ctx, 0, msg)
+	case msg.Key != "" && len(msg.Keys) == 0:
+		return t.publish(ctx, t.channelOf(msg.Key), msg)
+	}
+	// Batches are split by channel.
+	batches := make(map[int][]string)
+	if msg.Key != "" {
+		batches[t.channelOf(msg.Key)] = append(batches[t.channelOf(msg.Key)], msg.Key)
+	}
+	for _, key := range msg.Keys {
+		i := t.channelOf(key)
+		batches[i] = append(batches[i], key)
+	}
+	for i, keys := range batches {
+		if err := t.publish(ctx, i, Message{Keys: keys, Origin: msg.Origin}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *ShardedPubSubTransport) publish(ctx context.Context, i int, msg Message) error {
+	payload, _ := json.Marshal(msg)
+	return t.pub.Do(ctx, t.pub.B().Spublish().Channel(t.channels[i]).Message(string(payload)).Build()).Error()
+}
+
+func (t *ShardedPubSubTransport) channelOf(key string) int {
+	return int(Slot(key)) % len(t.channels)
+}
+
+// Subscribe subscribes to every channel separately, since in a cluster one
+// SSUBSCRIBE may only name channels of a single slot, and returns when the
+// first subscription fails. fn may be called from several goroutines.
+func (t *ShardedPubSubTransport) Subscribe(ctx context.Context, fn func(Message)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(t.channels))
+	for i, channel := range t.channels {
+		go func() {
+			errs <- t.sub.Receive(ctx, t.sub.B().Ssubscribe().Channel(channel).Build(), func(m rueidis.PubSubMessage) {
+				var msg Message
+				if err := json.Unmarshal([]byte(m.Message), &msg); err == nil && t.complete(i, msg) {
+					fn(msg)
+				}
+			})
+		}()
+	}
+	err := <-errs
+	cancel()
+	for range len(t.channels) - 1 {
+		<-errs
+	}
+	return err
+}
+
+// complete reports whether msg should be delivered: always, unless it is a
+// drain marker whose copies have not yet arrived on every channel.
+func (t *ShardedPubSubTransport) complete(channel int, msg Message) bool {
+	if msg.DrainToken == "" || len(t.channels) == 1 {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	seen := t.markers[msg.DrainToken]
+	if seen == nil {
+		seen = make(map[int]bool, len(t.channels))
+		t.markers[msg.DrainToken] = seen
+	}
+	seen[channel] = true
+	if len(seen) < len(t.channels) {
+		return false
+	}
+	delete(t.markers, msg.DrainToken)
+	return true
+}
+
+func (t *ShardedPubSubTransport) Close() {
+	t.sub.Close()
+}
+
+// Slot returns the Redis Cluster hash slot of key, hashing only its hash
+// tag when it has a non-empty one.
+func Slot(key string) uint16 {
+	if open := strings.IndexByte(key, '{'); open >= 0 {
+		if end := strings.IndexByte(key[open+1:], '}'); end > 0 {
+			key = key[open+1 : open+1+end]
+		}
+	}
+	return crc16(key) % 16384
+}
+
+// crc16 is the CRC-16/XMODEM checksum Redis Cluster hashes keys with.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
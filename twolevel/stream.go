@@ -0,0 +1,102 @@
+package twolevel
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+const (
+	streamMaxLen     = "100000"
+	streamReadCount  = 256
+	streamBlock      = 100 * time.Millisecond
+	streamRetryDelay = 100 * time.Millisecond
+	streamField      = "m"
+)
+
+// StreamTransport broadcasts invalidations as entries of a Redis Stream.
+// Each instance reads through its own consumer group, so every instance sees
+// every entry, and acknowledges entries only after applying them: after a
+// reconnect, entries that were delivered but not applied are replayed.
+type StreamTransport struct {
+	pub      rueidis.Client
+	sub      rueidis.Client
+	stream   string
+	group    string
+	consumer string
+}
+
+// NewStreamTransport creates group on stream, starting at new entries, and
+// returns a transport that publishes with pub and reads with sub. As with
+// PubSubTransport, Close only closes sub, so pub may be shared with an L2.
+func NewStreamTransport(ctx context.Context, pub, sub rueidis.Client, stream, group string) (*StreamTransport, error) {
+	err := pub.Do(ctx, pub.B().XgroupCreate().Key(stream).Group(group).Id("$").Mkstream().Build()).Error()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil, err
+	}
+	return &StreamTransport{pub: pub, sub: sub, stream: stream, group: group, consumer: group}, nil
+}
+
+func (t *StreamTransport) Publish(ctx context.Context, msg Message) error {
+	payload, _ := json.Marshal(msg)
+	return t.pub.Do(ctx, t.pub.B().Xadd().Key(t.stream).Maxlen().Almost().Threshold(streamMaxLen).
+		Id("*").FieldValue().FieldValue(streamField, string(payload)).Build()).Error()
+}
+
+func (t *StreamTransport) Subscribe(ctx context.Context, fn func(Message)) error {
+	// "0" re-reads this consumer's pending entries, i.e. those delivered but
+	// not yet acknowledged; once none are left, ">" reads new entries.
+	id := "0"
+	for ctx.Err() == nil {
+		streams, err := t.sub.Do(ctx, t.sub.B().Xreadgroup().Group(t.group, t.consumer).Count(streamReadCount).
+			Block(streamBlock.Milliseconds()).Streams().Key(t.stream).Id(id).Build()).AsXRead()
+		if rueidis.IsRedisNil(err) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			log.Printf("Invalidation stream read failed, replaying pending entries after %v: %v", streamRetryDelay, err)
+			id = "0"
+			select {
+			case <-ctx.Done():
+			case <-time.After(streamRetryDelay):
+			}
+			continue
+		}
+
+		entries := streams[t.stream]
+		if id == "0" && len(entries) == 0 {
+			id = ">"
+			continue
+		}
+		ids := make([]string, 0, len(entries))
+		for _, e := range entries {
+			var msg Message
+			if err := json.Unmarshal([]byte(e.FieldValues[streamField]), &msg); err == nil {
+				fn(msg)
+			}
+			ids = append(ids, e.ID)
+		}
+		if len(ids) > 0 {
+			if err := t.sub.Do(ctx, t.sub.B().Xack().Key(t.stream).Group(t.group).Id(ids...).Build()).Error(); err != nil && ctx.Err() == nil {
+				log.Printf("Failed to acknowledge invalidations: %v", err)
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// Close removes this instance's consumer group, so the stream does not keep
+// pending entries for it, and closes sub.
+func (t *StreamTransport) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	t.pub.Do(ctx, t.pub.B().XgroupDestroy().Key(t.stream).Group(t.group).Build())
+	t.sub.Close()
+}
@@ -0,0 +1,384 @@
+// Package twolevel implements a two-tier cache: an in-process L1 in front of
+// a shared L2, kept coherent by broadcasting invalidations over a transport.
+// The L1, L2 and transport are pluggable so strategies only supply configuration.
+package twolevel
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"caching-benchmark/tracing"
+)
+
+// L1 is the in-process cache tier.
+type L1 interface {
+	Get(key string) (string, bool)
+	Set(key, value string)
+	Del(key string)
+	// Clear drops every entry.
+	Clear()
+	// Wait blocks until buffered Sets have been applied.
+	Wait()
+	Close()
+}
+
+// L2 is the shared backing store.
+type L2 interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string) error
+	Close()
+}
+
+// Message is an invalidation broadcast between cache instances.
+type Message struct {
+	Key string `json:"key,omitempty"`
+	// Keys carries a batch of invalidated keys.
+	Keys []string `json:"keys,omitempty"`
+	// All invalidates every key.
+	All bool `json:"all,omitempty"`
+	// Origin identifies the publishing Cache so it can skip its own invalidations.
+	Origin string `json:"origin,omitempty"`
+	// DrainToken marks a message published by Drain rather than a write.
+	DrainToken string `json:"drain_token,omitempty"`
+}
+
+// Transport carries invalidation messages between cache instances.
+type Transport interface {
+	Publish(ctx context.Context, msg Message) error
+	// Subscribe delivers messages to fn until ctx is cancelled or the
+	// subscription fails.
+	Subscribe(ctx context.Context, fn func(Message)) error
+	Close()
+}
+
+// Options configures optional Cache behavior.
+type Options struct {
+	// WritePolicy selects how Set treats the local entry and L2.
+	WritePolicy WritePolicy
+	// FlushInterval is how often WriteBehind flushes buffered writes.
+	// Zero selects defaultFlushInterval.
+	FlushInterval time.Duration
+	// NegativeTTL caches L2 misses for this long. Zero disables negative caching.
+	NegativeTTL time.Duration
+	// BatchInterval publishes invalidations in batches at this interval
+	// instead of once per write. Zero publishes every invalidation immediately.
+	BatchInterval time.Duration
+	// BatchSize caps the keys per batch; a full batch is published early.
+	// Zero selects defaultBatchSize.
+	BatchSize int
+	// FlushOnResubscribe clears L1 after the invalidation listener recovers
+	// from a lost subscription, since invalidations sent while it was down
+	// were missed.
+	FlushOnResubscribe bool
+	// Compressor, when set, compresses every value before it is stored in
+	// L1 or L2 and decompresses it on the way out, so L1 entries cost their
+	// compressed size. Uncompressed values read from L2 are compressed
+	// before they are cached in L1.
+	Compressor Compressor
+	// OnInvalidate, when set, is called for every invalidation received
+	// from another instance with the number of keys it invalidates; a
+	// flush of every key counts as one.
+	OnInvalidate func(keys int)
+}
+
+// Cache combines an L1, an L2 and an invalidation transport.
+type Cache struct {
+	l1           L1
+	l2           L2
+	transport    Transport
+	opts         Options
+	id           string
+	cancel       context.CancelFunc
+	drainWaiters sync.Map // drain token -> chan struct{}
+	behind       *writeBuffer
+	negative     *negativeCache
+	batch        *invalidationBatch
+	listener     listenerStats
+	compression  *compression
+	// received counts the invalidations handled from other instances.
+	received struct {
+		messages, keys atomic.Int64
+	}
+}
+
+// New returns a Cache and starts listening for invalidations.
+// The Cache takes ownership of all three tiers and closes them in Close.
+// A nil transport disables invalidation, leaving L1 freshness to expiry alone.
+func New(l1 L1, l2 L2, transport Transport, opts Options) *Cache {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Cache{
+		l1:        l1,
+		l2:        l2,
+		transport: transport,
+		opts:      opts,
+		id:        strconv.FormatInt(time.Now().UnixNano(), 36),
+		cancel:    cancel,
+	}
+	if transport != nil {
+		go c.listen(ctx)
+	}
+	if opts.NegativeTTL > 0 {
+		c.negative = newNegativeCache(opts.NegativeTTL)
+	}
+	if transport != nil && opts.BatchInterval > 0 {
+		c.batch = newInvalidationBatch(opts.BatchSize)
+		go c.batchLoop(ctx)
+	}
+	if opts.Compressor != nil {
+		c.compression = &compression{c: opts.Compressor}
+	}
+	if opts.WritePolicy == WriteBehind {
+		c.behind = newWriteBuffer()
+		go c.flushLoop(ctx)
+	}
+	return c
+}
+
+// Get returns the value for key, reading through to L2 on an L1 miss.
+// Absent keys return ErrNotFound; with negative caching enabled, a cached
+// absence is reported as a hit. Operations traced in ctx record the L1
+// lookup, L2 fetch and L1 populate as spans.
+func (c *Cache) Get(ctx context.Context, key string) (value string, hit bool, err error) {
+	span := tracing.FromContext(ctx)
+	lookup := span.Child("l1.get")
+	val, found := c.l1.Get(key)
+	lookup.SetAttribute("cache.hit", found)
+	lookup.End(nil)
+	if found {
+		val, err := c.unpack(val)
+		return val, true, err
+	}
+	if c.behind != nil {
+		// A buffered write is newer than anything in L2.
+		if val, found := c.behind.get(key); found {
+			val, err := c.unpack(val)
+			return val, true, err
+		}
+	}
+	if c.negative != nil && c.negative.has(key) {
+		return "", true, ErrNotFound
+	}
+
+	fetch := span.Child("l2.get")
+	value, err = c.l2.Get(ctx, key)
+	fetch.End(err)
+	populate := span.Child("l1.populate")
+	if err == nil && c.compression != nil && !packed(value) {
+		c.l1.Set(key, c.compression.pack(value))
+	} else if err == nil {
+		c.l1.Set(key, value)
+		value, err = c.unpack(value)
+	} else if c.negative != nil && errors.Is(err, ErrNotFound) {
+		c.negative.put(key)
+	}
+	populate.End(nil)
+	return value, false, err
+}
+
+// Set writes value according to the write policy and broadcasts an
+// invalidation for key to the other instances. Operations traced in ctx
+// record the L2 write and the invalidation publish as spans.
+func (c *Cache) Set(ctx context.Context, key, value string) error {
+	if c.negative != nil {
+		c.negative.del(key)
+	}
+	if c.compression != nil {
+		value = c.compression.pack(value)
+	}
+	switch c.opts.WritePolicy {
+	case WriteBehind:
+		c.l1.Set(key, value)
+		c.behind.put(key, value)
+		return nil
+	case WriteThrough:
+		if err := c.setL2(ctx, key, value); err != nil {
+			c.l1.Del(key)
+			return err
+		}
+		c.l1.Set(key, value)
+	default:
+		err := c.setL2(ctx, key, value)
+		c.l1.Del(key)
+		if err != nil {
+			return err
+		}
+	}
+	publish := tracing.FromContext(ctx).Child("invalidation.publish")
+	publish.SetAttribute("invalidation.batched", c.batch != nil)
+	err := c.publish(ctx, key)
+	publish.End(err)
+	return err
+}
+
+func (c *Cache) setL2(ctx context.Context, key, value string) error {
+	span := tracing.FromContext(ctx).Child("l2.set")
+	err := c.l2.Set(ctx, key, value)
+	span.End(err)
+	return err
+}
+
+// unpack decompresses a value read from L1, the write buffer or L2.
+func (c *Cache) unpack(value string) (string, error) {
+	if c.compression == nil {
+		return value, nil
+	}
+	return c.compression.unpack(value)
+}
+
+// CompressionStats reports the work of Options.Compressor; it is zero
+// without one.
+func (c *Cache) CompressionStats() CompressionStats {
+	if c.compression == nil {
+		return CompressionStats{}
+	}
+	return c.compression.stats()
+}
+
+func (c *Cache) publish(ctx context.Context, key string) error {
+	if c.transport == nil {
+		return nil
+	}
+	if c.batch != nil {
+		c.batch.add(key)
+		return nil
+	}
+	return c.transport.Publish(ctx, Message{Key: key, Origin: c.id})
+}
+
+// InvalidateAll clears the local L1 and broadcasts a flush-all message so
+// every other instance clears its L1 too.
+func (c *Cache) InvalidateAll(ctx context.Context) error {
+	c.clearLocal()
+	if c.transport == nil {
+		return nil
+	}
+	return c.transport.Publish(ctx, Message{All: true, Origin: c.id})
+}
+
+func (c *Cache) clearLocal() {
+	c.l1.Clear()
+	if c.negative != nil {
+		c.negative.clear()
+	}
+}
+
+func (c *Cache) invalidateLocal(key string) {
+	c.l1.Del(key)
+	if c.negative != nil {
+		c.negative.del(key)
+	}
+}
+
+// Drain flushes buffered writes and L1 Sets and then round-trips a marker
+// through the transport. Transports deliver in order, so once the marker is
+// received every earlier invalidation has been applied. It returns the number
+// of buffered writes that could not be flushed to L2.
+func (c *Cache) Drain(ctx context.Context) (lost int64, err error) {
+	if c.behind != nil {
+		c.flush(ctx)
+		lost = int64(c.behind.len())
+	}
+	c.l1.Wait()
+	if c.transport == nil {
+		return lost, nil
+	}
+	if c.batch != nil {
+		if err := c.publishBatch(ctx); err != nil {
+			return lost, err
+		}
+	}
+
+	token := strconv.FormatInt(time.Now().UnixNano(), 36)
+	done := make(chan struct{})
+	c.drainWaiters.Store(token, done)
+	defer c.drainWaiters.Delete(token)
+
+	if err := c.transport.Publish(ctx, Message{DrainToken: token}); err != nil {
+		return lost, err
+	}
+
+	select {
+	case <-done:
+		return lost, nil
+	case <-ctx.Done():
+		return lost, ctx.Err()
+	}
+}
+
+// Close stops the invalidation listener and closes every tier.
+func (c *Cache) Close() {
+	c.cancel()
+	c.l1.Close()
+	if c.transport != nil {
+		c.transport.Close()
+	}
+	c.l2.Close()
+}
+
+func (c *Cache) handle(msg Message) {
+	// Set already applied this instance's own writes to its L1.
+	if msg.Origin != c.id {
+		switch {
+		case msg.All:
+			c.clearLocal()
+		case msg.Key != "":
+			c.invalidateLocal(msg.Key)
+		}
+		for _, key := range msg.Keys {
+			c.invalidateLocal(key)
+		}
+		n := invalidatedKeys(msg)
+		if n > 0 {
+			c.received.messages.Add(1)
+			c.received.keys.Add(int64(n))
+		}
+		if n > 0 && c.opts.OnInvalidate != nil {
+			c.opts.OnInvalidate(n)
+		}
+	}
+	if msg.DrainToken != "" {
+		if done, ok := c.drainWaiters.LoadAndDelete(msg.DrainToken); ok {
+			close(done.(chan struct{}))
+		}
+	}
+}
+
+// invalidatedKeys is the number of keys msg invalidates, counting All as one.
+func invalidatedKeys(msg Message) int {
+	n := len(msg.Keys)
+	if msg.All || msg.Key != "" {
+		n++
+	}
+	return n
+}
+
+// L1Keys returns the keys held in L1 when it was wrapped by TrackKeys, and
+// nil otherwise.
+func (c *Cache) L1Keys() []string {
+	if t, ok := c.l1.(*KeyTrackingL1); ok {
+		return t.Keys()
+	}
+	return nil
+}
+
+// Warm reads keys through the cache, filling L1 from L2, and returns how
+// many it read. Absent keys are skipped.
+func (c *Cache) Warm(ctx context.Context, keys []string) (int, error) {
+	n := 0
+	for _, key := range keys {
+		_, _, err := c.Get(ctx, key)
+		switch {
+		case err == nil:
+			n++
+		case errors.Is(err, ErrNotFound):
+		default:
+			return n, err
+		}
+	}
+	c.l1.Wait()
+	return n, nil
+}
@@ -0,0 +1,118 @@
+package twolevel
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// WritePolicy selects how a Cache applies writes.
+type WritePolicy int
+
+const (
+	// WriteInvalidate writes to L2 and drops the local entry, so the next
+	// read fetches the value back from L2.
+	WriteInvalidate WritePolicy = iota
+	// WriteThrough writes to L2 and then updates the local entry in place.
+	WriteThrough
+	// WriteBehind updates the local entry immediately and buffers the L2
+	// write, flushing it asynchronously. Buffered writes are lost if the
+	// process exits before they are flushed.
+	WriteBehind
+)
+
+func (p WritePolicy) String() string {
+	switch p {
+	case WriteThrough:
+		return "write-through"
+	case WriteBehind:
+		return "write-behind"
+	}
+	return "write-invalidate"
+}
+
+const defaultFlushInterval = 10 * time.Millisecond
+
+// writeBuffer holds the latest pending value per key; repeated writes to a
+// key before a flush coalesce into one L2 write.
+type writeBuffer struct {
+	mu      sync.Mutex
+	pending map[string]string
+	// flushMu serializes flushes so Drain waits for one already in progress.
+	flushMu sync.Mutex
+}
+
+func newWriteBuffer() *writeBuffer {
+	return &writeBuffer{pending: make(map[string]string)}
+}
+
+func (b *writeBuffer) put(key, value string) {
+	b.mu.Lock()
+	b.pending[key] = value
+	b.mu.Unlock()
+}
+
+func (b *writeBuffer) get(key string) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	value, ok := b.pending[key]
+	return value, ok
+}
+
+func (b *writeBuffer) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.pending)
+}
+
+// take removes and returns every pending write.
+func (b *writeBuffer) take() map[string]string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	batch := b.pending
+	b.pending = make(map[string]string)
+	return batch
+}
+
+// requeue returns a failed write to the buffer unless a newer one arrived.
+func (b *writeBuffer) requeue(key, value string) {
+	b.mu.Lock()
+	if _, newer := b.pending[key]; !newer {
+		b.pending[key] = value
+	}
+	b.mu.Unlock()
+}
+
+func (c *Cache) flushLoop(ctx context.Context) {
+	interval := c.opts.FlushInterval
+	if interval <= 0 {
+		interval = defaultFlushInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.flush(ctx)
+		}
+	}
+}
+
+// flush writes every buffered value to L2 and publishes its invalidation.
+// Writes that fail are requeued for the next flush.
+func (c *Cache) flush(ctx context.Context) {
+	c.behind.flushMu.Lock()
+	defer c.behind.flushMu.Unlock()
+	for key, value := range c.behind.take() {
+		if err := c.l2.Set(ctx, key, value); err != nil {
+			c.behind.requeue(key, value)
+			continue
+		}
+		if err := c.publish(ctx, key); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to publish invalidation for %s: %v", key, err)
+		}
+	}
+}
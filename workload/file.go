@@ -0,0 +1,63 @@
+package workload
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+)
+
+// fileVersion identifies the layout written by Save; Load rejects others.
+const fileVersion = 1
+
+// workloadFile is the gob-encoded form of a saved workload. Each distinct
+// key is stored once and operations refer to it by index, so a saved
+// workload is far smaller than its operations and loads with interned keys.
+type workloadFile struct {
+	Version int
+	Keys    []string
+	Ops     []fileOp
+}
+
+type fileOp struct {
+	Type   OperationType
+	Key    int
+	Offset int
+	Length int
+	Rank   int
+}
+
+// Save writes ops to w for Load, so a large generated workload can be reused
+// across runs instead of regenerated.
+func Save(w io.Writer, ops []Operation) error {
+	f := workloadFile{Version: fileVersion, Ops: make([]fileOp, len(ops))}
+	index := make(map[string]int)
+	for i, op := range ops {
+		k, ok := index[op.Key]
+		if !ok {
+			k = len(f.Keys)
+			index[op.Key] = k
+			f.Keys = append(f.Keys, op.Key)
+		}
+		f.Ops[i] = fileOp{Type: op.Type, Key: k, Offset: op.Offset, Length: op.Length, Rank: op.Rank}
+	}
+	return gob.NewEncoder(w).Encode(&f)
+}
+
+// Load reads a workload written by Save.
+func Load(r io.Reader) ([]Operation, error) {
+	var f workloadFile
+	if err := gob.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("failed to decode workload: %w", err)
+	}
+	if f.Version != fileVersion {
+		return nil, fmt.Errorf("workload file version %d, want %d", f.Version, fileVersion)
+	}
+	ops := make([]Operation, len(f.Ops))
+	for i, op := range f.Ops {
+		if op.Key < 0 || op.Key >= len(f.Keys) {
+			return nil, fmt.Errorf("workload operation %d refers to unknown key %d", i, op.Key)
+		}
+		ops[i] = Operation{Type: op.Type, Key: f.Keys[op.Key], Offset: op.Offset, Length: op.Length, Rank: op.Rank}
+	}
+	return ops, nil
+}
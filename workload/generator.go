@@ -3,7 +3,6 @@ package workload
 import (
 	"fmt"
 	"math/rand"
-	"time"
 
 	xrand "golang.org/x/exp/rand"
 )
@@ -13,56 +12,170 @@ type OperationType int
 const (
 	ReadOp OperationType = iota
 	WriteOp
+	// RangeReadOp reads Length bytes of a value starting at Offset.
+	RangeReadOp
+	// RMWOp reads a value and writes back a modification of it atomically.
+	RMWOp
+	// ScanOp reads up to Length keys starting with Key, and their values.
+	ScanOp
 )
 
+func (t OperationType) String() string {
+	switch t {
+	case ReadOp:
+		return "read"
+	case WriteOp:
+		return "write"
+	case RangeReadOp:
+		return "range-read"
+	case RMWOp:
+		return "rmw"
+	case ScanOp:
+		return "scan"
+	}
+	return "unknown"
+}
+
 type Operation struct {
 	Type OperationType
 	Key  string
+	// Offset and Length select the fragment read by a RangeReadOp; Length
+	// is also the key limit of a ScanOp.
+	Offset int
+	Length int
+	// Rank is the popularity rank of Key among the generated keys, 1 for
+	// the most popular; zero when unknown, as for traces and uniform or
+	// absent keys.
+	Rank int
 }
 
 // Generate generates a workload with a given number of operations and keys.
 // readWriteRatio determines the proportion of reads to writes (e.g., 0.9 for 90% reads).
-// zipfS and zipfV are parameters for the Zipf distribution, controlling the skew.
-func Generate(numOps, numKeys int, readWriteRatio, zipfS, zipfV float64) []Operation {
+// zipfS and zipfV are parameters for the Zipf distribution, controlling the skew;
+// zipfS at or below 1 is drawn from a table of the distribution instead.
+// The same seed always yields the same sequence of operations.
+func Generate(numOps, numKeys int, readWriteRatio, zipfS, zipfV float64, seed int64) []Operation {
 	ops := make([]Operation, numOps)
+	keyName := keyNamer(numKeys, numOps)
+	var cdf zipfCDF
+	if zipfS <= 1 {
+		cdf = newZipfCDF(numKeys, zipfS, zipfV)
+	}
+	generateChunks(ops, seed, func(chunk []Operation, seed int64) {
+		// Source and generator for Zipf distribution from x/exp/rand
+		zipfSource := xrand.NewSource(uint64(seed))
+		zipfRng := xrand.New(zipfSource)
+		draw := func() int { return cdf.draw(zipfRng) }
+		if cdf == nil {
+			zipf := xrand.NewZipf(zipfRng, zipfS, zipfV, uint64(numKeys-1))
+			draw = func() int { return int(zipf.Uint64()) }
+		}
 
-	// Source and generator for Zipf distribution from x/exp/rand
-	zipfSource := xrand.NewSource(uint64(time.Now().UnixNano()))
-	zipfRng := xrand.New(zipfSource)
-	zipf := xrand.NewZipf(zipfRng, zipfS, zipfV, uint64(numKeys-1))
-
-	// Generator for read/write ratio from math/rand
-	ratioRng := rand.New(rand.NewSource(time.Now().UnixNano()))
+		// Generator for read/write ratio from math/rand
+		ratioRng := rand.New(rand.NewSource(seed))
 
-	for i := 0; i < numOps; i++ {
-		key := fmt.Sprintf("key-%d", zipf.Uint64())
-		opType := ReadOp
-		if ratioRng.Float64() > readWriteRatio {
-			opType = WriteOp
+		for i := range chunk {
+			// Zipf draws 0 most often, so key-0 is the most popular key.
+			n := draw()
+			opType := ReadOp
+			if ratioRng.Float64() > readWriteRatio {
+				opType = WriteOp
+			}
+			chunk[i] = Operation{
+				Type: opType,
+				Key:  keyName(n),
+				Rank: n + 1,
+			}
 		}
-		ops[i] = Operation{
-			Type: opType,
-			Key:  key,
-		}
-	}
+	})
 	return ops
 }
 
 // GenerateUniform generates a workload where every key has an equal probability of being accessed.
 // This represents a worst-case scenario for caching.
-func GenerateUniform(numOps, numKeys int, readWriteRatio float64) []Operation {
+func GenerateUniform(numOps, numKeys int, readWriteRatio float64, seed int64) []Operation {
 	ops := make([]Operation, numOps)
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	keyName := keyNamer(numKeys, numOps)
+	generateChunks(ops, seed, func(chunk []Operation, seed int64) {
+		rng := rand.New(rand.NewSource(seed))
+		for i := range chunk {
+			key := keyName(rng.Intn(numKeys))
+			opType := ReadOp
+			if rng.Float64() > readWriteRatio {
+				opType = WriteOp
+			}
+			chunk[i] = Operation{
+				Type: opType,
+				Key:  key,
+			}
+		}
+	})
+	return ops
+}
+
+// GenerateHotKey generates a read-only workload that targets a single key.
+// Combined with periodic invalidation of that key it models a cache stampede.
+func GenerateHotKey(numOps int, key string) []Operation {
+	ops := make([]Operation, numOps)
+	for i := range ops {
+		ops[i] = Operation{Type: ReadOp, Key: key}
+	}
+	return ops
+}
+
+// WithAbsentReads redirects a fraction of the read operations in ops to keys
+// that are never populated, drawn uniformly from numAbsentKeys "missing-%d"
+// keys. It models miss storms for absent data and modifies ops in place.
+func WithAbsentReads(ops []Operation, fraction float64, numAbsentKeys int, seed int64) []Operation {
+	rng := rand.New(rand.NewSource(seed))
+	for i := range ops {
+		if ops[i].Type == ReadOp && rng.Float64() < fraction {
+			ops[i].Key = fmt.Sprintf("missing-%d", rng.Intn(numAbsentKeys))
+			ops[i].Rank = 0
+		}
+	}
+	return ops
+}
 
-	for i := 0; i < numOps; i++ {
-		key := fmt.Sprintf("key-%d", rng.Intn(numKeys))
-		opType := ReadOp
-		if rng.Float64() > readWriteRatio {
-			opType = WriteOp
+// WithRangeReads turns a fraction of the read operations in ops into range
+// reads of length bytes at offsets drawn uniformly from values of valueSize
+// bytes. It models clients that need only a fragment of a large value and
+// modifies ops in place.
+func WithRangeReads(ops []Operation, fraction float64, valueSize, length int, seed int64) []Operation {
+	rng := rand.New(rand.NewSource(seed))
+	for i := range ops {
+		if ops[i].Type == ReadOp && rng.Float64() < fraction {
+			ops[i].Type = RangeReadOp
+			ops[i].Offset = rng.Intn(valueSize - length + 1)
+			ops[i].Length = length
 		}
-		ops[i] = Operation{
-			Type: opType,
-			Key:  key,
+	}
+	return ops
+}
+
+// WithRMW turns a fraction of the write operations in ops into
+// read-modify-writes, modelling counters and other updates derived from the
+// current value. It modifies ops in place.
+func WithRMW(ops []Operation, fraction float64, seed int64) []Operation {
+	rng := rand.New(rand.NewSource(seed))
+	for i := range ops {
+		if ops[i].Type == WriteOp && rng.Float64() < fraction {
+			ops[i].Type = RMWOp
+		}
+	}
+	return ops
+}
+
+// WithScans turns a fraction of the read operations in ops into scans of up
+// to limit keys prefixed by the key read: a popular "key-1" also scans
+// "key-10" to "key-19" and so on, as a feed or session read fetches a
+// handful of related entries. It modifies ops in place.
+func WithScans(ops []Operation, fraction float64, limit int, seed int64) []Operation {
+	rng := rand.New(rand.NewSource(seed))
+	for i := range ops {
+		if ops[i].Type == ReadOp && rng.Float64() < fraction {
+			ops[i].Type = ScanOp
+			ops[i].Length = limit
 		}
 	}
 	return ops
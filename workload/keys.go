@@ -0,0 +1,81 @@
+package workload
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// KeyDeriver turns a logical key into the cache key an application would
+// actually use, e.g. by hashing a request URL built from it. The runner calls
+// it inside the measured path, so key construction cost is part of latency.
+type KeyDeriver interface {
+	Derive(key string) string
+}
+
+// NewKeyDeriver returns the deriver for kind:
+//   - "url-raw": the request URL itself is the key
+//   - "url-fnv": FNV-1a 64 of the request URL, hex encoded
+//   - "url-sha256": SHA-256 of the request URL, hex encoded
+//
+// paramBytes pads the URL's query string to model larger requests.
+func NewKeyDeriver(kind string, paramBytes int) (KeyDeriver, error) {
+	d := urlKeyDeriver{padding: strings.Repeat("x", paramBytes)}
+	switch kind {
+	case "url-raw":
+		d.hash = func(url string) string { return url }
+	case "url-fnv":
+		d.hash = func(url string) string {
+			h := fnv.New64a()
+			h.Write([]byte(url))
+			return strconv.FormatUint(h.Sum64(), 16)
+		}
+	case "url-sha256":
+		d.hash = func(url string) string {
+			sum := sha256.Sum256([]byte(url))
+			return hex.EncodeToString(sum[:])
+		}
+	default:
+		return nil, fmt.Errorf("unknown key derivation %q (want url-raw, url-fnv or url-sha256)", kind)
+	}
+	return d, nil
+}
+
+type urlKeyDeriver struct {
+	padding string
+	hash    func(url string) string
+}
+
+func (d urlKeyDeriver) Derive(key string) string {
+	var b strings.Builder
+	b.Grow(96 + len(key) + len(d.padding))
+	b.WriteString("https://api.example.com/v1/items/")
+	b.WriteString(key)
+	b.WriteString("?fields=id,name,price,stock&locale=en-US&currency=USD&page=1")
+	if d.padding != "" {
+		b.WriteString("&filter=")
+		b.WriteString(d.padding)
+	}
+	return d.hash(b.String())
+}
+
+// WithPrefix returns a deriver that namespaces the keys of d, or the logical
+// keys themselves when d is nil, under prefix.
+func WithPrefix(d KeyDeriver, prefix string) KeyDeriver {
+	return prefixDeriver{next: d, prefix: prefix}
+}
+
+type prefixDeriver struct {
+	next   KeyDeriver
+	prefix string
+}
+
+func (d prefixDeriver) Derive(key string) string {
+	if d.next != nil {
+		key = d.next.Derive(key)
+	}
+	return d.prefix + key
+}
@@ -0,0 +1,159 @@
+package workload
+
+import (
+	"fmt"
+	"math/rand"
+
+	xrand "golang.org/x/exp/rand"
+)
+
+// Distributions a Component draws its keys from.
+const (
+	// DistZipf draws keys with a Zipf distribution, the component's first
+	// key being the most popular.
+	DistZipf = "zipf"
+	// DistUniform draws every key of the component with equal probability.
+	DistUniform = "uniform"
+	// DistSequential walks the component's keys in order, wrapping around,
+	// as a background scan or batch job touches each key once per pass.
+	DistSequential = "sequential"
+)
+
+// Component is one source of traffic in a composite workload.
+type Component struct {
+	Name string
+	// Weight is the component's share of the operations, relative to the
+	// weights of the other components.
+	Weight float64
+	// Distribution is DistZipf, DistUniform or DistSequential.
+	Distribution string
+	// NumKeys is the size of the component's keyspace. Components own
+	// consecutive ranges of keys in the order they are listed.
+	NumKeys        int
+	ReadWriteRatio float64
+	// ZipfS and ZipfV parameterize DistZipf.
+	ZipfS float64
+	ZipfV float64
+}
+
+// ValidateMix checks that components describe a workload over at most
+// numKeys keys.
+func ValidateMix(components []Component, numKeys int) error {
+	total := 0
+	names := make(map[string]bool)
+	for _, c := range components {
+		if c.Weight <= 0 || c.NumKeys <= 0 {
+			return fmt.Errorf("workload component %q needs a positive weight and key count", c.Name)
+		}
+		if c.ReadWriteRatio < 0 || c.ReadWriteRatio > 1 {
+			return fmt.Errorf("workload component %q has read/write ratio %v outside [0, 1]", c.Name, c.ReadWriteRatio)
+		}
+		if names[c.Name] {
+			return fmt.Errorf("workload component %q is listed twice", c.Name)
+		}
+		names[c.Name] = true
+		switch c.Distribution {
+		case DistZipf:
+			if c.ZipfS <= 1 || c.ZipfV < 1 {
+				return fmt.Errorf("workload component %q needs Zipf s > 1 and v >= 1", c.Name)
+			}
+		case DistUniform, DistSequential:
+		default:
+			return fmt.Errorf("workload component %q has unknown distribution %q", c.Name, c.Distribution)
+		}
+		total += c.NumKeys
+	}
+	if total > numKeys {
+		return fmt.Errorf("workload components span %d keys, more than the %d populated", total, numKeys)
+	}
+	return nil
+}
+
+// MixComponentOf returns, for each of the first numKeys keys, the index of
+// the component owning it, or -1 for keys no component draws from.
+func MixComponentOf(components []Component, numKeys int) []int {
+	owner := make([]int, numKeys)
+	first := 0
+	for i := range owner {
+		owner[i] = -1
+	}
+	for ci, c := range components {
+		for k := first; k < min(first+c.NumKeys, numKeys); k++ {
+			owner[k] = ci
+		}
+		first += c.NumKeys
+	}
+	return owner
+}
+
+// MixReadRatio returns the fraction of a mix's operations that are reads.
+func MixReadRatio(components []Component) float64 {
+	var reads, total float64
+	for _, c := range components {
+		reads += c.Weight * c.ReadWriteRatio
+		total += c.Weight
+	}
+	if total == 0 {
+		return 0
+	}
+	return reads / total
+}
+
+// GenerateMix generates a workload interleaving the traffic of several
+// components, each picked for an operation with probability proportional
+// to its weight. A key's popularity across components is unknown, so
+// operations are not ranked. The same seed always yields the same sequence
+// of operations.
+func GenerateMix(numOps int, components []Component, seed int64) []Operation {
+	type source struct {
+		first int
+		next  func() int
+		pass  int
+	}
+	rng := rand.New(rand.NewSource(seed))
+	sources := make([]source, len(components))
+	var totalWeight float64
+	first := 0
+	for i, c := range components {
+		src := &sources[i]
+		src.first = first
+		switch c.Distribution {
+		case DistZipf:
+			zipf := xrand.NewZipf(xrand.New(xrand.NewSource(uint64(seed)+uint64(i))), c.ZipfS, c.ZipfV, uint64(c.NumKeys-1))
+			src.next = func() int { return int(zipf.Uint64()) }
+		case DistUniform:
+			src.next = func() int { return rng.Intn(c.NumKeys) }
+		case DistSequential:
+			src.next = func() int {
+				k := src.pass % c.NumKeys
+				src.pass++
+				return k
+			}
+		}
+		totalWeight += c.Weight
+		first += c.NumKeys
+	}
+
+	// The mix is generated sequentially: sequential components carry their
+	// position from one operation to the next.
+	keyName := keyNamer(first, numOps)
+	ops := make([]Operation, numOps)
+	for i := range ops {
+		u := rng.Float64() * totalWeight
+		ci := len(components) - 1
+		for j, c := range components {
+			if u < c.Weight {
+				ci = j
+				break
+			}
+			u -= c.Weight
+		}
+		c, src := components[ci], &sources[ci]
+		op := Operation{Type: ReadOp, Key: keyName(src.first + src.next())}
+		if rng.Float64() > c.ReadWriteRatio {
+			op.Type = WriteOp
+		}
+		ops[i] = op
+	}
+	return ops
+}
@@ -0,0 +1,59 @@
+package workload
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// chunkOps is the number of operations generated by each goroutine. It is
+// fixed, not derived from the CPU count, so a seed yields the same workload
+// on every host; workloads of up to chunkOps operations are generated
+// exactly as they were before generation was parallel.
+const chunkOps = 1 << 20
+
+// generateChunks fills ops by calling fill on consecutive chunks of it
+// concurrently. Each chunk gets its own seed derived from seed, the first
+// chunk seed itself.
+func generateChunks(ops []Operation, seed int64, fill func(chunk []Operation, seed int64)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	for c, start := 0, 0; start < len(ops); c, start = c+1, start+chunkOps {
+		chunk := ops[start:min(start+chunkOps, len(ops))]
+		chunkSeed := seed
+		if c > 0 {
+			// SplitMix64's increment spreads the chunk seeds apart.
+			chunkSeed = seed + int64(uint64(c)*0x9e3779b97f4a7c15)
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			fill(chunk, chunkSeed)
+			<-sem
+		}()
+	}
+	wg.Wait()
+}
+
+// keyNamer returns a function that names logical key n "key-<n>". When the
+// workload references most keys, the names are formatted once up front and
+// shared by every operation on the key instead of allocated per operation.
+func keyNamer(numKeys, numOps int) func(n int) string {
+	if numKeys > numOps {
+		return func(n int) string { return fmt.Sprintf("key-%d", n) }
+	}
+	names := make([]string, numKeys)
+	var wg sync.WaitGroup
+	for start := 0; start < numKeys; start += chunkOps {
+		wg.Add(1)
+		go func(start int) {
+			defer wg.Done()
+			for n := start; n < min(start+chunkOps, numKeys); n++ {
+				names[n] = fmt.Sprintf("key-%d", n)
+			}
+		}(start)
+	}
+	wg.Wait()
+	return func(n int) string { return names[n] }
+}
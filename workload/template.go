@@ -0,0 +1,166 @@
+package workload
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultKeyTemplate renders keys as the generator names them.
+const DefaultKeyTemplate = "key-{id}"
+
+// keyPad fills keys up to a template's fixed length.
+const keyPad = 'x'
+
+// NewKeyTemplate returns a deriver that renders logical keys through
+// template, modeling an application's key naming, e.g. "user:{id}:profile".
+// Placeholders are:
+//   - "{id}": the logical key's number, so "key-42" renders as 42
+//   - "{id:N}": the number zero-padded to N digits
+//   - "{pad}": filler bringing the key to keyBytes bytes
+//
+// Keys not named "key-<n>", such as never-populated "missing-<n>" keys,
+// replace {id} with the whole logical key, so they stay distinct from
+// populated ones. keyBytes, when positive, pads every shorter key to that
+// length; without {pad} the filler goes at the end.
+// numKeys is the number of populated keys, used to check that keyBytes
+// leaves room for the longest.
+func NewKeyTemplate(template string, keyBytes, numKeys int) (KeyDeriver, error) {
+	t := keyTemplate{keyBytes: keyBytes}
+	rest := template
+	for rest != "" {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			t.parts = append(t.parts, templatePart{literal: rest})
+			break
+		}
+		if open > 0 {
+			t.parts = append(t.parts, templatePart{literal: rest[:open]})
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("key template %q: unclosed {", template)
+		}
+		name := rest[open+1 : open+end]
+		rest = rest[open+end+1:]
+		switch {
+		case name == "id":
+			t.parts = append(t.parts, templatePart{id: true})
+			t.hasID = true
+		case strings.HasPrefix(name, "id:"):
+			width, err := strconv.Atoi(name[len("id:"):])
+			if err != nil || width <= 0 {
+				return nil, fmt.Errorf("key template %q: invalid width in {%s}", template, name)
+			}
+			t.parts = append(t.parts, templatePart{id: true, width: width})
+			t.hasID = true
+		case name == "pad":
+			if t.hasPad {
+				return nil, fmt.Errorf("key template %q: more than one {pad}", template)
+			}
+			t.padAt, t.hasPad = len(t.parts), true
+		default:
+			return nil, fmt.Errorf("key template %q: unknown placeholder {%s} (want id, id:N or pad)", template, name)
+		}
+	}
+	if !t.hasID {
+		return nil, fmt.Errorf("key template %q has no {id}, so every key would be the same", template)
+	}
+	if !t.hasPad {
+		t.padAt = len(t.parts)
+	}
+	if keyBytes > 0 && numKeys > 0 {
+		if n := t.length(strconv.Itoa(numKeys - 1)); n > keyBytes {
+			return nil, fmt.Errorf("key template %q renders %d-byte keys, longer than the %d-byte key size", template, n, keyBytes)
+		}
+	}
+	return t, nil
+}
+
+// TemplatePrefix returns the literal text template starts with, which
+// every key it renders shares.
+func TemplatePrefix(template string) string {
+	if i := strings.IndexByte(template, '{'); i >= 0 {
+		return template[:i]
+	}
+	return template
+}
+
+type templatePart struct {
+	literal string
+	id      bool
+	width   int
+}
+
+type keyTemplate struct {
+	parts    []templatePart
+	hasID    bool
+	hasPad   bool
+	padAt    int
+	keyBytes int
+}
+
+func (t keyTemplate) Derive(key string) string {
+	if n, ok := strings.CutPrefix(key, "key-"); ok && isDigits(n) {
+		return t.render(n)
+	}
+	return t.render(key)
+}
+
+// length is the length of the key for id before padding.
+func (t keyTemplate) length(id string) int {
+	n := 0
+	for _, p := range t.parts {
+		switch {
+		case !p.id:
+			n += len(p.literal)
+		case isDigits(id):
+			n += max(p.width, len(id))
+		default:
+			n += len(id)
+		}
+	}
+	return n
+}
+
+// render builds the key for id, zero-padding numeric ids to their width
+// and the key to keyBytes.
+func (t keyTemplate) render(id string) string {
+	n := t.length(id)
+	pad := max(t.keyBytes-n, 0)
+	var b strings.Builder
+	b.Grow(n + pad)
+	for i, p := range t.parts {
+		if i == t.padAt {
+			writeRepeat(&b, keyPad, pad)
+		}
+		switch {
+		case !p.id:
+			b.WriteString(p.literal)
+		case isDigits(id):
+			writeRepeat(&b, '0', p.width-len(id))
+			b.WriteString(id)
+		default:
+			b.WriteString(id)
+		}
+	}
+	if t.padAt == len(t.parts) {
+		writeRepeat(&b, keyPad, pad)
+	}
+	return b.String()
+}
+
+func writeRepeat(b *strings.Builder, c byte, n int) {
+	for range n {
+		b.WriteByte(c)
+	}
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
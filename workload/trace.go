@@ -0,0 +1,54 @@
+package workload
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// LoadTrace reads a recorded operation trace. Each non-empty line holds an
+// operation and a key separated by whitespace, e.g. "GET user:42". GET/READ
+// map to ReadOp and SET/WRITE to WriteOp, case-insensitively. Lines starting
+// with '#' are comments.
+func LoadTrace(r io.Reader) ([]Operation, error) {
+	var ops []Operation
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("trace line %d: expected \"<op> <key>\", got %q", lineNo, line)
+		}
+		var opType OperationType
+		switch strings.ToUpper(fields[0]) {
+		case "GET", "READ":
+			opType = ReadOp
+		case "SET", "WRITE":
+			opType = WriteOp
+		default:
+			return nil, fmt.Errorf("trace line %d: unknown operation %q", lineNo, fields[0])
+		}
+		ops = append(ops, Operation{Type: opType, Key: fields[1]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+// Keys returns the distinct keys referenced by ops in first-seen order.
+func Keys(ops []Operation) []string {
+	seen := make(map[string]struct{})
+	var keys []string
+	for _, op := range ops {
+		if _, ok := seen[op.Key]; !ok {
+			seen[op.Key] = struct{}{}
+			keys = append(keys, op.Key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,40 @@
+package workload
+
+import (
+	"math"
+	"sort"
+
+	xrand "golang.org/x/exp/rand"
+)
+
+// ZipfProbabilities returns the probability with which Generate draws each
+// of numKeys keys, key-0 first: proportional to 1/(v+k)^s for key-k.
+func ZipfProbabilities(numKeys int, s, v float64) []float64 {
+	p := make([]float64, numKeys)
+	var sum float64
+	for k := range p {
+		p[k] = math.Pow(v+float64(k), -s)
+		sum += p[k]
+	}
+	for k := range p {
+		p[k] /= sum
+	}
+	return p
+}
+
+// zipfCDF draws Zipf-distributed keys by inverting the cumulative
+// distribution. Unlike x/exp/rand's Zipf, which needs s > 1, it supports
+// any s > 0, at the cost of a table of numKeys entries.
+type zipfCDF []float64
+
+func newZipfCDF(numKeys int, s, v float64) zipfCDF {
+	cdf := ZipfProbabilities(numKeys, s, v)
+	for k := 1; k < len(cdf); k++ {
+		cdf[k] += cdf[k-1]
+	}
+	return cdf
+}
+
+func (c zipfCDF) draw(rng *xrand.Rand) int {
+	return min(sort.SearchFloat64s(c, rng.Float64()), len(c)-1)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"caching-benchmark/workload"
+)
+
+// workloadGeneration is part of every cached workload's ID; bump it when
+// generation changes so stale files are regenerated instead of replayed.
+const workloadGeneration = 1
+
+// cachedWorkload returns the scenario's workload from cfg.WorkloadCache,
+// generating and saving it there on first use. A cache that cannot be read
+// or written only costs the generation time it was meant to save.
+func cachedWorkload(cfg Config, seed int64) []workload.Operation {
+	path := filepath.Join(cfg.WorkloadCache, fmt.Sprintf("%016x.workload", workloadID(cfg, seed)))
+	w, err := loadWorkload(path)
+	if err == nil {
+		log.Printf("Loaded %d operations from %s", len(w), path)
+		return w
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("Failed to load cached workload %s, regenerating: %v", path, err)
+	}
+	w = buildWorkload(cfg, seed)
+	if err := saveWorkload(path, w); err != nil {
+		log.Printf("Failed to cache workload: %v", err)
+	}
+	return w
+}
+
+// workloadID fingerprints the settings buildWorkload reads.
+func workloadID(cfg Config, seed int64) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d\x00%d\x00%d\x00%d\x00%g\x00%g\x00%g\x00%v\x00%+v\x00",
+		workloadGeneration, seed, cfg.NumOperations, cfg.NumKeys, cfg.ReadWriteRatio, cfg.ZipfS, cfg.ZipfV,
+		cfg.StampedeInterval > 0, cfg.Mix)
+	fmt.Fprintf(h, "%v\x00%g\x00%g\x00%d\x00%d\x00%g\x00%g\x00%d",
+		cfg.Name == uniformScenario, cfg.AbsentReadFraction, cfg.RangeReadFraction, cfg.ValueSizeBytes, cfg.RangeReadBytes,
+		cfg.RMWFraction, cfg.ScanFraction, cfg.ScanLimit)
+	return h.Sum64()
+}
+
+func loadWorkload(path string) ([]workload.Operation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return workload.Load(bufio.NewReader(f))
+}
+
+// saveWorkload writes w to path through a temporary file, so concurrent or
+// interrupted benchmarks never leave a partial workload behind.
+func saveWorkload(path string, w []workload.Operation) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".workload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	buf := bufio.NewWriter(f)
+	if err := workload.Save(buf, w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
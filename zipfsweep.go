@@ -0,0 +1,327 @@
+package main
+
+import (
+	"caching-benchmark/benchmark"
+	"caching-benchmark/workload"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// skewTheory is the hit rate theory predicts for reads under one Zipf skew,
+// assuming independent draws from the workload's distribution.
+type skewTheory struct {
+	// che is the steady-state hit rate of an LRU L1 by Che's approximation.
+	che float64
+	// ideal is the hit rate of an L1 pinning the most popular keys it can
+	// hold, the best any eviction policy can achieve in steady state.
+	ideal float64
+	// firstReads is the fraction of reads of keys not referenced earlier in
+	// the workload, which every L1 misses when it starts empty; the
+	// steady-state predictions do not account for them.
+	firstReads float64
+}
+
+// skewPoint is one run of a skew sweep.
+type skewPoint struct {
+	s            float64
+	theory       skewTheory
+	hitRate      float64
+	backendPer1k float64
+	opsPerSec    float64
+}
+
+// skewCurve is the hit-rate-vs-skew curve of one strategy.
+type skewCurve struct {
+	strategy string
+	points   []skewPoint
+}
+
+// l1Capacity estimates how many of the scenario's values its L1 budget
+// holds, with the per-key overhead the CSC strategies size their caches by.
+func l1Capacity(cfg Config) int {
+	return int(l1Budget(cfg) / int64(cfg.ValueSizeBytes+50))
+}
+
+// runSkewSweep runs every strategy once per cfg.ZipfSkews, each time on a
+// workload regenerated with that skew, and compares the hit rates achieved
+// with the theoretical ones for the L1's capacity.
+func runSkewSweep(ctx context.Context, cfg Config, seed int64) ([]benchmark.Result, error) {
+	var results []benchmark.Result
+	var curves []skewCurve
+	capacity := l1Capacity(cfg)
+	defer func() {
+		printSkewCurves(curves, capacity)
+		if cfg.CurveDir != "" && len(curves) > 0 {
+			if err := writeSkewCurves(cfg.CurveDir, cfg.Name, curves); err != nil {
+				log.Printf("Failed to write skew curves: %v", err)
+			}
+		}
+	}()
+
+	workloads := make(map[float64][]workload.Operation, len(cfg.ZipfSkews))
+	theories := make(map[float64]skewTheory, len(cfg.ZipfSkews))
+	for _, spec := range strategySpecs(cfg) {
+		var curve skewCurve
+		for _, s := range cfg.ZipfSkews {
+			levelCfg := cfg
+			levelCfg.ZipfS = s
+			w, ok := workloads[s]
+			if !ok {
+				w = generateWorkload(levelCfg, seed)
+				workloads[s] = w
+				theories[s] = predictHitRates(w, workload.ZipfProbabilities(cfg.NumKeys, s, cfg.ZipfV), capacity)
+			}
+			theory := theories[s]
+
+			// Every run gets a fresh strategy: strategies are not reusable after Close.
+			ns, err := buildStrategy(levelCfg, spec)
+			if err != nil {
+				return results, err
+			}
+			log.Printf("\n--- Running Strategy: %s with Zipf s=%.2f (theoretical hit rate %.1f%% LRU, %.1f%% ideal) ---",
+				ns.strategy.Name(), s, theory.che*100, theory.ideal*100)
+			prep, err := prepareData(ctx, levelCfg, seed)
+			if err != nil {
+				return results, fmt.Errorf("failed to prepare data for strategy %s: %w", ns.strategy.Name(), err)
+			}
+			result, err := benchmark.NewRunner(ns.strategy, w, runnerOptions(levelCfg, ns, seed)).Run(ctx)
+			if err != nil {
+				log.Printf("Error running strategy %s with Zipf s=%.2f: %v", ns.strategy.Name(), s, err)
+				continue
+			}
+			result.Lifecycle.DataPrep = prep
+
+			curve.strategy = result.StrategyName
+			curve.points = append(curve.points, skewPoint{
+				s:            s,
+				theory:       theory,
+				hitRate:      result.HitRate,
+				backendPer1k: result.BackendRequestsPer1kOps,
+				opsPerSec:    result.OpsPerSecond,
+			})
+
+			result.StrategyName = fmt.Sprintf("%s [s=%.2f]", result.StrategyName, s)
+			results = append(results, result)
+			if result.Incomplete {
+				curves = append(curves, curve)
+				return results, nil
+			}
+		}
+		if len(curve.points) > 0 {
+			curves = append(curves, curve)
+		}
+	}
+	return results, nil
+}
+
+// predictHitRates computes the theoretical hit rates of an L1 holding
+// capacity keys under w, whose keys are drawn with probabilities p, most
+// popular first.
+func predictHitRates(w []workload.Operation, p []float64, capacity int) skewTheory {
+	t := skewTheory{che: cheHitRate(p, capacity), ideal: 1}
+	if capacity < len(p) {
+		t.ideal = 0
+		for _, pk := range p[:capacity] {
+			t.ideal += pk
+		}
+	}
+	seen := make(map[string]struct{}, len(p))
+	var reads, first int
+	for _, op := range w {
+		if _, ok := seen[op.Key]; !ok {
+			seen[op.Key] = struct{}{}
+			if op.Type == workload.ReadOp {
+				first++
+			}
+		}
+		if op.Type == workload.ReadOp {
+			reads++
+		}
+	}
+	if reads > 0 {
+		t.firstReads = float64(first) / float64(reads)
+	}
+	return t
+}
+
+// cheHitRate approximates the hit rate of an LRU cache of capacity keys
+// under independent draws with probabilities p. Che's approximation treats
+// the cache as holding every key requested within a characteristic time T,
+// chosen so that the expected number of such keys equals the capacity.
+func cheHitRate(p []float64, capacity int) float64 {
+	if capacity >= len(p) {
+		return 1
+	}
+	occupancy := func(t float64) float64 {
+		var n float64
+		for _, pk := range p {
+			n -= math.Expm1(-pk * t)
+		}
+		return n
+	}
+	// The occupancy at T=capacity is at most capacity, so the doubling
+	// always starts below the root.
+	lo, hi := 0.0, float64(capacity)
+	for occupancy(hi) < float64(capacity) {
+		lo, hi = hi, hi*2
+	}
+	for range 64 {
+		mid := (lo + hi) / 2
+		if occupancy(mid) < float64(capacity) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	var hit float64
+	for _, pk := range p {
+		hit -= pk * math.Expm1(-pk*hi)
+	}
+	return hit
+}
+
+// printSkewCurves prints one table per strategy with a row per skew.
+func printSkewCurves(curves []skewCurve, capacity int) {
+	for _, c := range curves {
+		log.Printf("\n--- Hit Rate vs Zipf Skew: %s (L1 holds ~%d keys) ---", c.strategy, capacity)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.AlignRight|tabwriter.Debug)
+		fmt.Fprintln(w, "Zipf s\tHit Rate (%)\tLRU (Che) (%)\tIdeal (%)\tGap to Ideal (pts)\tFirst-Reference Reads (%)\tBackend Req/1k Ops\tOps/sec\t")
+		for _, p := range c.points {
+			fmt.Fprintf(w, "%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.1f\t%.2f\t\n",
+				p.s,
+				p.hitRate*100,
+				p.theory.che*100,
+				p.theory.ideal*100,
+				(p.theory.ideal-p.hitRate)*100,
+				p.theory.firstReads*100,
+				p.backendPer1k,
+				p.opsPerSec,
+			)
+		}
+		w.Flush()
+	}
+}
+
+// writeSkewCurves writes the scenario's curves to dir as a CSV data file and
+// an SVG chart of hit rate against skew.
+func writeSkewCurves(dir, scenario string, curves []skewCurve) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	base := filepath.Join(dir, slug(scenario))
+
+	f, err := os.Create(base + ".csv")
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(f)
+	cw.Write([]string{"strategy", "zipf_s", "hit_rate", "che_hit_rate", "ideal_hit_rate", "first_reference_reads", "backend_requests_per_1k_ops", "ops_per_second"})
+	for _, c := range curves {
+		for _, p := range c.points {
+			cw.Write([]string{
+				c.strategy,
+				formatFloat(p.s),
+				formatFloat(p.hitRate),
+				formatFloat(p.theory.che),
+				formatFloat(p.theory.ideal),
+				formatFloat(p.theory.firstReads),
+				formatFloat(p.backendPer1k),
+				formatFloat(p.opsPerSec),
+			})
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(base+".svg", []byte(skewSVG(scenario, curves)), 0o644); err != nil {
+		return err
+	}
+	log.Printf("Skew curves written to %s.{csv,svg}", base)
+	return nil
+}
+
+// skewSVG renders hit rate against Zipf s, one line per strategy, over
+// dashed lines of the theoretical hit rates.
+func skewSVG(title string, curves []skewCurve) string {
+	const width, height, margin = 800.0, 500.0, 70.0
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	for _, c := range curves {
+		for _, p := range c.points {
+			minX, maxX = min(minX, p.s), max(maxX, p.s)
+		}
+	}
+	if maxX <= minX {
+		minX, maxX = minX-0.1, maxX+0.1
+	}
+	x := func(v float64) float64 { return margin + (v-minX)/(maxX-minX)*(width-2*margin) }
+	y := func(v float64) float64 { return height - margin - v*(height-2*margin) }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" font-family="sans-serif" font-size="12">`+"\n", width, height)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="white"/>`+"\n")
+	fmt.Fprintf(&b, `<text x="%.0f" y="25" text-anchor="middle" font-size="14">%s</text>`+"\n", width/2, svgEscape(title))
+	fmt.Fprintf(&b, `<line x1="%.0f" y1="%.0f" x2="%.0f" y2="%.0f" stroke="black"/>`+"\n", margin, height-margin, width-margin, height-margin)
+	fmt.Fprintf(&b, `<line x1="%.0f" y1="%.0f" x2="%.0f" y2="%.0f" stroke="black"/>`+"\n", margin, margin, margin, height-margin)
+	for i := 0; i <= 5; i++ {
+		vx, vy := minX+(maxX-minX)*float64(i)/5, float64(i)/5
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.0f" text-anchor="middle">%.2f</text>`+"\n", x(vx), height-margin+18, vx)
+		fmt.Fprintf(&b, `<text x="%.0f" y="%.1f" text-anchor="end">%.0f</text>`+"\n", margin-6, y(vy)+4, vy*100)
+	}
+	fmt.Fprintf(&b, `<text x="%.0f" y="%.0f" text-anchor="middle">Zipf s</text>`+"\n", width/2, height-20)
+	fmt.Fprintf(&b, `<text transform="translate(18,%.0f) rotate(-90)" text-anchor="middle">Hit rate (%%)</text>`+"\n", height/2)
+
+	polyline := func(points []skewPoint, value func(skewPoint) float64) string {
+		var pts []string
+		for _, p := range points {
+			pts = append(pts, fmt.Sprintf("%.1f,%.1f", x(p.s), y(value(p))))
+		}
+		return strings.Join(pts, " ")
+	}
+	legend := func(i int, color, dash, label string) {
+		ly := margin + float64(i)*18
+		fmt.Fprintf(&b, `<line x1="%.0f" y1="%.0f" x2="%.0f" y2="%.0f" stroke="%s" stroke-width="2" stroke-dasharray="%s"/>`+"\n",
+			margin+10, ly+6, margin+24, ly+6, color, dash)
+		fmt.Fprintf(&b, `<text x="%.0f" y="%.0f">%s</text>`+"\n", margin+30, ly+10, svgEscape(label))
+	}
+	// Every strategy ran the same workloads, so the theory is taken from
+	// the curve with the most points.
+	if len(curves) > 0 {
+		theory := curves[0].points
+		for _, c := range curves[1:] {
+			if len(c.points) > len(theory) {
+				theory = c.points
+			}
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="black" stroke-width="1.5" stroke-dasharray="6,4" points="%s"/>`+"\n",
+			polyline(theory, func(p skewPoint) float64 { return p.theory.ideal }))
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="gray" stroke-width="1.5" stroke-dasharray="2,3" points="%s"/>`+"\n",
+			polyline(theory, func(p skewPoint) float64 { return p.theory.che }))
+		legend(0, "black", "6,4", "Ideal (top keys pinned)")
+		legend(1, "gray", "2,3", "LRU (Che approximation)")
+	}
+	for i, c := range curves {
+		color := curveColors[i%len(curveColors)]
+		pts := polyline(c.points, func(p skewPoint) float64 { return p.hitRate })
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`+"\n", color, pts)
+		for _, pt := range strings.Fields(pts) {
+			cx, cy, _ := strings.Cut(pt, ",")
+			fmt.Fprintf(&b, `<circle cx="%s" cy="%s" r="3" fill="%s"/>`+"\n", cx, cy, color)
+		}
+		legend(i+2, color, "none", c.strategy)
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}